@@ -58,6 +58,7 @@ export function AuthProvider({ children }) {
   const canViewOwnExamHistory = permissionSet.has("system.exam_history.view");
   const canViewExamHistory = canViewAllExamHistory || canViewOwnExamHistory;
   const canViewSummary = permissionSet.has("system.report.view");
+  const canModerateQnA = permissionSet.has("qna.moderate");
 
   const visibleSidebarTabs = useMemo(() => {
     if (!currentUser) return null;
@@ -274,6 +275,7 @@ export function AuthProvider({ children }) {
     canViewOwnExamHistory,
     canViewExamHistory,
     canViewSummary,
+    canModerateQnA,
     visibleSidebarTabs,
     handleLoginFromBackend,
     handleRegisterFromBackend,
@@ -26,6 +26,7 @@ import {
   deleteCourseAttachmentApi,
 } from "../services/mediaApiService";
 import { normalizeExampleRecord, toCourseDraft } from "../services/courseService";
+import { fetchSkillsApi } from "../services/courseApiService";
 import { useAuth } from "../contexts/AuthContext";
 import { useAppData } from "../contexts/AppDataContext";
 import AllowedUsernameInput from "../components/shared/AllowedUsernameInput";
@@ -140,6 +141,13 @@ export default function EditorPage() {
   const selectedSubtopic = subtopicPages.find((subtopic) => subtopic.id === activeSubtopicId) ?? subtopicPages[0];
   const selectedSubtopicBody = selectedSubtopic?.bodyMarkdown ?? "";
   const skillRewards = useMemo(() => getSkillRewards(draft), [draft]);
+  const [skillCatalog, setSkillCatalog] = useState([]);
+
+  useEffect(() => {
+    fetchSkillsApi()
+      .then(({ skills }) => setSkillCatalog(skills))
+      .catch(() => {});
+  }, []);
 
   // Clear CodeMirror ref when switching to visual mode so insertAtCursor falls back
   useEffect(() => {
@@ -666,6 +674,7 @@ export default function EditorPage() {
               <div key={`skill-reward-${index}`} className="editor-skill-row">
                 <input
                   value={reward.skill}
+                  list="editor-skill-catalog"
                   onChange={(event) =>
                     updateSkillRewardAt(index, { ...reward, skill: event.target.value })
                   }
@@ -689,6 +698,11 @@ export default function EditorPage() {
         ) : (
           <p className="toc-empty" style={{ padding: "12px 16px" }}>ยังไม่ได้เพิ่มแท็กทักษะ</p>
         )}
+        <datalist id="editor-skill-catalog">
+          {skillCatalog.map((skill) => (
+            <option key={skill.id} value={skill.name}>{skill.category}</option>
+          ))}
+        </datalist>
       </div>
 
       <div className="editor-skill-card">
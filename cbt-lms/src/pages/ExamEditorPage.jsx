@@ -5,6 +5,7 @@ import { parseExamUploadJson } from "../services/examService";
 import { useAppData } from "../contexts/AppDataContext";
 import { useAuth } from "../contexts/AuthContext";
 import AllowedUsernameInput from "../components/shared/AllowedUsernameInput";
+import { fetchSkillsApi } from "../services/courseApiService";
 
 const toDomainRows = (domainPercentages) => {
   const entries = Object.entries(domainPercentages ?? {});
@@ -17,6 +18,13 @@ const toDomainRows = (domainPercentages) => {
   }));
 };
 
+const toRewardRows = (skillRewards) =>
+  (Array.isArray(skillRewards) ? skillRewards : []).map((reward) => ({
+    skill: reward.skill ?? "",
+    domain: reward.domain ?? "",
+    points: Number(reward.points ?? 0),
+  }));
+
 const toQuestions = (questions) => {
   if (!Array.isArray(questions) || !questions.length) {
     return [
@@ -51,6 +59,8 @@ export default function ExamEditorPage() {
   const draft = examEditorDraft;
   const [exam, setExam] = useState(draft);
   const [domainRows, setDomainRows] = useState(() => toDomainRows(draft.domainPercentages));
+  const [rewardRows, setRewardRows] = useState(() => toRewardRows(draft.skillRewards));
+  const [skillCatalog, setSkillCatalog] = useState([]);
   const [questions, setQuestions] = useState(() => toQuestions(draft.questions));
   const [selectedQuestionIndex, setSelectedQuestionIndex] = useState(0);
   const [importStatus, setImportStatus] = useState({ type: "", message: "" });
@@ -62,17 +72,35 @@ export default function ExamEditorPage() {
   useEffect(() => {
     setExam(draft);
     setDomainRows(toDomainRows(draft.domainPercentages));
+    setRewardRows(toRewardRows(draft.skillRewards));
     setQuestions(toQuestions(draft.questions));
     setSelectedQuestionIndex(0);
     setImportStatus({ type: "", message: "" });
   }, [draft]);
 
+  useEffect(() => {
+    fetchSkillsApi()
+      .then(({ skills }) => setSkillCatalog(skills))
+      .catch(() => {});
+  }, []);
+
   useEffect(() => {
     if (!saveToast) return;
     const timer = setTimeout(() => setSaveToast(""), 3000);
     return () => clearTimeout(timer);
   }, [saveToast]);
 
+  const rewardDomains = useMemo(
+    () => [...new Set(domainRows.map((row) => String(row.domain ?? "").trim()).filter(Boolean))],
+    [domainRows],
+  );
+
+  const updateRewardRow = (index, field, value) => {
+    setRewardRows((prev) =>
+      prev.map((entry, entryIndex) => (entryIndex === index ? { ...entry, [field]: value } : entry)),
+    );
+  };
+
   const domainTotal = useMemo(
     () => domainRows.reduce((sum, row) => sum + Number(row.percent || 0), 0),
     [domainRows],
@@ -175,6 +203,10 @@ export default function ExamEditorPage() {
       numberOfQuestions: Number(exam.numberOfQuestions ?? 0),
       defaultTime: Number(exam.defaultTime ?? 0),
       maxAttempts: Number(exam.maxAttempts ?? 0),
+      completionScore: Math.max(0, Number(exam.completionScore ?? 0)),
+      skillRewards: rewardRows
+        .map((row) => ({ skill: String(row.skill ?? "").trim(), domain: row.domain ?? "", points: Number(row.points ?? 0) }))
+        .filter((row) => row.skill && row.points > 0),
       questions: normalizedQuestions,
     });
 
@@ -554,6 +586,71 @@ export default function ExamEditorPage() {
         </div>
       </div>
 
+      <div className="editor-skill-card">
+        <div className="editor-skill-head">
+          <h3>รางวัลเมื่อสอบผ่าน</h3>
+          <button
+            type="button"
+            className="create-content-button"
+            onClick={() => setRewardRows((prev) => [...prev, { skill: "", domain: "", points: 10 }])}
+          >
+            + เพิ่มทักษะ
+          </button>
+        </div>
+        <p style={{ fontSize: "0.85rem", color: "#4a6590", marginBottom: "0.5rem", padding: "4px 16px 0" }}>
+          ให้คะแนนครั้งเดียวเมื่อผู้เรียนสอบผ่านเป็นครั้งแรก
+          <br />
+          ทักษะที่ผูกกับ Domain จะได้คะแนนตามสัดส่วนที่ตอบถูกใน Domain นั้น
+        </p>
+        <div className="editor-title-box" style={{ padding: "0 16px" }}>
+          <label htmlFor="exam-completion-score">คะแนนรวมเมื่อสอบผ่าน</label>
+          <input
+            id="exam-completion-score"
+            type="number"
+            min={0}
+            value={Number(exam.completionScore ?? 0)}
+            onChange={(event) => setExam((prev) => ({ ...prev, completionScore: Number(event.target.value) }))}
+          />
+        </div>
+        <div className="editor-skill-grid">
+          {rewardRows.map((row, index) => (
+            <div key={`reward-${index}`} className="editor-skill-row">
+              <input
+                value={row.skill}
+                list="exam-skill-catalog"
+                onChange={(event) => updateRewardRow(index, "skill", event.target.value)}
+                placeholder="เช่น Log Analysis"
+              />
+              <select value={row.domain} onChange={(event) => updateRewardRow(index, "domain", event.target.value)}>
+                <option value="">ทั้งข้อสอบ</option>
+                {rewardDomains.map((domain) => (
+                  <option key={domain} value={domain}>{domain}</option>
+                ))}
+              </select>
+              <input
+                type="number"
+                min={1}
+                value={Number(row.points ?? 0)}
+                onChange={(event) => updateRewardRow(index, "points", Number(event.target.value))}
+                placeholder="คะแนน"
+              />
+              <button
+                type="button"
+                className="toc-delete-button"
+                onClick={() => setRewardRows((prev) => prev.filter((_, entryIndex) => entryIndex !== index))}
+              >
+                ลบ
+              </button>
+            </div>
+          ))}
+        </div>
+        <datalist id="exam-skill-catalog">
+          {skillCatalog.map((skill) => (
+            <option key={skill.id} value={skill.name}>{skill.category}</option>
+          ))}
+        </datalist>
+      </div>
+
       <div className="editor-skill-card">
         <div className="editor-skill-head">
           <h3>Questions</h3>
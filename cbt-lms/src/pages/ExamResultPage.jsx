@@ -151,6 +151,12 @@ export default function ExamResultPage() {
           <p className="result-score-status" style={{ color: scoreColor }}>
             {scoreNum >= 90 ? "ยอดเยี่ยม" : scoreNum >= 70 ? "ผ่านเกณฑ์" : "ต้องพัฒนาเพิ่ม"}
           </p>
+          {result.reward && (result.reward.points > 0 || result.reward.skillRewards?.length > 0) && (
+            <p className="result-score-note">
+              ได้รับรางวัลสอบผ่านครั้งแรก: +{result.reward.points} คะแนน
+              {(result.reward.skillRewards ?? []).map((reward) => ` · ${reward.skill} +${reward.points}`).join("")}
+            </p>
+          )}
         </div>
       </div>
 
@@ -215,6 +215,7 @@ export default function ExamTakingPage() {
           totalQuestions: attempt?.totalQuestions ?? details.length,
           gradedTotal: gradedDetails.length,
           scorePercent: attempt?.scorePercent ?? 0,
+          reward: attempt?.reward ?? null,
           details,
           domainStats,
         },
@@ -1,6 +1,7 @@
-import { useState, useEffect, useCallback, useMemo } from "react";
+import { useState, useEffect, useCallback } from "react";
 import { fetchLeaderboardApi, fetchUserPublicProfileApi } from "../services/courseApiService";
-import { getAvatarColor, getInitials } from "../utils/avatar";
+import { avatarSrc, getAvatarColor, getInitials } from "../utils/avatar";
+import { getPageNumbers } from "../utils/pagination";
 import { getLevel, getLevelProgress, pointsToNext } from "../utils/level";
 
 function LevelBadge({ score, size = "sm" }) {
@@ -47,7 +48,7 @@ function AvatarCell({ name, username, avatarUrl }) {
         style={{ background: avatarUrl ? "transparent" : color }}
       >
         {avatarUrl ? (
-          <img src={avatarUrl} alt={name} className="leaderboard-avatar-img" />
+          <img src={avatarSrc(avatarUrl)} alt={name} className="leaderboard-avatar-img" />
         ) : (
           <span className="leaderboard-avatar-initials">{initials}</span>
         )}
@@ -96,7 +97,7 @@ function ProfileModal({ username, onClose }) {
                 style={{ background: profile.avatarUrl ? "transparent" : color }}
               >
                 {profile.avatarUrl ? (
-                  <img src={profile.avatarUrl} alt={profile.name} className="profile-modal-avatar-img" />
+                  <img src={avatarSrc(profile.avatarUrl)} alt={profile.name} className="profile-modal-avatar-img" />
                 ) : (
                   <span className="profile-modal-avatar-initials">{initials}</span>
                 )}
@@ -138,6 +139,20 @@ function ProfileModal({ username, onClose }) {
                 </div>
               </div>
             )}
+
+            {profile.badges?.length > 0 && (
+              <div className="profile-modal-skills">
+                <h3 className="profile-skills-title">เหรียญรางวัล</h3>
+                <div className="profile-skills-grid">
+                  {profile.badges.map((badge) => (
+                    <div key={badge.badgeId} className="profile-skill-item" title={badge.description}>
+                      {badge.imageUrl && <img src={badge.imageUrl} alt="" width={32} height={32} />}
+                      <span className="profile-skill-name">{badge.name}</span>
+                    </div>
+                  ))}
+                </div>
+              </div>
+            )}
           </>
         )}
       </div>
@@ -147,23 +162,40 @@ function ProfileModal({ username, onClose }) {
 
 const LIMIT_OPTIONS = [10, 50, 100];
 
+const PERIOD_OPTIONS = [
+  { value: "all", label: "ตลอดกาล" },
+  { value: "month", label: "เดือนนี้" },
+  { value: "week", label: "สัปดาห์นี้" },
+];
+
+const formatComputedAt = (value) =>
+  value ? new Date(value).toLocaleString("th-TH", { dateStyle: "medium", timeStyle: "short" }) : "";
+
 export default function LeaderboardPage() {
   const [ranking, setRanking] = useState([]);
+  const [me, setMe] = useState(null);
+  const [board, setBoard] = useState(null);
+  const [pagination, setPagination] = useState({ page: 1, total_pages: 1, total: 0 });
   const [loading, setLoading] = useState(true);
   const [selectedUsername, setSelectedUsername] = useState(null);
+  const [period, setPeriod] = useState("all");
   const [limit, setLimit] = useState(10);
+  const [page, setPage] = useState(1);
 
   useEffect(() => {
-    fetchLeaderboardApi()
-      .then(setRanking)
+    setLoading(true);
+    fetchLeaderboardApi({ period, page, limit })
+      .then((result) => {
+        setRanking(result.leaderboard);
+        setMe(result.me);
+        setBoard(result.board);
+        setPagination(result.pagination);
+      })
       .catch(() => {})
       .finally(() => setLoading(false));
-  }, []);
+  }, [period, page, limit]);
 
-  const displayed = useMemo(
-    () => (limit === 0 ? ranking : ranking.slice(0, limit)),
-    [ranking, limit],
-  );
+  const { page: currentPage, total_pages: totalPages } = pagination;
 
   return (
     <section className="workspace-content">
@@ -171,23 +203,41 @@ export default function LeaderboardPage() {
         <div>
           <h1>ลีดเดอร์บอร์ด</h1>
           <p>คะแนนรวมจากการตอบคำถามและการเรียนจบเนื้อหา</p>
+          {board?.computed_at && (
+            <p className="leaderboard-computed-at">อัปเดตล่าสุด {formatComputedAt(board.computed_at)}</p>
+          )}
         </div>
         <div className="leaderboard-limit-control">
+          <label htmlFor="lb-period">ช่วงเวลา</label>
+          <select
+            id="lb-period"
+            value={period}
+            onChange={(e) => { setPeriod(e.target.value); setPage(1); }}
+          >
+            {PERIOD_OPTIONS.map((option) => (
+              <option key={option.value} value={option.value}>{option.label}</option>
+            ))}
+          </select>
           <label htmlFor="lb-limit">แสดง</label>
           <select
             id="lb-limit"
             value={limit}
-            onChange={(e) => setLimit(Number(e.target.value))}
+            onChange={(e) => { setLimit(Number(e.target.value)); setPage(1); }}
           >
             {LIMIT_OPTIONS.map((n) => (
-              <option key={n} value={n}>Top {n}</option>
+              <option key={n} value={n}>{n}</option>
             ))}
-            <option value={0}>ทั้งหมด ({ranking.length})</option>
           </select>
-          <span>คน</span>
+          <span>คนต่อหน้า</span>
         </div>
       </header>
 
+      {me && (
+        <p className="leaderboard-my-rank">
+          อันดับของคุณ: <strong>#{me.rank}</strong> ({me.score} คะแนน)
+        </p>
+      )}
+
       <div className="leaderboard-card">
         {loading ? (
           <p>กำลังโหลด...</p>
@@ -206,13 +256,13 @@ export default function LeaderboardPage() {
               </tr>
             </thead>
             <tbody>
-              {displayed.map((item, index) => (
+              {ranking.map((item) => (
                 <tr
                   key={item.username}
-                  className={["leaderboard-rank-1", "leaderboard-rank-2", "leaderboard-rank-3"][index] ?? ""}
+                  className={["leaderboard-rank-1", "leaderboard-rank-2", "leaderboard-rank-3"][item.rank - 1] ?? ""}
                 >
                   <td>
-                    <span className="leaderboard-rank-badge">{index + 1}</span>
+                    <span className="leaderboard-rank-badge">{item.rank}</span>
                   </td>
                   <td>
                     <button
@@ -228,7 +278,7 @@ export default function LeaderboardPage() {
                   <td><LevelBadge score={item.total_score} /></td>
                   <td>{item.solved_questions}</td>
                   <td>{item.completed_courses}</td>
-                  <td>{item.total_score}</td>
+                  <td>{item.score}</td>
                 </tr>
               ))}
             </tbody>
@@ -236,6 +286,31 @@ export default function LeaderboardPage() {
         )}
       </div>
 
+      {totalPages > 1 && (
+        <nav className="pagination-bar" aria-label="Leaderboard pagination">
+          <button type="button" disabled={currentPage <= 1} onClick={() => setPage(currentPage - 1)}>
+            ← ก่อนหน้า
+          </button>
+          {getPageNumbers(currentPage, totalPages).map((p, i) =>
+            p === "…" ? (
+              <span key={`ellipsis-${i}`} className="pagination-ellipsis">…</span>
+            ) : (
+              <button
+                key={p}
+                type="button"
+                className={p === currentPage ? "active" : ""}
+                onClick={() => setPage(p)}
+              >
+                {p}
+              </button>
+            )
+          )}
+          <button type="button" disabled={currentPage >= totalPages} onClick={() => setPage(currentPage + 1)}>
+            ถัดไป →
+          </button>
+        </nav>
+      )}
+
       {selectedUsername && (
         <ProfileModal username={selectedUsername} onClose={() => setSelectedUsername(null)} />
       )}
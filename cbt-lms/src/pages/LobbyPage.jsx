@@ -1,9 +1,10 @@
-import { useEffect, useMemo } from "react";
+import { useEffect, useMemo, useState } from "react";
 import { useNavigate } from "react-router-dom";
 import { canViewItemByStatus } from "../services/accessControlService";
 import { useAuth } from "../contexts/AuthContext";
 import { useAppData } from "../contexts/AppDataContext";
 import { getSubtopicPages } from "../components/markdown/headingUtils";
+import { fetchRecommendationsApi } from "../services/courseApiService";
 
 const QUOTES = [
   "LMS status: 'In Progress.' My brain: 'In Bed.'",
@@ -33,11 +34,31 @@ export default function LobbyPage() {
   const { currentUserKey, canManageContent, canViewAllContent, canManageExams, canViewAllExams, currentUser } = useAuth();
   const { examples, examBank, loadExamples, loadExamCatalog, openContentDetail, openExam, canManageExamItem, userSkillScores, learningProgress } = useAppData();
 
+  // Server-ranked recommendations; null until loaded or when unavailable.
+  const [recommendations, setRecommendations] = useState(null);
+
   useEffect(() => {
     void loadExamples();
     void loadExamCatalog();
   }, [loadExamples, loadExamCatalog]);
 
+  useEffect(() => {
+    if (!currentUserKey) return;
+    fetchRecommendationsApi()
+      .then(setRecommendations)
+      .catch(() => setRecommendations(null));
+  }, [currentUserKey]);
+
+  // Matches recommendations of one item type to the loaded catalog items.
+  const pickRecommended = (itemType, items) => {
+    if (!recommendations?.length) return [];
+    const byId = new Map(items.map((item) => [item.id, item]));
+    return recommendations
+      .filter((rec) => rec.itemType === itemType && byId.has(rec.itemId))
+      .map((rec) => ({ ...byId.get(rec.itemId), reason: rec.explanation }))
+      .slice(0, 4);
+  };
+
   const dailyQuote = QUOTES[Math.floor(Math.random() * QUOTES.length)];
 
   const inProgressCourses = useMemo(() => {
@@ -61,6 +82,8 @@ export default function LobbyPage() {
   }, [examples, learningProgress, currentUserKey, canManageContent]);
 
   const recommendedCourses = useMemo(() => {
+    const fromServer = pickRecommended("course", examples);
+    if (fromServer.length) return fromServer;
     const userProgress = learningProgress[currentUserKey] ?? {};
     const mySkills = userSkillScores ?? {};
     return examples
@@ -82,14 +105,16 @@ export default function LobbyPage() {
           : (b.learnerCount ?? 0) - (a.learnerCount ?? 0)
       )
       .slice(0, 4);
-  }, [examples, learningProgress, currentUserKey, canManageContent, userSkillScores]);
+  }, [examples, learningProgress, currentUserKey, canManageContent, userSkillScores, recommendations]);
 
   const limitedExams = useMemo(() => {
+    const fromServer = pickRecommended("exam", examBank);
+    if (fromServer.length) return fromServer;
     return examBank
       .filter((exam) => canViewItemByStatus({ item: exam, currentUserKey, hasManageAccess: canManageExams, hasViewAllAccess: canViewAllExams }))
       .sort((a, b) => (b.attemptCount ?? 0) - (a.attemptCount ?? 0))
       .slice(0, 4);
-  }, [examBank, currentUserKey, canManageExams, canViewAllExams]);
+  }, [examBank, currentUserKey, canManageExams, canViewAllExams, recommendations]);
 
   const handleEnterClass = (example) => {
     const result = openContentDetail(example);
@@ -152,6 +177,7 @@ export default function LobbyPage() {
               <div className="example-head">
                 <h3 className="example-title">{example.title}</h3>
               </div>
+              {example.reason ? <p className="lobby-recommend-reason">{example.reason}</p> : null}
               {example.skills?.length ? (
                 <div className="skill-tags">
                   {example.skills.map((skill) => (
@@ -194,6 +220,7 @@ export default function LobbyPage() {
                   </button>
                 ) : null}
               </div>
+              {exam.reason ? <p className="lobby-recommend-reason">{exam.reason}</p> : null}
               <p>{exam.description}</p>
               <button type="button" className="enter-button" onClick={() => handleEnterExam(exam)}>
                 ดูรายละเอียดข้อสอบ
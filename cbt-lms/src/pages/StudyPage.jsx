@@ -2,9 +2,27 @@ import { useCallback, useEffect, useMemo, useRef, useState } from "react";
 import { useParams, useNavigate, useLocation } from "react-router-dom";
 import { getStoredImages } from "../services/contentImagesStore";
 import { fetchCourseImagesApi, fetchCourseAttachmentsApi } from "../services/mediaApiService";
-import { recordSubtopicTimeApi, fetchCourseQnAApi, postQnAQuestionApi, postQnAReplyApi } from "../services/courseApiService";
+import {
+  startReadingSessionApi,
+  sendReadingHeartbeatApi,
+  endReadingSessionApi,
+  fetchCourseQnAApi,
+  fetchCourseQnAForModerationApi,
+  fetchMyCohortApi,
+  postQnAQuestionApi,
+  postQnAReplyApi,
+  updateQnAQuestionApi,
+  deleteQnAQuestionApi,
+  updateQnAReplyApi,
+  deleteQnAReplyApi,
+  acceptQnAReplyApi,
+  setQnAStatusApi,
+  setQnAQuestionHiddenApi,
+  setQnAReplyHiddenApi,
+} from "../services/courseApiService";
 import MarkdownContent from "../components/markdown/MarkdownContent";
 import TableOfContents from "../components/markdown/TableOfContents";
+import ConfirmModal from "../components/ui/ConfirmModal";
 import { getSubtopicPages } from "../components/markdown/headingUtils";
 import { normalizeExampleRecord, toCourseDraft } from "../services/courseService";
 import { useAuth } from "../contexts/AuthContext";
@@ -12,6 +30,37 @@ import { useAppData } from "../contexts/AppDataContext";
 
 const normalizeAnswer = (value) => String(value ?? "").trim().toLowerCase();
 
+const formatQnaTime = (value) => new Date(value).toLocaleString("th-TH");
+
+const toQnaReply = (r) => ({
+  id: r.id,
+  questionId: r.questionId,
+  text: r.reply,
+  username: r.username,
+  name: r.name || r.username,
+  isInstructor: Boolean(r.isInstructor),
+  hidden: Boolean(r.hidden),
+  edited: Boolean(r.editedAt),
+  postedAt: formatQnaTime(r.createdAt),
+});
+
+const toQnaItem = (q) => ({
+  id: q.id,
+  subtopicId: q.subtopicId ?? "",
+  question: q.question,
+  username: q.username,
+  name: q.name || q.username,
+  status: q.status ?? "open",
+  acceptedReplyId: q.acceptedReplyId ?? null,
+  hidden: Boolean(q.hidden),
+  edited: Boolean(q.editedAt),
+  postedAt: formatQnaTime(q.createdAt),
+  replies: (q.replies ?? []).map(toQnaReply),
+});
+
+// Optimistic posts carry a temp- id until the server answers.
+const isSavedQna = (id) => typeof id === "number";
+
 function getAttachmentIcon(filename) {
   const ext = String(filename ?? "").split(".").pop().toLowerCase();
   const icons = { pdf: "📄", doc: "📝", docx: "📝", xls: "📊", xlsx: "📊", ppt: "📽️", pptx: "📽️", txt: "📃" };
@@ -22,7 +71,7 @@ export default function StudyPage() {
   const { courseId } = useParams();
   const navigate = useNavigate();
   const location = useLocation();
-  const { currentUserKey, users: authUsers } = useAuth();
+  const { currentUserKey, users: authUsers, canManageContent, canModerateQnA } = useAuth();
   const { examples, learningProgress, handleMarkSubtopicComplete, handleSubmitSubtopicAnswer } = useAppData();
 
   const contentItem = useMemo(() => {
@@ -39,7 +88,6 @@ export default function StudyPage() {
   const [contentImages, setContentImages] = useState(() => getStoredImages(draft?.sourceId ?? draft?.id ?? ""));
   const [attachments, setAttachments] = useState([]);
   const timeSpentRef = useRef(progress?.timeSpent ?? {});
-  const pendingSecondsRef = useRef(0);
   // lockedDisplaySeconds: null = unlocked, number = seconds spent so far (countdown display)
   const [lockedDisplaySeconds, setLockedDisplaySeconds] = useState(null);
   const [answerInputs, setAnswerInputs] = useState({});
@@ -49,7 +97,10 @@ export default function StudyPage() {
   const [expandedQnaId, setExpandedQnaId] = useState(null);
   const [highlightedQnaId, setHighlightedQnaId] = useState(null);
   const [tocTab, setTocTab] = useState("toc"); // "toc" | "qna"
-  const [qnaFilter, setQnaFilter] = useState("all"); // "all" | "answered" | "unanswered"
+  const [qnaFilter, setQnaFilter] = useState("all"); // "all" | "open" | "resolved"
+  const [qnaEdit, setQnaEdit] = useState(null); // { kind: "question" | "reply", id, text }
+  const [qnaDeleteTarget, setQnaDeleteTarget] = useState(null); // { kind, id, questionId }
+  const [qnaError, setQnaError] = useState("");
   const subtopicPages = useMemo(() => draft ? getSubtopicPages(draft.content, draft.title) : [], [draft?.content, draft?.title]);
   const selectedSubtopic = subtopicPages.find((subtopic) => subtopic.id === activeSubtopicId) ?? subtopicPages[0];
 
@@ -90,29 +141,13 @@ export default function StudyPage() {
       fetchCourseAttachmentsApi(draftCourseId)
         .then(setAttachments)
         .catch(() => {});
-      fetchCourseQnAApi(draftCourseId)
-        .then((questions) =>
-          setQnaItems(
-            questions.map((q) => ({
-              id: q.id,
-              subtopicId: q.subtopicId ?? "",
-              question: q.question,
-              username: q.username,
-              name: q.name || q.username,
-              postedAt: new Date(q.createdAt).toLocaleString("th-TH"),
-              replies: (q.replies ?? []).map((r) => ({
-                id: r.id,
-                text: r.reply,
-                username: r.username,
-                name: r.name || r.username,
-                postedAt: new Date(r.createdAt).toLocaleString("th-TH"),
-              })),
-            })),
-          ),
-        )
+      const fetchQnA = canModerateQnA ? fetchCourseQnAForModerationApi : fetchCourseQnAApi;
+      fetchMyCohortApi(draftCourseId)
+        .then((cohort) => fetchQnA(draftCourseId, cohort?.id))
+        .then((questions) => setQnaItems(questions.map(toQnaItem)))
         .catch(() => {});
     }
-  }, [draft?.sourceId, draft?.id]);
+  }, [draft?.sourceId, draft?.id, canModerateQnA]);
 
   // Auto-scroll & highlight when navigating from SummaryPage "ไปตอบ"
   useEffect(() => {
@@ -166,41 +201,80 @@ export default function StudyPage() {
     }
   };
 
-  // Timer: track time spent per subtopic, flush to backend every 30 s
+  // Timer: count time spent per subtopic locally for the unlock countdown.
   // Uses ref for accumulation to avoid re-renders every second.
   // Only updates state when lock status changes or countdown needs display.
+  // The backend credits time from a reading session kept alive by heartbeats.
   useEffect(() => {
     const courseId = draft?.sourceId;
     const subtopicId = selectedSubtopic?.id;
     if (!courseId || !subtopicId) return;
 
-    pendingSecondsRef.current = 0;
     const minTimeSecs = (selectedSubtopic?.minTimeMinutes ?? 0) * 60;
     const initial = timeSpentRef.current[subtopicId] ?? 0;
     setLockedDisplaySeconds(minTimeSecs > 0 && initial < minTimeSecs ? initial : null);
 
     const tick = () => {
-      pendingSecondsRef.current += 1;
       const newTotal = (timeSpentRef.current[subtopicId] ?? 0) + 1;
       timeSpentRef.current[subtopicId] = newTotal;
 
       if (minTimeSecs > 0 && newTotal <= minTimeSecs) {
         setLockedDisplaySeconds(newTotal < minTimeSecs ? newTotal : null);
       }
+    };
 
-      if (pendingSecondsRef.current >= 30) {
-        const toFlush = pendingSecondsRef.current;
-        pendingSecondsRef.current = 0;
-        recordSubtopicTimeApi(courseId, subtopicId, toFlush).catch(() => {});
+    let cancelled = false;
+    let sessionId = null;
+    let heartbeatMs = 30000;
+    let lastActivity = Date.now();
+    const markActive = () => {
+      lastActivity = Date.now();
+    };
+    const isIdle = () => document.visibilityState === "hidden" || Date.now() - lastActivity > heartbeatMs;
+    const activityEvents = ["mousemove", "keydown", "scroll", "touchstart"];
+    activityEvents.forEach((name) => window.addEventListener(name, markActive, { passive: true }));
+
+    let heartbeatTimer = null;
+    const startSession = () =>
+      startReadingSessionApi(courseId, subtopicId)
+        .then((payload) => {
+          const id = payload?.session?.id ?? null;
+          if (cancelled) {
+            if (id) endReadingSessionApi(id, true).catch(() => {});
+            return;
+          }
+          sessionId = id;
+          const seconds = Number(payload?.heartbeatSeconds);
+          if (seconds > 0 && seconds * 1000 !== heartbeatMs) {
+            heartbeatMs = seconds * 1000;
+            clearInterval(heartbeatTimer);
+            heartbeatTimer = setInterval(beat, heartbeatMs);
+          }
+        })
+        .catch(() => {});
+    const beat = () => {
+      if (!sessionId) {
+        startSession();
+        return;
       }
+      sendReadingHeartbeatApi(sessionId, isIdle()).catch((err) => {
+        if (err?.status === 410) {
+          sessionId = null;
+          startSession();
+        }
+      });
     };
 
+    startSession();
+    heartbeatTimer = setInterval(beat, heartbeatMs);
     const id = setInterval(tick, 1000);
     return () => {
+      cancelled = true;
       clearInterval(id);
-      if (pendingSecondsRef.current > 0) {
-        recordSubtopicTimeApi(courseId, subtopicId, pendingSecondsRef.current).catch(() => {});
-        pendingSecondsRef.current = 0;
+      clearInterval(heartbeatTimer);
+      activityEvents.forEach((name) => window.removeEventListener(name, markActive));
+      if (sessionId) {
+        endReadingSessionApi(sessionId, isIdle()).catch(() => {});
       }
     };
   }, [selectedSubtopic?.id, draft?.sourceId]);
@@ -249,6 +323,10 @@ export default function StudyPage() {
         question: text,
         username: currentUserKey,
         name: currentName,
+        status: "open",
+        acceptedReplyId: null,
+        hidden: false,
+        edited: false,
         postedAt: new Date().toLocaleString("th-TH"),
         replies: [],
       },
@@ -261,9 +339,7 @@ export default function StudyPage() {
           const q = res.question;
           setQnaItems((prev) =>
             prev.map((item) =>
-              item.id === tempId
-                ? { ...item, id: q.id, name: q.name || item.name, postedAt: new Date(q.createdAt).toLocaleString("th-TH") }
-                : item,
+              item.id === tempId ? { ...toQnaItem(q), replies: item.replies } : item,
             ),
           );
         }
@@ -284,7 +360,17 @@ export default function StudyPage() {
               ...item,
               replies: [
                 ...item.replies,
-                { id: tempId, text, username: currentUserKey, name: authUsers[currentUserKey]?.name ?? currentUserKey, postedAt: new Date().toLocaleString("th-TH") },
+                {
+                  id: tempId,
+                  questionId: qnaId,
+                  text,
+                  username: currentUserKey,
+                  name: authUsers[currentUserKey]?.name ?? currentUserKey,
+                  isInstructor: false,
+                  hidden: false,
+                  edited: false,
+                  postedAt: new Date().toLocaleString("th-TH"),
+                },
               ],
             }
           : item,
@@ -303,11 +389,7 @@ export default function StudyPage() {
               item.id === qnaId
                 ? {
                     ...item,
-                    replies: item.replies.map((rep) =>
-                      rep.id === tempId
-                        ? { ...rep, id: r.id, name: r.name || rep.name, postedAt: new Date(r.createdAt).toLocaleString("th-TH") }
-                        : rep,
-                    ),
+                    replies: item.replies.map((rep) => (rep.id === tempId ? toQnaReply(r) : rep)),
                   }
                 : item,
             ),
@@ -317,6 +399,110 @@ export default function StudyPage() {
       .catch(() => {});
   };
 
+  const replaceQnaQuestion = (q) => {
+    setQnaItems((prev) => prev.map((item) => (item.id === q.id ? toQnaItem(q) : item)));
+  };
+
+  const replaceQnaReply = (r) => {
+    setQnaItems((prev) =>
+      prev.map((item) =>
+        item.id === r.questionId
+          ? {
+              ...item,
+              // A hidden reply stops being the accepted answer.
+              acceptedReplyId: r.hidden && item.acceptedReplyId === r.id ? null : item.acceptedReplyId,
+              replies: item.replies.map((rep) => (rep.id === r.id ? toQnaReply(r) : rep)),
+            }
+          : item,
+      ),
+    );
+  };
+
+  const runQnaAction = (promise) => {
+    setQnaError("");
+    return promise.catch((err) => setQnaError(err?.message || "ทำรายการไม่สำเร็จ"));
+  };
+
+  const canResolveQna = (item) => item.username === currentUserKey || canManageContent;
+
+  const handleSaveQnaEdit = () => {
+    const text = (qnaEdit?.text ?? "").trim();
+    if (!qnaEdit || !text) return;
+    const { kind, id } = qnaEdit;
+    setQnaEdit(null);
+    if (kind === "question") {
+      runQnaAction(updateQnAQuestionApi(id, text).then((res) => res?.question && replaceQnaQuestion(res.question)));
+    } else {
+      runQnaAction(updateQnAReplyApi(id, text).then((res) => res?.reply && replaceQnaReply(res.reply)));
+    }
+  };
+
+  const handleDeleteQna = () => {
+    const target = qnaDeleteTarget;
+    setQnaDeleteTarget(null);
+    if (!target) return;
+    if (target.kind === "question") {
+      runQnaAction(
+        deleteQnAQuestionApi(target.id).then(() =>
+          setQnaItems((prev) => prev.filter((item) => item.id !== target.id)),
+        ),
+      );
+      return;
+    }
+    runQnaAction(
+      deleteQnAReplyApi(target.id).then(() =>
+        setQnaItems((prev) =>
+          prev.map((item) =>
+            item.id === target.questionId
+              ? {
+                  ...item,
+                  acceptedReplyId: item.acceptedReplyId === target.id ? null : item.acceptedReplyId,
+                  replies: item.replies.filter((rep) => rep.id !== target.id),
+                }
+              : item,
+          ),
+        ),
+      ),
+    );
+  };
+
+  const handleAcceptQnaReply = (item, replyId) => {
+    const nextId = item.acceptedReplyId === replyId ? 0 : replyId;
+    runQnaAction(acceptQnAReplyApi(item.id, nextId).then((res) => res?.question && replaceQnaQuestion(res.question)));
+  };
+
+  const handleToggleQnaStatus = (item) => {
+    const nextStatus = item.status === "resolved" ? "open" : "resolved";
+    runQnaAction(setQnAStatusApi(item.id, nextStatus).then((res) => res?.question && replaceQnaQuestion(res.question)));
+  };
+
+  const handleToggleQuestionHidden = (item) => {
+    runQnaAction(
+      setQnAQuestionHiddenApi(item.id, !item.hidden).then((res) => res?.question && replaceQnaQuestion(res.question)),
+    );
+  };
+
+  const handleToggleReplyHidden = (reply) => {
+    runQnaAction(setQnAReplyHiddenApi(reply.id, !reply.hidden).then((res) => res?.reply && replaceQnaReply(res.reply)));
+  };
+
+  const renderQnaEditForm = () => (
+    <div className="qna-reply-form">
+      <textarea
+        className="qna-textarea qna-textarea-sm"
+        value={qnaEdit.text}
+        onChange={(e) => setQnaEdit((prev) => ({ ...prev, text: e.target.value }))}
+        rows={2}
+      />
+      <button type="button" className="qna-submit-btn qna-submit-btn-sm" onClick={handleSaveQnaEdit} disabled={!qnaEdit.text.trim()}>
+        บันทึก
+      </button>
+      <button type="button" className="qna-action-btn" onClick={() => setQnaEdit(null)}>
+        ยกเลิก
+      </button>
+    </div>
+  );
+
   const handleCompleteSubtopic = () => {
     if (!selectedSubtopic || !canComplete) {
       return;
@@ -485,6 +671,8 @@ export default function StudyPage() {
             </div>
           </div>
 
+          {qnaError && <p className="qna-error">{qnaError}</p>}
+
           {qnaItems.filter((q) => q.subtopicId === (selectedSubtopic?.id ?? "")).length === 0 ? (
             <p className="qna-empty">ยังไม่มีคำถามในหัวข้อนี้ เป็นคนแรกที่ถาม!</p>
           ) : (
@@ -492,15 +680,30 @@ export default function StudyPage() {
               {qnaItems
                 .filter((q) => q.subtopicId === (selectedSubtopic?.id ?? ""))
                 .map((item) => (
-                  <div key={item.id} data-qna-id={item.id} className={`qna-item${highlightedQnaId === item.id ? " qna-item-highlight" : ""}`}>
+                  <div
+                    key={item.id}
+                    data-qna-id={item.id}
+                    className={`qna-item${highlightedQnaId === item.id ? " qna-item-highlight" : ""}${item.hidden ? " qna-item-hidden" : ""}`}
+                  >
                     <div className="qna-item-header">
                       <div className="qna-avatar">👤</div>
                       <div className="qna-item-meta">
                         <span className="qna-item-user">{item.name ?? item.username ?? "คุณ"}</span>
-                        <span className="qna-item-time">{item.postedAt}</span>
+                        <span className="qna-item-time">
+                          {item.postedAt}
+                          {item.edited && " (แก้ไขแล้ว)"}
+                        </span>
                       </div>
+                      <span className={`qna-status-badge qna-status-${item.status}`}>
+                        {item.status === "resolved" ? "✓ ได้คำตอบแล้ว" : "รอคำตอบ"}
+                      </span>
+                      {item.hidden && <span className="qna-hidden-badge">ซ่อนอยู่</span>}
                     </div>
-                    <p className="qna-item-text">{item.question}</p>
+                    {qnaEdit?.kind === "question" && qnaEdit.id === item.id ? (
+                      renderQnaEditForm()
+                    ) : (
+                      <p className="qna-item-text">{item.question}</p>
+                    )}
                     <div className="qna-item-actions">
                       <button
                         type="button"
@@ -509,20 +712,95 @@ export default function StudyPage() {
                       >
                         💬 {item.replies.length > 0 ? `ตอบกลับ (${item.replies.length})` : "ตอบกลับ"}
                       </button>
+                      {isSavedQna(item.id) && !item.hidden && canResolveQna(item) && (
+                        <button type="button" className="qna-action-btn" onClick={() => handleToggleQnaStatus(item)}>
+                          {item.status === "resolved" ? "เปิดคำถามอีกครั้ง" : "ทำเครื่องหมายว่าได้คำตอบแล้ว"}
+                        </button>
+                      )}
+                      {isSavedQna(item.id) && !item.hidden && item.username === currentUserKey && (
+                        <>
+                          <button
+                            type="button"
+                            className="qna-action-btn"
+                            onClick={() => setQnaEdit({ kind: "question", id: item.id, text: item.question })}
+                          >
+                            แก้ไข
+                          </button>
+                          <button
+                            type="button"
+                            className="qna-action-btn qna-action-danger"
+                            onClick={() => setQnaDeleteTarget({ kind: "question", id: item.id })}
+                          >
+                            ลบ
+                          </button>
+                        </>
+                      )}
+                      {isSavedQna(item.id) && canModerateQnA && (
+                        <button type="button" className="qna-action-btn" onClick={() => handleToggleQuestionHidden(item)}>
+                          {item.hidden ? "แสดงคำถาม" : "ซ่อนคำถาม"}
+                        </button>
+                      )}
                     </div>
 
                     {expandedQnaId === item.id && (
                       <div className="qna-replies">
                         {item.replies.map((reply) => (
-                          <div key={reply.id} className="qna-reply">
+                          <div
+                            key={reply.id}
+                            className={`qna-reply${item.acceptedReplyId === reply.id ? " qna-reply-accepted" : ""}${reply.hidden ? " qna-item-hidden" : ""}`}
+                          >
                             <div className="qna-item-header">
-                              <div className="qna-avatar qna-avatar-reply">🧑‍🏫</div>
+                              <div className="qna-avatar qna-avatar-reply">{reply.isInstructor ? "🧑‍🏫" : "👤"}</div>
                               <div className="qna-item-meta">
-                                <span className="qna-item-user">{reply.name ?? reply.username ?? "ผู้สอน"}</span>
-                                <span className="qna-item-time">{reply.postedAt}</span>
+                                <span className="qna-item-user">
+                                  {reply.name ?? reply.username ?? "ผู้ใช้"}
+                                  {reply.isInstructor && <span className="qna-instructor-badge">ผู้สอน</span>}
+                                </span>
+                                <span className="qna-item-time">
+                                  {reply.postedAt}
+                                  {reply.edited && " (แก้ไขแล้ว)"}
+                                </span>
                               </div>
+                              {item.acceptedReplyId === reply.id && <span className="qna-accepted-badge">✓ คำตอบที่ยอมรับ</span>}
+                              {reply.hidden && <span className="qna-hidden-badge">ซ่อนอยู่</span>}
                             </div>
-                            <p className="qna-item-text">{reply.text}</p>
+                            {qnaEdit?.kind === "reply" && qnaEdit.id === reply.id ? (
+                              renderQnaEditForm()
+                            ) : (
+                              <p className="qna-item-text">{reply.text}</p>
+                            )}
+                            {isSavedQna(reply.id) && (
+                              <div className="qna-item-actions">
+                                {!item.hidden && !reply.hidden && canResolveQna(item) && (
+                                  <button type="button" className="qna-action-btn" onClick={() => handleAcceptQnaReply(item, reply.id)}>
+                                    {item.acceptedReplyId === reply.id ? "ยกเลิกคำตอบที่ยอมรับ" : "ยอมรับคำตอบนี้"}
+                                  </button>
+                                )}
+                                {!reply.hidden && reply.username === currentUserKey && (
+                                  <>
+                                    <button
+                                      type="button"
+                                      className="qna-action-btn"
+                                      onClick={() => setQnaEdit({ kind: "reply", id: reply.id, text: reply.text })}
+                                    >
+                                      แก้ไข
+                                    </button>
+                                    <button
+                                      type="button"
+                                      className="qna-action-btn qna-action-danger"
+                                      onClick={() => setQnaDeleteTarget({ kind: "reply", id: reply.id, questionId: item.id })}
+                                    >
+                                      ลบ
+                                    </button>
+                                  </>
+                                )}
+                                {canModerateQnA && (
+                                  <button type="button" className="qna-action-btn" onClick={() => handleToggleReplyHidden(reply)}>
+                                    {reply.hidden ? "แสดงคำตอบ" : "ซ่อนคำตอบ"}
+                                  </button>
+                                )}
+                              </div>
+                            )}
                           </div>
                         ))}
                         <div className="qna-reply-form">
@@ -606,8 +884,8 @@ export default function StudyPage() {
               <div className="toc-qna-filter-bar">
                 {[
                   { key: "all", label: "ทั้งหมด" },
-                  { key: "unanswered", label: "ยังไม่ตอบ" },
-                  { key: "answered", label: "ตอบแล้ว" },
+                  { key: "open", label: "รอคำตอบ" },
+                  { key: "resolved", label: "ได้คำตอบแล้ว" },
                 ].map(({ key, label }) => (
                   <button
                     key={key}
@@ -617,9 +895,7 @@ export default function StudyPage() {
                   >
                     {label}
                     <span className="toc-qna-filter-count">
-                      {key === "all" ? qnaItems.length
-                        : key === "answered" ? qnaItems.filter((q) => q.replies.length > 0).length
-                        : qnaItems.filter((q) => q.replies.length === 0).length}
+                      {key === "all" ? qnaItems.length : qnaItems.filter((q) => q.status === key).length}
                     </span>
                   </button>
                 ))}
@@ -627,14 +903,10 @@ export default function StudyPage() {
               {qnaItems.length === 0 ? (
                 <p className="toc-qna-empty">ยังไม่มีคำถามในคอร์สนี้</p>
               ) : (() => {
-                const filtered = qnaItems.filter((q) =>
-                  qnaFilter === "answered" ? q.replies.length > 0
-                  : qnaFilter === "unanswered" ? q.replies.length === 0
-                  : true
-                );
+                const filtered = qnaItems.filter((q) => qnaFilter === "all" || q.status === qnaFilter);
                 return filtered.length === 0 ? (
                   <p className="toc-qna-empty">
-                    {qnaFilter === "answered" ? "ยังไม่มีคำถามที่ตอบแล้ว" : "ไม่มีคำถามที่ยังไม่ตอบ"}
+                    {qnaFilter === "resolved" ? "ยังไม่มีคำถามที่ได้คำตอบแล้ว" : "ไม่มีคำถามที่รอคำตอบ"}
                   </p>
                 ) : (
                 <div className="toc-qna-list">
@@ -650,9 +922,13 @@ export default function StudyPage() {
                         <p className="toc-qna-question">{item.question}</p>
                         <div className="toc-qna-meta">
                           <span>{item.postedAt}</span>
-                          {item.replies.length > 0 && (
-                            <span className="toc-qna-replied-badge">✓ ตอบแล้ว {item.replies.length}</span>
+                          {item.status === "resolved" ? (
+                            <span className="toc-qna-replied-badge">✓ ได้คำตอบแล้ว</span>
+                          ) : (
+                            <span className="toc-qna-open-badge">รอคำตอบ</span>
                           )}
+                          {item.replies.length > 0 && <span>{item.replies.length} คำตอบ</span>}
+                          {item.hidden && <span className="qna-hidden-badge">ซ่อนอยู่</span>}
                         </div>
                         <button
                           type="button"
@@ -664,8 +940,15 @@ export default function StudyPage() {
                         {isExpanded && (
                           <div className="toc-qna-reply-area">
                             {item.replies.map((reply) => (
-                              <div key={reply.id} className="toc-qna-reply-bubble">
-                                <span className="toc-qna-reply-label">🧑‍🏫 {reply.name ?? reply.username ?? "ผู้สอน"}</span>
+                              <div
+                                key={reply.id}
+                                className={`toc-qna-reply-bubble${item.acceptedReplyId === reply.id ? " qna-reply-accepted" : ""}`}
+                              >
+                                <span className="toc-qna-reply-label">
+                                  {reply.isInstructor ? "🧑‍🏫" : "👤"} {reply.name ?? reply.username ?? "ผู้ใช้"}
+                                  {reply.isInstructor && <span className="qna-instructor-badge">ผู้สอน</span>}
+                                  {item.acceptedReplyId === reply.id && <span className="qna-accepted-badge">✓ ยอมรับแล้ว</span>}
+                                </span>
                                 <p>{reply.text}</p>
                               </div>
                             ))}
@@ -700,6 +983,21 @@ export default function StudyPage() {
           )}
         </div>
       </div>
+      {qnaDeleteTarget && (
+        <ConfirmModal
+          title="ยืนยันการลบ?"
+          message={
+            qnaDeleteTarget.kind === "question"
+              ? "คำถามนี้และคำตอบทั้งหมดจะถูกลบ และไม่สามารถย้อนกลับได้"
+              : "คำตอบนี้จะถูกลบ และไม่สามารถย้อนกลับได้"
+          }
+          confirmLabel="ลบทิ้ง"
+          cancelLabel="ยกเลิก"
+          confirmDanger
+          onConfirm={handleDeleteQna}
+          onCancel={() => setQnaDeleteTarget(null)}
+        />
+      )}
     </section>
   );
 }
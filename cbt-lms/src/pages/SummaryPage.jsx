@@ -71,7 +71,7 @@ export default function SummaryPage() {
   // Course stats from API
   const [allCourseStats, setAllCourseStats] = useState([]);
   const [myCourseStats, setMyCourseStats] = useState([]);
-  const [courseDetail, setCourseDetail] = useState(null); // { subtopicTime, hardQuestions, unansweredQna }
+  const [courseDetail, setCourseDetail] = useState(null); // { subtopicTime, hardQuestions, unresolvedQna }
 
   // Exam stats from API
   const [allExamStats, setAllExamStats] = useState([]);
@@ -638,10 +638,10 @@ export default function SummaryPage() {
                             <span className="my-course-stat-label">คะแนนเฉลี่ย</span>
                           </div>
                           <div className="my-course-stat">
-                            <span className="my-course-stat-value" style={{ color: course.qnaUnanswered > 0 ? "#ef4444" : "#1f8d4e" }}>
-                              {course.qnaUnanswered}
+                            <span className="my-course-stat-value" style={{ color: course.qnaUnresolved > 0 ? "#ef4444" : "#1f8d4e" }}>
+                              {course.qnaUnresolved}
                             </span>
-                            <span className="my-course-stat-label">Q&A รอตอบ</span>
+                            <span className="my-course-stat-label">Q&A รอคำตอบ</span>
                           </div>
                         </div>
                         <div className="my-course-completion-bar">
@@ -674,17 +674,17 @@ export default function SummaryPage() {
 
                     <div className="chart-card chart-card-accent-blue">
                       <h3 className="chart-card-title">
-                        คำถามที่ยังไม่ตอบ
-                        {(courseDetail.unansweredQna?.length ?? 0) > 0 && (
-                          <span className="unanswered-count-badge">{courseDetail.unansweredQna.length}</span>
+                        คำถามที่ยังไม่ได้คำตอบ
+                        {(courseDetail.unresolvedQna?.length ?? 0) > 0 && (
+                          <span className="unanswered-count-badge">{courseDetail.unresolvedQna.length}</span>
                         )}
                       </h3>
-                      <p className="chart-card-sub">คำถามจากผู้เรียนที่รอการตอบกลับ</p>
-                      {(!courseDetail.unansweredQna || courseDetail.unansweredQna.length === 0) ? (
-                        <p className="chart-empty" style={{ color: "#1f8d4e" }}>ตอบครบหมดแล้ว!</p>
+                      <p className="chart-card-sub">คำถามจากผู้เรียนที่ยังไม่ถูกทำเครื่องหมายว่าได้คำตอบแล้ว</p>
+                      {(!courseDetail.unresolvedQna || courseDetail.unresolvedQna.length === 0) ? (
+                        <p className="chart-empty" style={{ color: "#1f8d4e" }}>ทุกคำถามได้คำตอบแล้ว!</p>
                       ) : (
                         <div className="unanswered-qna-list">
-                          {courseDetail.unansweredQna.map((q) => (
+                          {courseDetail.unresolvedQna.map((q) => (
                             <div key={q.id} className="unanswered-qna-item">
                               <div className="unanswered-qna-left">
                                 <span className="unanswered-qna-subtopic">{q.subtopicId}</span>
@@ -69,27 +69,67 @@ export const submitSubtopicAnswerApi = async (courseId, subtopicId, questionId,
     },
   );
 
-export const recordSubtopicTimeApi = async (courseId, subtopicId, seconds) =>
+export const startReadingSessionApi = async (courseId, subtopicId) =>
   request(
-    `/api/learning/courses/${encodeURIComponent(courseId)}/subtopics/${encodeURIComponent(subtopicId)}/time`,
+    `/api/learning/courses/${encodeURIComponent(courseId)}/subtopics/${encodeURIComponent(subtopicId)}/sessions`,
     {
       method: "POST",
       headers: authHeaders(),
-      body: JSON.stringify({ seconds }),
     },
   );
 
-export const completeCourseApi = async (courseId) =>
-  request(`/api/learning/courses/${encodeURIComponent(courseId)}/complete`, {
+export const sendReadingHeartbeatApi = async (sessionId, idle) =>
+  request(`/api/learning/sessions/${encodeURIComponent(sessionId)}/heartbeat`, {
     method: "POST",
     headers: authHeaders(),
+    body: JSON.stringify({ idle }),
   });
 
-export const fetchLeaderboardApi = async () => {
-  const payload = await request("/api/learning/leaderboard", {
+export const endReadingSessionApi = async (sessionId, idle) =>
+  request(`/api/learning/sessions/${encodeURIComponent(sessionId)}/end`, {
+    method: "POST",
+    headers: authHeaders(),
+    body: JSON.stringify({ idle }),
+    keepalive: true,
+  });
+
+export const completeCourseApi = async (courseId) =>
+  request(`/api/learning/courses/${encodeURIComponent(courseId)}/complete`, {
+    method: "POST",
     headers: authHeaders(),
   });
-  return Array.isArray(payload?.leaderboard) ? payload.leaderboard : [];
+
+// Returns { leaderboard, board, me, pagination }. The signed-in variant adds the
+// caller's own entry; visitors without learner access get the public board.
+export const fetchLeaderboardApi = async ({ period = "all", page = 1, limit = 20 } = {}) => {
+  const query = new URLSearchParams({ period, page: String(page), limit: String(limit) });
+  let payload;
+  try {
+    payload = await request(`/api/learning/leaderboard/me?${query}`, { headers: authHeaders() });
+  } catch {
+    payload = await request(`/api/learning/leaderboard?${query}`);
+  }
+  return {
+    leaderboard: Array.isArray(payload?.leaderboard) ? payload.leaderboard : [],
+    board: payload?.board ?? null,
+    me: payload?.me ?? null,
+    pagination: payload?.pagination ?? { page: 1, total_pages: 1, total: 0, limit },
+  };
+};
+
+// Returns { skills, levels }: the managed skills catalog and proficiency levels.
+export const fetchSkillsApi = async () => {
+  const payload = await request("/api/skills", { headers: authHeaders() });
+  return {
+    skills: Array.isArray(payload?.skills) ? payload.skills : [],
+    levels: Array.isArray(payload?.levels) ? payload.levels : [],
+  };
+};
+
+// Returns the courses and exams recommended to the current user, best first.
+export const fetchRecommendationsApi = async (limit = 12) => {
+  const payload = await request(`/api/me/recommendations?limit=${limit}`, { headers: authHeaders() });
+  return Array.isArray(payload?.recommendations) ? payload.recommendations : [];
 };
 
 export const fetchUserPublicProfileApi = async (username) =>
@@ -97,11 +137,26 @@ export const fetchUserPublicProfileApi = async (username) =>
 
 // ── Q&A ───────────────────────────────────────────────────────────────────────
 
-export const fetchCourseQnAApi = async (courseId) => {
-  const payload = await request(`/api/courses/${encodeURIComponent(courseId)}/qna`);
+// Without cohortId this returns the course-wide Q&A.
+// Cohort Q&A is private to the cohort, so it is read through the signed-in route.
+export const fetchCourseQnAApi = async (courseId, cohortId) => {
+  const payload = cohortId
+    ? await request(
+        `/api/learning/courses/${encodeURIComponent(courseId)}/qna?cohortId=${encodeURIComponent(cohortId)}`,
+        { headers: authHeaders() },
+      )
+    : await request(`/api/courses/${encodeURIComponent(courseId)}/qna`);
   return Array.isArray(payload?.questions) ? payload.questions : [];
 };
 
+// Resolves to null when the learner is not in a cohort of the course.
+export const fetchMyCohortApi = async (courseId) =>
+  request(`/api/learning/courses/${encodeURIComponent(courseId)}/cohort`, {
+    headers: authHeaders(),
+  })
+    .then((payload) => payload?.cohort ?? null)
+    .catch(() => null);
+
 export const postQnAQuestionApi = async (courseId, subtopicId, question) =>
   request(
     `/api/learning/courses/${encodeURIComponent(courseId)}/qna`,
@@ -122,6 +177,68 @@ export const postQnAReplyApi = async (questionId, reply) =>
     },
   );
 
+// Moderators also see hidden questions and replies.
+export const fetchCourseQnAForModerationApi = async (courseId, cohortId) => {
+  const query = cohortId ? `?cohortId=${encodeURIComponent(cohortId)}` : "";
+  const payload = await request(`/api/qna/courses/${encodeURIComponent(courseId)}${query}`, {
+    headers: authHeaders(),
+  });
+  return Array.isArray(payload?.questions) ? payload.questions : [];
+};
+
+export const updateQnAQuestionApi = async (questionId, question) =>
+  request(`/api/learning/qna/${encodeURIComponent(questionId)}`, {
+    method: "PUT",
+    headers: authHeaders(),
+    body: JSON.stringify({ question }),
+  });
+
+export const deleteQnAQuestionApi = async (questionId) =>
+  request(`/api/learning/qna/${encodeURIComponent(questionId)}`, {
+    method: "DELETE",
+    headers: authHeaders(),
+  });
+
+export const updateQnAReplyApi = async (replyId, reply) =>
+  request(`/api/learning/qna/replies/${encodeURIComponent(replyId)}`, {
+    method: "PUT",
+    headers: authHeaders(),
+    body: JSON.stringify({ reply }),
+  });
+
+export const deleteQnAReplyApi = async (replyId) =>
+  request(`/api/learning/qna/replies/${encodeURIComponent(replyId)}`, {
+    method: "DELETE",
+    headers: authHeaders(),
+  });
+
+// replyId 0 clears the accepted answer.
+export const acceptQnAReplyApi = async (questionId, replyId) =>
+  request(`/api/learning/qna/${encodeURIComponent(questionId)}/accepted-reply`, {
+    method: "PUT",
+    headers: authHeaders(),
+    body: JSON.stringify({ replyId }),
+  });
+
+export const setQnAStatusApi = async (questionId, status) =>
+  request(`/api/learning/qna/${encodeURIComponent(questionId)}/status`, {
+    method: "PUT",
+    headers: authHeaders(),
+    body: JSON.stringify({ status }),
+  });
+
+export const setQnAQuestionHiddenApi = async (questionId, hidden) =>
+  request(`/api/qna/${encodeURIComponent(questionId)}/${hidden ? "hide" : "unhide"}`, {
+    method: "POST",
+    headers: authHeaders(),
+  });
+
+export const setQnAReplyHiddenApi = async (replyId, hidden) =>
+  request(`/api/qna/replies/${encodeURIComponent(replyId)}/${hidden ? "hide" : "unhide"}`, {
+    method: "POST",
+    headers: authHeaders(),
+  });
+
 export const fetchUserScoresApi = async () => {
   const payload = await request("/api/learning/scores", {
     headers: authHeaders(),
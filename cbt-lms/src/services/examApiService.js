@@ -78,6 +78,8 @@ export const upsertExamApi = async (exam) =>
       numberOfQuestions: exam.numberOfQuestions,
       defaultTime:       exam.defaultTime,
       maxAttempts:       exam.maxAttempts ?? 0,
+      completionScore:         exam.completionScore ?? 0,
+      skillRewards:      Array.isArray(exam.skillRewards) ? exam.skillRewards : [],
       domainPercentages: Object.fromEntries(
         Object.entries(exam.domainPercentages ?? {}).map(([k, v]) => [k, Math.round(Number(v) || 0)]),
       ),
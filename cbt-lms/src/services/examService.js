@@ -53,6 +53,8 @@ export const normalizeExamRecord = (item) => {
     numberOfQuestions: Number(item.numberOfQuestions ?? questions.length ?? 0),
     defaultTime: Number(item.defaultTime ?? 0),
     maxAttempts: Number(item.maxAttempts ?? 0),
+    completionScore: Number(item.completionScore ?? 0),
+    skillRewards: Array.isArray(item.skillRewards) ? item.skillRewards : [],
     domainPercentages: item.domainPercentages ?? {},
     questions,
   };
@@ -1,3 +1,5 @@
+import { API_BASE_URL } from "../services/apiClient";
+
 export const avatarStorageKey = (username) => `profile_avatar_${username}`;
 
 export const getAvatarColor = (username) => {
@@ -15,3 +17,6 @@ export const getInitials = (name, username) => {
   if (words.length >= 2) return (words[0][0] + words[1][0]).toUpperCase();
   return text.slice(0, 2).toUpperCase();
 };
+
+// Public avatar URLs from the API are relative to the backend.
+export const avatarSrc = (url) => (url && url.startsWith("/api/") ? `${API_BASE_URL}${url}` : url || "");
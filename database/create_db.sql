@@ -6,6 +6,46 @@
 BEGIN;
 
 -- ---------- DROP (order-safe) ----------
+DROP TABLE IF EXISTS exam_pass_awards CASCADE;
+DROP TABLE IF EXISTS exam_skill_rewards CASCADE;
+DROP TABLE IF EXISTS role_competencies CASCADE;
+DROP TABLE IF EXISTS skill_levels CASCADE;
+DROP TABLE IF EXISTS skill_aliases CASCADE;
+DROP TABLE IF EXISTS skills CASCADE;
+DROP TABLE IF EXISTS leaderboard_snapshots CASCADE;
+DROP TABLE IF EXISTS leaderboard_boards CASCADE;
+DROP TABLE IF EXISTS user_badges CASCADE;
+DROP TABLE IF EXISTS badges CASCADE;
+DROP TABLE IF EXISTS user_calendar_feeds CASCADE;
+DROP TABLE IF EXISTS course_live_session_attendees CASCADE;
+DROP TABLE IF EXISTS course_live_sessions CASCADE;
+DROP TABLE IF EXISTS user_invite_redemptions CASCADE;
+DROP TABLE IF EXISTS user_invite_groups CASCADE;
+DROP TABLE IF EXISTS user_invites CASCADE;
+DROP TABLE IF EXISTS user_group_members CASCADE;
+DROP TABLE IF EXISTS user_groups CASCADE;
+DROP TABLE IF EXISTS course_cohort_members CASCADE;
+DROP TABLE IF EXISTS course_cohorts CASCADE;
+DROP TABLE IF EXISTS course_enrollment_requests CASCADE;
+DROP TABLE IF EXISTS course_reviews CASCADE;
+DROP TABLE IF EXISTS learning_time_cursors CASCADE;
+DROP TABLE IF EXISTS learning_reading_sessions CASCADE;
+DROP TABLE IF EXISTS learning_notes CASCADE;
+DROP TABLE IF EXISTS learning_bookmarks CASCADE;
+DROP TABLE IF EXISTS learning_course_positions CASCADE;
+DROP TABLE IF EXISTS account_tokens CASCADE;
+DROP TABLE IF EXISTS notification_preferences CASCADE;
+DROP TABLE IF EXISTS mail_outbox CASCADE;
+DROP TABLE IF EXISTS notifications CASCADE;
+DROP TABLE IF EXISTS completion_records CASCADE;
+DROP TABLE IF EXISTS assignment_targets CASCADE;
+DROP TABLE IF EXISTS assignments CASCADE;
+DROP TABLE IF EXISTS learning_path_enrollments CASCADE;
+DROP TABLE IF EXISTS learning_path_completions CASCADE;
+DROP TABLE IF EXISTS learning_path_skill_rewards CASCADE;
+DROP TABLE IF EXISTS learning_path_steps CASCADE;
+DROP TABLE IF EXISTS learning_paths CASCADE;
+DROP TABLE IF EXISTS impersonation_logs CASCADE;
 DROP TABLE IF EXISTS app_settings CASCADE;
 DROP TABLE IF EXISTS exam_attempt_answers CASCADE;
 DROP TABLE IF EXISTS exam_attempts CASCADE;
@@ -55,10 +95,19 @@ CREATE TABLE users (
   role_code     TEXT         NOT NULL DEFAULT 'user',
   status        TEXT         NOT NULL DEFAULT 'active',
   created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  anonymised_at TIMESTAMPTZ  NULL,                    -- ลบข้อมูลระบุตัวตนแล้ว (PDPA)
+  manager_username TEXT      NULL,                    -- หัวหน้างานโดยตรง
+  email         TEXT         NOT NULL DEFAULT '',      -- ว่าง = ยังไม่ได้ระบุอีเมล
+  email_verified_at TIMESTAMPTZ NULL,                  -- ยืนยันอีเมลแล้วเมื่อ
+  activation_pending BOOLEAN NOT NULL DEFAULT FALSE,   -- สมัครผ่านโดเมนอีเมล รอยืนยันอีเมลก่อนเปิดใช้งาน
   CONSTRAINT fk_users_role
-    FOREIGN KEY (role_code) REFERENCES roles(code)
+    FOREIGN KEY (role_code) REFERENCES roles(code),
+  CONSTRAINT fk_users_manager
+    FOREIGN KEY (manager_username) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
 );
 
+CREATE UNIQUE INDEX ux_users_verified_email ON users(email) WHERE email <> '' AND email_verified_at IS NOT NULL;  -- อีเมลซ้ำได้จนกว่าจะยืนยัน
+
 CREATE TABLE refresh_tokens (
   id          BIGSERIAL    PRIMARY KEY,
   user_id     BIGINT       NOT NULL,
@@ -100,12 +149,28 @@ CREATE TABLE user_login_logs (
 
 CREATE INDEX ix_refresh_tokens_user ON refresh_tokens(user_id);
 CREATE INDEX ix_users_role_code ON users(role_code);
+CREATE INDEX ix_users_manager ON users(manager_username);
 CREATE INDEX ix_role_permissions_permission ON role_permissions(permission_code);
 CREATE INDEX ix_role_permissions_role_code ON role_permissions(role_code);
 
 CREATE INDEX ix_login_logs_user ON user_login_logs(user_id);
 CREATE INDEX ix_login_logs_time ON user_login_logs(logged_in_at);
 
+-- ประวัติการเข้าดูระบบในมุมมองของผู้ใช้ (impersonation) โดยผู้ดูแลระบบ
+CREATE TABLE impersonation_logs (
+  id              BIGSERIAL    PRIMARY KEY,
+  admin_username  TEXT         NOT NULL,
+  target_username TEXT         NOT NULL,
+  reason          TEXT         NOT NULL DEFAULT '',
+  ip_address      TEXT         NOT NULL DEFAULT '',
+  started_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  expires_at      TIMESTAMPTZ  NOT NULL,
+  ended_at        TIMESTAMPTZ
+);
+
+CREATE INDEX ix_impersonation_logs_admin  ON impersonation_logs(admin_username);
+CREATE INDEX ix_impersonation_logs_target ON impersonation_logs(target_username);
+
 CREATE TABLE app_settings (
   key        TEXT        PRIMARY KEY,
   value      TEXT        NOT NULL DEFAULT '',
@@ -118,7 +183,7 @@ CREATE TABLE user_avatars (
   data_url   TEXT        NOT NULL,
   updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
   CONSTRAINT fk_user_avatars_user
-    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
 );
 
 -- คะแนนรวมของผู้ใช้
@@ -127,23 +192,31 @@ CREATE TABLE user_scores (
   total      INT         NOT NULL DEFAULT 0 CHECK (total >= 0),
   updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
   CONSTRAINT fk_user_scores_user
-    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
 );
 
--- ประวัติการได้คะแนนของผู้ใช้ (สำหรับ leaderboard รายช่วงเวลา)
+-- สมุดบัญชีคะแนน (ledger): ทุกการเปลี่ยนแปลงของ user_scores / user_skill_scores บันทึกที่นี่
+-- ใช้กับ leaderboard รายช่วงเวลา และการ reconcile ยอดคะแนน
 CREATE TABLE user_score_events (
-  id        BIGSERIAL    PRIMARY KEY,
-  username  TEXT         NOT NULL,
-  score     INT          NOT NULL,
-  reason    TEXT         NOT NULL,  -- 'subtopic_complete' | 'course_complete'
-  course_id TEXT,
-  earned_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  id         BIGSERIAL    PRIMARY KEY,
+  username   TEXT         NOT NULL,
+  score      INT          NOT NULL,
+  skill      TEXT         NOT NULL DEFAULT '',  -- ว่าง = คะแนนรวม, มีค่า = คะแนนทักษะ
+  reason     TEXT         NOT NULL,  -- 'subtopic_complete' | 'course_complete' | 'path_complete' | 'exam_pass' | 'adjustment' | 'opening_balance'
+  course_id  TEXT,
+  exam_id    TEXT,
+  note       TEXT         NOT NULL DEFAULT '',  -- เหตุผลของการปรับคะแนนด้วยมือ
+  created_by TEXT         NULL,                 -- ผู้ปรับคะแนน (adjustment)
+  earned_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
   CONSTRAINT fk_score_events_user
-    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE
+    FOREIGN KEY (username)   REFERENCES users(username) ON DELETE CASCADE  ON UPDATE CASCADE,
+  CONSTRAINT fk_score_events_created_by
+    FOREIGN KEY (created_by) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
 );
 
 CREATE INDEX ix_score_events_user ON user_score_events(username);
 CREATE INDEX ix_score_events_time ON user_score_events(earned_at);
+CREATE INDEX ix_score_events_skill ON user_score_events(skill, earned_at) WHERE skill <> '';
 
 -- คะแนนแยกตามทักษะของผู้ใช้
 CREATE TABLE user_skill_scores (
@@ -152,7 +225,7 @@ CREATE TABLE user_skill_scores (
   points     INT  NOT NULL DEFAULT 0 CHECK (points >= 0),
   PRIMARY KEY (username, skill),
   CONSTRAINT fk_user_skill_scores_user
-    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
 );
 
 -- ==========================================================
@@ -175,9 +248,13 @@ CREATE TABLE courses (
   skill_points              INT          NOT NULL DEFAULT 0,
   subtopic_completion_score INT          NOT NULL DEFAULT 0,
   course_completion_score   INT          NOT NULL DEFAULT 0,
+  validity_days             INT          NOT NULL DEFAULT 0,   -- 0 = ไม่หมดอายุ
+  enrollment_mode           TEXT         NOT NULL DEFAULT 'open'
+                            CHECK (enrollment_mode IN ('open', 'approval', 'invite_only', 'capacity')),
+  capacity                  INT          NOT NULL DEFAULT 0 CHECK (capacity >= 0),  -- 0 = ไม่จำกัดที่นั่ง
   created_at                TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
   CONSTRAINT fk_courses_owner
-    FOREIGN KEY (owner_username) REFERENCES users(username) ON DELETE SET NULL
+    FOREIGN KEY (owner_username) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
 );
 
 CREATE INDEX ix_courses_owner  ON courses(owner_username);
@@ -229,7 +306,7 @@ CREATE TABLE user_course_enrollments (
   completed_at TIMESTAMPTZ  NULL,
   PRIMARY KEY (username, course_id),
   CONSTRAINT fk_enrollments_user
-    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE,
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
   CONSTRAINT fk_enrollments_course
     FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE
 );
@@ -246,7 +323,7 @@ CREATE TABLE learning_subtopic_progress (
   completed_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
   PRIMARY KEY (username, course_id, subtopic_id),
   CONSTRAINT fk_subtopic_progress_user
-    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE,
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
   CONSTRAINT fk_subtopic_progress_course
     FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE
 );
@@ -264,7 +341,7 @@ CREATE TABLE learning_subtopic_answers (
   answered_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
   PRIMARY KEY (username, course_id, subtopic_id, question_id),
   CONSTRAINT fk_subtopic_answers_user
-    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE,
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
   CONSTRAINT fk_subtopic_answers_course
     FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE
 );
@@ -280,7 +357,7 @@ CREATE TABLE learning_subtopic_time (
   updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
   PRIMARY KEY (username, course_id, subtopic_id),
   CONSTRAINT fk_subtopic_time_user
-    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE,
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
   CONSTRAINT fk_subtopic_time_course
     FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE
 );
@@ -296,13 +373,22 @@ CREATE TABLE qna_questions (
   id          BIGSERIAL    PRIMARY KEY,
   course_id   TEXT         NOT NULL,
   subtopic_id TEXT         NOT NULL DEFAULT '',
+  cohort_id   BIGINT       NULL,                 -- NULL = ถามตอบทั้งคอร์ส (FK อยู่ในส่วน course_cohorts)
   username    TEXT         NOT NULL,
   question    TEXT         NOT NULL,
+  status      TEXT         NOT NULL DEFAULT 'open'
+              CHECK (status IN ('open', 'resolved')),
+  accepted_reply_id BIGINT NULL,                 -- คำตอบที่ยอมรับ (FK อยู่ท้ายส่วนนี้)
+  hidden_at   TIMESTAMPTZ  NULL,                 -- ซ่อนโดยผู้ดูแลถามตอบ
+  hidden_by   TEXT         NULL,
+  edited_at   TIMESTAMPTZ  NULL,
   created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
   CONSTRAINT fk_qna_questions_course
     FOREIGN KEY (course_id) REFERENCES courses(id) ON DELETE CASCADE,
   CONSTRAINT fk_qna_questions_user
-    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_qna_questions_hidden_by
+    FOREIGN KEY (hidden_by) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
 );
 
 CREATE INDEX ix_qna_questions_course ON qna_questions(course_id);
@@ -314,15 +400,24 @@ CREATE TABLE qna_replies (
   question_id BIGINT       NOT NULL,
   username    TEXT         NOT NULL,
   reply       TEXT         NOT NULL,
+  hidden_at   TIMESTAMPTZ  NULL,
+  hidden_by   TEXT         NULL,
+  edited_at   TIMESTAMPTZ  NULL,
   created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
   CONSTRAINT fk_qna_replies_question
     FOREIGN KEY (question_id) REFERENCES qna_questions(id) ON DELETE CASCADE,
   CONSTRAINT fk_qna_replies_user
-    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_qna_replies_hidden_by
+    FOREIGN KEY (hidden_by) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
 );
 
 CREATE INDEX ix_qna_replies_question ON qna_replies(question_id);
 
+ALTER TABLE qna_questions
+  ADD CONSTRAINT fk_qna_questions_accepted_reply
+    FOREIGN KEY (accepted_reply_id) REFERENCES qna_replies(id) ON DELETE SET NULL;
+
 -- ==========================================================
 -- EXAMS (ข้อสอบ)
 -- ==========================================================
@@ -343,9 +438,11 @@ CREATE TABLE exams (
   number_of_questions INT          NOT NULL DEFAULT 0,
   default_time        INT          NOT NULL DEFAULT 0,  -- minutes
   max_attempts        INT          NOT NULL DEFAULT 0,  -- 0 = unlimited
+  validity_days       INT          NOT NULL DEFAULT 0,  -- 0 = ไม่หมดอายุ
+  completion_score    INT          NOT NULL DEFAULT 0 CHECK (completion_score >= 0),  -- คะแนนเมื่อสอบผ่านครั้งแรก
   created_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
   CONSTRAINT fk_exams_owner
-    FOREIGN KEY (owner_username) REFERENCES users(username) ON DELETE SET NULL
+    FOREIGN KEY (owner_username) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
 );
 
 CREATE INDEX ix_exams_owner  ON exams(owner_username);
@@ -396,7 +493,7 @@ CREATE TABLE exam_attempts (
   started_at      TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
   finished_at     TIMESTAMPTZ,
   CONSTRAINT fk_exam_attempts_user
-    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE,
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
   CONSTRAINT fk_exam_attempts_exam
     FOREIGN KEY (exam_id)  REFERENCES exams(id)       ON DELETE CASCADE
 );
@@ -417,4 +514,587 @@ CREATE TABLE exam_attempt_answers (
     FOREIGN KEY (question_id) REFERENCES exam_questions(id)  ON DELETE CASCADE
 );
 
+-- ==========================================================
+-- LEARNING PATHS (เส้นทางการเรียนรู้)
+-- ==========================================================
+
+-- เส้นทางการเรียนรู้: ลำดับของ course/exam ที่ต้องทำตามลำดับ
+CREATE TABLE learning_paths (
+  id               TEXT         PRIMARY KEY,
+  title            TEXT         NOT NULL,
+  description      TEXT         NOT NULL DEFAULT '',
+  image            TEXT         NOT NULL DEFAULT '',
+  status           TEXT         NOT NULL DEFAULT 'inactive' CHECK (status IN ('active','inactive')),
+  owner_username   TEXT,
+  completion_score INT          NOT NULL DEFAULT 0 CHECK (completion_score >= 0),  -- คะแนนเมื่อจบเส้นทาง
+  created_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_learning_paths_owner
+    FOREIGN KEY (owner_username) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+-- ขั้นตอนในเส้นทาง (ขั้นถัดไปปลดล็อกเมื่อขั้นก่อนหน้าเสร็จ)
+CREATE TABLE learning_path_steps (
+  path_id   TEXT  NOT NULL,
+  position  INT   NOT NULL,
+  item_type TEXT  NOT NULL CHECK (item_type IN ('course','exam')),
+  item_id   TEXT  NOT NULL,  -- courses.id หรือ exams.id ตาม item_type
+  PRIMARY KEY (path_id, position),
+  UNIQUE (path_id, item_type, item_id),
+  CONSTRAINT fk_learning_path_steps_path
+    FOREIGN KEY (path_id) REFERENCES learning_paths(id) ON DELETE CASCADE
+);
+
+CREATE INDEX ix_learning_path_steps_item ON learning_path_steps(item_type, item_id);
+
+-- คะแนนทักษะที่ได้เมื่อจบเส้นทาง
+CREATE TABLE learning_path_skill_rewards (
+  path_id TEXT NOT NULL,
+  skill   TEXT NOT NULL,
+  points  INT  NOT NULL DEFAULT 0 CHECK (points >= 0),
+  PRIMARY KEY (path_id, skill),
+  CONSTRAINT fk_learning_path_skill_rewards_path
+    FOREIGN KEY (path_id) REFERENCES learning_paths(id) ON DELETE CASCADE
+);
+
+-- ผู้ใช้ที่จบเส้นทางแล้ว (ให้รางวัลครั้งเดียว)
+CREATE TABLE learning_path_completions (
+  path_id       TEXT         NOT NULL,
+  username      TEXT         NOT NULL,
+  awarded_score INT          NOT NULL DEFAULT 0,
+  completed_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (path_id, username),
+  CONSTRAINT fk_learning_path_completions_path
+    FOREIGN KEY (path_id)  REFERENCES learning_paths(id)  ON DELETE CASCADE,
+  CONSTRAINT fk_learning_path_completions_user
+    FOREIGN KEY (username) REFERENCES users(username)     ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_learning_path_completions_user ON learning_path_completions(username);
+
+-- ผู้เรียนที่ลงทะเบียนเส้นทาง (ขั้นตอนจะปลดล็อกตามลำดับเฉพาะผู้ที่ลงทะเบียนหรือได้รับมอบหมาย)
+CREATE TABLE learning_path_enrollments (
+  path_id     TEXT         NOT NULL,
+  username    TEXT         NOT NULL,
+  enrolled_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (path_id, username),
+  CONSTRAINT fk_learning_path_enrollments_path
+    FOREIGN KEY (path_id)  REFERENCES learning_paths(id)  ON DELETE CASCADE,
+  CONSTRAINT fk_learning_path_enrollments_user
+    FOREIGN KEY (username) REFERENCES users(username)     ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_learning_path_enrollments_user ON learning_path_enrollments(username);
+
+-- ==========================================================
+-- ASSIGNMENTS (การมอบหมายการเรียน)
+-- ==========================================================
+
+-- การมอบหมาย course / exam / path พร้อมกำหนดส่ง (สถานะคำนวณจากข้อมูลการเรียน/การสอบ)
+CREATE TABLE assignments (
+  id              BIGSERIAL    PRIMARY KEY,
+  item_type       TEXT         NOT NULL CHECK (item_type IN ('course','exam','path')),
+  item_id         TEXT         NOT NULL,
+  due_at          TIMESTAMPTZ  NOT NULL,
+  recurrence_days INT          NOT NULL DEFAULT 0 CHECK (recurrence_days >= 0),  -- 0 = ครั้งเดียว, >0 = ต้องทำซ้ำทุก N วัน
+  note            TEXT         NOT NULL DEFAULT '',
+  created_by      TEXT,
+  created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_assignments_created_by
+    FOREIGN KEY (created_by) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_assignments_item ON assignments(item_type, item_id);
+
+-- ผู้รับการมอบหมาย: รายบุคคล (user) หรือทั้ง role
+CREATE TABLE assignment_targets (
+  assignment_id BIGINT NOT NULL,
+  target_type   TEXT   NOT NULL CHECK (target_type IN ('user','role')),
+  target_value  TEXT   NOT NULL,  -- username หรือ role code
+  PRIMARY KEY (assignment_id, target_type, target_value),
+  CONSTRAINT fk_assignment_targets_assignment
+    FOREIGN KEY (assignment_id) REFERENCES assignments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX ix_assignment_targets_value ON assignment_targets(target_type, target_value);
+
+-- ประวัติการเรียนจบ course / สอบผ่าน exam (เก็บทุกครั้ง ใช้คำนวณวันหมดอายุของใบรับรอง)
+CREATE TABLE completion_records (
+  id           BIGSERIAL    PRIMARY KEY,
+  username     TEXT         NOT NULL,
+  item_type    TEXT         NOT NULL CHECK (item_type IN ('course','exam')),
+  item_id      TEXT         NOT NULL,
+  attempt_id   BIGINT       UNIQUE,  -- exam attempt ที่สอบผ่าน
+  completed_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_completion_records_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_completion_records_attempt
+    FOREIGN KEY (attempt_id) REFERENCES exam_attempts(id) ON DELETE SET NULL
+);
+
+CREATE INDEX ix_completion_records_user_item ON completion_records(username, item_type, item_id, completed_at DESC);
+CREATE INDEX ix_completion_records_item ON completion_records(item_type, item_id);
+
+-- การแจ้งเตือนในระบบ (ตอบคำถาม, คำถามใหม่, กำหนดส่งงาน, ผลสอบ, เปลี่ยน role)
+CREATE TABLE notifications (
+  id         BIGSERIAL    PRIMARY KEY,
+  username   TEXT         NOT NULL,
+  type       TEXT         NOT NULL,  -- qna_reply | qna_question | assignment_new | assignment_due | assignment_overdue | exam_graded | role_changed | certificate_issued
+  title      TEXT         NOT NULL,
+  body       TEXT         NOT NULL DEFAULT '',
+  link       TEXT         NOT NULL DEFAULT '',
+  dedupe_key TEXT,                   -- กันแจ้งเตือนซ้ำ (เช่น เตือนกำหนดส่ง)
+  created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  read_at    TIMESTAMPTZ,
+  UNIQUE (username, dedupe_key),
+  CONSTRAINT fk_notifications_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_notifications_user ON notifications(username, created_at DESC);
+CREATE INDEX ix_notifications_unread ON notifications(username) WHERE read_at IS NULL;
+
+-- คิวอีเมลขาออก (transactional outbox) ส่งโดย background worker พร้อม retry/backoff
+CREATE TABLE mail_outbox (
+  id              BIGSERIAL    PRIMARY KEY,
+  username        TEXT,
+  to_address      TEXT         NOT NULL,
+  template        TEXT         NOT NULL,  -- password_reset | password_reset_request | email_verification | assignment_new | assignment_due | certificate_issued
+  locale          TEXT         NOT NULL DEFAULT 'th',
+  payload         JSONB        NOT NULL DEFAULT '{}',
+  status          TEXT         NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','sent','failed')),
+  attempts        INT          NOT NULL DEFAULT 0,
+  last_error      TEXT         NOT NULL DEFAULT '',
+  next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  sent_at         TIMESTAMPTZ,
+  CONSTRAINT fk_mail_outbox_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_mail_outbox_pending ON mail_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX ix_mail_outbox_user ON mail_outbox(username);
+
+-- การตั้งค่าการแจ้งเตือนของผู้ใช้: ภาษาอีเมล และประเภทอีเมลที่ไม่ต้องการรับ
+CREATE TABLE notification_preferences (
+  username      TEXT         PRIMARY KEY,
+  locale        TEXT         NOT NULL DEFAULT 'th' CHECK (locale IN ('th','en')),
+  email_opt_out TEXT[]       NOT NULL DEFAULT '{}',
+  updated_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_notification_preferences_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- โทเคนใช้ครั้งเดียวที่ส่งทางอีเมล (รีเซ็ตรหัสผ่าน / ยืนยันอีเมล) เก็บเฉพาะค่า hash
+CREATE TABLE account_tokens (
+  id          BIGSERIAL    PRIMARY KEY,
+  user_id     BIGINT       NOT NULL,
+  purpose     TEXT         NOT NULL CHECK (purpose IN ('password_reset','email_verify')),
+  token_hash  TEXT         NULL UNIQUE,       -- ตั้งค่าเมื่อส่งอีเมลที่มีลิงก์
+  email       TEXT         NOT NULL DEFAULT '',   -- อีเมลที่โทเคนนี้ออกให้
+  expires_at  TIMESTAMPTZ  NOT NULL,
+  used_at     TIMESTAMPTZ  NULL,
+  created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_account_tokens_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX ix_account_tokens_user ON account_tokens(user_id, purpose);
+
+-- กลุ่มผู้ใช้ที่ผู้ดูแลกำหนดเอง (แยกจากบทบาท)
+CREATE TABLE user_groups (
+  id          BIGSERIAL    PRIMARY KEY,
+  name        TEXT         NOT NULL UNIQUE,
+  created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE user_group_members (
+  group_id    BIGINT       NOT NULL,
+  username    TEXT         NOT NULL,
+  added_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (group_id, username),
+  CONSTRAINT fk_user_group_members_group
+    FOREIGN KEY (group_id) REFERENCES user_groups(id) ON DELETE CASCADE,
+  CONSTRAINT fk_user_group_members_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_user_group_members_user ON user_group_members(username);
+
+-- ลิงก์เชิญสมัครสมาชิก: กำหนดบทบาทและกลุ่มล่วงหน้า มีวันหมดอายุ จำนวนครั้งที่ใช้ได้ และเพิกถอนได้
+CREATE TABLE user_invites (
+  id          BIGSERIAL    PRIMARY KEY,
+  token_hash  TEXT         NOT NULL UNIQUE,
+  label       TEXT         NOT NULL DEFAULT '',
+  role_code   TEXT         NOT NULL,
+  max_uses    INT          NOT NULL CHECK (max_uses > 0),
+  use_count   INT          NOT NULL DEFAULT 0,
+  expires_at  TIMESTAMPTZ  NOT NULL,
+  revoked_at  TIMESTAMPTZ  NULL,
+  created_by  TEXT         NULL,
+  created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_user_invites_role
+    FOREIGN KEY (role_code) REFERENCES roles(code) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_user_invites_creator
+    FOREIGN KEY (created_by) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE TABLE user_invite_groups (
+  invite_id   BIGINT       NOT NULL,
+  group_id    BIGINT       NOT NULL,
+  PRIMARY KEY (invite_id, group_id),
+  CONSTRAINT fk_user_invite_groups_invite
+    FOREIGN KEY (invite_id) REFERENCES user_invites(id) ON DELETE CASCADE,
+  CONSTRAINT fk_user_invite_groups_group
+    FOREIGN KEY (group_id) REFERENCES user_groups(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_invite_redemptions (
+  invite_id   BIGINT       NOT NULL,
+  username    TEXT         NOT NULL,
+  redeemed_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (invite_id, username),
+  CONSTRAINT fk_user_invite_redemptions_invite
+    FOREIGN KEY (invite_id) REFERENCES user_invites(id) ON DELETE CASCADE,
+  CONSTRAINT fk_user_invite_redemptions_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_user_invite_redemptions_user ON user_invite_redemptions(username);
+
+-- ตำแหน่งล่าสุดที่ผู้เรียนอ่านค้างไว้ในแต่ละคอร์ส บุ๊กมาร์ก และโน้ตส่วนตัว (markdown) ที่ผูกกับหัวข้อย่อย
+CREATE TABLE learning_course_positions (
+  username    TEXT         NOT NULL,
+  course_id   TEXT         NOT NULL,
+  subtopic_id TEXT         NOT NULL,
+  visited_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (username, course_id),
+  CONSTRAINT fk_course_positions_user
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_course_positions_course
+    FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE
+);
+
+CREATE TABLE learning_bookmarks (
+  username    TEXT         NOT NULL,
+  course_id   TEXT         NOT NULL,
+  subtopic_id TEXT         NOT NULL,
+  created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (username, course_id, subtopic_id),
+  CONSTRAINT fk_learning_bookmarks_user
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_learning_bookmarks_course
+    FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE
+);
+
+CREATE TABLE learning_notes (
+  id          BIGSERIAL    PRIMARY KEY,
+  username    TEXT         NOT NULL,
+  course_id   TEXT         NOT NULL,
+  subtopic_id TEXT         NOT NULL,
+  body        TEXT         NOT NULL,
+  created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_learning_notes_user
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_learning_notes_course
+    FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE
+);
+
+CREATE INDEX ix_learning_notes_user_course ON learning_notes(username, course_id, subtopic_id);
+
+-- เซสชันการอ่านที่เซิร์ฟเวอร์ออกให้: นับเวลาเรียนจาก heartbeat แทนจำนวนวินาทีที่ไคลเอนต์ส่งมา
+-- learning_time_cursors เก็บเวลาที่นับให้ผู้ใช้ไปแล้ว เพื่อไม่ให้หลายแท็บนับเวลาซ้ำกัน
+CREATE TABLE learning_reading_sessions (
+  id               BIGSERIAL    PRIMARY KEY,
+  username         TEXT         NOT NULL,
+  course_id        TEXT         NOT NULL,
+  subtopic_id      TEXT         NOT NULL,
+  started_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  last_beat_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  ended_at         TIMESTAMPTZ  NULL,
+  credited_seconds INT          NOT NULL DEFAULT 0,
+  CONSTRAINT fk_reading_sessions_user
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_reading_sessions_course
+    FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE
+);
+
+CREATE INDEX ix_reading_sessions_open ON learning_reading_sessions(username) WHERE ended_at IS NULL;
+
+CREATE TABLE learning_time_cursors (
+  username       TEXT         PRIMARY KEY,
+  credited_until TIMESTAMPTZ  NOT NULL,
+  CONSTRAINT fk_time_cursors_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- คะแนน (1–5) และรีวิวคอร์สจากผู้เรียนที่เรียนจบแล้ว พร้อมการซ่อนรีวิวและคำตอบจากผู้สอน
+CREATE TABLE course_reviews (
+  id          BIGSERIAL    PRIMARY KEY,
+  course_id   TEXT         NOT NULL,
+  username    TEXT         NOT NULL,
+  rating      SMALLINT     NOT NULL CHECK (rating BETWEEN 1 AND 5),
+  review      TEXT         NOT NULL DEFAULT '',
+  reply       TEXT         NOT NULL DEFAULT '',
+  reply_by    TEXT         NULL,
+  replied_at  TIMESTAMPTZ  NULL,
+  hidden_at   TIMESTAMPTZ  NULL,
+  hidden_by   TEXT         NULL,
+  created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  UNIQUE (course_id, username),
+  CONSTRAINT fk_course_reviews_course
+    FOREIGN KEY (course_id) REFERENCES courses(id)    ON DELETE CASCADE,
+  CONSTRAINT fk_course_reviews_user
+    FOREIGN KEY (username)  REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_course_reviews_reply_by
+    FOREIGN KEY (reply_by)  REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+  CONSTRAINT fk_course_reviews_hidden_by
+    FOREIGN KEY (hidden_by) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_course_reviews_user ON course_reviews(username);
+
+-- คำขอลงทะเบียนที่รออนุมัติ, รายชื่อสำรอง (waitlist) และคำเชิญ — ผู้ที่ลงทะเบียนแล้วอยู่ใน user_course_enrollments
+CREATE TABLE course_enrollment_requests (
+  course_id    TEXT         NOT NULL,
+  username     TEXT         NOT NULL,
+  status       TEXT         NOT NULL CHECK (status IN ('pending', 'waitlisted', 'invited', 'rejected')),
+  requested_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  decided_by   TEXT         NULL,
+  decided_at   TIMESTAMPTZ  NULL,
+  PRIMARY KEY (course_id, username),
+  CONSTRAINT fk_enrollment_requests_course
+    FOREIGN KEY (course_id)  REFERENCES courses(id)    ON DELETE CASCADE,
+  CONSTRAINT fk_enrollment_requests_user
+    FOREIGN KEY (username)   REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_enrollment_requests_decided_by
+    FOREIGN KEY (decided_by) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_enrollment_requests_user  ON course_enrollment_requests(username);
+CREATE INDEX ix_enrollment_requests_queue ON course_enrollment_requests(course_id, status, requested_at);
+
+-- รุ่นของคอร์ส (cohort): วันเริ่ม/สิ้นสุด ผู้สอน และการปลดล็อกบททีละช่วงตามตาราง
+CREATE TABLE course_cohorts (
+  id                   BIGSERIAL    PRIMARY KEY,
+  course_id            TEXT         NOT NULL,
+  name                 TEXT         NOT NULL,
+  instructor_username  TEXT         NULL,
+  starts_at            TIMESTAMPTZ  NOT NULL,
+  ends_at              TIMESTAMPTZ  NOT NULL,
+  unlock_interval_days INT          NOT NULL DEFAULT 0 CHECK (unlock_interval_days >= 0),  -- 0 = ปลดล็อกทุกบทตั้งแต่วันเริ่ม
+  created_at           TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  UNIQUE (course_id, name),
+  CHECK (ends_at > starts_at),
+  CONSTRAINT fk_course_cohorts_course
+    FOREIGN KEY (course_id)           REFERENCES courses(id)    ON DELETE CASCADE,
+  CONSTRAINT fk_course_cohorts_instructor
+    FOREIGN KEY (instructor_username) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_course_cohorts_instructor ON course_cohorts(instructor_username);
+
+-- ผู้เรียนอยู่ได้เพียงรุ่นเดียวต่อคอร์ส
+CREATE TABLE course_cohort_members (
+  cohort_id BIGINT       NOT NULL,
+  course_id TEXT         NOT NULL,
+  username  TEXT         NOT NULL,
+  added_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (cohort_id, username),
+  UNIQUE (course_id, username),
+  CONSTRAINT fk_cohort_members_cohort
+    FOREIGN KEY (cohort_id) REFERENCES course_cohorts(id) ON DELETE CASCADE,
+  CONSTRAINT fk_cohort_members_course
+    FOREIGN KEY (course_id) REFERENCES courses(id)        ON DELETE CASCADE,
+  CONSTRAINT fk_cohort_members_user
+    FOREIGN KEY (username)  REFERENCES users(username)     ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_cohort_members_user ON course_cohort_members(username);
+
+ALTER TABLE qna_questions
+  ADD CONSTRAINT fk_qna_questions_cohort
+    FOREIGN KEY (cohort_id) REFERENCES course_cohorts(id) ON DELETE SET NULL;
+
+CREATE INDEX ix_qna_questions_cohort ON qna_questions(cohort_id);
+
+-- คลาสสด (ในห้องเรียนหรือออนไลน์) ของคอร์ส: ลงทะเบียน จำกัดที่นั่ง และเช็กชื่อ
+CREATE TABLE course_live_sessions (
+  id                  BIGSERIAL    PRIMARY KEY,
+  course_id           TEXT         NOT NULL,
+  cohort_id           BIGINT       NULL,                                   -- NULL = ผู้เรียนทุกคนของคอร์ส
+  title               TEXT         NOT NULL,
+  description         TEXT         NOT NULL DEFAULT '',
+  starts_at           TIMESTAMPTZ  NOT NULL,
+  ends_at             TIMESTAMPTZ  NOT NULL,
+  location            TEXT         NOT NULL DEFAULT '',
+  meeting_url         TEXT         NOT NULL DEFAULT '',
+  capacity            INT          NOT NULL DEFAULT 0 CHECK (capacity >= 0),  -- 0 = ไม่จำกัด
+  required            BOOLEAN      NOT NULL DEFAULT FALSE,                 -- ต้องเข้าร่วมก่อนจบคอร์ส
+  instructor_username TEXT         NULL,
+  checkin_secret      TEXT         NOT NULL,                               -- ใช้สร้างรหัสเช็กชื่อที่หมุนเวียน
+  created_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CHECK (ends_at > starts_at),
+  CONSTRAINT fk_live_sessions_course
+    FOREIGN KEY (course_id)           REFERENCES courses(id)        ON DELETE CASCADE,
+  CONSTRAINT fk_live_sessions_cohort
+    FOREIGN KEY (cohort_id)           REFERENCES course_cohorts(id) ON DELETE CASCADE,
+  CONSTRAINT fk_live_sessions_instructor
+    FOREIGN KEY (instructor_username) REFERENCES users(username)    ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_live_sessions_course     ON course_live_sessions(course_id, starts_at);
+CREATE INDEX ix_live_sessions_instructor ON course_live_sessions(instructor_username);
+
+CREATE TABLE course_live_session_attendees (
+  session_id        BIGINT       NOT NULL,
+  username          TEXT         NOT NULL,
+  registered_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  attended_at       TIMESTAMPTZ  NULL,
+  attendance_method TEXT         NULL CHECK (attendance_method IN ('instructor', 'code')),
+  marked_by         TEXT         NULL,
+  PRIMARY KEY (session_id, username),
+  CONSTRAINT fk_live_session_attendees_session
+    FOREIGN KEY (session_id) REFERENCES course_live_sessions(id) ON DELETE CASCADE,
+  CONSTRAINT fk_live_session_attendees_user
+    FOREIGN KEY (username)   REFERENCES users(username)          ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_live_session_attendees_marker
+    FOREIGN KEY (marked_by)  REFERENCES users(username)          ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_live_session_attendees_user ON course_live_session_attendees(username);
+
+-- ลิงก์ปฏิทิน iCalendar ส่วนตัว (เก็บเฉพาะ hash ของ token)
+CREATE TABLE user_calendar_feeds (
+  username   TEXT         PRIMARY KEY,
+  token_hash TEXT         NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_calendar_feeds_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+-- เหรียญรางวัล (badge): เกณฑ์ที่ตรวจอัตโนมัติเมื่อเกิดเหตุการณ์ และมอบย้อนหลังเมื่อสร้างใหม่
+CREATE TABLE badges (
+  id          BIGSERIAL    PRIMARY KEY,
+  name        TEXT         NOT NULL UNIQUE,
+  description TEXT         NOT NULL DEFAULT '',
+  image_url   TEXT         NOT NULL DEFAULT '',
+  criteria    TEXT         NOT NULL CHECK (criteria IN ('courses_completed', 'skill_points', 'exam_score', 'login_streak')),
+  threshold   INT          NOT NULL CHECK (threshold > 0),   -- จำนวนคอร์ส / คะแนนทักษะ / % คะแนนสอบ / จำนวนวันติดต่อกัน
+  skill       TEXT         NOT NULL DEFAULT '',              -- ใช้กับ skill_points
+  exam_id     TEXT         NULL,                             -- ใช้กับ exam_score; NULL = ข้อสอบใดก็ได้
+  active      BOOLEAN      NOT NULL DEFAULT TRUE,
+  created_by  TEXT         NULL,
+  created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  CONSTRAINT fk_badges_exam
+    FOREIGN KEY (exam_id)    REFERENCES exams(id)       ON DELETE CASCADE,
+  CONSTRAINT fk_badges_created_by
+    FOREIGN KEY (created_by) REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+-- ประวัติการได้รับเหรียญ
+CREATE TABLE user_badges (
+  badge_id   BIGINT       NOT NULL,
+  username   TEXT         NOT NULL,
+  awarded_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (badge_id, username),
+  CONSTRAINT fk_user_badges_badge
+    FOREIGN KEY (badge_id) REFERENCES badges(id)      ON DELETE CASCADE,
+  CONSTRAINT fk_user_badges_user
+    FOREIGN KEY (username) REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_user_badges_user ON user_badges(username, awarded_at);
+
+-- กระดานผู้นำ (leaderboard): snapshot ที่คำนวณล่วงหน้าเป็นระยะ แยกตามช่วงเวลาและทักษะ
+CREATE TABLE leaderboard_boards (
+  board       TEXT         PRIMARY KEY,   -- all / week / month / skill:<ทักษะ>
+  period      TEXT         NOT NULL,
+  starts_at   TIMESTAMPTZ  NULL,
+  ends_at     TIMESTAMPTZ  NULL,
+  computed_at TIMESTAMPTZ  NOT NULL
+);
+
+CREATE TABLE leaderboard_snapshots (
+  board    TEXT  NOT NULL,
+  username TEXT  NOT NULL,
+  score    INT   NOT NULL,
+  rank     INT   NOT NULL,
+  PRIMARY KEY (board, username),
+  CONSTRAINT fk_leaderboard_snapshots_board
+    FOREIGN KEY (board)    REFERENCES leaderboard_boards(board) ON DELETE CASCADE,
+  CONSTRAINT fk_leaderboard_snapshots_user
+    FOREIGN KEY (username) REFERENCES users(username)           ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX ix_leaderboard_snapshots_rank ON leaderboard_snapshots(board, rank);
+
+-- แคตตาล็อกทักษะ: คอร์ส เส้นทาง เหรียญ และคะแนน อ้างอิงทักษะด้วยชื่อหลัก (name)
+CREATE TABLE skills (
+  id          BIGSERIAL    PRIMARY KEY,
+  name        TEXT         NOT NULL,
+  category    TEXT         NOT NULL DEFAULT '',
+  description TEXT         NOT NULL DEFAULT '',
+  created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX ux_skills_name ON skills(LOWER(name));
+
+-- ชื่อเรียกอื่นของทักษะ (เช่น Golang → Go); alias_key = ตัวพิมพ์เล็ก ตัดช่องว่างซ้ำ
+CREATE TABLE skill_aliases (
+  alias_key TEXT    PRIMARY KEY,
+  alias     TEXT    NOT NULL,
+  skill_id  BIGINT  NOT NULL,
+  CONSTRAINT fk_skill_aliases_skill
+    FOREIGN KEY (skill_id) REFERENCES skills(id) ON DELETE CASCADE
+);
+
+CREATE INDEX ix_skill_aliases_skill ON skill_aliases(skill_id);
+
+-- ระดับความชำนาญ คำนวณจากคะแนนทักษะ (ต่ำกว่าระดับแรก = ระดับ 0)
+CREATE TABLE skill_levels (
+  level      INT   PRIMARY KEY CHECK (level > 0),
+  name       TEXT  NOT NULL,
+  min_points INT   NOT NULL UNIQUE CHECK (min_points > 0)
+);
+
+-- โปรไฟล์สมรรถนะของตำแหน่งงาน: ทักษะและระดับที่ต้องการ
+CREATE TABLE role_competencies (
+  role_code TEXT    NOT NULL,
+  skill_id  BIGINT  NOT NULL,
+  level     INT     NOT NULL CHECK (level > 0),
+  PRIMARY KEY (role_code, skill_id),
+  CONSTRAINT fk_role_competencies_role
+    FOREIGN KEY (role_code) REFERENCES roles(code) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_role_competencies_skill
+    FOREIGN KEY (skill_id)  REFERENCES skills(id)  ON DELETE CASCADE
+);
+
+-- คะแนนทักษะเมื่อสอบผ่าน; domain ว่าง = ได้เต็ม, มีค่า = คิดตามสัดส่วนที่ตอบถูกใน domain นั้น
+CREATE TABLE exam_skill_rewards (
+  exam_id TEXT  NOT NULL,
+  skill   TEXT  NOT NULL,
+  domain  TEXT  NOT NULL DEFAULT '',
+  points  INT   NOT NULL CHECK (points > 0),
+  PRIMARY KEY (exam_id, skill, domain),
+  CONSTRAINT fk_exam_skill_rewards_exam
+    FOREIGN KEY (exam_id) REFERENCES exams(id) ON DELETE CASCADE
+);
+
+-- การจ่ายรางวัลข้อสอบ: จ่ายครั้งเดียวต่อผู้ใช้และข้อสอบ เมื่อสอบผ่านครั้งแรก
+CREATE TABLE exam_pass_awards (
+  username   TEXT         NOT NULL,
+  exam_id    TEXT         NOT NULL,
+  attempt_id BIGINT       NULL,
+  awarded_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (username, exam_id),
+  CONSTRAINT fk_exam_pass_awards_user
+    FOREIGN KEY (username)   REFERENCES users(username)   ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT fk_exam_pass_awards_exam
+    FOREIGN KEY (exam_id)    REFERENCES exams(id)         ON DELETE CASCADE,
+  CONSTRAINT fk_exam_pass_awards_attempt
+    FOREIGN KEY (attempt_id) REFERENCES exam_attempts(id) ON DELETE SET NULL
+);
+
 COMMIT;
@@ -35,11 +35,14 @@ INSERT INTO permissions (code, module, action, description) VALUES
   ('exam.take',                     'exam',       'take',               'เข้าทำข้อสอบ'),
   ('exam.manage',                   'exam',       'manage',             'สร้าง / แก้ไขข้อสอบ'),
   ('exam.view_all',                 'exam',       'view_all',           'ดูข้อสอบทั้งหมด (รวม Private)'),
+  ('qna.moderate',                  'qna',        'moderate',           'ดูแลกระทู้ถามตอบ (ซ่อน / แสดงโพสต์)'),
   ('system.report.view',            'system',     'report.view',        'ดูรายงานสรุปผล'),
   ('system.exam_history.view',      'system',     'exam_history.view',  'ดูประวัติการสอบของตัวเอง'),
   ('management.users.manage',       'management', 'users.manage',       'จัดการผู้ใช้'),
+  ('management.users.impersonate',  'management', 'users.impersonate',  'ดูระบบในมุมมองของผู้ใช้ (อ่านอย่างเดียว)'),
   ('management.roles.manage',       'management', 'roles.manage',       'จัดการสิทธิ์การใช้งาน'),
-  ('management.exam_history.view',  'management', 'exam_history.view',  'ดูประวัติการสอบของทุกคน');
+  ('management.exam_history.view',  'management', 'exam_history.view',  'ดูประวัติการสอบของทุกคน'),
+  ('management.assignments.manage', 'management', 'assignments.manage', 'มอบหมายการเรียน / ดูรายงานการปฏิบัติตาม');
 
 INSERT INTO role_permissions (role_code, permission_code) VALUES
   ('user',       'content.learn'),
@@ -51,6 +54,7 @@ INSERT INTO role_permissions (role_code, permission_code) VALUES
   ('instructor', 'exam.take'),
   ('instructor', 'exam.manage'),
   ('instructor', 'exam.view_all'),
+  ('instructor', 'qna.moderate'),
   ('instructor', 'system.report.view'),
   ('instructor', 'system.exam_history.view');
 
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// accountMailCooldown is the minimum time between two verification or reset mails
+// for the same account.
+const accountMailCooldown = time.Minute
+
+const forgotPasswordMessage = "if the account exists and has a verified email, a reset link has been sent"
+
+// parseEmail normalizes an optional email from a request; "" means no email.
+func parseEmail(raw string) (string, error) {
+	email := data.NormalizeEmail(raw)
+	if email != "" && !data.IsValidEmail(email) {
+		return "", fiber.NewError(fiber.StatusBadRequest, "email is invalid")
+	}
+	return email, nil
+}
+
+// checkNewUserEmail validates the optional email of an account about to be created.
+func (h *Handler) checkNewUserEmail(raw string) (string, error) {
+	email, err := parseEmail(raw)
+	if err != nil || email == "" {
+		return email, err
+	}
+	taken, err := data.EmailInUse(email)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "cannot validate email")
+	}
+	if taken {
+		return "", fiber.NewError(fiber.StatusConflict, "email already in use")
+	}
+	return email, nil
+}
+
+// sendEmailVerification issues a verification token for email and queues the mail
+// that delivers its link. The mail goes to the unverified address itself.
+func (h *Handler) sendEmailVerification(userID int64, username, email string) error {
+	ttl := time.Duration(h.cfg.EmailVerifyHours) * time.Hour
+	tokenID, err := data.CreateAccountToken(userID, data.TokenPurposeEmailVerify, email, ttl)
+	if err != nil {
+		return err
+	}
+	return data.EnqueueMailTo(username, email, data.MailEmailVerification, map[string]string{
+		"email":          email,
+		"expiresInHours": strconv.Itoa(h.cfg.EmailVerifyHours),
+		"tokenId":        strconv.FormatInt(tokenID, 10),
+	})
+}
+
+// assignUserEmail sets the user's email and, when it changed, sends a verification
+// mail. Mail errors are logged; the email itself is already saved.
+func (h *Handler) assignUserEmail(userID int64, username, email string) error {
+	changed, err := data.SetUserEmail(username, email)
+	if err != nil {
+		return err
+	}
+	if changed && email != "" {
+		if err := h.sendEmailVerification(userID, username, email); err != nil {
+			log.Printf("send email verification for %s: %v", username, err)
+		}
+	}
+	return nil
+}
+
+// UpdateMyEmail sets the caller's email address. A new address starts unverified and a
+// verification link is mailed to it; an empty email removes the address.
+func (h *Handler) UpdateMyEmail(c *fiber.Ctx) error {
+	userID, err := auth.CurrentUserID(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	var req updateEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	email, err := parseEmail(req.Email)
+	if err != nil {
+		return err
+	}
+
+	if err := h.assignUserEmail(userID, username, email); err != nil {
+		if errors.Is(err, data.ErrEmailTaken) {
+			return fiber.NewError(fiber.StatusConflict, "email already in use")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update email")
+	}
+	current, err := data.GetUserEmail(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get email")
+	}
+	return c.JSON(fiber.Map{
+		"message":        "update email success",
+		"email":          current.Email,
+		"email_verified": current.VerifiedAt != nil,
+	})
+}
+
+// ResendEmailVerification mails a fresh verification link for the caller's email.
+func (h *Handler) ResendEmailVerification(c *fiber.Ctx) error {
+	userID, err := auth.CurrentUserID(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	current, err := data.GetUserEmail(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get email")
+	}
+	if current.Email == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "no email address is set")
+	}
+	if current.VerifiedAt != nil {
+		return fiber.NewError(fiber.StatusConflict, "email is already verified")
+	}
+	recent, err := data.RecentAccountTokenExists(userID, data.TokenPurposeEmailVerify, accountMailCooldown)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot send verification email")
+	}
+	if recent {
+		return fiber.NewError(fiber.StatusTooManyRequests, "a verification email was sent recently, please wait before retrying")
+	}
+	if err := h.sendEmailVerification(userID, username, current.Email); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot send verification email")
+	}
+	return c.JSON(fiber.Map{"message": "verification email sent"})
+}
+
+func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
+	var req tokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "token is required")
+	}
+	if err := data.VerifyEmailToken(auth.HashOneTimeToken(req.Token)); err != nil {
+		if errors.Is(err, data.ErrInvalidToken) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, data.ErrEmailTaken) {
+			return fiber.NewError(fiber.StatusConflict, "email already in use")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot verify email")
+	}
+	return c.JSON(fiber.Map{"message": "email verified"})
+}
+
+// ResendActivation mails a new verification link to an account that is waiting for
+// activation, for learners who cannot sign in yet. Like ForgotPassword it always
+// answers the same.
+func (h *Handler) ResendActivation(c *fiber.Ctx) error {
+	var req forgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Login = strings.TrimSpace(req.Login)
+	if req.Login == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "login is required")
+	}
+
+	if err := h.resendActivation(req.Login); err != nil {
+		log.Printf("resend activation for %q: %v", req.Login, err)
+	}
+	return c.JSON(fiber.Map{"message": "if the account is awaiting activation, a verification link has been sent"})
+}
+
+func (h *Handler) resendActivation(login string) error {
+	user, err := data.FindUserByLogin(login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	pending, err := data.IsActivationPending(user.ID)
+	if err != nil || !pending {
+		return err
+	}
+	email, err := data.GetUserEmail(user.Username)
+	if err != nil || email.Email == "" {
+		return err
+	}
+	recent, err := data.RecentAccountTokenExists(user.ID, data.TokenPurposeEmailVerify, accountMailCooldown)
+	if err != nil || recent {
+		return err
+	}
+	return h.sendEmailVerification(user.ID, user.Username, email.Email)
+}
+
+// ForgotPassword mails a single-use reset link to the verified email of the account
+// named by username or email. The response is the same whether or not a mail was
+// sent, so it cannot be used to discover accounts.
+func (h *Handler) ForgotPassword(c *fiber.Ctx) error {
+	var req forgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Login = strings.TrimSpace(req.Login)
+	if req.Login == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "login is required")
+	}
+
+	if err := h.sendPasswordReset(req.Login); err != nil {
+		log.Printf("forgot password for %q: %v", req.Login, err)
+	}
+	return c.JSON(fiber.Map{"message": forgotPasswordMessage})
+}
+
+func (h *Handler) sendPasswordReset(login string) error {
+	user, err := data.FindUserByLogin(login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if strings.ToLower(strings.TrimSpace(user.Status)) != "active" {
+		return nil
+	}
+	email, err := data.GetUserEmail(user.Username)
+	if err != nil {
+		return err
+	}
+	if email.Email == "" || email.VerifiedAt == nil {
+		return nil
+	}
+	recent, err := data.RecentAccountTokenExists(user.ID, data.TokenPurposePasswordReset, accountMailCooldown)
+	if err != nil || recent {
+		return err
+	}
+
+	ttl := time.Duration(h.cfg.PasswordResetMinutes) * time.Minute
+	tokenID, err := data.CreateAccountToken(user.ID, data.TokenPurposePasswordReset, email.Email, ttl)
+	if err != nil {
+		return err
+	}
+	return data.EnqueueMailTo(user.Username, email.Email, data.MailPasswordResetRequest, map[string]string{
+		"expiresInMinutes": strconv.Itoa(h.cfg.PasswordResetMinutes),
+		"tokenId":          strconv.FormatInt(tokenID, 10),
+	})
+}
+
+// ResetPassword redeems a reset token and sets the new password. Every session of the
+// user is signed out.
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
+	var req resetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	req.NewPassword = strings.TrimSpace(req.NewPassword)
+	if req.Token == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "token is required")
+	}
+	if len(req.NewPassword) < 8 {
+		return fiber.NewError(fiber.StatusBadRequest, "new_password must be at least 8 characters")
+	}
+
+	if err := data.ResetPasswordWithToken(auth.HashOneTimeToken(req.Token), req.NewPassword); err != nil {
+		if errors.Is(err, data.ErrInvalidToken) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reset password")
+	}
+	clearAuthCookies(c, isSecureCookie(h.cfg.CORSOrigins))
+	return c.JSON(fiber.Map{"message": "reset password success"})
+}
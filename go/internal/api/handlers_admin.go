@@ -5,7 +5,9 @@ import (
 	"backend/internal/data"
 	"database/sql"
 	"errors"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -237,6 +239,10 @@ func (h *Handler) CreateUserByAdmin(c *fiber.Ctx) error {
 	if req.Status != "active" && req.Status != "inactive" {
 		return fiber.NewError(fiber.StatusBadRequest, "status is invalid")
 	}
+	email, err := h.checkNewUserEmail(req.Email)
+	if err != nil {
+		return err
+	}
 
 	user, err := data.CreateUser(req.Name, req.Username, req.EmployeeCode, req.Password, req.Role, req.Status)
 	if err != nil {
@@ -245,6 +251,13 @@ func (h *Handler) CreateUserByAdmin(c *fiber.Ctx) error {
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot create user")
 	}
+	if email != "" {
+		if err := h.assignUserEmail(user.ID, user.Username, email); err != nil {
+			log.Printf("set email for new user %s: %v", user.Username, err)
+		} else {
+			user.Email = email
+		}
+	}
 	userPayload, err := toAuthUserPayload(user)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot load user permissions")
@@ -296,6 +309,8 @@ func (h *Handler) UpdateUserByAdmin(c *fiber.Ctx) error {
 		req.Role = ""
 		req.Status = ""
 		req.EmployeeCode = ""
+		req.ManagerUsername = nil
+		req.Email = nil
 	}
 
 	// Prevent assigning admin role to anyone
@@ -312,20 +327,46 @@ func (h *Handler) UpdateUserByAdmin(c *fiber.Ctx) error {
 			return fiber.NewError(fiber.StatusBadRequest, "role is invalid")
 		}
 	}
+	if req.Status != "" && req.Status != "active" && req.Status != "inactive" {
+		return fiber.NewError(fiber.StatusBadRequest, data.ErrInvalidStatus.Error())
+	}
 	if req.EmployeeCode != "" && !data.IsValidEmployeeCode(req.EmployeeCode) {
 		return fiber.NewError(fiber.StatusBadRequest, "employee_code must be in format XXXX-XX-XXXX")
 	}
+	if req.ManagerUsername != nil && data.NormalizeUsername(*req.ManagerUsername) != "" {
+		if _, err := data.FindUserByUsername(*req.ManagerUsername); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(fiber.StatusBadRequest, "manager not found")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "cannot validate manager")
+		}
+	}
+	var email string
+	if req.Email != nil {
+		if email, err = parseEmail(*req.Email); err != nil {
+			return err
+		}
+	}
 
-	user, err := data.UpdateUserByUsername(username, req.Name, req.Role, req.Status, req.EmployeeCode)
+	// Manager and profile changes are saved together, so a failure leaves both untouched.
+	user, err := data.UpdateUserWithManager(username, req.Name, req.Role, req.Status, req.EmployeeCode, req.ManagerUsername)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fiber.NewError(fiber.StatusNotFound, "user not found")
 		}
-		if errors.Is(err, data.ErrInvalidStatus) {
+		if errors.Is(err, data.ErrInvalidStatus) || errors.Is(err, data.ErrManagerCycle) {
 			return fiber.NewError(fiber.StatusBadRequest, err.Error())
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot update user")
 	}
+	if req.Email != nil {
+		if err := h.assignUserEmail(user.ID, user.Username, email); err != nil {
+			if errors.Is(err, data.ErrEmailTaken) {
+				return fiber.NewError(fiber.StatusConflict, "email already in use")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "cannot update email")
+		}
+	}
 
 	userPayload, err := toUserPayload(user)
 	if err != nil {
@@ -399,6 +440,11 @@ func (h *Handler) ResetUserPasswordByAdmin(c *fiber.Ctx) error {
 	if err := data.RevokeAllRefreshTokensByUserID(user.ID); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot revoke sessions")
 	}
+	if err := data.EnqueueMail(user.Username, data.MailPasswordReset, map[string]string{
+		"at": time.Now().Format("2006-01-02 15:04"),
+	}); err != nil {
+		log.Printf("enqueue password reset mail for %s: %v", user.Username, err)
+	}
 
 	return c.JSON(fiber.Map{"message": "reset password success"})
 }
@@ -21,7 +21,11 @@ func (h *Handler) GetCourseLearners(c *fiber.Ctx) error {
 	if courseID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
 	}
-	learners, err := data.GetCourseLearners(courseID)
+	cohortID, err := cohortQuery(c, courseID)
+	if err != nil {
+		return err
+	}
+	learners, err := data.GetCourseLearners(courseID, cohortID)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot get course learners")
 	}
@@ -86,24 +90,30 @@ func (h *Handler) GetExamDetailAnalytics(c *fiber.Ctx) error {
 	return c.JSON(detail)
 }
 
-// GetCourseDetailAnalytics returns subtopic time, hard questions, and unanswered Q&A for a course.
+// GetCourseDetailAnalytics returns subtopic time, hard questions, and unresolved Q&A for a course.
+// ?cohortId narrows them to one cohort.
 func (h *Handler) GetCourseDetailAnalytics(c *fiber.Ctx) error {
 	courseID := strings.TrimSpace(c.Params("courseId"))
 	if courseID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
 	}
 
-	subtopicTime, err := data.GetCourseSubtopicTime(courseID)
+	cohortID, err := cohortQuery(c, courseID)
+	if err != nil {
+		return err
+	}
+
+	subtopicTime, err := data.GetCourseSubtopicTime(courseID, cohortID)
 	if err != nil {
 		subtopicTime = []data.SubtopicTimeStat{}
 	}
-	unansweredQnA, err := data.GetCourseUnansweredQnA(courseID)
+	unresolvedQnA, err := data.GetCourseUnresolvedQnA(courseID, cohortID)
 	if err != nil {
-		unansweredQnA = []data.UnansweredQnA{}
+		unresolvedQnA = []data.UnresolvedQnA{}
 	}
 
 	return c.JSON(data.CourseDetailAnalytics{
 		SubtopicTime:  subtopicTime,
-		UnansweredQnA: unansweredQnA,
+		UnresolvedQnA: unresolvedQnA,
 	})
 }
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var validAssignmentStatuses = []string{
+	data.AssignmentStatusAssigned,
+	data.AssignmentStatusInProgress,
+	data.AssignmentStatusCompleted,
+	data.AssignmentStatusOverdue,
+}
+
+// parseDueAt accepts a plain date (due at the end of that day, server time) or an
+// RFC 3339 timestamp.
+func parseDueAt(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GetMyAssignments lists the caller's assignments with their derived status.
+func (h *Handler) GetMyAssignments(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	assignments, err := data.ListUserAssignments(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get assignments")
+	}
+	return c.JSON(fiber.Map{"assignments": assignments})
+}
+
+func (h *Handler) ListAssignments(c *fiber.Ctx) error {
+	limit, offset, page := parsePage(c)
+	assignments, total, err := data.ListAssignments(limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list assignments")
+	}
+	return c.JSON(fiber.Map{"assignments": assignments, "pagination": paginationMeta(total, limit, page)})
+}
+
+func (h *Handler) CreateAssignment(c *fiber.Ctx) error {
+	return h.saveAssignment(c, 0)
+}
+
+func (h *Handler) UpdateAssignment(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid assignment id")
+	}
+	return h.saveAssignment(c, id)
+}
+
+func (h *Handler) saveAssignment(c *fiber.Ctx, id int64) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+
+	var req assignmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	itemType := strings.ToLower(strings.TrimSpace(req.ItemType))
+	itemID := strings.TrimSpace(req.ItemID)
+	if itemType != data.PathItemCourse && itemType != data.PathItemExam && itemType != data.AssignmentItemPath {
+		return fiber.NewError(fiber.StatusBadRequest, "itemType must be course, exam, or path")
+	}
+	if itemID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "itemId is required")
+	}
+	dueAt, err := parseDueAt(req.DueAt)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "dueAt must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+	if req.RecurrenceDays < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "recurrenceDays must not be negative")
+	}
+
+	targets := make([]data.AssignmentTarget, 0, len(req.Usernames)+len(req.Roles))
+	for _, u := range req.Usernames {
+		u = data.NormalizeUsername(u)
+		if u == "" {
+			continue
+		}
+		if _, err := data.FindUserByUsername(u); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fiber.NewError(fiber.StatusBadRequest, "user not found: "+u)
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "cannot find user")
+		}
+		targets = append(targets, data.AssignmentTarget{Type: data.AssignmentTargetUser, Value: u})
+	}
+	for _, r := range req.Roles {
+		r = data.NormalizeRoleName(r)
+		if r == "" {
+			continue
+		}
+		exists, err := data.RoleExists(r)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "cannot check role")
+		}
+		if !exists {
+			return fiber.NewError(fiber.StatusBadRequest, "role not found: "+r)
+		}
+		targets = append(targets, data.AssignmentTarget{Type: data.AssignmentTargetRole, Value: r})
+	}
+	if len(targets) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "at least one username or role is required")
+	}
+
+	saved, err := data.SaveAssignment(data.Assignment{
+		ID:             id,
+		ItemType:       itemType,
+		ItemID:         itemID,
+		DueAt:          dueAt,
+		RecurrenceDays: req.RecurrenceDays,
+		Note:           strings.TrimSpace(req.Note),
+		Targets:        targets,
+	}, username)
+	if err != nil {
+		if errors.Is(err, data.ErrAssignmentItemMissing) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "assignment not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot save assignment")
+	}
+	if err := data.NotifyAssignmentCreated(saved.ID); err != nil {
+		log.Printf("notify assignment %d: %v", saved.ID, err)
+	}
+	return c.JSON(fiber.Map{"assignment": saved})
+}
+
+func (h *Handler) DeleteAssignment(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid assignment id")
+	}
+	if err := data.DeleteAssignment(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "assignment not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot delete assignment")
+	}
+	return c.JSON(fiber.Map{"message": "assignment deleted"})
+}
+
+// GetComplianceReport lists one row per assignment and assignee.
+// Filters: assignment_id, role, status, search (name, username or employee code).
+func (h *Handler) GetComplianceReport(c *fiber.Ctx) error {
+	limit, offset, page := parsePage(c)
+	f := data.ComplianceFilter{
+		Role:   data.NormalizeRoleName(c.Query("role")),
+		Status: strings.ToLower(strings.TrimSpace(c.Query("status"))),
+		Search: strings.TrimSpace(c.Query("search")),
+	}
+	if raw := strings.TrimSpace(c.Query("assignment_id")); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid assignment_id")
+		}
+		f.AssignmentID = id
+	}
+	if f.Status != "" && !slices.Contains(validAssignmentStatuses, f.Status) {
+		return fiber.NewError(fiber.StatusBadRequest, "status must be assigned, in_progress, completed, or overdue")
+	}
+
+	rows, summary, total, err := data.GetComplianceReport(f, limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get compliance report")
+	}
+	return c.JSON(fiber.Map{
+		"rows":       rows,
+		"summary":    summary,
+		"pagination": paginationMeta(total, limit, page),
+	})
+}
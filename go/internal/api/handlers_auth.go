@@ -8,6 +8,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -37,18 +38,65 @@ func (h *Handler) Register(c *fiber.Ctx) error {
 	if len(req.Password) < 8 {
 		return fiber.NewError(fiber.StatusBadRequest, "password must be at least 8 characters")
 	}
+	email, err := h.checkNewUserEmail(req.Email)
+	if err != nil {
+		return err
+	}
 
-	user, err := data.CreateUser(req.Name, req.Username, req.EmployeeCode, req.Password, "user", "active")
+	settings, err := data.GetRegistrationSettings()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot load registration settings")
+	}
+	req.InviteToken = strings.TrimSpace(req.InviteToken)
+	inviteHash := ""
+	activationPending := false
+	switch {
+	case settings.Mode == data.RegistrationClosed:
+		return fiber.NewError(fiber.StatusForbidden, "registration is closed")
+	case req.InviteToken != "":
+		inviteHash = auth.HashOneTimeToken(req.InviteToken)
+	case settings.Mode == data.RegistrationInviteOnly:
+		return fiber.NewError(fiber.StatusForbidden, "registration requires an invite")
+	case settings.Mode == data.RegistrationDomain:
+		if email == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "email is required")
+		}
+		if !settings.AllowsEmail(email) {
+			return fiber.NewError(fiber.StatusForbidden, "email domain is not allowed to register")
+		}
+		// The domain only counts once the address is proven, so the account stays
+		// inactive until the email is verified.
+		activationPending = true
+	}
+
+	user, err := data.RegisterUser(data.NewUser{
+		Name:         req.Name,
+		Username:     req.Username,
+		EmployeeCode: req.EmployeeCode,
+		Email:        email,
+		Password:     req.Password,
+	}, inviteHash, activationPending)
 	if err != nil {
-		if data.IsDuplicateKey(err) {
+		switch {
+		case errors.Is(err, data.ErrInvalidInvite):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, data.ErrEmailTaken):
+			return fiber.NewError(fiber.StatusConflict, "email already in use")
+		case data.IsDuplicateKey(err):
 			return fiber.NewError(fiber.StatusConflict, "username already exists")
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot create user")
 	}
+	if email != "" {
+		if err := h.sendEmailVerification(user.ID, user.Username, email); err != nil {
+			log.Printf("send email verification for %s: %v", user.Username, err)
+		}
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "register success",
-		"user":    user,
+		"message":             "register success",
+		"user":                user,
+		"activation_required": activationPending,
 	})
 }
 
@@ -71,6 +119,9 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "login failed")
 	}
 	if strings.ToLower(strings.TrimSpace(user.Status)) != "active" {
+		if pending, _ := data.IsActivationPending(user.ID); pending {
+			return fiber.NewError(fiber.StatusUnauthorized, "verify your email to activate the account")
+		}
 		return fiber.NewError(fiber.StatusUnauthorized, "user is inactive")
 	}
 
@@ -96,7 +147,9 @@ func (h *Handler) Login(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot store refresh token")
 	}
 
-	_ = data.RecordLoginLog(user.ID)
+	if err := data.RecordLoginLog(user.ID); err == nil {
+		awardEarnedBadges(user.Username, data.BadgeLoginStreak)
+	}
 
 	userPayload, err := toUserPayload(user)
 	if err != nil {
@@ -226,6 +279,15 @@ func (h *Handler) Me(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot load user permissions")
 	}
+	email, err := data.GetUserEmail(user.Username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get email")
+	}
+	userPayload["email"] = email.Email
+	userPayload["email_verified"] = email.VerifiedAt != nil
+	if impersonator := auth.ImpersonatorUsername(c); impersonator != "" {
+		userPayload["impersonated_by"] = impersonator
+	}
 	return c.JSON(userPayload)
 }
 
@@ -330,6 +392,23 @@ func setAuthCookies(c *fiber.Ctx, accessToken string, accessTTL int, refreshToke
 	})
 }
 
+// setAccessCookie replaces only the access token cookie; a non-positive TTL clears it.
+func setAccessCookie(c *fiber.Ctx, accessToken string, accessTTL int, secure bool) {
+	maxAge := accessTTL * 60
+	if accessTTL <= 0 {
+		maxAge = -1
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     "access_token",
+		Value:    accessToken,
+		HTTPOnly: true,
+		Secure:   secure,
+		SameSite: "Lax",
+		Path:     "/",
+		MaxAge:   maxAge,
+	})
+}
+
 func clearAuthCookies(c *fiber.Ctx, secure bool) {
 	sameSite := "Lax"
 	c.Cookie(&fiber.Cookie{Name: "access_token", Value: "", HTTPOnly: true, Secure: secure, SameSite: sameSite, Path: "/", MaxAge: -1})
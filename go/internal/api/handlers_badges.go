@@ -0,0 +1,202 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxBadgeImageBytes = 2 * 1024 * 1024 // 2 MB (base64-encoded)
+
+func badgeError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, data.ErrBadgeNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, data.ErrInvalidBadgeCriteria):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case data.IsDuplicateKey(err):
+		return fiber.NewError(fiber.StatusConflict, "a badge with this name already exists")
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, fallback)
+}
+
+func badgeID(c *fiber.Ctx) (int64, error) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid badge id")
+	}
+	return id, nil
+}
+
+// awardEarnedBadges evaluates the user's badges after an event and notifies them of
+// new ones. Failures are logged: badges never block the action that earned them.
+func awardEarnedBadges(username string, criteria ...string) []data.Badge {
+	earned, err := data.AwardEarnedBadges(username, criteria...)
+	if err != nil {
+		log.Printf("award badges for %s: %v", username, err)
+	}
+	for _, b := range earned {
+		if err := data.NotifyBadgeAwarded([]string{username}, b); err != nil {
+			log.Printf("notify badge %d for %s: %v", b.ID, username, err)
+		}
+	}
+	return earned
+}
+
+// awardBadgeRetroactively gives a new or changed badge to everyone who already
+// qualifies and returns how many users earned it.
+func awardBadgeRetroactively(b data.Badge) int {
+	awarded, err := data.AwardBadgeRetroactively(b)
+	if err != nil {
+		log.Printf("award badge %d retroactively: %v", b.ID, err)
+		return 0
+	}
+	if err := data.NotifyBadgeAwarded(awarded, b); err != nil {
+		log.Printf("notify badge %d: %v", b.ID, err)
+	}
+	return len(awarded)
+}
+
+// GetMyBadges returns the caller's badges and the active badges they can earn.
+func (h *Handler) GetMyBadges(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	earned, err := data.ListUserBadges(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get badges")
+	}
+	available, err := data.ListBadges(false)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get badges")
+	}
+	return c.JSON(fiber.Map{"earned": earned, "available": available})
+}
+
+func (h *Handler) ListBadgesAdmin(c *fiber.Ctx) error {
+	badges, err := data.ListBadges(true)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list badges")
+	}
+	return c.JSON(fiber.Map{"badges": badges})
+}
+
+func (h *Handler) CreateBadge(c *fiber.Ctx) error {
+	return h.saveBadge(c, 0)
+}
+
+func (h *Handler) UpdateBadge(c *fiber.Ctx) error {
+	id, err := badgeID(c)
+	if err != nil {
+		return err
+	}
+	return h.saveBadge(c, id)
+}
+
+// saveBadge creates or updates a badge, then awards it to everyone who already
+// meets its criteria.
+func (h *Handler) saveBadge(c *fiber.Ctx, id int64) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	var req badgeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name is required")
+	}
+	active := req.Active == nil || *req.Active
+
+	badge, err := data.SaveBadge(data.Badge{
+		ID:          id,
+		Name:        req.Name,
+		Description: strings.TrimSpace(req.Description),
+		Criteria:    strings.TrimSpace(req.Criteria),
+		Threshold:   req.Threshold,
+		Skill:       strings.TrimSpace(req.Skill),
+		ExamID:      strings.TrimSpace(req.ExamID),
+		Active:      active,
+	}, username)
+	if err != nil {
+		return badgeError(err, "cannot save badge")
+	}
+	awarded := awardBadgeRetroactively(badge)
+	badge.AwardedCount += awarded
+
+	status := fiber.StatusOK
+	if id == 0 {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(fiber.Map{"badge": badge, "newlyAwarded": awarded})
+}
+
+func (h *Handler) DeleteBadge(c *fiber.Ctx) error {
+	id, err := badgeID(c)
+	if err != nil {
+		return err
+	}
+	if err := data.DeleteBadge(id); err != nil {
+		return badgeError(err, "cannot delete badge")
+	}
+	return c.JSON(fiber.Map{"message": "badge deleted"})
+}
+
+func (h *Handler) UploadBadgeImage(c *fiber.Ctx) error {
+	id, err := badgeID(c)
+	if err != nil {
+		return err
+	}
+	var req badgeImageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if len(req.DataURL) > maxBadgeImageBytes {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "image must not exceed 2 MB")
+	}
+	decoded, err := decodeDataURL(req.DataURL)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid image data")
+	}
+	if err := validateImageBytes(decoded); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "file is not a valid image")
+	}
+	if _, err := data.GetBadge(id); err != nil {
+		return badgeError(err, "cannot get badge")
+	}
+	filename := fmt.Sprintf("%d%s", id, extFromDataURL(req.DataURL))
+	url, err := saveBytesToFile("uploads/badges", filename, decoded)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot save image file")
+	}
+	if err := data.SetBadgeImage(id, url); err != nil {
+		return badgeError(err, "cannot save badge image")
+	}
+	return c.JSON(fiber.Map{"url": url})
+}
+
+// ListBadgeAwards returns the award history of a badge.
+func (h *Handler) ListBadgeAwards(c *fiber.Ctx) error {
+	id, err := badgeID(c)
+	if err != nil {
+		return err
+	}
+	if _, err := data.GetBadge(id); err != nil {
+		return badgeError(err, "cannot get badge")
+	}
+	limit, offset, page := parsePage(c)
+	awards, total, err := data.ListBadgeAwards(id, limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list badge awards")
+	}
+	return c.JSON(fiber.Map{"awards": awards, "pagination": paginationMeta(total, limit, page)})
+}
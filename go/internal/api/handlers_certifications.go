@@ -0,0 +1,89 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetMyCertifications lists the caller's latest completion of each course and exam
+// with its expiry and status (valid, expiring_soon, expired).
+func (h *Handler) GetMyCertifications(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	certs, err := data.ListUserCertifications(username, h.cfg.CertExpiringSoonDays)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get certifications")
+	}
+	return c.JSON(fiber.Map{
+		"certifications":   certs,
+		"expiringSoonDays": h.cfg.CertExpiringSoonDays,
+	})
+}
+
+// RecertifyCourse reopens an expired (or soon expiring) course so it can be retaken.
+// Exams need no reset: attempts before the expiry stop counting against the limit.
+func (h *Handler) RecertifyCourse(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	waitlisted, err := data.StartCourseRecertification(username, courseID, h.cfg.CertExpiringSoonDays)
+	if err != nil {
+		if errors.Is(err, data.ErrRecertificationNotDue) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		if errors.Is(err, data.ErrCourseNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot start recertification")
+	}
+	if waitlisted {
+		return c.JSON(fiber.Map{"message": "course is full, you are on the waitlist", "status": data.EnrollmentStatusWaitlisted})
+	}
+	return c.JSON(fiber.Map{"message": "recertification started", "status": data.EnrollmentStatusEnrolled})
+}
+
+// ListExpiringCertifications lists certifications of active users expiring soon.
+// Filters: days (default CERT_EXPIRING_SOON_DAYS), include_expired, item_type, item_id,
+// search (name, username or employee code).
+func (h *Handler) ListExpiringCertifications(c *fiber.Ctx) error {
+	limit, offset, page := parsePage(c)
+	f := data.ExpiringCertificationFilter{
+		WithinDays:     h.cfg.CertExpiringSoonDays,
+		IncludeExpired: c.QueryBool("include_expired"),
+		ItemType:       strings.ToLower(strings.TrimSpace(c.Query("item_type"))),
+		ItemID:         strings.TrimSpace(c.Query("item_id")),
+		Search:         strings.TrimSpace(c.Query("search")),
+	}
+	if raw := strings.TrimSpace(c.Query("days")); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "days must be a non-negative integer")
+		}
+		f.WithinDays = days
+	}
+	if f.ItemType != "" && f.ItemType != data.PathItemCourse && f.ItemType != data.PathItemExam {
+		return fiber.NewError(fiber.StatusBadRequest, "item_type must be course or exam")
+	}
+
+	certs, total, err := data.ListExpiringCertifications(f, limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list expiring certifications")
+	}
+	return c.JSON(fiber.Map{
+		"certifications": certs,
+		"withinDays":     f.WithinDays,
+		"pagination":     paginationMeta(total, limit, page),
+	})
+}
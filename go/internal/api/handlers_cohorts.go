@@ -0,0 +1,314 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// cohortScheduleEntry is one chapter of a cohort's unlock schedule.
+type cohortScheduleEntry struct {
+	Chapter    int       `json:"chapter"`
+	SubtopicID string    `json:"subtopicId"`
+	Title      string    `json:"title"`
+	UnlocksAt  time.Time `json:"unlocksAt"`
+	Unlocked   bool      `json:"unlocked"`
+}
+
+// courseChapters numbers the chapters of a course: every level-2 heading starts a
+// chapter, as in parseMarkdownOutline (headingUtils.js). Headings before the first
+// chapter belong to chapter 0, the introduction.
+func courseChapters(content string) (chapterOf map[string]int, chapters []courseHeading) {
+	chapterOf = make(map[string]int)
+	for _, heading := range parseCourseHeadings(content) {
+		if heading.Level == 2 {
+			chapters = append(chapters, heading)
+		}
+		chapterOf[heading.ID] = len(chapters)
+	}
+	return chapterOf, chapters
+}
+
+// checkCohortSchedule rejects learning activity outside the learner's cohort dates
+// or in a chapter that has not unlocked yet. An empty subtopicID checks that the
+// whole course is unlocked; any other subtopicID must be in the course outline.
+// Learners outside any cohort are not restricted.
+func checkCohortSchedule(username, courseID, subtopicID string) error {
+	cohort, err := data.GetLearnerCohort(username, courseID)
+	if errors.Is(err, data.ErrCohortNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot check cohort schedule")
+	}
+	now := time.Now()
+	if now.Before(cohort.StartsAt) {
+		return fiber.NewError(fiber.StatusForbidden, "your cohort starts on "+cohort.StartsAt.Format("2006-01-02"))
+	}
+	if now.After(cohort.EndsAt) {
+		return fiber.NewError(fiber.StatusForbidden, "your cohort ended on "+cohort.EndsAt.Format("2006-01-02"))
+	}
+	if cohort.UnlockIntervalDays == 0 && subtopicID == "" {
+		return nil
+	}
+
+	_, content, err := data.GetCourseOutline(courseID)
+	if err != nil {
+		return enrollmentError(err, "cannot check cohort schedule")
+	}
+	chapterOf, chapters := courseChapters(content)
+	chapter := len(chapters)
+	if subtopicID != "" {
+		var ok bool
+		if chapter, ok = chapterOf[subtopicID]; !ok {
+			return fiber.NewError(fiber.StatusNotFound, "subtopic not found")
+		}
+	}
+	if cohort.UnlockIntervalDays == 0 {
+		return nil
+	}
+	if unlocksAt := cohort.ChapterUnlockAt(chapter); now.Before(unlocksAt) {
+		return fiber.NewError(fiber.StatusForbidden, "this chapter unlocks on "+unlocksAt.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// cohortQuery reads the optional ?cohortId filter and checks it belongs to the
+// course. 0 means no filter.
+func cohortQuery(c *fiber.Ctx, courseID string) (int64, error) {
+	value := strings.TrimSpace(c.Query("cohortId"))
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid cohortId")
+	}
+	if _, err := data.GetCohort(courseID, id); err != nil {
+		if errors.Is(err, data.ErrCohortNotFound) {
+			return 0, fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return 0, fiber.NewError(fiber.StatusInternalServerError, "cannot get cohort")
+	}
+	return id, nil
+}
+
+// cohortTarget reads the :id course and :cohortId route params.
+func cohortTarget(c *fiber.Ctx) (string, int64, error) {
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	cohortID, err := strconv.ParseInt(c.Params("cohortId"), 10, 64)
+	if err != nil || cohortID <= 0 {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "invalid cohort id")
+	}
+	return courseID, cohortID, nil
+}
+
+func cohortError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, data.ErrCourseNotFound), errors.Is(err, data.ErrCohortNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, data.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, "only the course owner can manage cohorts")
+	case errors.Is(err, data.ErrUnknownUsername), errors.Is(err, data.ErrInvalidCohortDates):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, data.ErrCohortConflict):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case data.IsDuplicateKey(err):
+		return fiber.NewError(fiber.StatusConflict, "a cohort with this name already exists")
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, fallback)
+}
+
+func (h *Handler) ListCohorts(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	cohorts, err := data.ListCohorts(courseID, username, auth.IsAdminContext(c))
+	if err != nil {
+		return cohortError(err, "cannot list cohorts")
+	}
+	return c.JSON(fiber.Map{"cohorts": cohorts})
+}
+
+func (h *Handler) CreateCohort(c *fiber.Ctx) error {
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	return h.saveCohort(c, courseID, 0)
+}
+
+func (h *Handler) UpdateCohort(c *fiber.Ctx) error {
+	courseID, cohortID, err := cohortTarget(c)
+	if err != nil {
+		return err
+	}
+	return h.saveCohort(c, courseID, cohortID)
+}
+
+func (h *Handler) saveCohort(c *fiber.Ctx, courseID string, id int64) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	var req cohortRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name is required")
+	}
+	startsAt, err := parseStartAt(req.StartsAt)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "startsAt must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+	endsAt, err := parseDueAt(req.EndsAt)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "endsAt must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+	if req.UnlockIntervalDays < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "unlockIntervalDays must not be negative")
+	}
+
+	cohort, err := data.SaveCohort(data.Cohort{
+		ID:                 id,
+		CourseID:           courseID,
+		Name:               req.Name,
+		InstructorUsername: data.NormalizeUsername(req.InstructorUsername),
+		StartsAt:           startsAt,
+		EndsAt:             endsAt,
+		UnlockIntervalDays: req.UnlockIntervalDays,
+	}, username, auth.IsAdminContext(c))
+	if err != nil {
+		return cohortError(err, "cannot save cohort")
+	}
+	status := fiber.StatusOK
+	if id == 0 {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(fiber.Map{"cohort": cohort})
+}
+
+// parseStartAt accepts a plain date (starting at midnight, server time) or an
+// RFC 3339 timestamp.
+func parseStartAt(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func (h *Handler) DeleteCohort(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, cohortID, err := cohortTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := data.DeleteCohort(courseID, cohortID, username, auth.IsAdminContext(c)); err != nil {
+		return cohortError(err, "cannot delete cohort")
+	}
+	return c.JSON(fiber.Map{"message": "cohort deleted"})
+}
+
+func (h *Handler) ListCohortMembers(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, cohortID, err := cohortTarget(c)
+	if err != nil {
+		return err
+	}
+	members, err := data.ListCohortMembers(courseID, cohortID, username, auth.IsAdminContext(c))
+	if err != nil {
+		return cohortError(err, "cannot list cohort members")
+	}
+	return c.JSON(fiber.Map{"members": members})
+}
+
+// SetCohortMembers replaces the members of a cohort. New members are enrolled in
+// the course and notified.
+func (h *Handler) SetCohortMembers(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, cohortID, err := cohortTarget(c)
+	if err != nil {
+		return err
+	}
+	var req cohortMembersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	isAdmin := auth.IsAdminContext(c)
+	added, err := data.SetCohortMembers(courseID, cohortID, req.Usernames, username, isAdmin)
+	if err != nil {
+		return cohortError(err, "cannot update cohort members")
+	}
+	if err := data.NotifyEnrollmentChanged(added, courseID, data.EnrollmentStatusEnrolled); err != nil {
+		log.Printf("notify cohort %d members: %v", cohortID, err)
+	}
+	members, err := data.ListCohortMembers(courseID, cohortID, username, isAdmin)
+	if err != nil {
+		return cohortError(err, "cannot list cohort members")
+	}
+	return c.JSON(fiber.Map{"members": members})
+}
+
+// GetMyCohort returns the caller's cohort in a course with its chapter schedule.
+func (h *Handler) GetMyCohort(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	cohort, err := data.GetLearnerCohort(username, courseID)
+	if err != nil {
+		if errors.Is(err, data.ErrCohortNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "you are not in a cohort of this course")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get cohort")
+	}
+	_, content, err := data.GetCourseOutline(courseID)
+	if err != nil {
+		return enrollmentError(err, "cannot get course")
+	}
+
+	now := time.Now()
+	_, chapters := courseChapters(content)
+	schedule := make([]cohortScheduleEntry, 0, len(chapters))
+	for i, chapter := range chapters {
+		unlocksAt := cohort.ChapterUnlockAt(i + 1)
+		schedule = append(schedule, cohortScheduleEntry{
+			Chapter:    i + 1,
+			SubtopicID: chapter.ID,
+			Title:      chapter.Text,
+			UnlocksAt:  unlocksAt,
+			Unlocked:   !now.Before(unlocksAt),
+		})
+	}
+	return c.JSON(fiber.Map{"cohort": cohort, "schedule": schedule})
+}
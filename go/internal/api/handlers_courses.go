@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"slices"
@@ -33,7 +34,11 @@ var validCourseStatuses = []string{"active", "inprogress", "inactive"}
 
 func (h *Handler) ListCourses(c *fiber.Ctx) error {
 	limit, offset, page := parsePage(c)
-	courses, total, err := data.ListCourses(limit, offset)
+	sort := strings.ToLower(strings.TrimSpace(c.Query("sort", data.CourseSortPopular)))
+	if !data.IsValidCourseSort(sort) {
+		return fiber.NewError(fiber.StatusBadRequest, "sort must be popular, rating or newest")
+	}
+	courses, total, err := data.ListCourses(sort, limit, offset)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot list courses")
 	}
@@ -64,12 +69,31 @@ func (h *Handler) UpsertCourse(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "status must be active, inprogress, or inactive")
 	}
 
+	if req.ValidityDays < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "validityDays must be >= 0")
+	}
+	req.EnrollmentMode = strings.ToLower(strings.TrimSpace(req.EnrollmentMode))
+	if req.EnrollmentMode != "" && !data.IsValidEnrollmentMode(req.EnrollmentMode) {
+		return fiber.NewError(fiber.StatusBadRequest, data.ErrInvalidEnrollmentMode.Error())
+	}
+	capacity := -1
+	if req.Capacity != nil {
+		if *req.Capacity < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "capacity must be >= 0")
+		}
+		capacity = *req.Capacity
+	}
+
 	skillRewards := make([]data.SkillReward, 0, len(req.SkillRewards))
 	for _, sr := range req.SkillRewards {
 		if strings.TrimSpace(sr.Skill) != "" {
 			skillRewards = append(skillRewards, data.SkillReward{Skill: sr.Skill, Points: sr.Points})
 		}
 	}
+	skillRewards, err = data.ResolveSkillRewards(skillRewards)
+	if err != nil {
+		return rewardSkillError(err)
+	}
 
 	visibility := strings.ToLower(strings.TrimSpace(req.Visibility))
 	if visibility != "private" {
@@ -93,6 +117,9 @@ func (h *Handler) UpsertCourse(c *fiber.Ctx) error {
 		SkillPoints:             req.SkillPoints,
 		SubtopicCompletionScore: req.SubtopicCompletionScore,
 		CourseCompletionScore:   req.CourseCompletionScore,
+		ValidityDays:            req.ValidityDays,
+		EnrollmentMode:          req.EnrollmentMode,
+		Capacity:                capacity,
 		SkillRewards:            skillRewards,
 	}
 
@@ -103,6 +130,12 @@ func (h *Handler) UpsertCourse(c *fiber.Ctx) error {
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot save course")
 	}
+	// A larger capacity or a switch to open mode may free waitlisted seats.
+	if promoted, err := data.PromoteWaitlist(saved.ID); err != nil {
+		log.Printf("promote waitlist of course %s: %v", saved.ID, err)
+	} else {
+		notifyWaitlistPromotion(saved.ID, promoted)
+	}
 
 	return c.JSON(fiber.Map{"course": saved})
 }
@@ -190,10 +223,16 @@ func (h *Handler) MarkSubtopicComplete(c *fiber.Ctx) error {
 	if courseID == "" || subtopicID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "courseId and subtopicId are required")
 	}
+	if err := checkItemUnlocked(username, data.PathItemCourse, courseID); err != nil {
+		return err
+	}
+	if err := checkCohortSchedule(username, courseID, subtopicID); err != nil {
+		return err
+	}
 
 	awarded, err := data.MarkSubtopicComplete(username, courseID, subtopicID)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "cannot mark subtopic complete")
+		return enrollmentError(err, "cannot mark subtopic complete")
 	}
 
 	return c.JSON(fiber.Map{"message": "subtopic marked complete", "awarded_score": awarded})
@@ -217,37 +256,21 @@ func (h *Handler) SubmitSubtopicAnswer(c *fiber.Ctx) error {
 	if strings.TrimSpace(req.QuestionID) == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "questionId is required")
 	}
+	if err := checkCohortSchedule(username, courseID, subtopicID); err != nil {
+		return err
+	}
 
 	if err := data.UpsertSubtopicAnswer(username, courseID, subtopicID, req.QuestionID, req.TypedAnswer, req.IsCorrect); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "cannot save answer")
+		return enrollmentError(err, "cannot save answer")
 	}
 
 	return c.JSON(fiber.Map{"message": "answer saved"})
 }
 
+// RecordSubtopicTime used to add client-reported seconds. Reading time now comes
+// from reading sessions only, so old clients are told the endpoint is gone.
 func (h *Handler) RecordSubtopicTime(c *fiber.Ctx) error {
-	username, err := auth.CurrentUsername(c)
-	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
-	}
-	courseID := strings.TrimSpace(c.Params("courseId"))
-	subtopicID := strings.TrimSpace(c.Params("subtopicId"))
-	if courseID == "" || subtopicID == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "courseId and subtopicId are required")
-	}
-
-	var req subtopicTimeRequest
-	if err := c.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
-	}
-	if req.Seconds <= 0 {
-		return c.JSON(fiber.Map{"message": "ok"})
-	}
-
-	if err := data.UpsertSubtopicTime(username, courseID, subtopicID, req.Seconds); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "cannot record time")
-	}
-	return c.JSON(fiber.Map{"message": "time recorded"})
+	return fiber.NewError(fiber.StatusGone, "time is tracked with reading sessions")
 }
 
 func (h *Handler) CompleteCourse(c *fiber.Ctx) error {
@@ -259,27 +282,33 @@ func (h *Handler) CompleteCourse(c *fiber.Ctx) error {
 	if courseID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
 	}
+	if err := checkItemUnlocked(username, data.PathItemCourse, courseID); err != nil {
+		return err
+	}
+	if err := checkCohortSchedule(username, courseID, ""); err != nil {
+		return err
+	}
 
 	awardedScore, skillRewards, err := data.AwardCourseCompletion(username, courseID)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "cannot complete course")
+		return enrollmentError(err, "cannot complete course")
+	}
+	completedPaths, pathErr := data.AwardPathCompletions(username, data.PathItemCourse, courseID)
+	// Badges also count path rewards paid before a failure.
+	badges := awardEarnedBadges(username, data.BadgeCoursesCompleted, data.BadgeSkillPoints)
+	if pathErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot award learning path completion")
 	}
 
 	return c.JSON(fiber.Map{
-		"message":       "course completed",
-		"awarded_score": awardedScore,
-		"skill_rewards": skillRewards,
+		"message":         "course completed",
+		"awarded_score":   awardedScore,
+		"skill_rewards":   skillRewards,
+		"completed_paths": completedPaths,
+		"badges":          badges,
 	})
 }
 
-func (h *Handler) GetLeaderboard(c *fiber.Ctx) error {
-	entries, err := data.GetLeaderboard()
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "cannot get leaderboard")
-	}
-	return c.JSON(fiber.Map{"leaderboard": entries})
-}
-
 func (h *Handler) GetUserPublicProfile(c *fiber.Ctx) error {
 	username := c.Params("username")
 	profile, err := data.GetPublicUserProfile(username)
@@ -415,67 +444,6 @@ func (h *Handler) DeleteCourseAttachment(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "attachment deleted"})
 }
 
-// ── Q&A ───────────────────────────────────────────────────────────────────────
-
-func (h *Handler) GetCourseQnA(c *fiber.Ctx) error {
-	courseID := strings.TrimSpace(c.Params("courseId"))
-	if courseID == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
-	}
-	questions, err := data.GetCourseQnA(courseID)
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "cannot get Q&A")
-	}
-	return c.JSON(fiber.Map{"questions": questions})
-}
-
-func (h *Handler) PostQnAQuestion(c *fiber.Ctx) error {
-	username, err := auth.CurrentUsername(c)
-	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
-	}
-	courseID := strings.TrimSpace(c.Params("courseId"))
-	if courseID == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
-	}
-	var req qnaQuestionRequest
-	if err := c.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
-	}
-	if strings.TrimSpace(req.Question) == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "question is required")
-	}
-	q, err := data.CreateQnAQuestion(courseID, strings.TrimSpace(req.SubtopicID), username, strings.TrimSpace(req.Question))
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "cannot create question")
-	}
-	return c.JSON(fiber.Map{"question": q})
-}
-
-func (h *Handler) PostQnAReply(c *fiber.Ctx) error {
-	username, err := auth.CurrentUsername(c)
-	if err != nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
-	}
-	questionIDStr := strings.TrimSpace(c.Params("questionId"))
-	questionID, err := strconv.ParseInt(questionIDStr, 10, 64)
-	if err != nil || questionID <= 0 {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid questionId")
-	}
-	var req qnaReplyRequest
-	if err := c.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
-	}
-	if strings.TrimSpace(req.Reply) == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "reply is required")
-	}
-	r, err := data.CreateQnAReply(questionID, username, strings.TrimSpace(req.Reply))
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "cannot create reply")
-	}
-	return c.JSON(fiber.Map{"reply": r})
-}
-
 func (h *Handler) GetUserScores(c *fiber.Ctx) error {
 	username, err := auth.CurrentUsername(c)
 	if err != nil {
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// enrollmentError maps enrollment errors of learning endpoints to HTTP errors and
+// anything else to a 500 with the fallback message.
+func enrollmentError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, data.ErrCourseNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, data.ErrNotEnrolled), errors.Is(err, data.ErrInviteOnly), errors.Is(err, data.ErrAttendanceRequired):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	case errors.Is(err, data.ErrCompletedEnrollment):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, fallback)
+}
+
+func (h *Handler) GetEnrollment(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	enrollment, err := data.GetEnrollment(username, courseID)
+	if err != nil {
+		return enrollmentError(err, "cannot get enrollment")
+	}
+	return c.JSON(fiber.Map{"enrollment": enrollment})
+}
+
+// Enroll enrolls the caller or, depending on the course mode, files an approval
+// request or joins the waitlist. The returned status tells which.
+func (h *Handler) Enroll(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	enrollment, err := data.Enroll(username, courseID)
+	if err != nil {
+		return enrollmentError(err, "cannot enroll")
+	}
+	if enrollment.Status == data.EnrollmentStatusPending {
+		if err := data.NotifyEnrollmentRequested(username, courseID); err != nil {
+			log.Printf("notify enrollment request %s/%s: %v", courseID, username, err)
+		}
+	}
+	return c.JSON(fiber.Map{"enrollment": enrollment})
+}
+
+// Unenroll leaves a course or withdraws the caller's request or invitation.
+func (h *Handler) Unenroll(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	promoted, err := data.Unenroll(username, courseID)
+	if err != nil {
+		if errors.Is(err, data.ErrNotEnrolled) {
+			return fiber.NewError(fiber.StatusNotFound, "enrollment not found")
+		}
+		return enrollmentError(err, "cannot unenroll")
+	}
+	notifyWaitlistPromotion(courseID, promoted)
+	return c.JSON(fiber.Map{"message": "unenrolled"})
+}
+
+func notifyWaitlistPromotion(courseID string, promoted []string) {
+	if err := data.NotifyEnrollmentChanged(promoted, courseID, data.EnrollmentStatusEnrolled); err != nil {
+		log.Printf("notify waitlist promotion in course %s: %v", courseID, err)
+	}
+}
+
+// ListCourseEnrollments returns the roster of a course for its owner or an admin.
+func (h *Handler) ListCourseEnrollments(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	status := strings.ToLower(strings.TrimSpace(c.Query("status")))
+	limit, offset, page := parsePage(c)
+	entries, total, err := data.ListCourseEnrollments(courseID, status, username, auth.IsAdminContext(c), limit, offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidEnrollmentStatus):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, data.ErrCourseNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, data.ErrForbidden):
+			return fiber.NewError(fiber.StatusForbidden, "only the course owner can view enrollments")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list enrollments")
+	}
+	return c.JSON(fiber.Map{"enrollments": entries, "pagination": paginationMeta(total, limit, page)})
+}
+
+func (h *Handler) ApproveEnrollment(c *fiber.Ctx) error {
+	return h.decideEnrollment(c, true)
+}
+
+func (h *Handler) RejectEnrollment(c *fiber.Ctx) error {
+	return h.decideEnrollment(c, false)
+}
+
+// decideEnrollment approves or rejects a pending request. The course owner, the
+// learner's managers and admins may decide.
+func (h *Handler) decideEnrollment(c *fiber.Ctx, approve bool) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("id"))
+	learner := data.NormalizeUsername(c.Params("username"))
+	if courseID == "" || learner == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id and username are required")
+	}
+	if err := data.DecideEnrollmentRequest(courseID, learner, approve, username, auth.IsAdminContext(c)); err != nil {
+		switch {
+		case errors.Is(err, data.ErrCourseNotFound), errors.Is(err, data.ErrNoPendingEnrollment):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, data.ErrForbidden):
+			return fiber.NewError(fiber.StatusForbidden, "only the course owner or the learner's manager can decide")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot decide enrollment request")
+	}
+
+	status := data.EnrollmentStatusRejected
+	if approve {
+		status = data.EnrollmentStatusEnrolled
+	}
+	if err := data.NotifyEnrollmentChanged([]string{learner}, courseID, status); err != nil {
+		log.Printf("notify enrollment decision %s/%s: %v", courseID, learner, err)
+	}
+	return c.JSON(fiber.Map{"username": learner, "status": status})
+}
+
+// ListTeamEnrollmentRequests returns pending requests of the caller's reports.
+func (h *Handler) ListTeamEnrollmentRequests(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	requests, err := data.ListTeamEnrollmentRequests(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list enrollment requests")
+	}
+	return c.JSON(fiber.Map{"requests": requests})
+}
+
+// BulkEnroll enrolls or invites users to a course, bypassing its mode and capacity.
+func (h *Handler) BulkEnroll(c *fiber.Ctx) error {
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	var req bulkEnrollRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if len(req.Usernames) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "usernames are required")
+	}
+	changed, err := data.BulkEnroll(courseID, req.Usernames, req.Invite)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCourseNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, data.ErrUnknownUsername):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot enroll users")
+	}
+
+	status := data.EnrollmentStatusEnrolled
+	if req.Invite {
+		status = data.EnrollmentStatusInvited
+	}
+	if err := data.NotifyEnrollmentChanged(changed, courseID, status); err != nil {
+		log.Printf("notify bulk enrollment in course %s: %v", courseID, err)
+	}
+	return c.JSON(fiber.Map{"status": status, "usernames": changed, "count": len(changed)})
+}
+
+// AdminUnenroll removes a user from a course, including completed enrollments.
+func (h *Handler) AdminUnenroll(c *fiber.Ctx) error {
+	courseID := strings.TrimSpace(c.Params("id"))
+	learner := data.NormalizeUsername(c.Params("username"))
+	if courseID == "" || learner == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id and username are required")
+	}
+	promoted, err := data.UnenrollUser(learner, courseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCourseNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, data.ErrNotEnrolled):
+			return fiber.NewError(fiber.StatusNotFound, "enrollment not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot unenroll user")
+	}
+	notifyWaitlistPromotion(courseID, promoted)
+	return c.JSON(fiber.Map{"message": "user unenrolled"})
+}
@@ -5,6 +5,7 @@ import (
 	"backend/internal/data"
 	"database/sql"
 	"errors"
+	"log"
 	"slices"
 	"strconv"
 	"strings"
@@ -101,6 +102,12 @@ func (h *Handler) UpsertExam(c *fiber.Ctx) error {
 	if req.MaxAttempts < 0 {
 		return fiber.NewError(fiber.StatusBadRequest, "maxAttempts must be >= 0")
 	}
+	if req.ValidityDays < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "validityDays must be >= 0")
+	}
+	if req.CompletionScore < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "completionScore must be >= 0")
+	}
 	if len(req.Questions) > 500 {
 		return fiber.NewError(fiber.StatusBadRequest, "too many questions (max 500)")
 	}
@@ -129,6 +136,27 @@ func (h *Handler) UpsertExam(c *fiber.Ctx) error {
 		})
 	}
 
+	domains := make(map[string]bool)
+	for domain := range req.DomainPercentages {
+		domains[strings.TrimSpace(domain)] = true
+	}
+	for _, q := range questions {
+		domains[q.Domain] = true
+	}
+	skillRewards := make([]data.ExamSkillReward, 0, len(req.SkillRewards))
+	for _, sr := range req.SkillRewards {
+		if strings.TrimSpace(sr.Skill) != "" {
+			skillRewards = append(skillRewards, data.ExamSkillReward{Skill: sr.Skill, Domain: sr.Domain, Points: sr.Points})
+		}
+	}
+	skillRewards, err = data.ResolveExamSkillRewards(skillRewards, domains)
+	if errors.Is(err, data.ErrInvalidExamReward) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return rewardSkillError(err)
+	}
+
 	visibility := strings.ToLower(strings.TrimSpace(req.Visibility))
 	if visibility != "private" {
 		visibility = "public"
@@ -151,6 +179,9 @@ func (h *Handler) UpsertExam(c *fiber.Ctx) error {
 		NumberOfQuestions: req.NumberOfQuestions,
 		DefaultTime:       req.DefaultTime,
 		MaxAttempts:       req.MaxAttempts,
+		ValidityDays:      req.ValidityDays,
+		CompletionScore:   req.CompletionScore,
+		SkillRewards:      skillRewards,
 		DomainPercentages: req.DomainPercentages,
 		Questions:         questions,
 	}
@@ -232,6 +263,9 @@ func (h *Handler) SaveExamAttempt(c *fiber.Ctx) error {
 	if examID == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "exam id is required")
 	}
+	if err := checkItemUnlocked(username, data.PathItemExam, examID); err != nil {
+		return err
+	}
 
 	// Enforce max attempts limit on the backend
 	maxAttempts, attemptCount, err := data.CheckExamAttemptLimit(examID, username)
@@ -262,7 +296,21 @@ func (h *Handler) SaveExamAttempt(c *fiber.Ctx) error {
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "cannot save attempt")
 	}
-	return c.JSON(fiber.Map{"attempt": attempt, "details": details})
+	if err := data.NotifyExamGraded(username, attempt); err != nil {
+		log.Printf("notify exam graded %d: %v", attempt.ID, err)
+	}
+
+	completedPaths := []data.PathCompletion{}
+	var pathErr error
+	if attempt.ScorePercent >= data.ExamPassPercent {
+		completedPaths, pathErr = data.AwardPathCompletions(username, data.PathItemExam, examID)
+	}
+	// Badges also count path rewards paid before a failure.
+	badges := awardEarnedBadges(username, data.BadgeExamScore, data.BadgeSkillPoints)
+	if pathErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot award learning path completion")
+	}
+	return c.JSON(fiber.Map{"attempt": attempt, "details": details, "completed_paths": completedPaths, "badges": badges})
 }
 
 func (h *Handler) GetMyExamAttempts(c *fiber.Ctx) error {
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StartImpersonation swaps the caller's access cookie for a short-lived, read-only
+// session as the target user. The admin's refresh cookie is kept, so calling
+// /auth/refresh (or letting the session expire) returns to the admin session.
+func (h *Handler) StartImpersonation(c *fiber.Ctx) error {
+	if auth.IsImpersonating(c) {
+		return fiber.NewError(fiber.StatusForbidden, "already in an impersonation session")
+	}
+	callerUsername, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "cannot identify caller")
+	}
+	username := data.NormalizeUsername(c.Params("username"))
+	if username == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "username is required")
+	}
+	if username == callerUsername {
+		return fiber.NewError(fiber.StatusBadRequest, "cannot impersonate yourself")
+	}
+
+	var req impersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "reason is required")
+	}
+
+	target, err := data.FindUserByUsername(username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot find user")
+	}
+	if strings.ToLower(strings.TrimSpace(target.Role)) == "admin" {
+		return fiber.NewError(fiber.StatusForbidden, "cannot impersonate admin accounts")
+	}
+	if strings.ToLower(strings.TrimSpace(target.Status)) != "active" {
+		return fiber.NewError(fiber.StatusBadRequest, "user is inactive")
+	}
+
+	permissions, err := data.PermissionsForUser(target.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot load permissions")
+	}
+
+	expiresAt := time.Now().Add(time.Duration(h.cfg.ImpersonationTTL) * time.Minute)
+	logID, err := data.CreateImpersonationLog(callerUsername, target.Username, req.Reason, c.IP(), expiresAt)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot record impersonation")
+	}
+	log.Printf("impersonation: %s started viewing as %s (log %d)", callerUsername, target.Username, logID)
+
+	accessToken, err := auth.GenerateImpersonationToken(target, callerUsername, logID, h.cfg.JWTSecret, h.cfg.ImpersonationTTL, permissions)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot generate token")
+	}
+	setAccessCookie(c, accessToken, h.cfg.ImpersonationTTL, isSecureCookie(h.cfg.CORSOrigins))
+
+	userPayload, err := toUserPayload(target)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot load user permissions")
+	}
+	userPayload["impersonated_by"] = callerUsername
+
+	return c.JSON(fiber.Map{
+		"message":          "impersonation started",
+		"expires_in":       h.cfg.ImpersonationTTL * 60,
+		"impersonation_id": logID,
+		"user":             userPayload,
+	})
+}
+
+// EndImpersonation closes the audit entry and drops the impersonation access cookie.
+// The client then calls /auth/refresh to get its own session back.
+func (h *Handler) EndImpersonation(c *fiber.Ctx) error {
+	logID := auth.ImpersonationID(c)
+	if !auth.IsImpersonating(c) || logID == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "not in an impersonation session")
+	}
+	if err := data.EndImpersonationLog(logID); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot record impersonation end")
+	}
+	log.Printf("impersonation: %s stopped (log %d)", auth.ImpersonatorUsername(c), logID)
+
+	setAccessCookie(c, "", -1, isSecureCookie(h.cfg.CORSOrigins))
+	return c.JSON(fiber.Map{"message": "impersonation ended"})
+}
+
+func (h *Handler) ListImpersonationLogs(c *fiber.Ctx) error {
+	limit, offset, page := parsePage(c)
+	logs, total, err := data.ListImpersonationLogs(limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list impersonation logs")
+	}
+	return c.JSON(fiber.Map{"logs": logs, "pagination": paginationMeta(total, limit, page)})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// bangkok is the zone leaderboard dates are given in. Thailand has no DST.
+var bangkok = time.FixedZone("Asia/Bangkok", 7*60*60)
+
+// leaderboardFilter reads the board and segment from the query string. from and
+// to are YYYY-MM-DD dates in Asia/Bangkok; to is inclusive.
+func leaderboardFilter(c *fiber.Ctx) (data.LeaderboardFilter, error) {
+	f := data.LeaderboardFilter{
+		Period: strings.TrimSpace(c.Query("period", data.LeaderboardAllTime)),
+		Skill:  strings.TrimSpace(c.Query("skill")),
+		Role:   strings.TrimSpace(c.Query("role")),
+	}
+	if raw := strings.TrimSpace(c.Query("groupId")); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			return f, fiber.NewError(fiber.StatusBadRequest, "invalid groupId")
+		}
+		f.GroupID = id
+	}
+	if f.Period == data.LeaderboardCustom {
+		from, err := time.ParseInLocation("2006-01-02", c.Query("from"), bangkok)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusBadRequest, "from must be a YYYY-MM-DD date")
+		}
+		to, err := time.ParseInLocation("2006-01-02", c.Query("to"), bangkok)
+		if err != nil {
+			return f, fiber.NewError(fiber.StatusBadRequest, "to must be a YYYY-MM-DD date")
+		}
+		f.From, f.To = from, to.AddDate(0, 0, 1)
+	}
+	if f.Skill != "" {
+		// Aliases rank on the canonical skill; unknown skills give an empty board.
+		if skill, err := data.ResolveSkill(f.Skill); err == nil {
+			f.Skill = skill
+		}
+	}
+	if err := data.ValidateLeaderboardFilter(f); err != nil {
+		return f, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return f, nil
+}
+
+func leaderboardError(err error) error {
+	if errors.Is(err, data.ErrInvalidLeaderboard) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, "cannot get leaderboard")
+}
+
+// GetLeaderboard is the public leaderboard. Custom date ranges are computed live,
+// so they are only available to signed-in learners through GetMyLeaderboard.
+func (h *Handler) GetLeaderboard(c *fiber.Ctx) error {
+	f, err := leaderboardFilter(c)
+	if err != nil {
+		return err
+	}
+	if f.Period == data.LeaderboardCustom {
+		return fiber.NewError(fiber.StatusBadRequest, "custom date ranges require signing in")
+	}
+	limit, offset, page := parsePage(c)
+	entries, total, board, err := data.GetLeaderboard(f, limit, offset)
+	if err != nil {
+		return leaderboardError(err)
+	}
+	return c.JSON(fiber.Map{
+		"leaderboard": entries,
+		"board":       board,
+		"pagination":  paginationMeta(total, limit, page),
+	})
+}
+
+// GetMyLeaderboard returns a leaderboard page together with the caller's own
+// entry, which is nil when they have no score on the board.
+func (h *Handler) GetMyLeaderboard(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	f, err := leaderboardFilter(c)
+	if err != nil {
+		return err
+	}
+	limit, offset, page := parsePage(c)
+	entries, total, board, err := data.GetLeaderboard(f, limit, offset)
+	if err != nil {
+		return leaderboardError(err)
+	}
+	me, err := data.GetLeaderboardEntry(f, username)
+	if err != nil {
+		return leaderboardError(err)
+	}
+	return c.JSON(fiber.Map{
+		"leaderboard": entries,
+		"board":       board,
+		"me":          me,
+		"pagination":  paginationMeta(total, limit, page),
+	})
+}
+
+// GetUserAvatar serves an active user's avatar image so that leaderboards and
+// public profiles can show it.
+func (h *Handler) GetUserAvatar(c *fiber.Ctx) error {
+	urlPath, err := data.GetActiveUserAvatar(c.Params("username"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get avatar")
+	}
+	if urlPath == "" {
+		return fiber.NewError(fiber.StatusNotFound, "avatar not found")
+	}
+	fsPath := strings.TrimPrefix(urlPath, "/")
+	fileData, err := os.ReadFile(fsPath)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "avatar not found")
+	}
+	c.Set(fiber.HeaderContentType, mimeFromExt(filepath.Ext(fsPath)))
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Send(fileData)
+}
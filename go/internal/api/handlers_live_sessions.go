@@ -0,0 +1,426 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func liveSessionError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, data.ErrLiveSessionNotFound), errors.Is(err, data.ErrCourseNotFound), errors.Is(err, data.ErrCohortNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, data.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, "only the course owner or the session instructor can manage this live session")
+	case errors.Is(err, data.ErrNotEnrolled), errors.Is(err, data.ErrInviteOnly), errors.Is(err, data.ErrLiveSessionOtherCohort):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	case errors.Is(err, data.ErrLiveSessionFull), errors.Is(err, data.ErrLiveSessionOver),
+		errors.Is(err, data.ErrCheckinClosed), errors.Is(err, data.ErrAlreadyAttended):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, data.ErrInvalidLiveSessionTimes), errors.Is(err, data.ErrUnknownUsername),
+		errors.Is(err, data.ErrInvalidCheckinCode), errors.Is(err, data.ErrNotLiveSessionLearner):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, fallback)
+}
+
+func (h *Handler) checkinPeriod() time.Duration {
+	return time.Duration(h.cfg.CheckinCodeSeconds) * time.Second
+}
+
+// liveSessionTarget reads the :id course and :sessionId route params.
+func liveSessionTarget(c *fiber.Ctx) (string, int64, error) {
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	sessionID, err := strconv.ParseInt(c.Params("sessionId"), 10, 64)
+	if err != nil || sessionID <= 0 {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "invalid session id")
+	}
+	return courseID, sessionID, nil
+}
+
+// runLiveSession loads a session the caller may run: the course owner, an admin or
+// the session's instructor.
+func runLiveSession(c *fiber.Ctx) (data.LiveSession, string, error) {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return data.LiveSession{}, "", fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, sessionID, err := liveSessionTarget(c)
+	if err != nil {
+		return data.LiveSession{}, "", err
+	}
+	session, err := data.GetLiveSession(courseID, sessionID, "")
+	if err != nil {
+		return session, "", liveSessionError(err, "cannot get live session")
+	}
+	if err := data.CanRunLiveSession(session, username, auth.IsAdminContext(c)); err != nil {
+		return session, "", liveSessionError(err, "cannot get live session")
+	}
+	return session, username, nil
+}
+
+func (h *Handler) ListManagedLiveSessions(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	sessions, err := data.ListManagedLiveSessions(courseID, username, auth.IsAdminContext(c))
+	if err != nil {
+		return liveSessionError(err, "cannot list live sessions")
+	}
+	return c.JSON(fiber.Map{"sessions": sessions})
+}
+
+func (h *Handler) CreateLiveSession(c *fiber.Ctx) error {
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	return h.saveLiveSession(c, courseID, 0)
+}
+
+func (h *Handler) UpdateLiveSession(c *fiber.Ctx) error {
+	courseID, sessionID, err := liveSessionTarget(c)
+	if err != nil {
+		return err
+	}
+	return h.saveLiveSession(c, courseID, sessionID)
+}
+
+func (h *Handler) saveLiveSession(c *fiber.Ctx, courseID string, id int64) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	var req liveSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "title is required")
+	}
+	startsAt, err := time.Parse(time.RFC3339, strings.TrimSpace(req.StartsAt))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "startsAt must be an RFC 3339 timestamp")
+	}
+	endsAt, err := time.Parse(time.RFC3339, strings.TrimSpace(req.EndsAt))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "endsAt must be an RFC 3339 timestamp")
+	}
+	if req.Capacity < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "capacity must not be negative")
+	}
+	req.Location = strings.TrimSpace(req.Location)
+	req.MeetingURL = strings.TrimSpace(req.MeetingURL)
+	if req.Location == "" && req.MeetingURL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "location or meetingUrl is required")
+	}
+	if req.MeetingURL != "" && !validMeetingURL(req.MeetingURL) {
+		return fiber.NewError(fiber.StatusBadRequest, "meetingUrl must be an http(s) URL")
+	}
+
+	session, err := data.SaveLiveSession(data.LiveSession{
+		ID:                 id,
+		CourseID:           courseID,
+		CohortID:           req.CohortID,
+		Title:              req.Title,
+		Description:        strings.TrimSpace(req.Description),
+		StartsAt:           startsAt,
+		EndsAt:             endsAt,
+		Location:           req.Location,
+		MeetingURL:         req.MeetingURL,
+		Capacity:           req.Capacity,
+		Required:           req.Required,
+		InstructorUsername: data.NormalizeUsername(req.InstructorUsername),
+	}, username, auth.IsAdminContext(c))
+	if err != nil {
+		return liveSessionError(err, "cannot save live session")
+	}
+	status := fiber.StatusOK
+	if id == 0 {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(fiber.Map{"session": session})
+}
+
+func (h *Handler) DeleteLiveSession(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, sessionID, err := liveSessionTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := data.DeleteLiveSession(courseID, sessionID, username, auth.IsAdminContext(c)); err != nil {
+		return liveSessionError(err, "cannot delete live session")
+	}
+	return c.JSON(fiber.Map{"message": "live session deleted"})
+}
+
+func (h *Handler) ListLiveSessionAttendees(c *fiber.Ctx) error {
+	session, _, err := runLiveSession(c)
+	if err != nil {
+		return err
+	}
+	attendees, err := data.ListLiveSessionAttendees(session.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list attendees")
+	}
+	return c.JSON(fiber.Map{"session": session, "attendees": attendees})
+}
+
+// SetLiveSessionAttendance lets the instructor mark learners as attended (or not).
+func (h *Handler) SetLiveSessionAttendance(c *fiber.Ctx) error {
+	session, username, err := runLiveSession(c)
+	if err != nil {
+		return err
+	}
+	var req attendanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if len(req.Usernames) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "usernames is required")
+	}
+	if err := data.SetLiveSessionAttendance(session.ID, req.Usernames, req.Attended, username); err != nil {
+		return liveSessionError(err, "cannot record attendance")
+	}
+	attendees, err := data.ListLiveSessionAttendees(session.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list attendees")
+	}
+	return c.JSON(fiber.Map{"attendees": attendees})
+}
+
+// GetCheckinCode returns the current check-in code for the instructor to display.
+// Clients refresh it at expiresAt.
+func (h *Handler) GetCheckinCode(c *fiber.Ctx) error {
+	session, _, err := runLiveSession(c)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !session.CheckinOpen(now) {
+		return liveSessionError(data.ErrCheckinClosed, "cannot get check-in code")
+	}
+	period := h.checkinPeriod()
+	return c.JSON(fiber.Map{
+		"code":      session.CheckinCode(now, period),
+		"expiresAt": now.Truncate(period).Add(period),
+		"closesAt":  session.EndsAt,
+	})
+}
+
+// ListLearnerLiveSessions lists the sessions of a course open to the caller.
+func (h *Handler) ListLearnerLiveSessions(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	sessions, err := data.ListCourseLiveSessions(courseID, username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list live sessions")
+	}
+	return c.JSON(fiber.Map{"sessions": sessions})
+}
+
+func learnerSessionID(c *fiber.Ctx) (string, int64, error) {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return "", 0, fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "invalid session id")
+	}
+	return username, id, nil
+}
+
+func (h *Handler) RegisterForLiveSession(c *fiber.Ctx) error {
+	username, id, err := learnerSessionID(c)
+	if err != nil {
+		return err
+	}
+	session, err := data.RegisterForLiveSession(username, id)
+	if err != nil {
+		return liveSessionError(err, "cannot register for live session")
+	}
+	return c.JSON(fiber.Map{"session": session})
+}
+
+func (h *Handler) CancelLiveSessionRegistration(c *fiber.Ctx) error {
+	username, id, err := learnerSessionID(c)
+	if err != nil {
+		return err
+	}
+	if err := data.CancelLiveSessionRegistration(username, id); err != nil {
+		return liveSessionError(err, "cannot cancel registration")
+	}
+	return c.JSON(fiber.Map{"message": "registration cancelled"})
+}
+
+// CheckInToLiveSession records the caller's attendance with the code shown in the
+// session.
+func (h *Handler) CheckInToLiveSession(c *fiber.Ctx) error {
+	username, id, err := learnerSessionID(c)
+	if err != nil {
+		return err
+	}
+	var req checkinRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	session, err := data.CheckInToLiveSession(username, id, req.Code, h.checkinPeriod())
+	if err != nil {
+		return liveSessionError(err, "cannot check in")
+	}
+	return c.JSON(fiber.Map{"session": session})
+}
+
+// DownloadLiveSessionCalendar returns the caller's sessions as an iCalendar file.
+func (h *Handler) DownloadLiveSessionCalendar(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	return h.sendCalendar(c, username)
+}
+
+// RotateCalendarFeed issues a new secret calendar feed URL for the caller, revoking
+// the previous one. Calendar apps subscribe to it without logging in.
+func (h *Handler) RotateCalendarFeed(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	token, tokenHash, err := auth.GenerateOneTimeToken()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot create calendar feed")
+	}
+	if err := data.SetCalendarFeedToken(username, tokenHash); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot create calendar feed")
+	}
+	return c.JSON(fiber.Map{"url": c.BaseURL() + "/api/calendar/" + token + ".ics"})
+}
+
+// GetCalendarFeed serves a calendar feed by its secret token.
+func (h *Handler) GetCalendarFeed(c *fiber.Ctx) error {
+	token := strings.TrimSuffix(c.Params("token"), ".ics")
+	if token == "" {
+		return fiber.NewError(fiber.StatusNotFound, "calendar feed not found")
+	}
+	username, err := data.FindCalendarFeedUser(auth.HashOneTimeToken(token))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "calendar feed not found")
+	}
+	return h.sendCalendar(c, username)
+}
+
+func (h *Handler) sendCalendar(c *fiber.Ctx, username string) error {
+	sessions, err := data.ListCalendarLiveSessions(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list live sessions")
+	}
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="live-sessions.ics"`)
+	return c.SendString(buildICalendar(sessions, time.Now()))
+}
+
+// buildICalendar renders sessions as an RFC 5545 calendar.
+func buildICalendar(sessions []data.LiveSession, now time.Time) string {
+	const stamp = "20060102T150405Z"
+	var sb strings.Builder
+	line := func(name, value string) {
+		writeICalLine(&sb, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//CBT LMS//Live Sessions//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", "CBT LMS live sessions")
+	for _, s := range sessions {
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("live-session-%d@cbt-lms", s.ID))
+		line("DTSTAMP", now.UTC().Format(stamp))
+		line("DTSTART", s.StartsAt.UTC().Format(stamp))
+		line("DTEND", s.EndsAt.UTC().Format(stamp))
+		line("SUMMARY", escapeICalText(s.CourseTitle+": "+s.Title))
+		description := s.Description
+		if s.MeetingURL != "" {
+			description = strings.TrimSpace(description + "\n\n" + s.MeetingURL)
+		}
+		if description != "" {
+			line("DESCRIPTION", escapeICalText(description))
+		}
+		location := s.Location
+		if location == "" {
+			location = s.MeetingURL
+		}
+		if location != "" {
+			line("LOCATION", escapeICalText(location))
+		}
+		if validMeetingURL(s.MeetingURL) {
+			line("URL", s.MeetingURL)
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return sb.String()
+}
+
+var iCalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escapeICalText(value string) string {
+	return iCalEscaper.Replace(value)
+}
+
+// validMeetingURL accepts absolute http(s) URLs without spaces or control
+// characters. The URL property of a calendar is written unescaped, so anything
+// else could break out of it.
+func validMeetingURL(raw string) bool {
+	if raw == "" || strings.IndexFunc(raw, func(r rune) bool { return unicode.IsControl(r) || unicode.IsSpace(r) }) >= 0 {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// writeICalLine folds content lines longer than 75 octets without splitting UTF-8
+// characters, as RFC 5545 requires.
+func writeICalLine(sb *strings.Builder, content string) {
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		sb.WriteString(content[:cut])
+		sb.WriteString("\r\n ")
+		content = content[cut:]
+		limit = 74 // the leading space counts toward the next line
+	}
+	sb.WriteString(content)
+	sb.WriteString("\r\n")
+}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxNoteLength = 20000
+
+var (
+	headingLinePattern  = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.+?)\s*#*\s*$`)
+	headingImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\([^)]+\)`)
+	headingLinkPattern  = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`)
+	headingMarkPattern  = regexp.MustCompile("[`*_~]")
+	slugStripPattern    = regexp.MustCompile(`[^\w\s\-\x{0E00}-\x{0E7F}]`)
+	slugSpacePattern    = regexp.MustCompile(`\s+`)
+	slugDashPattern     = regexp.MustCompile(`-+`)
+	filenameUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+)
+
+type courseHeading struct {
+	ID    string
+	Text  string
+	Level int
+}
+
+// parseCourseHeadings lists the markdown headings of a course with the subtopic IDs
+// the web client derives from them (see headingUtils.js), in document order.
+func parseCourseHeadings(content string) []courseHeading {
+	var headings []courseHeading
+	seen := make(map[string]int)
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		match := headingLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		text := headingImagePattern.ReplaceAllString(match[2], "$1")
+		text = headingLinkPattern.ReplaceAllString(text, "$1")
+		text = strings.TrimSpace(headingMarkPattern.ReplaceAllString(text, ""))
+
+		slugSource := text
+		if slugSource == "" {
+			slugSource = "heading-" + strconv.Itoa(len(headings)+1)
+		}
+		slug := strings.ToLower(slugSource)
+		slug = slugStripPattern.ReplaceAllString(slug, "")
+		slug = slugSpacePattern.ReplaceAllString(slug, "-")
+		slug = slugDashPattern.ReplaceAllString(slug, "-")
+		slug = strings.Trim(slug, "-")
+
+		seen[slug]++
+		id := slug
+		if seen[slug] > 1 {
+			id = slug + "-" + strconv.Itoa(seen[slug])
+		}
+		if text == "" {
+			text = "หัวข้อ"
+		}
+		headings = append(headings, courseHeading{ID: id, Text: text, Level: len(match[1])})
+	}
+	return headings
+}
+
+// learningTarget reads the courseId and subtopicId route params.
+func learningTarget(c *fiber.Ctx) (courseID, subtopicID string, err error) {
+	courseID = strings.TrimSpace(c.Params("courseId"))
+	subtopicID = strings.TrimSpace(c.Params("subtopicId"))
+	if courseID == "" || subtopicID == "" {
+		return "", "", fiber.NewError(fiber.StatusBadRequest, "courseId and subtopicId are required")
+	}
+	return courseID, subtopicID, nil
+}
+
+func parseNoteBody(c *fiber.Ctx) (string, error) {
+	var req noteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return "", fiber.NewError(fiber.StatusBadRequest, "body is required")
+	}
+	if utf8.RuneCountInString(body) > maxNoteLength {
+		return "", fiber.NewError(fiber.StatusBadRequest, "body must be at most "+strconv.Itoa(maxNoteLength)+" characters")
+	}
+	return body, nil
+}
+
+// VisitSubtopic records the subtopic the learner is reading, so the course can
+// reopen there.
+func (h *Handler) VisitSubtopic(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, subtopicID, err := learningTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := data.SaveLastVisitedSubtopic(username, courseID, subtopicID); err != nil {
+		if errors.Is(err, data.ErrCourseNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot save position")
+	}
+	return c.JSON(fiber.Map{"message": "position saved", "lastSubtopicId": subtopicID})
+}
+
+// ListBookmarks returns the caller's bookmarks. ?courseId= limits them to one course.
+func (h *Handler) ListBookmarks(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	bookmarks, err := data.ListBookmarks(username, strings.TrimSpace(c.Query("courseId")))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list bookmarks")
+	}
+	return c.JSON(fiber.Map{"bookmarks": bookmarks})
+}
+
+// AddBookmark bookmarks a subtopic. Bookmarking it again is a no-op.
+func (h *Handler) AddBookmark(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, subtopicID, err := learningTarget(c)
+	if err != nil {
+		return err
+	}
+	bookmark, err := data.AddBookmark(username, courseID, subtopicID)
+	if err != nil {
+		if errors.Is(err, data.ErrCourseNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot add bookmark")
+	}
+	return c.JSON(fiber.Map{"bookmark": bookmark})
+}
+
+func (h *Handler) RemoveBookmark(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, subtopicID, err := learningTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := data.RemoveBookmark(username, courseID, subtopicID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "bookmark not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot remove bookmark")
+	}
+	return c.JSON(fiber.Map{"message": "bookmark removed"})
+}
+
+// ListNotes returns the caller's notes in a course. ?subtopicId= limits them to one
+// subtopic.
+func (h *Handler) ListNotes(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	notes, err := data.ListNotes(username, courseID, strings.TrimSpace(c.Query("subtopicId")))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list notes")
+	}
+	return c.JSON(fiber.Map{"notes": notes})
+}
+
+func (h *Handler) CreateNote(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, subtopicID, err := learningTarget(c)
+	if err != nil {
+		return err
+	}
+	body, err := parseNoteBody(c)
+	if err != nil {
+		return err
+	}
+	note, err := data.CreateNote(username, courseID, subtopicID, body)
+	if err != nil {
+		if errors.Is(err, data.ErrCourseNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot create note")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"note": note})
+}
+
+func (h *Handler) UpdateNote(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid note id")
+	}
+	body, err := parseNoteBody(c)
+	if err != nil {
+		return err
+	}
+	note, err := data.UpdateNote(id, username, body)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "note not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update note")
+	}
+	return c.JSON(fiber.Map{"note": note})
+}
+
+func (h *Handler) DeleteNote(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid note id")
+	}
+	if err := data.DeleteNote(id, username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "note not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot delete note")
+	}
+	return c.JSON(fiber.Map{"message": "note deleted"})
+}
+
+// ExportNotes downloads the caller's notes of a course as one markdown file, grouped
+// under the course headings in reading order. Notes whose heading no longer exists
+// come last, under their subtopic ID.
+func (h *Handler) ExportNotes(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	title, content, err := data.GetCourseOutline(courseID)
+	if err != nil {
+		if errors.Is(err, data.ErrCourseNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get course")
+	}
+	notes, err := data.ListNotes(username, courseID, "")
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list notes")
+	}
+
+	bySubtopic := make(map[string][]data.LearningNote)
+	var order []string
+	for _, n := range notes {
+		if _, ok := bySubtopic[n.SubtopicID]; !ok {
+			order = append(order, n.SubtopicID)
+		}
+		bySubtopic[n.SubtopicID] = append(bySubtopic[n.SubtopicID], n)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	writeSection := func(heading string, items []data.LearningNote) {
+		fmt.Fprintf(&sb, "## %s\n\n", heading)
+		for i, n := range items {
+			if i > 0 {
+				sb.WriteString("---\n\n")
+			}
+			sb.WriteString(n.Body)
+			sb.WriteString("\n\n")
+		}
+	}
+	for _, heading := range parseCourseHeadings(content) {
+		if items, ok := bySubtopic[heading.ID]; ok {
+			writeSection(heading.Text, items)
+			delete(bySubtopic, heading.ID)
+		}
+	}
+	for _, subtopicID := range order {
+		if items, ok := bySubtopic[subtopicID]; ok {
+			writeSection(subtopicID, items)
+		}
+	}
+
+	filename := fmt.Sprintf("notes-%s-%s.md", filenameUnsafeChars.ReplaceAllString(courseID, "-"), time.Now().Format("20060102"))
+	c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.SendString(sb.String())
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// notificationStreamHeartbeat keeps idle event streams alive through proxies.
+const notificationStreamHeartbeat = 25 * time.Second
+
+// ListNotifications returns the caller's notifications, newest first.
+// ?unread=true → only unread ones.
+func (h *Handler) ListNotifications(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	limit, offset, page := parsePage(c)
+	items, total, err := data.ListNotifications(username, c.QueryBool("unread"), limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list notifications")
+	}
+	unread, err := data.CountUnreadNotifications(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot count notifications")
+	}
+	return c.JSON(fiber.Map{
+		"notifications": items,
+		"unreadCount":   unread,
+		"pagination":    paginationMeta(total, limit, page),
+	})
+}
+
+func (h *Handler) GetUnreadNotificationCount(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	unread, err := data.CountUnreadNotifications(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot count notifications")
+	}
+	return c.JSON(fiber.Map{"unreadCount": unread})
+}
+
+func (h *Handler) MarkNotificationRead(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid notification id")
+	}
+	if err := data.MarkNotificationRead(username, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "notification not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update notification")
+	}
+	return c.JSON(fiber.Map{"message": "notification marked as read"})
+}
+
+func (h *Handler) MarkAllNotificationsRead(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	updated, err := data.MarkAllNotificationsRead(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update notifications")
+	}
+	return c.JSON(fiber.Map{"message": "notifications marked as read", "updated": updated})
+}
+
+// StreamNotifications is a server-sent events stream for the caller. It sends an
+// "unread" event with the current count on connect and whenever it changes, and a
+// "notification" event for each new notification. The stream ends when the access
+// token expires or, checked on each heartbeat, the account is no longer active; the
+// client reconnects with a fresh token.
+func (h *Handler) StreamNotifications(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	expiresAt, err := auth.TokenExpiry(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	unread, err := data.CountUnreadNotifications(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot count notifications")
+	}
+	events, unsubscribe, err := data.SubscribeNotifications(username)
+	if err != nil {
+		if errors.Is(err, data.ErrTooManyNotificationStreams) {
+			return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot open notification stream")
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		if writeSSE(w, "unread", fiber.Map{"unreadCount": unread}) != nil {
+			return
+		}
+		heartbeat := time.NewTicker(notificationStreamHeartbeat)
+		defer heartbeat.Stop()
+		expiry := time.NewTimer(time.Until(expiresAt))
+		defer expiry.Stop()
+
+		for {
+			select {
+			case ev := <-events:
+				if ev.Notification != nil {
+					if writeSSE(w, "notification", ev.Notification) != nil {
+						return
+					}
+				}
+				count, err := data.CountUnreadNotifications(username)
+				if err != nil {
+					continue
+				}
+				if writeSSE(w, "unread", fiber.Map{"unreadCount": count}) != nil {
+					return
+				}
+			case <-expiry.C:
+				return
+			case <-heartbeat.C:
+				if !streamUserActive(username) {
+					return
+				}
+				if _, err := w.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+				if w.Flush() != nil {
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// streamUserActive reports whether a live stream may stay open for the user. A
+// failed lookup keeps the stream; the next heartbeat checks again.
+func streamUserActive(username string) bool {
+	user, err := data.FindUserByUsername(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	return err != nil || strings.ToLower(strings.TrimSpace(user.Status)) == "active"
+}
+
+// writeSSE writes one event and flushes; an error means the client has gone away.
+func writeSSE(w *bufio.Writer, event string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, body); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// GetNotificationPreferences returns the caller's mail language and which optional
+// mails they receive.
+func (h *Handler) GetNotificationPreferences(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	prefs, err := data.GetNotificationPreferences(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get notification preferences")
+	}
+	return c.JSON(fiber.Map{"preferences": prefs})
+}
+
+func (h *Handler) UpdateNotificationPreferences(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	var req notificationPreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Locale = strings.ToLower(strings.TrimSpace(req.Locale))
+	for t := range req.Email {
+		if !slices.Contains(data.OptionalMailTypes, t) {
+			return fiber.NewError(fiber.StatusBadRequest, "unknown email type: "+t)
+		}
+	}
+
+	prefs, err := data.SaveNotificationPreferences(username, data.NotificationPreferences{Locale: req.Locale, Email: req.Email})
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidLocale) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot save notification preferences")
+	}
+	return c.JSON(fiber.Map{"preferences": prefs})
+}
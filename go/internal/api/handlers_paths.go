@@ -0,0 +1,245 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var validPathStatuses = []string{"active", "inactive"}
+
+// ListLearningPaths returns the active learning paths (public).
+func (h *Handler) ListLearningPaths(c *fiber.Ctx) error {
+	paths, err := data.ListLearningPaths(true)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list learning paths")
+	}
+	return c.JSON(fiber.Map{"paths": paths})
+}
+
+// ListLearningPathsAdmin returns every learning path, including inactive ones.
+func (h *Handler) ListLearningPathsAdmin(c *fiber.Ctx) error {
+	paths, err := data.ListLearningPaths(false)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list learning paths")
+	}
+	return c.JSON(fiber.Map{"paths": paths})
+}
+
+func (h *Handler) GetLearningPath(c *fiber.Ctx) error {
+	id := strings.TrimSpace(c.Params("id"))
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "path id is required")
+	}
+	path, err := data.GetLearningPath(id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get learning path")
+	}
+	if err != nil || path.Status != "active" {
+		return fiber.NewError(fiber.StatusNotFound, "learning path not found")
+	}
+	return c.JSON(fiber.Map{"path": path})
+}
+
+func (h *Handler) UpsertLearningPath(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	isAdmin := auth.IsAdminContext(c)
+
+	var req learningPathRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.ID = strings.TrimSpace(req.ID)
+	req.Title = strings.TrimSpace(req.Title)
+	req.Status = strings.ToLower(strings.TrimSpace(req.Status))
+	if req.ID == "" || req.Title == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "id and title are required")
+	}
+	if req.Status == "" {
+		req.Status = "inactive"
+	}
+	if !slices.Contains(validPathStatuses, req.Status) {
+		return fiber.NewError(fiber.StatusBadRequest, "status must be active or inactive")
+	}
+	if req.CompletionScore < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "completionScore must not be negative")
+	}
+
+	steps := make([]data.LearningPathStep, 0, len(req.Steps))
+	seen := map[string]bool{}
+	for _, s := range req.Steps {
+		itemType := strings.ToLower(strings.TrimSpace(s.ItemType))
+		itemID := strings.TrimSpace(s.ItemID)
+		if itemType != data.PathItemCourse && itemType != data.PathItemExam {
+			return fiber.NewError(fiber.StatusBadRequest, "step itemType must be course or exam")
+		}
+		if itemID == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "step itemId is required")
+		}
+		if seen[itemType+":"+itemID] {
+			return fiber.NewError(fiber.StatusBadRequest, "a course or exam can appear only once in a path")
+		}
+		seen[itemType+":"+itemID] = true
+		steps = append(steps, data.LearningPathStep{ItemType: itemType, ItemID: itemID})
+	}
+	if req.Status == "active" && len(steps) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "an active path needs at least one step")
+	}
+
+	skillRewards := make([]data.SkillReward, 0, len(req.SkillRewards))
+	for _, sr := range req.SkillRewards {
+		if strings.TrimSpace(sr.Skill) == "" {
+			continue
+		}
+		if sr.Points < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "skill reward points must not be negative")
+		}
+		skillRewards = append(skillRewards, data.SkillReward{Skill: strings.TrimSpace(sr.Skill), Points: sr.Points})
+	}
+	skillRewards, err = data.ResolveSkillRewards(skillRewards)
+	if err != nil {
+		return rewardSkillError(err)
+	}
+
+	saved, err := data.UpsertLearningPath(data.LearningPath{
+		ID:              req.ID,
+		Title:           req.Title,
+		Description:     strings.TrimSpace(req.Description),
+		Image:           strings.TrimSpace(req.Image),
+		Status:          req.Status,
+		CompletionScore: req.CompletionScore,
+		Steps:           steps,
+		SkillRewards:    skillRewards,
+	}, username, isAdmin)
+	if err != nil {
+		if errors.Is(err, data.ErrForbidden) {
+			return fiber.NewError(fiber.StatusForbidden, "not allowed to edit this learning path")
+		}
+		if errors.Is(err, data.ErrPathItemMissing) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot save learning path")
+	}
+	return c.JSON(fiber.Map{"path": saved})
+}
+
+func (h *Handler) DeleteLearningPath(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	id := strings.TrimSpace(c.Params("id"))
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "path id is required")
+	}
+
+	if err := data.DeleteLearningPath(id, username, auth.IsAdminContext(c)); err != nil {
+		if errors.Is(err, data.ErrForbidden) {
+			return fiber.NewError(fiber.StatusForbidden, "not allowed to delete this learning path")
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "learning path not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot delete learning path")
+	}
+	return c.JSON(fiber.Map{"message": "learning path deleted"})
+}
+
+func (h *Handler) GetPathProgress(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	id := strings.TrimSpace(c.Params("id"))
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "path id is required")
+	}
+
+	progress, err := data.GetPathProgress(username, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "learning path not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get path progress")
+	}
+	return c.JSON(fiber.Map{"progress": progress})
+}
+
+// EnrollInPath puts the caller on a learning path; its steps then unlock in order.
+func (h *Handler) EnrollInPath(c *fiber.Ctx) error {
+	return h.setPathEnrollment(c, true)
+}
+
+// LeavePath takes the caller off a learning path they enrolled in.
+func (h *Handler) LeavePath(c *fiber.Ctx) error {
+	return h.setPathEnrollment(c, false)
+}
+
+func (h *Handler) setPathEnrollment(c *fiber.Ctx, enroll bool) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	id := strings.TrimSpace(c.Params("id"))
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "path id is required")
+	}
+
+	if enroll {
+		err = data.EnrollInPath(username, id)
+	} else {
+		err = data.LeavePath(username, id)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if enroll {
+				return fiber.NewError(fiber.StatusNotFound, "learning path not found")
+			}
+			return fiber.NewError(fiber.StatusNotFound, "not enrolled in this learning path")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update path enrollment")
+	}
+
+	progress, err := data.GetPathProgress(username, id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get path progress")
+	}
+	return c.JSON(fiber.Map{"progress": progress})
+}
+
+// GetPathStats returns per-path learner, completion and per-step stats.
+// ?scope=my → only paths owned by the current user; otherwise all paths.
+func (h *Handler) GetPathStats(c *fiber.Ctx) error {
+	owner := ""
+	if c.Query("scope") == "my" {
+		username, err := auth.CurrentUsername(c)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+		}
+		owner = username
+	}
+	stats, err := data.GetPathStats(owner)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get path stats")
+	}
+	return c.JSON(fiber.Map{"paths": stats})
+}
+
+// checkItemUnlocked maps a learning path lock to 403.
+func checkItemUnlocked(username, itemType, itemID string) error {
+	if err := data.CheckItemUnlocked(username, itemType, itemID); err != nil {
+		if errors.Is(err, data.ErrItemLocked) {
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot check learning path prerequisites")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"archive/zip"
+	"backend/internal/auth"
+	"backend/internal/data"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportMyData returns a ZIP with every dataset holding the caller's personal data,
+// each as both <name>.json and <name>.csv, plus the avatar image when one is set.
+func (h *Handler) ExportMyData(c *fiber.Ctx) error {
+	if auth.IsImpersonating(c) {
+		return fiber.NewError(fiber.StatusForbidden, "personal data export is not available while impersonating")
+	}
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+
+	tables, err := data.ExportPersonalData(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot export personal data")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, table := range tables {
+		if err := writePersonalDataTable(zw, table); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "cannot build export archive")
+		}
+	}
+	if avatarURL, err := data.GetAvatar(username); err == nil && avatarURL != "" {
+		fsPath := strings.TrimPrefix(avatarURL, "/")
+		if content, err := os.ReadFile(fsPath); err == nil {
+			w, err := zw.Create("avatar" + filepath.Ext(fsPath))
+			if err == nil {
+				_, err = w.Write(content)
+			}
+			if err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "cannot build export archive")
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot build export archive")
+	}
+
+	filename := fmt.Sprintf("personal-data-%s-%s.zip", username, time.Now().Format("20060102"))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.Send(buf.Bytes())
+}
+
+func writePersonalDataTable(zw *zip.Writer, table data.PersonalDataTable) error {
+	records := make([]map[string]any, 0, len(table.Rows))
+	for _, row := range table.Rows {
+		record := make(map[string]any, len(table.Columns))
+		for i, col := range table.Columns {
+			record[col] = row[i]
+		}
+		records = append(records, record)
+	}
+	jsonBytes, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	w, err := zw.Create(table.Name + ".json")
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(jsonBytes); err != nil {
+		return err
+	}
+
+	w, err = zw.Create(table.Name + ".csv")
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil { // BOM so Excel reads Thai text as UTF-8
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(table.Columns); err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		values := make([]string, len(row))
+		for i, v := range row {
+			values[i] = personalDataCell(v)
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func personalDataCell(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case []byte:
+		return string(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// AnonymiseUser pseudonymises a user's identity (PDPA) while keeping learning
+// records and scores for aggregate statistics. The account is deactivated.
+func (h *Handler) AnonymiseUser(c *fiber.Ctx) error {
+	username := data.NormalizeUsername(c.Params("username"))
+	if username == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "username is required")
+	}
+	callerUsername, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "cannot identify caller")
+	}
+	if username == callerUsername {
+		return fiber.NewError(fiber.StatusForbidden, "cannot anonymise your own account")
+	}
+
+	target, err := data.FindUserByUsername(username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot find user")
+	}
+	if strings.ToLower(strings.TrimSpace(target.Role)) == "admin" {
+		return fiber.NewError(fiber.StatusForbidden, "cannot anonymise admin accounts")
+	}
+
+	avatarURL, err := data.GetAvatar(username)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get avatar")
+	}
+
+	pseudonym, err := data.AnonymiseUser(username)
+	if err != nil {
+		if errors.Is(err, data.ErrAlreadyAnonymised) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot anonymise user")
+	}
+
+	if avatarURL != "" {
+		if err := os.Remove(strings.TrimPrefix(avatarURL, "/")); err != nil && !os.IsNotExist(err) {
+			log.Printf("anonymise %s: remove avatar: %v", pseudonym, err)
+		}
+	}
+	log.Printf("user anonymised by %s: id=%d username=%s", callerUsername, target.ID, pseudonym)
+
+	return c.JSON(fiber.Map{
+		"message":  "anonymise user success",
+		"id":       target.ID,
+		"username": pseudonym,
+	})
+}
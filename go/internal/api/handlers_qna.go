@@ -0,0 +1,330 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// qnaParamID reads a positive id route param.
+func qnaParamID(c *fiber.Ctx, name string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(c.Params(name)), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid "+name)
+	}
+	return id, nil
+}
+
+// GetCourseQnA returns the course-wide Q&A, or one cohort's Q&A with ?cohortId.
+// Cohort Q&A needs a signed-in member, so the public route only serves course-wide
+// questions.
+func (h *Handler) GetCourseQnA(c *fiber.Ctx) error {
+	return h.getCourseQnA(c, false)
+}
+
+// GetCourseQnAForModeration also returns hidden questions and replies.
+func (h *Handler) GetCourseQnAForModeration(c *fiber.Ctx) error {
+	return h.getCourseQnA(c, true)
+}
+
+func (h *Handler) getCourseQnA(c *fiber.Ctx, includeHidden bool) error {
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	cohortID, err := cohortQuery(c, courseID)
+	if err != nil {
+		return err
+	}
+	if cohortID != 0 {
+		// A cohort's Q&A is private to its members, its instructor and the course owner.
+		username, err := auth.CurrentUsername(c)
+		if err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "sign in to view cohort Q&A")
+		}
+		if err := data.CheckCohortAccess(courseID, cohortID, username, auth.IsAdminContext(c)); err != nil {
+			if errors.Is(err, data.ErrForbidden) {
+				return fiber.NewError(fiber.StatusForbidden, "only cohort members can view its Q&A")
+			}
+			return cohortError(err, "cannot get cohort")
+		}
+	}
+	questions, err := data.GetCourseQnA(courseID, cohortID, includeHidden)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get Q&A")
+	}
+	return c.JSON(fiber.Map{"questions": questions})
+}
+
+func (h *Handler) PostQnAQuestion(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	var req qnaQuestionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if strings.TrimSpace(req.Question) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "question is required")
+	}
+	q, err := data.CreateQnAQuestion(courseID, strings.TrimSpace(req.SubtopicID), username, strings.TrimSpace(req.Question))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot create question")
+	}
+	if err := data.NotifyQnAQuestion(q); err != nil {
+		log.Printf("notify qna question %d: %v", q.ID, err)
+	}
+	return c.JSON(fiber.Map{"question": q})
+}
+
+func (h *Handler) PostQnAReply(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	questionID, err := qnaParamID(c, "questionId")
+	if err != nil {
+		return err
+	}
+	var req qnaReplyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if strings.TrimSpace(req.Reply) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "reply is required")
+	}
+	r, err := data.CreateQnAReply(questionID, username, strings.TrimSpace(req.Reply), auth.IsAdminContext(c))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "question not found")
+		}
+		if errors.Is(err, data.ErrForbidden) {
+			return fiber.NewError(fiber.StatusForbidden, "only cohort members can reply to its Q&A")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot create reply")
+	}
+	if err := data.NotifyQnAReply(questionID, username); err != nil {
+		log.Printf("notify qna reply %d: %v", r.ID, err)
+	}
+	return c.JSON(fiber.Map{"reply": r})
+}
+
+// UpdateQnAQuestion edits the caller's own question.
+func (h *Handler) UpdateQnAQuestion(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	questionID, err := qnaParamID(c, "questionId")
+	if err != nil {
+		return err
+	}
+	var req qnaQuestionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if strings.TrimSpace(req.Question) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "question is required")
+	}
+	q, err := data.UpdateQnAQuestion(questionID, username, strings.TrimSpace(req.Question))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "question not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update question")
+	}
+	return c.JSON(fiber.Map{"question": q})
+}
+
+// DeleteQnAQuestion deletes the caller's own question with its replies.
+func (h *Handler) DeleteQnAQuestion(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	questionID, err := qnaParamID(c, "questionId")
+	if err != nil {
+		return err
+	}
+	if err := data.DeleteQnAQuestion(questionID, username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "question not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot delete question")
+	}
+	return c.JSON(fiber.Map{"message": "question deleted"})
+}
+
+// UpdateQnAReply edits the caller's own reply.
+func (h *Handler) UpdateQnAReply(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	replyID, err := qnaParamID(c, "replyId")
+	if err != nil {
+		return err
+	}
+	var req qnaReplyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if strings.TrimSpace(req.Reply) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "reply is required")
+	}
+	r, err := data.UpdateQnAReply(replyID, username, strings.TrimSpace(req.Reply), auth.IsAdminContext(c))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "reply not found")
+		}
+		if errors.Is(err, data.ErrForbidden) {
+			return fiber.NewError(fiber.StatusForbidden, "only cohort members can reply to its Q&A")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update reply")
+	}
+	return c.JSON(fiber.Map{"reply": r})
+}
+
+// DeleteQnAReply deletes the caller's own reply.
+func (h *Handler) DeleteQnAReply(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	replyID, err := qnaParamID(c, "replyId")
+	if err != nil {
+		return err
+	}
+	if err := data.DeleteQnAReply(replyID, username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "reply not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot delete reply")
+	}
+	return c.JSON(fiber.Map{"message": "reply deleted"})
+}
+
+// AcceptQnAReply marks the accepted answer of a question, or clears it with
+// replyId 0. Only the question author, the course owner, the cohort instructor
+// or an admin may do so.
+func (h *Handler) AcceptQnAReply(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	questionID, err := qnaParamID(c, "questionId")
+	if err != nil {
+		return err
+	}
+	var req qnaAcceptRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if req.ReplyID < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid replyId")
+	}
+	q, err := data.AcceptQnAReply(questionID, req.ReplyID, username, auth.IsAdminContext(c))
+	if err != nil {
+		return qnaResolveError(err)
+	}
+	return c.JSON(fiber.Map{"question": q})
+}
+
+// SetQnAStatus opens or resolves a question. Only the question author, the course
+// owner, the cohort instructor or an admin may do so.
+func (h *Handler) SetQnAStatus(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	questionID, err := qnaParamID(c, "questionId")
+	if err != nil {
+		return err
+	}
+	var req qnaStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	q, err := data.SetQnAStatus(questionID, strings.TrimSpace(strings.ToLower(req.Status)), username, auth.IsAdminContext(c))
+	if err != nil {
+		return qnaResolveError(err)
+	}
+	return c.JSON(fiber.Map{"question": q})
+}
+
+func qnaResolveError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fiber.NewError(fiber.StatusNotFound, "question not found")
+	case errors.Is(err, data.ErrForbidden):
+		return fiber.NewError(fiber.StatusForbidden, "only the question author or an instructor can resolve this question")
+	case errors.Is(err, data.ErrInvalidQnAStatus), errors.Is(err, data.ErrQnAReplyNotQuestion):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, "cannot update question")
+}
+
+func (h *Handler) HideQnAQuestion(c *fiber.Ctx) error {
+	return h.setQnAQuestionHidden(c, true)
+}
+
+func (h *Handler) UnhideQnAQuestion(c *fiber.Ctx) error {
+	return h.setQnAQuestionHidden(c, false)
+}
+
+func (h *Handler) setQnAQuestionHidden(c *fiber.Ctx, hidden bool) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	questionID, err := qnaParamID(c, "questionId")
+	if err != nil {
+		return err
+	}
+	q, err := data.SetQnAQuestionHidden(questionID, hidden, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "question not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update question")
+	}
+	return c.JSON(fiber.Map{"question": q})
+}
+
+func (h *Handler) HideQnAReply(c *fiber.Ctx) error {
+	return h.setQnAReplyHidden(c, true)
+}
+
+func (h *Handler) UnhideQnAReply(c *fiber.Ctx) error {
+	return h.setQnAReplyHidden(c, false)
+}
+
+func (h *Handler) setQnAReplyHidden(c *fiber.Ctx, hidden bool) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	replyID, err := qnaParamID(c, "replyId")
+	if err != nil {
+		return err
+	}
+	r, err := data.SetQnAReplyHidden(replyID, hidden, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "reply not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update reply")
+	}
+	return c.JSON(fiber.Map{"reply": r})
+}
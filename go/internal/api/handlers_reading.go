@@ -0,0 +1,85 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// heartbeatLimits allows up to two heartbeat intervals per heartbeat, so a late
+// heartbeat still counts in full.
+func (h *Handler) heartbeatLimits() data.HeartbeatLimits {
+	return data.HeartbeatLimits{
+		MaxCredit:   2 * time.Duration(h.cfg.HeartbeatSeconds) * time.Second,
+		IdleTimeout: time.Duration(h.cfg.ReadingIdleSeconds) * time.Second,
+	}
+}
+
+// StartReadingSession opens a reading session on a subtopic. The client then sends a
+// heartbeat every heartbeatSeconds while the subtopic stays open.
+func (h *Handler) StartReadingSession(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, subtopicID, err := learningTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := checkCohortSchedule(username, courseID, subtopicID); err != nil {
+		return err
+	}
+	session, err := data.StartReadingSession(username, courseID, subtopicID, h.heartbeatLimits().IdleTimeout)
+	if err != nil {
+		return enrollmentError(err, "cannot start reading session")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"session":          session,
+		"heartbeatSeconds": h.cfg.HeartbeatSeconds,
+	})
+}
+
+// ReadingHeartbeat credits the time since the previous heartbeat. Clients send
+// idle=true when the learner has not interacted with the page since then.
+func (h *Handler) ReadingHeartbeat(c *fiber.Ctx) error {
+	return h.recordHeartbeat(c, false)
+}
+
+// EndReadingSession sends a last heartbeat and closes the session.
+func (h *Handler) EndReadingSession(c *fiber.Ctx) error {
+	return h.recordHeartbeat(c, true)
+}
+
+func (h *Handler) recordHeartbeat(c *fiber.Ctx, end bool) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid session id")
+	}
+	var req readingHeartbeatRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+		}
+	}
+
+	session, err := data.RecordHeartbeat(id, username, req.Idle, end, h.heartbeatLimits())
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return fiber.NewError(fiber.StatusNotFound, "reading session not found")
+		case errors.Is(err, data.ErrReadingSessionEnded):
+			return fiber.NewError(fiber.StatusGone, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot record heartbeat")
+	}
+	return c.JSON(fiber.Map{"session": session})
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultRecommendationLimit = 10
+	maxRecommendationLimit     = 50
+)
+
+// GetMyRecommendations ranks the courses and exams the current user could take
+// next, each with the reasons it was picked.
+func (h *Handler) GetMyRecommendations(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultRecommendationLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultRecommendationLimit
+	}
+	limit = min(limit, maxRecommendationLimit)
+
+	recs, err := data.RecommendFor(username, limit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "user not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get recommendations")
+	}
+	return c.JSON(fiber.Map{"recommendations": recs})
+}
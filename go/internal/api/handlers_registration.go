@@ -0,0 +1,232 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultInviteDays = 7
+	maxInviteDays     = 365
+	maxInviteUses     = 10000
+)
+
+// GetInviteByToken describes an invite link before the learner signs up with it.
+func (h *Handler) GetInviteByToken(c *fiber.Ctx) error {
+	token := strings.TrimSpace(c.Params("token"))
+	invite, err := data.GetActiveInviteByToken(auth.HashOneTimeToken(token))
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidInvite) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot load invite")
+	}
+	groups := make([]string, 0, len(invite.Groups))
+	for _, g := range invite.Groups {
+		groups = append(groups, g.Name)
+	}
+	return c.JSON(fiber.Map{
+		"role":       invite.Role,
+		"groups":     groups,
+		"expires_at": invite.ExpiresAt,
+	})
+}
+
+// GetRegistrationSettings returns the registration mode and allowed domains. It is
+// also served publicly so the sign-up page can adapt.
+func (h *Handler) GetRegistrationSettings(c *fiber.Ctx) error {
+	settings, err := data.GetRegistrationSettings()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot load registration settings")
+	}
+	return c.JSON(settings)
+}
+
+func (h *Handler) UpdateRegistrationSettings(c *fiber.Ctx) error {
+	var req registrationSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	settings, err := data.SaveRegistrationSettings(data.RegistrationSettings{
+		Mode:           strings.ToLower(strings.TrimSpace(req.Mode)),
+		AllowedDomains: req.AllowedDomains,
+	})
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidRegistrationMode) || errors.Is(err, data.ErrNoAllowedDomains) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot save registration settings")
+	}
+	return c.JSON(settings)
+}
+
+// ListInvites returns invites, newest first. ?active=true → only redeemable ones.
+func (h *Handler) ListInvites(c *fiber.Ctx) error {
+	limit, offset, page := parsePage(c)
+	invites, total, err := data.ListInvites(c.QueryBool("active"), limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list invites")
+	}
+	return c.JSON(fiber.Map{"invites": invites, "pagination": paginationMeta(total, limit, page)})
+}
+
+// CreateInvite issues an invite link. The token is only returned here; the server
+// keeps its hash.
+func (h *Handler) CreateInvite(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	var req createInviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Role = strings.TrimSpace(req.Role)
+	if req.Role == "" {
+		req.Role = "user"
+	}
+	if strings.ToLower(req.Role) == "admin" {
+		return fiber.NewError(fiber.StatusForbidden, "cannot invite users with admin role")
+	}
+	roleExists, err := data.RoleExists(req.Role)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot validate role")
+	}
+	if !roleExists {
+		return fiber.NewError(fiber.StatusBadRequest, "role is invalid")
+	}
+	if req.MaxUses == 0 {
+		req.MaxUses = 1
+	}
+	if req.MaxUses < 1 || req.MaxUses > maxInviteUses {
+		return fiber.NewError(fiber.StatusBadRequest, "max_uses must be between 1 and "+strconv.Itoa(maxInviteUses))
+	}
+	if req.ExpiresInDays == 0 {
+		req.ExpiresInDays = defaultInviteDays
+	}
+	if req.ExpiresInDays < 1 || req.ExpiresInDays > maxInviteDays {
+		return fiber.NewError(fiber.StatusBadRequest, "expires_in_days must be between 1 and "+strconv.Itoa(maxInviteDays))
+	}
+
+	rawToken, tokenHash, err := auth.GenerateOneTimeToken()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot generate invite token")
+	}
+	expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+	invite, err := data.CreateInvite(tokenHash, req.Label, req.Role, req.GroupIDs, req.MaxUses, expiresAt, username)
+	if err != nil {
+		if errors.Is(err, data.ErrGroupNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot create invite")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"invite":     invite,
+		"token":      rawToken,
+		"invite_url": h.cfg.AppBaseURL + "/register?invite=" + rawToken,
+	})
+}
+
+func (h *Handler) RevokeInvite(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid invite id")
+	}
+	invite, err := data.RevokeInvite(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "invite not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot revoke invite")
+	}
+	return c.JSON(fiber.Map{"message": "invite revoked", "invite": invite})
+}
+
+func (h *Handler) ListGroups(c *fiber.Ctx) error {
+	groups, err := data.ListGroups()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list groups")
+	}
+	return c.JSON(fiber.Map{"groups": groups})
+}
+
+func (h *Handler) CreateGroup(c *fiber.Ctx) error {
+	var req createGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "name is required")
+	}
+	group, err := data.CreateGroup(req.Name)
+	if err != nil {
+		if data.IsDuplicateKey(err) {
+			return fiber.NewError(fiber.StatusConflict, "group already exists")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot create group")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"group": group})
+}
+
+func (h *Handler) DeleteGroup(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid group id")
+	}
+	if err := data.DeleteGroup(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "group not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot delete group")
+	}
+	return c.JSON(fiber.Map{"message": "group deleted"})
+}
+
+func (h *Handler) ListGroupMembers(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid group id")
+	}
+	members, err := data.ListGroupMembers(id)
+	if err != nil {
+		if errors.Is(err, data.ErrGroupNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list group members")
+	}
+	return c.JSON(fiber.Map{"members": members})
+}
+
+// SetGroupMembers replaces the member list of a group.
+func (h *Handler) SetGroupMembers(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid group id")
+	}
+	var req setGroupMembersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if err := data.SetGroupMembers(id, req.Usernames); err != nil {
+		switch {
+		case errors.Is(err, data.ErrGroupNotFound):
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		case errors.Is(err, data.ErrUnknownUsername):
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update group members")
+	}
+	members, err := data.ListGroupMembers(id)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list group members")
+	}
+	return c.JSON(fiber.Map{"members": members})
+}
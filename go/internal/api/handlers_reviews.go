@@ -0,0 +1,188 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxReviewLength = 5000
+
+// courseReviewTarget reads the :id course and :reviewId route params.
+func courseReviewTarget(c *fiber.Ctx) (string, int64, error) {
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	reviewID, err := strconv.ParseInt(c.Params("reviewId"), 10, 64)
+	if err != nil || reviewID <= 0 {
+		return "", 0, fiber.NewError(fiber.StatusBadRequest, "invalid review id")
+	}
+	return courseID, reviewID, nil
+}
+
+// ListCourseReviews returns the visible reviews of a course with its rating summary.
+func (h *Handler) ListCourseReviews(c *fiber.Ctx) error {
+	return h.listCourseReviews(c, false)
+}
+
+// ListCourseReviewsForModeration also returns hidden reviews.
+func (h *Handler) ListCourseReviewsForModeration(c *fiber.Ctx) error {
+	return h.listCourseReviews(c, true)
+}
+
+func (h *Handler) listCourseReviews(c *fiber.Ctx, includeHidden bool) error {
+	courseID := strings.TrimSpace(c.Params("id"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "course id is required")
+	}
+	limit, offset, page := parsePage(c)
+	reviews, total, err := data.ListCourseReviews(courseID, includeHidden, limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list reviews")
+	}
+	summary, err := data.GetCourseRatingSummary(courseID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get rating summary")
+	}
+	return c.JSON(fiber.Map{"summary": summary, "reviews": reviews, "pagination": paginationMeta(total, limit, page)})
+}
+
+func (h *Handler) GetMyCourseReview(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	review, err := data.GetMyCourseReview(username, courseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "review not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get review")
+	}
+	return c.JSON(fiber.Map{"review": review})
+}
+
+// SaveCourseReview creates or updates the caller's review of a course they completed.
+func (h *Handler) SaveCourseReview(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	var req courseReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		return fiber.NewError(fiber.StatusBadRequest, "rating must be between 1 and 5")
+	}
+	req.Review = strings.TrimSpace(req.Review)
+	if utf8.RuneCountInString(req.Review) > maxReviewLength {
+		return fiber.NewError(fiber.StatusBadRequest, "review must be at most "+strconv.Itoa(maxReviewLength)+" characters")
+	}
+
+	review, err := data.UpsertCourseReview(username, courseID, req.Rating, req.Review)
+	if err != nil {
+		if errors.Is(err, data.ErrCourseNotCompleted) {
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot save review")
+	}
+	return c.JSON(fiber.Map{"review": review})
+}
+
+func (h *Handler) DeleteMyCourseReview(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID := strings.TrimSpace(c.Params("courseId"))
+	if courseID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "courseId is required")
+	}
+	if err := data.DeleteMyCourseReview(username, courseID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "review not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot delete review")
+	}
+	return c.JSON(fiber.Map{"message": "review deleted"})
+}
+
+func (h *Handler) HideCourseReview(c *fiber.Ctx) error {
+	return h.setCourseReviewHidden(c, true)
+}
+
+func (h *Handler) UnhideCourseReview(c *fiber.Ctx) error {
+	return h.setCourseReviewHidden(c, false)
+}
+
+func (h *Handler) setCourseReviewHidden(c *fiber.Ctx, hidden bool) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, reviewID, err := courseReviewTarget(c)
+	if err != nil {
+		return err
+	}
+	review, err := data.SetCourseReviewHidden(courseID, reviewID, hidden, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "review not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot update review")
+	}
+	return c.JSON(fiber.Map{"review": review})
+}
+
+// ReplyToCourseReview sets the instructor reply of a review. Only the course owner or
+// an admin may reply; an empty reply removes it.
+func (h *Handler) ReplyToCourseReview(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	courseID, reviewID, err := courseReviewTarget(c)
+	if err != nil {
+		return err
+	}
+	var req reviewReplyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	req.Reply = strings.TrimSpace(req.Reply)
+	if utf8.RuneCountInString(req.Reply) > maxReviewLength {
+		return fiber.NewError(fiber.StatusBadRequest, "reply must be at most "+strconv.Itoa(maxReviewLength)+" characters")
+	}
+
+	review, err := data.ReplyToCourseReview(courseID, reviewID, req.Reply, username, auth.IsAdminContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return fiber.NewError(fiber.StatusNotFound, "review not found")
+		case errors.Is(err, data.ErrForbidden):
+			return fiber.NewError(fiber.StatusForbidden, "only the course owner can reply to reviews")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reply to review")
+	}
+	if err := data.NotifyReviewReply(review); err != nil {
+		log.Printf("notify review reply %d: %v", review.ID, err)
+	}
+	return c.JSON(fiber.Map{"review": review})
+}
@@ -0,0 +1,877 @@
+package api
+
+import (
+	"backend/internal/data"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SCIM provisioning lets an HR system or identity provider manage accounts.
+// Users map onto the users table (userName → username, name/displayName → name,
+// enterprise employeeNumber → employee_code, active → status). Groups map onto
+// roles: a user belongs to exactly one group, so adding a member moves the user
+// into that role and removing a member moves them back to "user". The admin
+// role and admin accounts are never exposed to or modified by SCIM.
+
+const defaultSCIMPageSize = 100
+const maxSCIMPageSize = 200
+const maxSCIMGroupMembers = 10000
+
+type scimProblem struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (p *scimProblem) Error() string { return p.detail }
+
+func newSCIMProblem(status int, scimType, detail string) *scimProblem {
+	return &scimProblem{status: status, scimType: scimType, detail: detail}
+}
+
+func scimJSON(c *fiber.Ctx, v any) error {
+	if err := c.JSON(v); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, scimContentType)
+	return nil
+}
+
+// scimFail writes err as a SCIM error response; non-SCIM errors become a 500.
+func scimFail(c *fiber.Ctx, err error) error {
+	problem := newSCIMProblem(fiber.StatusInternalServerError, "", "internal server error")
+	errors.As(err, &problem)
+	body := fiber.Map{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(problem.status),
+		"detail":  problem.detail,
+	}
+	if problem.scimType != "" {
+		body["scimType"] = problem.scimType
+	}
+	return scimJSON(c.Status(problem.status), body)
+}
+
+// SCIMAuth checks the static bearer token configured by SCIM_BEARER_TOKEN.
+// SCIM is disabled (404) while no token is configured.
+func (h *Handler) SCIMAuth(c *fiber.Ctx) error {
+	if h.cfg.SCIMToken == "" {
+		return scimFail(c, newSCIMProblem(fiber.StatusNotFound, "", "SCIM provisioning is disabled"))
+	}
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(h.cfg.SCIMToken)) != 1 {
+		return scimFail(c, newSCIMProblem(fiber.StatusUnauthorized, "", "invalid bearer token"))
+	}
+	return c.Next()
+}
+
+func (h *Handler) GetSCIMServiceProviderConfig(c *fiber.Ctx) error {
+	return scimJSON(c, fiber.Map{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          fiber.Map{"supported": true},
+		"bulk":           fiber.Map{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         fiber.Map{"supported": true, "maxResults": maxSCIMPageSize},
+		"changePassword": fiber.Map{"supported": false},
+		"sort":           fiber.Map{"supported": false},
+		"etag":           fiber.Map{"supported": false},
+		"authenticationSchemes": []fiber.Map{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Static bearer token configured on the server",
+		}},
+	})
+}
+
+// ── Filtering & paging ───────────────────────────────────────────────────────
+
+type scimFilterTerm struct {
+	attr  string
+	op    string
+	value string
+}
+
+var scimFilterAnd = regexp.MustCompile(`(?i)\s+and\s+`)
+var scimFilterTermPattern = regexp.MustCompile(`(?i)^([a-z0-9:._-]+)\s+(eq|co|sw)\s+("(?:[^"\\]|\\.)*"|true|false)$`)
+var scimMemberPathPattern = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+// parseSCIMFilter supports the subset IdPs actually send: attribute comparisons
+// with eq/co/sw joined by "and".
+func parseSCIMFilter(filter string) ([]scimFilterTerm, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return nil, nil
+	}
+	terms := make([]scimFilterTerm, 0)
+	for _, part := range scimFilterAnd.Split(filter, -1) {
+		m := scimFilterTermPattern.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return nil, newSCIMProblem(fiber.StatusBadRequest, "invalidFilter", "unsupported filter expression: "+part)
+		}
+		value := m[3]
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, newSCIMProblem(fiber.StatusBadRequest, "invalidFilter", "invalid filter value: "+value)
+			}
+			value = unquoted
+		}
+		terms = append(terms, scimFilterTerm{attr: scimAttr(m[1]), op: strings.ToLower(m[2]), value: value})
+	}
+	return terms, nil
+}
+
+// scimAttr lower-cases an attribute path and strips any schema URN prefix.
+func scimAttr(path string) string {
+	p := strings.ToLower(strings.TrimSpace(path))
+	for _, schema := range []string{scimUserSchema, scimGroupSchema, scimEnterpriseSchema} {
+		p = strings.TrimPrefix(p, strings.ToLower(schema)+":")
+	}
+	return p
+}
+
+func scimUserFilter(terms []scimFilterTerm) (data.UserFilter, error) {
+	f := data.UserFilter{ExcludeRole: "admin"}
+	for _, t := range terms {
+		if t.op != "eq" && t.attr != "displayname" && t.attr != "name.formatted" {
+			return f, newSCIMProblem(fiber.StatusBadRequest, "invalidFilter", "only eq is supported for "+t.attr)
+		}
+		switch t.attr {
+		case "username":
+			f.Username = t.value
+		case "displayname", "name.formatted":
+			f.Name, f.NameMatch = t.value, t.op
+		case "active":
+			f.Status = "inactive"
+			if strings.EqualFold(t.value, "true") {
+				f.Status = "active"
+			}
+		case "employeenumber":
+			f.EmployeeCode = t.value
+		case "roles", "roles.value", "groups", "groups.value":
+			f.Role = t.value
+		default:
+			return f, newSCIMProblem(fiber.StatusBadRequest, "invalidFilter", "unsupported filter attribute: "+t.attr)
+		}
+	}
+	return f, nil
+}
+
+func scimPage(c *fiber.Ctx) (startIndex, count int) {
+	startIndex, err := strconv.Atoi(c.Query("startIndex", "1"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err = strconv.Atoi(c.Query("count", strconv.Itoa(defaultSCIMPageSize)))
+	if err != nil || count < 0 {
+		count = defaultSCIMPageSize
+	}
+	if count > maxSCIMPageSize {
+		count = maxSCIMPageSize
+	}
+	return startIndex, count
+}
+
+func scimListResponse(resources []fiber.Map, total, startIndex int) fiber.Map {
+	return fiber.Map{
+		"schemas":      []string{scimListSchema},
+		"totalResults": total,
+		"startIndex":   startIndex,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	}
+}
+
+func scimDecode(c *fiber.Ctx, v any) error {
+	if err := json.Unmarshal(c.Body(), v); err != nil {
+		return newSCIMProblem(fiber.StatusBadRequest, "invalidSyntax", "invalid request body")
+	}
+	return nil
+}
+
+// ── Users ────────────────────────────────────────────────────────────────────
+
+func scimUserResource(c *fiber.Ctx, u data.AuthUser) fiber.Map {
+	id := strconv.FormatInt(u.ID, 10)
+	return fiber.Map{
+		"schemas":            []string{scimUserSchema, scimEnterpriseSchema},
+		"id":                 id,
+		"userName":           u.Username,
+		"name":               scimName{Formatted: u.Name},
+		"displayName":        u.Name,
+		"active":             u.Status == "active",
+		"roles":              []scimMultiValue{{Value: u.Role, Primary: true}},
+		"groups":             []scimMultiValue{{Value: u.Role}},
+		scimEnterpriseSchema: scimEnterpriseUser{EmployeeNumber: u.EmployeeCode},
+		"meta": fiber.Map{
+			"resourceType": "User",
+			"created":      u.CreatedAt,
+			"location":     c.BaseURL() + "/scim/v2/Users/" + id,
+		},
+	}
+}
+
+func authUserFromRecord(u data.AuthUserRecord) data.AuthUser {
+	return data.AuthUser{
+		ID:           u.ID,
+		Name:         u.Name,
+		Username:     u.Username,
+		EmployeeCode: u.EmployeeCode,
+		Role:         u.Role,
+		Status:       u.Status,
+		CreatedAt:    u.CreatedAt,
+	}
+}
+
+func scimFullName(n scimName) string {
+	if formatted := strings.TrimSpace(n.Formatted); formatted != "" {
+		return formatted
+	}
+	return strings.TrimSpace(strings.TrimSpace(n.GivenName) + " " + strings.TrimSpace(n.FamilyName))
+}
+
+func scimPrimaryValue(values []scimMultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// scimBool accepts JSON booleans as well as "True"/"False" strings (sent by Azure AD).
+func scimBool(raw json.RawMessage) (bool, bool) {
+	var b bool
+	if json.Unmarshal(raw, &b) == nil {
+		return b, true
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if parsed, err := strconv.ParseBool(s); err == nil {
+			return parsed, true
+		}
+	}
+	return false, false
+}
+
+// scimUserChanges collects attribute updates; empty fields are left unchanged.
+type scimUserChanges struct {
+	name         string
+	givenName    string
+	familyName   string
+	employeeCode string
+	role         string
+	status       string
+}
+
+func (ch *scimUserChanges) fromRequest(req scimUserRequest) {
+	if req.Name != nil {
+		ch.name = scimFullName(*req.Name)
+	}
+	if ch.name == "" {
+		ch.name = strings.TrimSpace(req.DisplayName)
+	}
+	ch.role = scimPrimaryValue(req.Roles)
+	if req.Enterprise != nil {
+		ch.employeeCode = req.Enterprise.EmployeeNumber
+	}
+	if req.Active != nil {
+		ch.status = "inactive"
+		if *req.Active {
+			ch.status = "active"
+		}
+	}
+}
+
+// set applies a single PATCH value. Attributes the LMS does not store are ignored.
+func (ch *scimUserChanges) set(target data.AuthUserRecord, path string, raw json.RawMessage) error {
+	invalid := newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "invalid value for "+path)
+	var s string
+	switch scimAttr(path) {
+	case "active":
+		active, ok := scimBool(raw)
+		if !ok {
+			return invalid
+		}
+		ch.status = "inactive"
+		if active {
+			ch.status = "active"
+		}
+	case "username":
+		if json.Unmarshal(raw, &s) != nil {
+			return invalid
+		}
+		if data.NormalizeUsername(s) != target.Username {
+			return newSCIMProblem(fiber.StatusBadRequest, "mutability", "userName cannot be changed")
+		}
+	case "displayname", "name.formatted":
+		if json.Unmarshal(raw, &s) != nil {
+			return invalid
+		}
+		ch.name = strings.TrimSpace(s)
+	case "name.givenname":
+		if json.Unmarshal(raw, &s) != nil {
+			return invalid
+		}
+		ch.givenName = s
+	case "name.familyname":
+		if json.Unmarshal(raw, &s) != nil {
+			return invalid
+		}
+		ch.familyName = s
+	case "name":
+		var n scimName
+		if json.Unmarshal(raw, &n) != nil {
+			return invalid
+		}
+		ch.name = scimFullName(n)
+	case "roles":
+		var values []scimMultiValue
+		if json.Unmarshal(raw, &values) != nil {
+			var single scimMultiValue
+			if json.Unmarshal(raw, &single) != nil {
+				return invalid
+			}
+			values = []scimMultiValue{single}
+		}
+		ch.role = scimPrimaryValue(values)
+	case "employeenumber":
+		if json.Unmarshal(raw, &s) != nil {
+			return invalid
+		}
+		ch.employeeCode = s
+	case strings.ToLower(scimEnterpriseSchema):
+		var ent scimEnterpriseUser
+		if json.Unmarshal(raw, &ent) != nil {
+			return invalid
+		}
+		ch.employeeCode = ent.EmployeeNumber
+	}
+	return nil
+}
+
+func (ch *scimUserChanges) validate() error {
+	if ch.name == "" && (ch.givenName != "" || ch.familyName != "") {
+		ch.name = scimFullName(scimName{GivenName: ch.givenName, FamilyName: ch.familyName})
+	}
+	ch.employeeCode = data.NormalizeEmployeeCode(ch.employeeCode)
+	if ch.employeeCode != "" && !data.IsValidEmployeeCode(ch.employeeCode) {
+		return newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "employeeNumber must be in format XXXX-XX-XXXX")
+	}
+	ch.role = data.NormalizeRoleName(ch.role)
+	if ch.role == "admin" {
+		return newSCIMProblem(fiber.StatusForbidden, "", "cannot assign admin role")
+	}
+	if ch.role != "" {
+		exists, err := data.RoleExists(ch.role)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "role is invalid")
+		}
+	}
+	return nil
+}
+
+func loadSCIMUser(c *fiber.Ctx) (data.AuthUserRecord, error) {
+	notFound := newSCIMProblem(fiber.StatusNotFound, "", "user not found")
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return data.AuthUserRecord{}, notFound
+	}
+	user, err := data.FindUserByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return data.AuthUserRecord{}, notFound
+		}
+		return data.AuthUserRecord{}, err
+	}
+	// Admin accounts are invisible to SCIM, so they cannot be read or modified.
+	if strings.EqualFold(strings.TrimSpace(user.Role), "admin") {
+		return data.AuthUserRecord{}, notFound
+	}
+	return user, nil
+}
+
+// updateSCIMUser persists ch and revokes refresh tokens when the user ends up inactive.
+func (h *Handler) updateSCIMUser(c *fiber.Ctx, target data.AuthUserRecord, ch scimUserChanges) error {
+	if err := ch.validate(); err != nil {
+		return scimFail(c, err)
+	}
+	updated, err := data.UpdateUserByUsername(target.Username, ch.name, ch.role, ch.status, ch.employeeCode)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	if updated.Status == "inactive" {
+		if err := data.RevokeAllRefreshTokensByUserID(updated.ID); err != nil {
+			return scimFail(c, err)
+		}
+	}
+	return scimJSON(c, scimUserResource(c, authUserFromRecord(updated)))
+}
+
+func (h *Handler) ListSCIMUsers(c *fiber.Ctx) error {
+	terms, err := parseSCIMFilter(c.Query("filter"))
+	if err != nil {
+		return scimFail(c, err)
+	}
+	filter, err := scimUserFilter(terms)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	startIndex, count := scimPage(c)
+	users, total, err := data.SearchUsers(filter, count, startIndex-1)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	resources := make([]fiber.Map, 0, len(users))
+	for _, u := range users {
+		resources = append(resources, scimUserResource(c, u))
+	}
+	return scimJSON(c, scimListResponse(resources, total, startIndex))
+}
+
+func (h *Handler) GetSCIMUser(c *fiber.Ctx) error {
+	user, err := loadSCIMUser(c)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	return scimJSON(c, scimUserResource(c, authUserFromRecord(user)))
+}
+
+// CreateSCIMUser provisions a new account. Without a password the account gets
+// DEFAULT_RESET_PASSWORD; without roles it joins the "user" role.
+func (h *Handler) CreateSCIMUser(c *fiber.Ctx) error {
+	var req scimUserRequest
+	if err := scimDecode(c, &req); err != nil {
+		return scimFail(c, err)
+	}
+	username := data.NormalizeUsername(req.UserName)
+	if username == "" {
+		return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "userName is required"))
+	}
+
+	ch := scimUserChanges{status: "active"}
+	ch.fromRequest(req)
+	if ch.name == "" {
+		ch.name = username
+	}
+	if ch.role == "" {
+		ch.role = "user"
+	}
+	if err := ch.validate(); err != nil {
+		return scimFail(c, err)
+	}
+
+	password := req.Password
+	if password == "" {
+		password = h.cfg.DefaultResetPassword
+	} else if len(password) < 8 {
+		return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "password must be at least 8 characters"))
+	}
+
+	user, err := data.CreateUser(ch.name, username, ch.employeeCode, password, ch.role, ch.status)
+	if err != nil {
+		if data.IsDuplicateKey(err) {
+			return scimFail(c, newSCIMProblem(fiber.StatusConflict, "uniqueness", "userName already exists"))
+		}
+		return scimFail(c, err)
+	}
+	return scimJSON(c.Status(fiber.StatusCreated), scimUserResource(c, user))
+}
+
+func (h *Handler) ReplaceSCIMUser(c *fiber.Ctx) error {
+	target, err := loadSCIMUser(c)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	var req scimUserRequest
+	if err := scimDecode(c, &req); err != nil {
+		return scimFail(c, err)
+	}
+	if username := data.NormalizeUsername(req.UserName); username != "" && username != target.Username {
+		return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "mutability", "userName cannot be changed"))
+	}
+
+	var ch scimUserChanges
+	ch.fromRequest(req)
+	return h.updateSCIMUser(c, target, ch)
+}
+
+func (h *Handler) PatchSCIMUser(c *fiber.Ctx) error {
+	target, err := loadSCIMUser(c)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	var req scimPatchRequest
+	if err := scimDecode(c, &req); err != nil {
+		return scimFail(c, err)
+	}
+	if len(req.Operations) == 0 {
+		return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "Operations is required"))
+	}
+
+	var ch scimUserChanges
+	for _, op := range req.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path != "" {
+				if err := ch.set(target, op.Path, op.Value); err != nil {
+					return scimFail(c, err)
+				}
+				continue
+			}
+			var attrs map[string]json.RawMessage
+			if json.Unmarshal(op.Value, &attrs) != nil {
+				return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "value must be an object when path is omitted"))
+			}
+			for key, raw := range attrs {
+				if err := ch.set(target, key, raw); err != nil {
+					return scimFail(c, err)
+				}
+			}
+		case "remove":
+			switch scimAttr(op.Path) {
+			case "roles":
+				ch.role = "user"
+			case "username", "name", "name.formatted", "displayname", "active", "employeenumber":
+				return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "mutability", op.Path+" cannot be removed"))
+			}
+		default:
+			return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "unsupported patch op: "+op.Op))
+		}
+	}
+	return h.updateSCIMUser(c, target, ch)
+}
+
+// DeleteSCIMUser deactivates rather than deletes, so learning history is kept.
+func (h *Handler) DeleteSCIMUser(c *fiber.Ctx) error {
+	target, err := loadSCIMUser(c)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	if _, err := data.UpdateUserByUsername(target.Username, "", "", "inactive", ""); err != nil {
+		return scimFail(c, err)
+	}
+	if err := data.RevokeAllRefreshTokensByUserID(target.ID); err != nil {
+		return scimFail(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ── Groups (roles) ───────────────────────────────────────────────────────────
+
+func (h *Handler) scimGroupResource(c *fiber.Ctx, role data.Role) (fiber.Map, error) {
+	resource := fiber.Map{
+		"schemas":     []string{scimGroupSchema},
+		"id":          role.Code,
+		"displayName": role.Name,
+		"meta": fiber.Map{
+			"resourceType": "Group",
+			"location":     c.BaseURL() + "/scim/v2/Groups/" + role.Code,
+		},
+	}
+	if strings.Contains(c.Query("excludedAttributes"), "members") {
+		return resource, nil
+	}
+	users, _, err := data.SearchUsers(data.UserFilter{Role: role.Code}, maxSCIMGroupMembers, 0)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]scimMultiValue, 0, len(users))
+	for _, u := range users {
+		members = append(members, scimMultiValue{Value: strconv.FormatInt(u.ID, 10), Display: u.Username})
+	}
+	resource["members"] = members
+	return resource, nil
+}
+
+func (h *Handler) respondSCIMGroup(c *fiber.Ctx, status int, role data.Role) error {
+	resource, err := h.scimGroupResource(c, role)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	return scimJSON(c.Status(status), resource)
+}
+
+// scimGroups lists every role except admin.
+func scimGroups() ([]data.Role, error) {
+	roles, err := data.ListRoles()
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]data.Role, 0, len(roles))
+	for _, r := range roles {
+		if r.Code != "admin" {
+			groups = append(groups, r)
+		}
+	}
+	return groups, nil
+}
+
+func loadSCIMGroup(c *fiber.Ctx) (data.Role, error) {
+	code := data.NormalizeRoleName(c.Params("id"))
+	groups, err := scimGroups()
+	if err != nil {
+		return data.Role{}, err
+	}
+	for _, g := range groups {
+		if g.Code == code {
+			return g, nil
+		}
+	}
+	return data.Role{}, newSCIMProblem(fiber.StatusNotFound, "", "group not found")
+}
+
+func scimMemberIDs(values []scimMultiValue) ([]string, error) {
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, err := strconv.ParseInt(v.Value, 10, 64); err != nil {
+			return nil, newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "invalid member id: "+v.Value)
+		}
+		ids = append(ids, v.Value)
+	}
+	return ids, nil
+}
+
+func renameSCIMGroup(role *data.Role, displayName string) error {
+	displayName = strings.TrimSpace(displayName)
+	if displayName == "" || displayName == role.Name {
+		return nil
+	}
+	if data.IsBuiltInRole(role.Code) {
+		return newSCIMProblem(fiber.StatusBadRequest, "mutability", "cannot rename a built-in role")
+	}
+	updated, err := data.UpdateRoleName(role.Code, displayName)
+	if err != nil {
+		return err
+	}
+	*role = updated
+	return nil
+}
+
+func (h *Handler) ListSCIMGroups(c *fiber.Ctx) error {
+	terms, err := parseSCIMFilter(c.Query("filter"))
+	if err != nil {
+		return scimFail(c, err)
+	}
+	groups, err := scimGroups()
+	if err != nil {
+		return scimFail(c, err)
+	}
+
+	matched := make([]data.Role, 0, len(groups))
+	for _, g := range groups {
+		ok := true
+		for _, t := range terms {
+			if t.op != "eq" {
+				return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidFilter", "only eq is supported for groups"))
+			}
+			switch t.attr {
+			case "displayname":
+				ok = ok && (strings.EqualFold(g.Name, t.value) || g.Code == data.NormalizeRoleName(t.value))
+			case "id":
+				ok = ok && g.Code == data.NormalizeRoleName(t.value)
+			default:
+				return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidFilter", "unsupported filter attribute: "+t.attr))
+			}
+		}
+		if ok {
+			matched = append(matched, g)
+		}
+	}
+
+	startIndex, count := scimPage(c)
+	page := matched[min(startIndex-1, len(matched)):min(startIndex-1+count, len(matched))]
+	resources := make([]fiber.Map, 0, len(page))
+	for _, g := range page {
+		resource, err := h.scimGroupResource(c, g)
+		if err != nil {
+			return scimFail(c, err)
+		}
+		resources = append(resources, resource)
+	}
+	return scimJSON(c, scimListResponse(resources, len(matched), startIndex))
+}
+
+func (h *Handler) GetSCIMGroup(c *fiber.Ctx) error {
+	role, err := loadSCIMGroup(c)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	return h.respondSCIMGroup(c, fiber.StatusOK, role)
+}
+
+// CreateSCIMGroup creates a role whose code is derived from displayName.
+func (h *Handler) CreateSCIMGroup(c *fiber.Ctx) error {
+	var req scimGroupRequest
+	if err := scimDecode(c, &req); err != nil {
+		return scimFail(c, err)
+	}
+	name := strings.TrimSpace(req.DisplayName)
+	code := strings.Join(strings.Fields(data.NormalizeRoleName(name)), "_")
+	if code == "" {
+		return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "displayName is required"))
+	}
+	if data.IsBuiltInRole(code) {
+		return scimFail(c, newSCIMProblem(fiber.StatusConflict, "uniqueness", "group already exists"))
+	}
+	ids, err := scimMemberIDs(req.Members)
+	if err != nil {
+		return scimFail(c, err)
+	}
+
+	role, err := data.CreateRole(code, name)
+	if err != nil {
+		if data.IsDuplicateKey(err) {
+			return scimFail(c, newSCIMProblem(fiber.StatusConflict, "uniqueness", "group already exists"))
+		}
+		return scimFail(c, err)
+	}
+	if _, err := data.MoveUsersToRole(ids, "", role.Code); err != nil {
+		return scimFail(c, err)
+	}
+	return h.respondSCIMGroup(c, fiber.StatusCreated, role)
+}
+
+func (h *Handler) ReplaceSCIMGroup(c *fiber.Ctx) error {
+	role, err := loadSCIMGroup(c)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	var req scimGroupRequest
+	if err := scimDecode(c, &req); err != nil {
+		return scimFail(c, err)
+	}
+	ids, err := scimMemberIDs(req.Members)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	if err := renameSCIMGroup(&role, req.DisplayName); err != nil {
+		return scimFail(c, err)
+	}
+	if err := data.ReplaceRoleMembers(role.Code, ids); err != nil {
+		return scimFail(c, err)
+	}
+	return h.respondSCIMGroup(c, fiber.StatusOK, role)
+}
+
+func (h *Handler) PatchSCIMGroup(c *fiber.Ctx) error {
+	role, err := loadSCIMGroup(c)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	var req scimPatchRequest
+	if err := scimDecode(c, &req); err != nil {
+		return scimFail(c, err)
+	}
+	if len(req.Operations) == 0 {
+		return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "Operations is required"))
+	}
+
+	for _, op := range req.Operations {
+		if err := h.applySCIMGroupOperation(&role, op); err != nil {
+			return scimFail(c, err)
+		}
+	}
+	return h.respondSCIMGroup(c, fiber.StatusOK, role)
+}
+
+func (h *Handler) applySCIMGroupOperation(role *data.Role, op scimPatchOperation) error {
+	invalid := newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "invalid value for "+op.Path)
+	kind := strings.ToLower(op.Op)
+	attr := scimAttr(op.Path)
+
+	if kind == "remove" {
+		var ids []string
+		if m := scimMemberPathPattern.FindStringSubmatch(strings.TrimSpace(op.Path)); m != nil {
+			ids = []string{m[1]}
+		} else if attr != "members" {
+			return newSCIMProblem(fiber.StatusBadRequest, "mutability", op.Path+" cannot be removed")
+		} else if len(op.Value) > 0 {
+			var values []scimMultiValue
+			if json.Unmarshal(op.Value, &values) != nil {
+				return invalid
+			}
+			var err error
+			if ids, err = scimMemberIDs(values); err != nil {
+				return err
+			}
+		} else {
+			return data.ReplaceRoleMembers(role.Code, nil)
+		}
+		_, err := data.MoveUsersToRole(ids, role.Code, "user")
+		return err
+	}
+	if kind != "add" && kind != "replace" {
+		return newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "unsupported patch op: "+op.Op)
+	}
+
+	attrs := map[string]json.RawMessage{attr: op.Value}
+	if op.Path == "" {
+		attrs = nil
+		if json.Unmarshal(op.Value, &attrs) != nil {
+			return newSCIMProblem(fiber.StatusBadRequest, "invalidValue", "value must be an object when path is omitted")
+		}
+	}
+	for key, raw := range attrs {
+		switch scimAttr(key) {
+		case "displayname":
+			var name string
+			if json.Unmarshal(raw, &name) != nil {
+				return invalid
+			}
+			if err := renameSCIMGroup(role, name); err != nil {
+				return err
+			}
+		case "members":
+			var values []scimMultiValue
+			if json.Unmarshal(raw, &values) != nil {
+				return invalid
+			}
+			ids, err := scimMemberIDs(values)
+			if err != nil {
+				return err
+			}
+			if kind == "replace" {
+				if err := data.ReplaceRoleMembers(role.Code, ids); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := data.MoveUsersToRole(ids, "", role.Code); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteSCIMGroup returns the group's members to "user" and deletes the role.
+func (h *Handler) DeleteSCIMGroup(c *fiber.Ctx) error {
+	role, err := loadSCIMGroup(c)
+	if err != nil {
+		return scimFail(c, err)
+	}
+	if data.IsBuiltInRole(role.Code) {
+		return scimFail(c, newSCIMProblem(fiber.StatusBadRequest, "mutability", fmt.Sprintf("cannot delete built-in group %q", role.Code)))
+	}
+	if err := data.ReplaceRoleMembers(role.Code, nil); err != nil {
+		return scimFail(c, err)
+	}
+	if err := data.DeleteRole(role.Code); err != nil {
+		return scimFail(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
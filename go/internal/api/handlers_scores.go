@@ -0,0 +1,63 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListUserScoreEvents returns a page of a user's score ledger.
+func (h *Handler) ListUserScoreEvents(c *fiber.Ctx) error {
+	limit, offset, page := parsePage(c)
+	events, total, err := data.ListScoreEvents(c.Params("username"), limit, offset)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list score events")
+	}
+	return c.JSON(fiber.Map{
+		"events":     events,
+		"pagination": paginationMeta(total, limit, page),
+	})
+}
+
+// AdjustUserScore adds or removes points by hand. The reason is kept in the ledger.
+func (h *Handler) AdjustUserScore(c *fiber.Ctx) error {
+	adminUsername, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	var req scoreAdjustmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	event, err := data.AdjustScore(c.Params("username"), req.Skill, req.Points, req.Reason, adminUsername)
+	switch {
+	case errors.Is(err, data.ErrInvalidScoreAdjustment):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case data.IsForeignKeyViolation(err):
+		return fiber.NewError(fiber.StatusNotFound, "user not found")
+	case err != nil:
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot adjust score")
+	}
+	log.Printf("score adjustment by %s: %s %+d skill=%q: %s", adminUsername, event.Username, event.Points, event.Skill, event.Note)
+	if event.Points > 0 {
+		awardEarnedBadges(event.Username, data.BadgeSkillPoints)
+	}
+	return c.Status(fiber.StatusCreated).JSON(event)
+}
+
+// ReconcileScores reports balances that drifted from the score ledger. With
+// ?apply=true the balances are rebuilt from the ledger.
+func (h *Handler) ReconcileScores(c *fiber.Ctx) error {
+	apply := c.QueryBool("apply")
+	drift, err := data.ReconcileScores(apply)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot reconcile scores")
+	}
+	if apply && len(drift) > 0 {
+		log.Printf("score reconcile: rebuilt %d balances from the ledger", len(drift))
+	}
+	return c.JSON(fiber.Map{"drift": drift, "applied": apply})
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func skillError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, data.ErrSkillNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, data.ErrSkillConflict), errors.Is(err, data.ErrSkillInUse):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, data.ErrInvalidSkill), errors.Is(err, data.ErrInvalidSkillLevels):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, fallback)
+}
+
+// rewardSkillError is skillError for skill rewards, where an unknown skill is a bad
+// request rather than a missing resource.
+func rewardSkillError(err error) error {
+	if errors.Is(err, data.ErrSkillNotFound) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error()+" (ask an admin to add it to the skill catalog)")
+	}
+	return skillError(err, "cannot resolve skills")
+}
+
+func skillID(c *fiber.Ctx) (int64, error) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "invalid skill id")
+	}
+	return id, nil
+}
+
+// ListSkills returns the skills catalog and the proficiency levels.
+func (h *Handler) ListSkills(c *fiber.Ctx) error {
+	skills, err := data.ListSkills()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list skills")
+	}
+	levels, err := data.ListSkillLevels()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot list skill levels")
+	}
+	return c.JSON(fiber.Map{"skills": skills, "levels": levels})
+}
+
+func (h *Handler) CreateSkill(c *fiber.Ctx) error {
+	return h.saveSkill(c, 0)
+}
+
+func (h *Handler) UpdateSkill(c *fiber.Ctx) error {
+	id, err := skillID(c)
+	if err != nil {
+		return err
+	}
+	return h.saveSkill(c, id)
+}
+
+func (h *Handler) saveSkill(c *fiber.Ctx, id int64) error {
+	var req skillRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	skill, err := data.SaveSkill(data.Skill{
+		ID:          id,
+		Name:        req.Name,
+		Category:    req.Category,
+		Description: req.Description,
+		Aliases:     req.Aliases,
+	})
+	if err != nil {
+		return skillError(err, "cannot save skill")
+	}
+	status := fiber.StatusOK
+	if id == 0 {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(skill)
+}
+
+func (h *Handler) DeleteSkill(c *fiber.Ctx) error {
+	id, err := skillID(c)
+	if err != nil {
+		return err
+	}
+	if err := data.DeleteSkill(id); err != nil {
+		return skillError(err, "cannot delete skill")
+	}
+	return c.JSON(fiber.Map{"message": "skill deleted"})
+}
+
+// MergeSkill folds another skill, with its aliases and references, into this one.
+func (h *Handler) MergeSkill(c *fiber.Ctx) error {
+	id, err := skillID(c)
+	if err != nil {
+		return err
+	}
+	var req skillMergeRequest
+	if err := c.BodyParser(&req); err != nil || req.SourceID <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "sourceId is required")
+	}
+	skill, err := data.MergeSkill(id, req.SourceID)
+	if err != nil {
+		return skillError(err, "cannot merge skills")
+	}
+	return c.JSON(skill)
+}
+
+func (h *Handler) UpdateSkillLevels(c *fiber.Ctx) error {
+	var req []skillLevelBody
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	levels := make([]data.SkillLevel, 0, len(req))
+	for _, l := range req {
+		levels = append(levels, data.SkillLevel{Name: l.Name, MinPoints: l.MinPoints})
+	}
+	saved, err := data.SetSkillLevels(levels)
+	if err != nil {
+		return skillError(err, "cannot save skill levels")
+	}
+	return c.JSON(fiber.Map{"levels": saved})
+}
+
+func (h *Handler) GetRoleCompetencies(c *fiber.Ctx) error {
+	competencies, err := data.GetRoleCompetencies(c.Params("code"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get competencies")
+	}
+	return c.JSON(fiber.Map{"role": data.NormalizeRoleName(c.Params("code")), "competencies": competencies})
+}
+
+func (h *Handler) UpdateRoleCompetencies(c *fiber.Ctx) error {
+	code := strings.TrimSpace(c.Params("code"))
+	exists, err := data.RoleExists(code)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot validate role")
+	}
+	if !exists {
+		return fiber.NewError(fiber.StatusNotFound, "role not found")
+	}
+	var req []competencyBody
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	competencies := make([]data.Competency, 0, len(req))
+	for _, body := range req {
+		competencies = append(competencies, data.Competency{SkillID: body.SkillID, Level: body.Level})
+	}
+	saved, err := data.SetRoleCompetencies(code, competencies)
+	if err != nil {
+		return skillError(err, "cannot save competencies")
+	}
+	return c.JSON(fiber.Map{"role": data.NormalizeRoleName(code), "competencies": saved})
+}
+
+func skillGapResponse(c *fiber.Ctx, username string) error {
+	report, err := data.GetSkillGap(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fiber.NewError(fiber.StatusNotFound, "user not found")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get skill gap")
+	}
+	return c.JSON(report)
+}
+
+// GetMySkillGap compares the caller's skill points with their role's profile.
+func (h *Handler) GetMySkillGap(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	return skillGapResponse(c, username)
+}
+
+func (h *Handler) GetUserSkillGap(c *fiber.Ctx) error {
+	return skillGapResponse(c, c.Params("username"))
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetMyTeam returns learning progress for the caller's direct and indirect reports.
+// Access comes from the reporting line itself: callers without reports get an empty team.
+func (h *Handler) GetMyTeam(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	members, err := data.GetTeamProgress(username, h.cfg.TeamOverdueDays)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get team progress")
+	}
+
+	var direct, completed, overdue, assignmentsOverdue, passed int
+	var seconds int64
+	for _, m := range members {
+		if m.Depth == 1 {
+			direct++
+		}
+		completed += m.CoursesCompleted
+		overdue += m.CoursesOverdue
+		assignmentsOverdue += m.AssignmentsOverdue
+		passed += m.ExamsPassed
+		seconds += m.SecondsSpent
+	}
+	summary := fiber.Map{
+		"members":            len(members),
+		"directReports":      direct,
+		"coursesCompleted":   completed,
+		"coursesOverdue":     overdue,
+		"assignmentsOverdue": assignmentsOverdue,
+		"examsPassed":        passed,
+		"secondsSpent":       seconds,
+		"overdueAfterDays":   h.cfg.TeamOverdueDays,
+	}
+
+	return c.JSON(fiber.Map{"summary": summary, "members": members})
+}
+
+// GetTeamMember returns course and exam detail for one member of the caller's subtree.
+// Users outside the subtree are reported as not found.
+func (h *Handler) GetTeamMember(c *fiber.Ctx) error {
+	username, err := auth.CurrentUsername(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "invalid token")
+	}
+	detail, err := data.GetTeamMemberDetail(username, c.Params("username"), h.cfg.TeamOverdueDays)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fiber.NewError(fiber.StatusNotFound, "team member not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot get team member")
+	}
+	return c.JSON(detail)
+}
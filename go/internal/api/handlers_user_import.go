@@ -0,0 +1,254 @@
+package api
+
+import (
+	"backend/internal/data"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxUserImportBytes = 5 * 1024 * 1024 // 5 MB
+const maxUserImportRows = 2000
+
+// userImportColumns maps accepted header names to the canonical column key.
+var userImportColumns = map[string]string{
+	"name":          "name",
+	"ชื่อ":          "name",
+	"username":      "username",
+	"employee_code": "employee_code",
+	"employee code": "employee_code",
+	"role":          "role",
+	"status":        "status",
+	"password":      "password",
+}
+
+type userImportRowReport struct {
+	Row          int      `json:"row"`
+	Name         string   `json:"name"`
+	Username     string   `json:"username"`
+	EmployeeCode string   `json:"employee_code"`
+	Role         string   `json:"role"`
+	Status       string   `json:"status"`
+	Action       string   `json:"action"` // create | update | invalid
+	Errors       []string `json:"errors"`
+}
+
+// ImportUsers validates an uploaded CSV/XLSX user list and, unless dry_run=false,
+// only reports what would happen. With dry_run=false and no invalid rows, every
+// row is applied in one transaction.
+func (h *Handler) ImportUsers(c *fiber.Ctx) error {
+	dryRun := c.Query("dry_run", "true") != "false"
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "file is required")
+	}
+	if file.Size > maxUserImportBytes {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "file must not exceed 5 MB")
+	}
+	f, err := file.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "cannot read file")
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "cannot read file")
+	}
+
+	sheetRows, err := readSpreadsheetRows(file.Filename, content)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if len(sheetRows) < 2 {
+		return fiber.NewError(fiber.StatusBadRequest, "file must contain a header row and at least one user")
+	}
+	if len(sheetRows)-1 > maxUserImportRows {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("too many rows (max %d)", maxUserImportRows))
+	}
+
+	columns := map[string]int{}
+	for i, header := range sheetRows[0] {
+		if key, ok := userImportColumns[strings.ToLower(strings.TrimSpace(header))]; ok {
+			columns[key] = i
+		}
+	}
+	for _, required := range []string{"name", "username"} {
+		if _, ok := columns[required]; !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "missing required column: "+required)
+		}
+	}
+
+	reports, rows, err := h.validateUserImport(sheetRows[1:], columns)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot validate import")
+	}
+
+	summary := fiber.Map{"total": len(reports), "create": 0, "update": 0, "invalid": 0}
+	for _, r := range reports {
+		summary[r.Action] = summary[r.Action].(int) + 1
+	}
+
+	if dryRun {
+		return c.JSON(fiber.Map{"dry_run": true, "summary": summary, "rows": reports})
+	}
+	if summary["invalid"].(int) > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "import has invalid rows; nothing was applied",
+			"dry_run": false,
+			"summary": summary,
+			"rows":    reports,
+		})
+	}
+
+	defaultPassword, err := data.GetDefaultResetPassword(h.cfg.DefaultResetPassword)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot load default reset password")
+	}
+	created, updated, err := data.ApplyUserImport(rows, defaultPassword)
+	if err != nil {
+		if data.IsDuplicateKey(err) {
+			return fiber.NewError(fiber.StatusConflict, "username already exists")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "cannot import users")
+	}
+	return c.JSON(fiber.Map{
+		"message": "import success",
+		"dry_run": false,
+		"summary": summary,
+		"created": created,
+		"updated": updated,
+		"rows":    reports,
+	})
+}
+
+// validateUserImport applies the same rules as CreateUserByAdmin/UpdateUserByAdmin to every row.
+func (h *Handler) validateUserImport(sheetRows [][]string, columns map[string]int) ([]userImportRowReport, []data.UserImportRow, error) {
+	cell := func(row []string, key string) string {
+		idx, ok := columns[key]
+		if !ok || idx >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[idx])
+	}
+
+	roles, err := data.ListRoles()
+	if err != nil {
+		return nil, nil, err
+	}
+	validRoles := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		validRoles[r.Code] = true
+	}
+
+	usernames := make([]string, 0, len(sheetRows))
+	for _, row := range sheetRows {
+		if u := data.NormalizeUsername(cell(row, "username")); u != "" {
+			usernames = append(usernames, u)
+		}
+	}
+	existing, err := data.FindUsersByUsernames(usernames)
+	if err != nil {
+		return nil, nil, err
+	}
+	codes := make([]string, 0, len(sheetRows))
+	for _, row := range sheetRows {
+		if code := data.NormalizeEmployeeCode(cell(row, "employee_code")); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	codeOwners, err := data.FindEmployeeCodeOwners(codes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seenUsernames := map[string]int{}
+	seenEmployeeCodes := map[string]int{}
+	reports := make([]userImportRowReport, 0, len(sheetRows))
+	rows := make([]data.UserImportRow, 0, len(sheetRows))
+	for i, row := range sheetRows {
+		rowNumber := i + 2 // 1-based, after the header row
+		r := userImportRowReport{
+			Row:          rowNumber,
+			Name:         cell(row, "name"),
+			Username:     data.NormalizeUsername(cell(row, "username")),
+			EmployeeCode: data.NormalizeEmployeeCode(cell(row, "employee_code")),
+			Role:         data.NormalizeRoleName(cell(row, "role")),
+			Status:       strings.ToLower(cell(row, "status")),
+			Errors:       []string{},
+		}
+		password := cell(row, "password")
+		current, exists := existing[r.Username]
+
+		if r.Name == "" {
+			r.Errors = append(r.Errors, "name is required")
+		}
+		if r.Username == "" {
+			r.Errors = append(r.Errors, "username is required")
+		} else if first, dup := seenUsernames[r.Username]; dup {
+			r.Errors = append(r.Errors, fmt.Sprintf("duplicate username (row %d)", first))
+		} else {
+			seenUsernames[r.Username] = rowNumber
+		}
+		if r.EmployeeCode != "" {
+			if !data.IsValidEmployeeCode(r.EmployeeCode) {
+				r.Errors = append(r.Errors, "employee_code must be in format XXXX-XX-XXXX")
+			} else if first, dup := seenEmployeeCodes[r.EmployeeCode]; dup {
+				r.Errors = append(r.Errors, fmt.Sprintf("duplicate employee_code (row %d)", first))
+			} else if owner, taken := codeOwners[r.EmployeeCode]; taken && (!exists || data.NormalizeEmployeeCode(current.EmployeeCode) != r.EmployeeCode) {
+				r.Errors = append(r.Errors, fmt.Sprintf("employee_code already belongs to %s", owner))
+			} else {
+				seenEmployeeCodes[r.EmployeeCode] = rowNumber
+			}
+		} else if exists {
+			r.EmployeeCode = current.EmployeeCode
+		}
+		if r.Role == "" {
+			r.Role = "user"
+			if exists {
+				r.Role = current.Role
+			}
+		}
+		if r.Role == "admin" {
+			r.Errors = append(r.Errors, "cannot assign admin role")
+		} else if !validRoles[r.Role] {
+			r.Errors = append(r.Errors, "role is invalid")
+		}
+		if r.Status == "" {
+			r.Status = "active"
+			if exists {
+				r.Status = current.Status
+			}
+		}
+		if r.Status != "active" && r.Status != "inactive" {
+			r.Errors = append(r.Errors, "status is invalid")
+		}
+		if password != "" && len(password) < 8 {
+			r.Errors = append(r.Errors, "password must be at least 8 characters")
+		}
+		if exists && strings.ToLower(strings.TrimSpace(current.Role)) == "admin" {
+			r.Errors = append(r.Errors, "cannot modify admin accounts")
+		}
+
+		switch {
+		case len(r.Errors) > 0:
+			r.Action = "invalid"
+		case exists:
+			r.Action = "update"
+		default:
+			r.Action = "create"
+		}
+		reports = append(reports, r)
+		rows = append(rows, data.UserImportRow{
+			Name:         r.Name,
+			Username:     r.Username,
+			EmployeeCode: r.EmployeeCode,
+			Role:         r.Role,
+			Status:       r.Status,
+			Password:     password,
+		})
+	}
+	return reports, rows, nil
+}
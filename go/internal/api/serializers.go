@@ -29,13 +29,15 @@ func toAuthUserPayload(user data.AuthUser) (fiber.Map, error) {
 		return nil, err
 	}
 	return fiber.Map{
-		"id":            user.ID,
-		"name":          user.Name,
-		"username":      user.Username,
-		"employee_code": user.EmployeeCode,
-		"role":          user.Role,
-		"status":        user.Status,
-		"created_at":    user.CreatedAt,
-		"permissions":   permissions,
+		"id":             user.ID,
+		"name":           user.Name,
+		"username":       user.Username,
+		"employee_code":  user.EmployeeCode,
+		"role":           user.Role,
+		"status":         user.Status,
+		"created_at":     user.CreatedAt,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"permissions":    permissions,
 	}, nil
 }
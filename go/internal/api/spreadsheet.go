@@ -0,0 +1,209 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// readSpreadsheetRows returns the rows of a CSV file or of the first worksheet of an
+// XLSX workbook. Cells are returned as their displayed text; trailing empty rows are dropped.
+func readSpreadsheetRows(filename string, content []byte) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return readCSVRows(content)
+	case ".xlsx":
+		return readXLSXRows(content)
+	default:
+		return nil, fmt.Errorf("unsupported file type; allowed: csv, xlsx")
+	}
+}
+
+func readCSVRows(content []byte) ([][]string, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")) // Excel writes a UTF-8 BOM
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	return trimEmptyRows(rows), nil
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSXRows(content []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	sheetFile, ok := files[firstSheetPath(files)]
+	if !ok {
+		return nil, fmt.Errorf("invalid xlsx: worksheet not found")
+	}
+	var sheet xlsxSheet
+	if err := decodeZipXML(sheetFile, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		values := []string{}
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				if col, err = xlsxColumnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			switch cell.Type {
+			case "s":
+				var idx int
+				if _, err := fmt.Sscanf(cell.Value, "%d", &idx); err == nil && idx >= 0 && idx < len(shared.Items) {
+					values[col] = shared.Items[idx].String()
+				}
+			case "inlineStr":
+				values[col] = cell.Inline.String()
+			default:
+				values[col] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return trimEmptyRows(rows), nil
+}
+
+// firstSheetPath resolves the first sheet listed in the workbook, falling back to sheet1.xml.
+func firstSheetPath(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+	wbFile, ok := files["xl/workbook.xml"]
+	relFile, relOK := files["xl/_rels/workbook.xml.rels"]
+	if !ok || !relOK {
+		return fallback
+	}
+	var wb xlsxWorkbook
+	var rels xlsxRelationships
+	if decodeZipXML(wbFile, &wb) != nil || decodeZipXML(relFile, &rels) != nil || len(wb.Sheets) == 0 {
+		return fallback
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != wb.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+func decodeZipXML(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("invalid xlsx: %w", err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, 50*1024*1024)).Decode(v); err != nil {
+		return fmt.Errorf("invalid xlsx: %w", err)
+	}
+	return nil
+}
+
+// xlsxMaxColumn is the zero-based index of XFD, the last column Excel allows.
+const xlsxMaxColumn = 16383
+
+// xlsxColumnIndex converts a cell reference such as "C12" to a zero-based column index.
+func xlsxColumnIndex(ref string) (int, error) {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col-1 > xlsxMaxColumn {
+			return 0, fmt.Errorf("invalid xlsx: cell %q is past column XFD", ref)
+		}
+	}
+	if col == 0 {
+		return 0, fmt.Errorf("invalid xlsx: cell %q has no column", ref)
+	}
+	return col - 1, nil
+}
+
+func trimEmptyRows(rows [][]string) [][]string {
+	for len(rows) > 0 {
+		last := rows[len(rows)-1]
+		if strings.TrimSpace(strings.Join(last, "")) != "" {
+			break
+		}
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
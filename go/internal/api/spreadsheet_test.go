@@ -0,0 +1,88 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestXLSXColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{ref: "A1", want: 0},
+		{ref: "C12", want: 2},
+		{ref: "Z3", want: 25},
+		{ref: "AA1", want: 26},
+		{ref: "XFD1", want: 16383},
+		{ref: "XFE1", wantErr: true},
+		{ref: "ZZZZZZZZ1", wantErr: true},
+		{ref: strings.Repeat("Z", 64) + "1", wantErr: true},
+		{ref: "12", wantErr: true},
+		{ref: "a1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := xlsxColumnIndex(tt.ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("xlsxColumnIndex(%q) = %d, want error", tt.ref, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("xlsxColumnIndex(%q) = %d, %v, want %d", tt.ref, got, err, tt.want)
+		}
+	}
+}
+
+func TestReadXLSXRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		cells   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "refs", cells: `<c r="A1" t="inlineStr"><is><t>name</t></is></c><c r="C1"><v>7</v></c>`, want: []string{"name", "", "7"}},
+		{name: "no refs", cells: `<c><v>a</v></c><c><v>b</v></c>`, want: []string{"a", "b"}},
+		{name: "no column", cells: `<c r="12"><v>x</v></c>`, wantErr: true},
+		{name: "past XFD", cells: `<c r="ZZZZZZZZ1"><v>x</v></c>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readXLSXRows(xlsxWithRow(t, tt.cells))
+			if tt.wantErr {
+				if err == nil || !strings.HasPrefix(err.Error(), "invalid xlsx") {
+					t.Fatalf("readXLSXRows() error = %v, want invalid xlsx", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readXLSXRows() error = %v", err)
+			}
+			if len(rows) != 1 || strings.Join(rows[0], "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("readXLSXRows() = %q, want [%q]", rows, tt.want)
+			}
+		})
+	}
+}
+
+// xlsxWithRow builds a minimal workbook whose first sheet has one row of cells.
+func xlsxWithRow(t *testing.T, cells string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(`<worksheet><sheetData><row>` + cells + `</row></sheetData></worksheet>`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,11 @@
+package api
+
+type assignmentRequest struct {
+	ItemType       string   `json:"itemType"`
+	ItemID         string   `json:"itemId"`
+	DueAt          string   `json:"dueAt"` // YYYY-MM-DD (end of day) or RFC 3339
+	RecurrenceDays int      `json:"recurrenceDays"`
+	Note           string   `json:"note"`
+	Usernames      []string `json:"usernames"`
+	Roles          []string `json:"roles"`
+}
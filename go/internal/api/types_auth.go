@@ -4,7 +4,10 @@ type registerRequest struct {
 	Name         string `json:"name"`
 	Username     string `json:"username"`
 	EmployeeCode string `json:"employee_code"`
+	Email        string `json:"email"`
 	Password     string `json:"password"`
+	// InviteToken is the token from an invite link; it sets the role and groups.
+	InviteToken string `json:"invite_token"`
 }
 
 type loginRequest struct {
@@ -24,6 +27,24 @@ type updateProfileNameRequest struct {
 	Name string `json:"name"`
 }
 
+type updateEmailRequest struct {
+	Email string `json:"email"`
+}
+
+type tokenRequest struct {
+	Token string `json:"token"`
+}
+
+type forgotPasswordRequest struct {
+	// Login is a username or an email address.
+	Login string `json:"login"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
 type changePasswordRequest struct {
 	CurrentPassword string `json:"current_password"`
 	NewPassword     string `json:"new_password"`
@@ -33,6 +54,7 @@ type adminCreateUserRequest struct {
 	Name         string `json:"name"`
 	Username     string `json:"username"`
 	EmployeeCode string `json:"employee_code"`
+	Email        string `json:"email"`
 	Password     string `json:"password"`
 	Role         string `json:"role"`
 	Status       string `json:"status"`
@@ -43,6 +65,10 @@ type adminUpdateUserRequest struct {
 	EmployeeCode string `json:"employee_code"`
 	Role         string `json:"role"`
 	Status       string `json:"status"`
+	// ManagerUsername sets the line manager when present; "" clears it.
+	ManagerUsername *string `json:"manager_username"`
+	// Email changes the address when present; "" removes it.
+	Email *string `json:"email"`
 }
 
 type adminResetPasswordRequest struct {
@@ -65,3 +91,29 @@ type updateRoleRequest struct {
 type updateRolePermissionsRequest struct {
 	Permissions []string `json:"permissions"`
 }
+
+type registrationSettingsRequest struct {
+	Mode           string   `json:"mode"`
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
+type createInviteRequest struct {
+	Label    string  `json:"label"`
+	Role     string  `json:"role"`
+	GroupIDs []int64 `json:"group_ids"`
+	// MaxUses defaults to 1 and ExpiresInDays to 7 when omitted.
+	MaxUses       int `json:"max_uses"`
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+type createGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type setGroupMembersRequest struct {
+	Usernames []string `json:"usernames"`
+}
+
+type impersonateRequest struct {
+	Reason string `json:"reason"`
+}
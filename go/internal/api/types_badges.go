@@ -0,0 +1,15 @@
+package api
+
+type badgeRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Criteria    string `json:"criteria"`
+	Threshold   int    `json:"threshold"`
+	Skill       string `json:"skill"`  // skill_points only
+	ExamID      string `json:"examId"` // exam_score only; empty = any exam
+	Active      *bool  `json:"active"` // defaults to true
+}
+
+type badgeImageRequest struct {
+	DataURL string `json:"data_url"`
+}
@@ -13,6 +13,9 @@ type courseRequest struct {
 	SkillPoints             int               `json:"skillPoints"`
 	SubtopicCompletionScore int               `json:"subtopicCompletionScore"`
 	CourseCompletionScore   int               `json:"courseCompletionScore"`
+	ValidityDays            int               `json:"validityDays"`
+	EnrollmentMode          string            `json:"enrollmentMode"` // empty keeps the current mode
+	Capacity                *int              `json:"capacity"`       // omitted keeps the current capacity
 	SkillRewards            []skillRewardBody `json:"skillRewards"`
 }
 
@@ -31,8 +34,12 @@ type subtopicAnswerRequest struct {
 	IsCorrect   bool   `json:"isCorrect"`
 }
 
-type subtopicTimeRequest struct {
-	Seconds int `json:"seconds"`
+type readingHeartbeatRequest struct {
+	Idle bool `json:"idle"`
+}
+
+type noteRequest struct {
+	Body string `json:"body"`
 }
 
 type courseImageRequest struct {
@@ -49,6 +56,62 @@ type qnaReplyRequest struct {
 	Reply string `json:"reply"`
 }
 
+type qnaAcceptRequest struct {
+	ReplyID int64 `json:"replyId"` // 0 clears the accepted answer
+}
+
+type qnaStatusRequest struct {
+	Status string `json:"status"`
+}
+
 type avatarRequest struct {
 	DataURL string `json:"data_url"`
 }
+
+type courseReviewRequest struct {
+	Rating int    `json:"rating"`
+	Review string `json:"review"`
+}
+
+type reviewReplyRequest struct {
+	Reply string `json:"reply"`
+}
+
+type bulkEnrollRequest struct {
+	Usernames []string `json:"usernames"`
+	Invite    bool     `json:"invite"` // invite instead of enrolling directly
+}
+
+type cohortRequest struct {
+	Name               string `json:"name"`
+	InstructorUsername string `json:"instructorUsername"`
+	StartsAt           string `json:"startsAt"` // YYYY-MM-DD (start of day) or RFC 3339
+	EndsAt             string `json:"endsAt"`   // YYYY-MM-DD (end of day) or RFC 3339
+	UnlockIntervalDays int    `json:"unlockIntervalDays"`
+}
+
+type cohortMembersRequest struct {
+	Usernames []string `json:"usernames"`
+}
+
+type liveSessionRequest struct {
+	CohortID           *int64 `json:"cohortId"` // omit for every learner of the course
+	Title              string `json:"title"`
+	Description        string `json:"description"`
+	StartsAt           string `json:"startsAt"` // RFC 3339
+	EndsAt             string `json:"endsAt"`   // RFC 3339
+	Location           string `json:"location"`
+	MeetingURL         string `json:"meetingUrl"`
+	Capacity           int    `json:"capacity"` // 0 = unlimited
+	Required           bool   `json:"required"` // attendance counts toward course completion
+	InstructorUsername string `json:"instructorUsername"`
+}
+
+type attendanceRequest struct {
+	Usernames []string `json:"usernames"`
+	Attended  bool     `json:"attended"`
+}
+
+type checkinRequest struct {
+	Code string `json:"code"`
+}
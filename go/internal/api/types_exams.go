@@ -13,10 +13,19 @@ type examRequest struct {
 	NumberOfQuestions int               `json:"numberOfQuestions"`
 	DefaultTime       int               `json:"defaultTime"`
 	MaxAttempts       int               `json:"maxAttempts"`
+	ValidityDays      int               `json:"validityDays"`
+	CompletionScore   int               `json:"completionScore"`
+	SkillRewards      []examRewardBody  `json:"skillRewards"`
 	DomainPercentages map[string]int    `json:"domainPercentages"`
 	Questions         []examQuestionReq `json:"questions"`
 }
 
+type examRewardBody struct {
+	Skill  string `json:"skill"`
+	Domain string `json:"domain"`
+	Points int    `json:"points"`
+}
+
 type examQuestionReq struct {
 	ID           string   `json:"id"`
 	Domain       string   `json:"domain"`
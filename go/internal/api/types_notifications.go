@@ -0,0 +1,6 @@
+package api
+
+type notificationPreferencesRequest struct {
+	Locale string          `json:"locale"`
+	Email  map[string]bool `json:"email"`
+}
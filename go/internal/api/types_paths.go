@@ -0,0 +1,17 @@
+package api
+
+type learningPathRequest struct {
+	ID              string            `json:"id"`
+	Title           string            `json:"title"`
+	Description     string            `json:"description"`
+	Image           string            `json:"image"`
+	Status          string            `json:"status"`
+	CompletionScore int               `json:"completionScore"`
+	Steps           []pathStepBody    `json:"steps"`
+	SkillRewards    []skillRewardBody `json:"skillRewards"`
+}
+
+type pathStepBody struct {
+	ItemType string `json:"itemType"`
+	ItemID   string `json:"itemId"`
+}
@@ -0,0 +1,56 @@
+package api
+
+import "encoding/json"
+
+// SCIM 2.0 (RFC 7643/7644) request and resource shapes. Only the attributes
+// that map onto LMS users and roles are modelled; anything else is ignored.
+
+const (
+	scimUserSchema       = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimEnterpriseSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	scimGroupSchema      = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListSchema       = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema      = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimContentType      = "application/scim+json"
+)
+
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type scimMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimEnterpriseUser struct {
+	EmployeeNumber string `json:"employeeNumber"`
+}
+
+type scimUserRequest struct {
+	UserName    string              `json:"userName"`
+	Name        *scimName           `json:"name"`
+	DisplayName string              `json:"displayName"`
+	Active      *bool               `json:"active"`
+	Password    string              `json:"password"`
+	Roles       []scimMultiValue    `json:"roles"`
+	Enterprise  *scimEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+type scimGroupRequest struct {
+	DisplayName string           `json:"displayName"`
+	Members     []scimMultiValue `json:"members"`
+}
+
+type scimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type scimPatchRequest struct {
+	Operations []scimPatchOperation `json:"Operations"`
+}
@@ -0,0 +1,7 @@
+package api
+
+type scoreAdjustmentRequest struct {
+	Skill  string `json:"skill"` // empty adjusts the overall points
+	Points int    `json:"points"`
+	Reason string `json:"reason"`
+}
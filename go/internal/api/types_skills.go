@@ -0,0 +1,22 @@
+package api
+
+type skillRequest struct {
+	Name        string   `json:"name"`
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases"`
+}
+
+type skillMergeRequest struct {
+	SourceID int64 `json:"sourceId"`
+}
+
+type skillLevelBody struct {
+	Name      string `json:"name"`
+	MinPoints int    `json:"minPoints"`
+}
+
+type competencyBody struct {
+	SkillID int64 `json:"skillId"`
+	Level   int   `json:"level"`
+}
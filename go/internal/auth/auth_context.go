@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
@@ -68,6 +69,69 @@ func CurrentUsername(c *fiber.Ctx) (string, error) {
 	return username, nil
 }
 
+// TokenExpiry returns when the caller's access token expires.
+func TokenExpiry(c *fiber.Ctx) (time.Time, error) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return time.Time{}, errors.New("invalid token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return time.Time{}, errors.New("invalid token claims")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}, errors.New("missing exp claim")
+	}
+	return time.Unix(int64(exp), 0), nil
+}
+
+// ImpersonatorUsername returns the admin behind an impersonation session, or "" for a normal session.
+func ImpersonatorUsername(c *fiber.Ctx) string {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	impersonator, _ := claims["impersonator"].(string)
+	return strings.TrimSpace(impersonator)
+}
+
+// ImpersonationID returns the audit log id carried by an impersonation session, or 0.
+func ImpersonationID(c *fiber.Ctx) int64 {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return 0
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0
+	}
+	raw, _ := claims["impersonation_id"].(string)
+	id, _ := strconv.ParseInt(raw, 10, 64)
+	return id
+}
+
+func IsImpersonating(c *fiber.Ctx) bool {
+	return ImpersonatorUsername(c) != ""
+}
+
+// RejectImpersonatedWrites makes impersonation sessions read-only by refusing
+// every state-changing request.
+func RejectImpersonatedWrites(c *fiber.Ctx) error {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+	if IsImpersonating(c) {
+		return fiber.NewError(fiber.StatusForbidden, "read-only impersonation session")
+	}
+	return c.Next()
+}
+
 func IsAdminContext(c *fiber.Ctx) bool {
 	return isAdminRole(currentUserRole(c))
 }
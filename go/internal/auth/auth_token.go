@@ -14,15 +14,19 @@ import (
 )
 
 func GenerateAccessToken(user data.AuthUserRecord, jwtSecret string, accessTTLMinutes int, permissions []string) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["sub"] = strconv.FormatInt(user.ID, 10)
-	claims["username"] = user.Username
-	claims["role"] = user.Role
-	claims["permissions"] = permissions
-	claims["exp"] = time.Now().Add(time.Duration(accessTTLMinutes) * time.Minute).Unix()
-
-	return token.SignedString([]byte(jwtSecret))
+	claims := accessClaims(user, accessTTLMinutes, permissions)
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+}
+
+// accessClaims are the claims every access token carries for user.
+func accessClaims(user data.AuthUserRecord, ttlMinutes int, permissions []string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":         strconv.FormatInt(user.ID, 10),
+		"username":    user.Username,
+		"role":        user.Role,
+		"permissions": permissions,
+		"exp":         time.Now().Add(time.Duration(ttlMinutes) * time.Minute).Unix(),
+	}
 }
 
 func GenerateRefreshToken() (string, string, error) {
@@ -39,3 +43,23 @@ func HashRefreshToken(rawToken string) string {
 	sum := sha256.Sum256([]byte(rawToken))
 	return hex.EncodeToString(sum[:])
 }
+
+// GenerateImpersonationToken issues a short-lived access token for target that is
+// marked with the impersonating admin and the audit log entry that started it.
+// No refresh token is issued; the admin's own refresh cookie is left untouched.
+func GenerateImpersonationToken(target data.AuthUserRecord, impersonator string, logID int64, jwtSecret string, ttlMinutes int, permissions []string) (string, error) {
+	claims := accessClaims(target, ttlMinutes, permissions)
+	claims["impersonator"] = impersonator
+	claims["impersonation_id"] = strconv.FormatInt(logID, 10)
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+}
+
+// GenerateOneTimeToken returns a random token for emailed links (password reset, email
+// verification) and its hash; only the hash is stored.
+func GenerateOneTimeToken() (string, string, error) {
+	return GenerateRefreshToken()
+}
+
+func HashOneTimeToken(rawToken string) string {
+	return HashRefreshToken(rawToken)
+}
@@ -15,12 +15,16 @@ const (
 	PermissionExamManage  = "exam.manage"
 	PermissionExamViewAll = "exam.view_all"
 
+	PermissionQnAModerate = "qna.moderate"
+
 	PermissionSystemReport      = "system.report.view"
 	PermissionSystemExamHistory = "system.exam_history.view"
 
 	PermissionUserManage            = "management.users.manage"
+	PermissionUserImpersonate       = "management.users.impersonate"
 	PermissionRoleManage            = "management.roles.manage"
 	PermissionManagementExamHistory = "management.exam_history.view"
+	PermissionAssignmentManage      = "management.assignments.manage"
 )
 
 func normalizeRole(role string) string {
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 func LoadConfig() AppConfig {
@@ -27,6 +28,25 @@ func LoadConfig() AppConfig {
 		ExamSeedDir:          getStringEnv("EXAM_SEED_DIR", "../cbt-lms/public/exam"),
 		RateLimitAuth:        getIntEnv("RATE_LIMIT_AUTH", 200),
 		RateLimitPublic:      getIntEnv("RATE_LIMIT_PUBLIC", 1000),
+		RateLimitAccount:     getIntEnv("RATE_LIMIT_ACCOUNT", 5),
+		RateLimitCheckin:     getIntEnv("RATE_LIMIT_CHECKIN", 5),
+		ImpersonationTTL:     getIntEnv("IMPERSONATION_MINUTES", 15),
+		SCIMToken:            os.Getenv("SCIM_BEARER_TOKEN"),
+		TeamOverdueDays:      getIntEnv("TEAM_OVERDUE_DAYS", 30),
+		CertExpiringSoonDays: getIntEnv("CERT_EXPIRING_SOON_DAYS", 30),
+		AssignmentRemindDays: getIntEnv("ASSIGNMENT_REMIND_DAYS", 3),
+		LeaderboardMinutes:   getIntEnv("LEADERBOARD_REFRESH_MINUTES", 10),
+		AppBaseURL:           strings.TrimRight(getStringEnv("APP_BASE_URL", "http://localhost:5173"), "/"),
+		SMTPHost:             os.Getenv("SMTP_HOST"),
+		SMTPPort:             getIntEnv("SMTP_PORT", 587),
+		SMTPUsername:         os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:         os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:             getStringEnv("SMTP_FROM", "CBT LMS <no-reply@localhost>"),
+		PasswordResetMinutes: getIntEnv("PASSWORD_RESET_MINUTES", 30),
+		EmailVerifyHours:     getIntEnv("EMAIL_VERIFY_HOURS", 48),
+		HeartbeatSeconds:     getIntEnv("READING_HEARTBEAT_SECONDS", 30),
+		ReadingIdleSeconds:   getIntEnv("READING_IDLE_SECONDS", 300),
+		CheckinCodeSeconds:   getIntEnv("CHECKIN_CODE_SECONDS", 60),
 	}
 }
 
@@ -14,4 +14,23 @@ type AppConfig struct {
 	ExamSeedDir          string
 	RateLimitAuth        int
 	RateLimitPublic      int
+	RateLimitAccount     int
+	RateLimitCheckin     int
+	ImpersonationTTL     int
+	SCIMToken            string
+	TeamOverdueDays      int
+	CertExpiringSoonDays int
+	AssignmentRemindDays int
+	LeaderboardMinutes   int
+	AppBaseURL           string
+	SMTPHost             string
+	SMTPPort             int
+	SMTPUsername         string
+	SMTPPassword         string
+	SMTPFrom             string
+	PasswordResetMinutes int
+	EmailVerifyHours     int
+	HeartbeatSeconds     int
+	ReadingIdleSeconds   int
+	CheckinCodeSeconds   int
 }
@@ -0,0 +1,281 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeEmailVerify   = "email_verify"
+)
+
+var (
+	ErrEmailTaken   = errors.New("email is already in use")
+	ErrInvalidToken = errors.New("token is invalid or expired")
+)
+
+type UserEmail struct {
+	Email      string     `json:"email"`
+	VerifiedAt *time.Time `json:"email_verified_at"`
+}
+
+func EnsureAccountEmailSchema() error {
+	_, err := db.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS email TEXT NOT NULL DEFAULT '';
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+		-- Only a verified address is unique, so nobody can hold an address they do not own.
+		DROP INDEX IF EXISTS ux_users_email;
+		CREATE UNIQUE INDEX IF NOT EXISTS ux_users_verified_email ON users(email)
+			WHERE email <> '' AND email_verified_at IS NOT NULL;
+
+		-- Usernames that are email addresses become the (unverified) email.
+		UPDATE users u SET email = u.username
+		WHERE u.email = '' AND u.username ~ '^[^@\s]+@[^@\s]+\.[^@\s]+$'
+		  AND NOT EXISTS (SELECT 1 FROM users o WHERE o.email = u.username);
+
+		CREATE TABLE IF NOT EXISTS account_tokens (
+			id         BIGSERIAL    PRIMARY KEY,
+			user_id    BIGINT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			purpose    TEXT         NOT NULL CHECK (purpose IN ('password_reset','email_verify')),
+			token_hash TEXT         NOT NULL UNIQUE,
+			email      TEXT         NOT NULL DEFAULT '',
+			expires_at TIMESTAMPTZ  NOT NULL,
+			used_at    TIMESTAMPTZ,
+			created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS ix_account_tokens_user ON account_tokens(user_id, purpose);
+		-- The hash is set when the mail carrying the token is delivered.
+		ALTER TABLE account_tokens ALTER COLUMN token_hash DROP NOT NULL;
+	`)
+	return err
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsValidEmail accepts a bare address such as name@example.com (no display name).
+func IsValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndex(email, "@"):], ".")
+}
+
+func GetUserEmail(username string) (UserEmail, error) {
+	var e UserEmail
+	err := db.QueryRow(`SELECT email, email_verified_at FROM users WHERE username = $1`,
+		NormalizeUsername(username)).Scan(&e.Email, &e.VerifiedAt)
+	return e, err
+}
+
+// EmailInUse reports whether an account has already verified the address. Unverified
+// claims do not count: several accounts may wait on the same address until one of
+// them verifies it.
+func EmailInUse(email string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND email <> '' AND email_verified_at IS NOT NULL)`,
+		NormalizeEmail(email)).Scan(&exists)
+	return exists, err
+}
+
+// SetUserEmail changes the user's email. A changed address must be verified again;
+// setting the same address is a no-op. It returns whether the address changed, and
+// ErrEmailTaken when another account has verified the address.
+func SetUserEmail(username, email string) (bool, error) {
+	email = NormalizeEmail(email)
+	username = NormalizeUsername(username)
+	if email != "" {
+		var taken bool
+		if err := db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND email_verified_at IS NOT NULL AND username <> $2)`,
+			email, username).Scan(&taken); err != nil {
+			return false, err
+		}
+		if taken {
+			return false, ErrEmailTaken
+		}
+	}
+	result, err := db.Exec(`
+		UPDATE users SET email = $2, email_verified_at = NULL
+		WHERE username = $1 AND email <> $2`,
+		username, email)
+	if err != nil {
+		if IsDuplicateKey(err) {
+			return false, ErrEmailTaken
+		}
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// FindUserByLogin looks a user up by username or, failing that, by email. An
+// address verified by an account belongs to it; otherwise the latest account
+// claiming the address is returned.
+func FindUserByLogin(login string) (AuthUserRecord, error) {
+	user, err := FindUserByUsername(login)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return user, err
+	}
+	var username string
+	if err := db.QueryRow(`
+		SELECT username FROM users WHERE email = $1 AND email <> ''
+		ORDER BY email_verified_at IS NULL, created_at DESC, id DESC
+		LIMIT 1`,
+		NormalizeEmail(login)).Scan(&username); err != nil {
+		return AuthUserRecord{}, err
+	}
+	return FindUserByUsername(username)
+}
+
+// accountTokenPaths are the frontend pages that redeem each token purpose.
+var accountTokenPaths = map[string]string{
+	TokenPurposePasswordReset: "/reset-password",
+	TokenPurposeEmailVerify:   "/verify-email",
+}
+
+// CreateAccountToken records a single-use token and returns its id. Earlier unused
+// tokens of the same purpose are invalidated, so only the latest link works. The
+// token has no hash yet and cannot be redeemed until MintAccountToken sets one when
+// its mail is delivered, so the outbox only ever holds the id.
+func CreateAccountToken(userID int64, purpose, email string, ttl time.Duration) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE account_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose); err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := tx.QueryRow(`
+		INSERT INTO account_tokens (user_id, purpose, email, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`,
+		userID, purpose, email, time.Now().Add(ttl)).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// MintAccountToken sets the hash of the raw token about to be mailed for the token
+// with this id and returns the page path that redeems it. Each delivery attempt mints
+// again, so a link from an earlier attempt stops working. ErrInvalidToken means the
+// token was used, replaced or has expired and the mail should not be sent.
+func MintAccountToken(id int64, tokenHash string) (string, error) {
+	var purpose string
+	err := db.QueryRow(`
+		UPDATE account_tokens SET token_hash = $2
+		WHERE id = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING purpose`, id, tokenHash).Scan(&purpose)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidToken
+	}
+	return accountTokenPaths[purpose], err
+}
+
+// RecentAccountTokenExists reports whether a token of this purpose was issued to the
+// user within the given window; used to throttle repeated requests per account.
+func RecentAccountTokenExists(userID int64, purpose string, within time.Duration) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM account_tokens
+			WHERE user_id = $1 AND purpose = $2 AND created_at > $3)`,
+		userID, purpose, time.Now().Add(-within)).Scan(&exists)
+	return exists, err
+}
+
+// consumeAccountToken marks a valid token as used and returns its user and email.
+func consumeAccountToken(tx *sql.Tx, purpose, tokenHash string) (userID int64, email string, err error) {
+	err = tx.QueryRow(`
+		UPDATE account_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, email`, tokenHash, purpose).Scan(&userID, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", ErrInvalidToken
+	}
+	return userID, email, err
+}
+
+// VerifyEmailToken redeems an email verification token. The token only verifies the
+// address it was issued for, so it is void once the user changes their email. An
+// account waiting for activation (domain-mode registration) becomes active. Other
+// accounts' unverified claims on the address are dropped; ErrEmailTaken means
+// another account verified it first.
+func VerifyEmailToken(tokenHash string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, email, err := consumeAccountToken(tx, TokenPurposeEmailVerify, tokenHash)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(`
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()),
+		    status = CASE WHEN activation_pending THEN 'active' ELSE status END,
+		    activation_pending = FALSE
+		WHERE id = $1 AND email = $2`, userID, email)
+	if err != nil {
+		if IsDuplicateKey(err) {
+			return ErrEmailTaken
+		}
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInvalidToken
+	}
+	if _, err := tx.Exec(`
+		UPDATE users SET email = ''
+		WHERE email = $2 AND id <> $1 AND email_verified_at IS NULL`, userID, email); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ResetPasswordWithToken redeems a password reset token, sets the new password and
+// revokes every refresh token of the user so all sessions must sign in again.
+func ResetPasswordWithToken(tokenHash, newPassword string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, _, err := consumeAccountToken(tx, TokenPurposePasswordReset, tokenHash)
+	if err != nil {
+		return err
+	}
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE users SET password_hash = $2 WHERE id = $1`, []any{userID, string(hashed)}},
+		{`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, []any{userID}},
+		{`UPDATE account_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = 'password_reset' AND used_at IS NULL`, []any{userID}},
+	}
+	for _, s := range statements {
+		if _, err := tx.Exec(s.query, s.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -227,7 +227,9 @@ func getTopEnrollment() ([]EnrollmentStat, error) {
 	return result, rows.Err()
 }
 
-func GetCourseLearners(courseID string) ([]CourseLearnerStatus, error) {
+// GetCourseLearners lists active users with their status in a course. A non-zero
+// cohortID narrows the list to that cohort's members.
+func GetCourseLearners(courseID string, cohortID int64) ([]CourseLearnerStatus, error) {
 	rows, err := db.Query(`
 		SELECT
 			u.username,
@@ -246,13 +248,14 @@ func GetCourseLearners(courseID string) ([]CourseLearnerStatus, error) {
 			GROUP BY username
 		) a ON a.username = u.username
 		WHERE u.status = 'active'
+		  AND ($2::bigint = 0 OR u.username IN (SELECT username FROM course_cohort_members WHERE cohort_id = $2))
 		ORDER BY
 			CASE
 				WHEN e.completed_at IS NOT NULL THEN 0
 				WHEN e.enrolled_at  IS NOT NULL THEN 1
 				ELSE 2
 			END,
-			u.name`, courseID)
+			u.name`, courseID, cohortID)
 	if err != nil {
 		return nil, err
 	}
@@ -13,7 +13,7 @@ type CourseInstructorStats struct {
 	NotStarted     int    `json:"notStarted"`
 	AvgScore       int    `json:"avgScore"`
 	QnaTotal       int    `json:"qnaTotal"`
-	QnaUnanswered  int    `json:"qnaUnanswered"`
+	QnaUnresolved  int    `json:"qnaUnresolved"`
 }
 
 type SubtopicTimeStat struct {
@@ -23,7 +23,7 @@ type SubtopicTimeStat struct {
 	Learners   int     `json:"learners"`
 }
 
-type UnansweredQnA struct {
+type UnresolvedQnA struct {
 	ID         int64     `json:"id"`
 	SubtopicID string    `json:"subtopicId"`
 	Question   string    `json:"question"`
@@ -33,7 +33,7 @@ type UnansweredQnA struct {
 
 type CourseDetailAnalytics struct {
 	SubtopicTime  []SubtopicTimeStat `json:"subtopicTime"`
-	UnansweredQnA []UnansweredQnA    `json:"unansweredQna"`
+	UnresolvedQnA []UnresolvedQnA    `json:"unresolvedQna"`
 }
 
 // ── Queries ──────────────────────────────────────────────────────────────────
@@ -57,12 +57,11 @@ func GetAllCourseStats(ownerUsername string) ([]CourseInstructorStats, error) {
 			COUNT(DISTINCT e.username)                                                    AS learners,
 			COUNT(DISTINCT CASE WHEN e.completed_at IS NOT NULL THEN e.username END)      AS completed,
 			COUNT(DISTINCT CASE WHEN e.completed_at IS NULL AND e.username IS NOT NULL THEN e.username END) AS in_progress,
-			COALESCE((SELECT COUNT(*) FROM qna_questions WHERE course_id = c.id), 0)      AS qna_total,
+			COALESCE((SELECT COUNT(*) FROM qna_questions WHERE course_id = c.id AND hidden_at IS NULL), 0) AS qna_total,
 			COALESCE((
 				SELECT COUNT(*) FROM qna_questions q2
-				WHERE q2.course_id = c.id
-				  AND NOT EXISTS (SELECT 1 FROM qna_replies r WHERE r.question_id = q2.id)
-			), 0) AS qna_unanswered
+				WHERE q2.course_id = c.id AND q2.hidden_at IS NULL AND q2.status = 'open'
+			), 0) AS qna_unresolved
 		FROM courses c
 		LEFT JOIN user_course_enrollments e ON e.course_id = c.id
 	`
@@ -82,7 +81,7 @@ func GetAllCourseStats(ownerUsername string) ([]CourseInstructorStats, error) {
 	var result []CourseInstructorStats
 	for rows.Next() {
 		var s CourseInstructorStats
-		if err := rows.Scan(&s.ID, &s.Title, &s.Learners, &s.Completed, &s.InProgress, &s.QnaTotal, &s.QnaUnanswered); err != nil {
+		if err := rows.Scan(&s.ID, &s.Title, &s.Learners, &s.Completed, &s.InProgress, &s.QnaTotal, &s.QnaUnresolved); err != nil {
 			continue
 		}
 		s.NotStarted = totalLearners - s.Learners
@@ -103,8 +102,9 @@ func GetAllCourseStats(ownerUsername string) ([]CourseInstructorStats, error) {
 	return result, rows.Err()
 }
 
-// GetCourseSubtopicTime returns average time per subtopic for a course.
-func GetCourseSubtopicTime(courseID string) ([]SubtopicTimeStat, error) {
+// GetCourseSubtopicTime returns average time per subtopic for a course, or for one
+// cohort of it when cohortID is non-zero.
+func GetCourseSubtopicTime(courseID string, cohortID int64) ([]SubtopicTimeStat, error) {
 	rows, err := db.Query(`
 		SELECT
 			subtopic_id,
@@ -112,8 +112,9 @@ func GetCourseSubtopicTime(courseID string) ([]SubtopicTimeStat, error) {
 			COUNT(DISTINCT username) AS learners
 		FROM learning_subtopic_time
 		WHERE course_id = $1 AND seconds_spent > 0
+		  AND ($2::bigint = 0 OR username IN (SELECT username FROM course_cohort_members WHERE cohort_id = $2))
 		GROUP BY subtopic_id
-		ORDER BY avg_minutes DESC`, courseID)
+		ORDER BY avg_minutes DESC`, courseID, cohortID)
 	if err != nil {
 		return nil, err
 	}
@@ -131,23 +132,25 @@ func GetCourseSubtopicTime(courseID string) ([]SubtopicTimeStat, error) {
 	return result, rows.Err()
 }
 
-// GetCourseUnansweredQnA returns Q&A questions without any replies.
-func GetCourseUnansweredQnA(courseID string) ([]UnansweredQnA, error) {
+// GetCourseUnresolvedQnA returns the visible Q&A questions that are still open, of
+// one cohort when cohortID is non-zero.
+func GetCourseUnresolvedQnA(courseID string, cohortID int64) ([]UnresolvedQnA, error) {
 	rows, err := db.Query(`
 		SELECT q.id, q.subtopic_id, q.question, u.name, q.created_at
 		FROM qna_questions q
 		JOIN users u ON u.username = q.username
 		WHERE q.course_id = $1
-		  AND NOT EXISTS (SELECT 1 FROM qna_replies r WHERE r.question_id = q.id)
-		ORDER BY q.created_at DESC`, courseID)
+		  AND ($2::bigint = 0 OR q.cohort_id = $2)
+		  AND q.hidden_at IS NULL AND q.status = 'open'
+		ORDER BY q.created_at DESC`, courseID, cohortID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var result []UnansweredQnA
+	var result []UnresolvedQnA
 	for rows.Next() {
-		var q UnansweredQnA
+		var q UnresolvedQnA
 		if err := rows.Scan(&q.ID, &q.SubtopicID, &q.Question, &q.Asker, &q.CreatedAt); err != nil {
 			continue
 		}
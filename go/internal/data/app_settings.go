@@ -61,3 +61,28 @@ func SetDefaultResetPassword(value string) error {
 	)
 	return err
 }
+
+// getSetting returns the stored value of key, or fallback when it was never set.
+func getSetting(key, fallback string) (string, error) {
+	var value string
+	err := db.QueryRow(`SELECT value FROM app_settings WHERE key = $1`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return fallback, nil
+	}
+	return value, err
+}
+
+func setSetting(exec interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, key, value string) error {
+	_, err := exec.Exec(
+		`INSERT INTO app_settings (key, value, updated_at)
+		 VALUES ($1, $2, NOW())
+		 ON CONFLICT (key) DO UPDATE
+		 SET value = EXCLUDED.value,
+		     updated_at = NOW()`,
+		key,
+		value,
+	)
+	return err
+}
@@ -0,0 +1,500 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AssignmentItemPath = "path"
+
+	AssignmentTargetUser = "user"
+	AssignmentTargetRole = "role"
+
+	AssignmentStatusAssigned   = "assigned"
+	AssignmentStatusInProgress = "in_progress"
+	AssignmentStatusCompleted  = "completed"
+	AssignmentStatusOverdue    = "overdue"
+)
+
+var ErrAssignmentItemMissing = errors.New("assigned course, exam or path does not exist")
+
+type AssignmentTarget struct {
+	Type  string `json:"type"` // user | role
+	Value string `json:"value"`
+}
+
+type Assignment struct {
+	ID             int64              `json:"id"`
+	ItemType       string             `json:"itemType"`
+	ItemID         string             `json:"itemId"`
+	ItemTitle      string             `json:"itemTitle"`
+	DueAt          time.Time          `json:"dueAt"`
+	RecurrenceDays int                `json:"recurrenceDays"` // 0 = one-off
+	Note           string             `json:"note"`
+	CreatedBy      string             `json:"createdBy"`
+	CreatedAt      time.Time          `json:"createdAt"`
+	Targets        []AssignmentTarget `json:"targets"`
+	AssigneeCount  int                `json:"assigneeCount"`
+}
+
+// UserAssignment is one assignment as it applies to one learner, with its status
+// derived from enrollment, attempt and path completion data.
+type UserAssignment struct {
+	AssignmentID   int64      `json:"assignmentId"`
+	ItemType       string     `json:"itemType"`
+	ItemID         string     `json:"itemId"`
+	ItemTitle      string     `json:"itemTitle"`
+	Note           string     `json:"note"`
+	DueAt          time.Time  `json:"dueAt"`
+	RecurrenceDays int        `json:"recurrenceDays"`
+	Status         string     `json:"status"`
+	CompletedAt    *time.Time `json:"completedAt"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	LastActivityAt *time.Time `json:"lastActivityAt"`
+}
+
+type ComplianceRow struct {
+	UserAssignment
+	Username     string `json:"username"`
+	Name         string `json:"name"`
+	EmployeeCode string `json:"employeeCode"`
+	Role         string `json:"role"`
+}
+
+type ComplianceSummary struct {
+	Total          int     `json:"total"`
+	Assigned       int     `json:"assigned"`
+	InProgress     int     `json:"inProgress"`
+	Completed      int     `json:"completed"`
+	Overdue        int     `json:"overdue"`
+	CompletionRate float64 `json:"completionRate"`
+}
+
+type ComplianceFilter struct {
+	AssignmentID int64
+	Role         string
+	Status       string
+	Search       string // name, username or employee code
+}
+
+// assignmentItemTitleSQL resolves the title of assignment a's course, exam or path.
+var assignmentItemTitleSQL = `
+	COALESCE(CASE a.item_type
+		WHEN 'course' THEN (SELECT title FROM courses WHERE id = a.item_id)
+		WHEN 'exam'   THEN (SELECT title FROM exams WHERE id = a.item_id)
+		ELSE (SELECT title FROM learning_paths WHERE id = a.item_id)
+	END, '')`
+
+// assigneeRowsSQL expands every assignment to its active assignees and derives, per
+// learner, the latest completion and latest learning activity on the assigned item.
+var assigneeRowsSQL = `
+	WITH au AS (
+		SELECT DISTINCT t.assignment_id, u.id AS user_id
+		FROM assignment_targets t
+		JOIN users u ON (t.target_type = 'user' AND u.username = t.target_value)
+		             OR (t.target_type = 'role' AND u.role_code = t.target_value)
+		WHERE u.status = 'active'
+	)
+	SELECT a.id, a.item_type, a.item_id, ` + assignmentItemTitleSQL + `, a.note,
+	       a.due_at,
+	       CASE WHEN a.recurrence_days > 0 THEN a.recurrence_days
+	            ELSE COALESCE(CASE a.item_type
+	                WHEN 'course' THEN (SELECT validity_days FROM courses WHERE id = a.item_id)
+	                WHEN 'exam'   THEN (SELECT validity_days FROM exams WHERE id = a.item_id)
+	            END, 0)
+	       END AS recurrence_days,
+	       u.username, u.name, u.employee_code, u.role_code,
+	       CASE a.item_type
+	           WHEN 'path' THEN (
+	               SELECT c.completed_at FROM learning_path_completions c
+	               WHERE c.username = u.username AND c.path_id = a.item_id)
+	           ELSE (
+	               SELECT MAX(r.completed_at) FROM completion_records r
+	               WHERE r.username = u.username AND r.item_type = a.item_type AND r.item_id = a.item_id)
+	       END AS completed_at,
+	       CASE a.item_type
+	           WHEN 'course' THEN GREATEST(
+	               (SELECT e.enrolled_at FROM user_course_enrollments e
+	                WHERE e.username = u.username AND e.course_id = a.item_id),
+	               (SELECT MAX(p.completed_at) FROM learning_subtopic_progress p
+	                WHERE p.username = u.username AND p.course_id = a.item_id),
+	               (SELECT MAX(t.updated_at) FROM learning_subtopic_time t
+	                WHERE t.username = u.username AND t.course_id = a.item_id))
+	           WHEN 'exam' THEN (
+	               SELECT MAX(x.started_at) FROM exam_attempts x
+	               WHERE x.username = u.username AND x.exam_id = a.item_id)
+	           ELSE (
+	               SELECT MAX(act.at)
+	               FROM learning_path_steps s
+	               JOIN LATERAL (
+	                   SELECT e.enrolled_at AS at FROM user_course_enrollments e
+	                   WHERE s.item_type = 'course' AND e.course_id = s.item_id AND e.username = u.username
+	                   UNION ALL
+	                   SELECT x.started_at FROM exam_attempts x
+	                   WHERE s.item_type = 'exam' AND x.exam_id = s.item_id AND x.username = u.username
+	               ) act ON TRUE
+	               WHERE s.path_id = a.item_id)
+	       END AS last_activity_at
+	FROM au
+	JOIN assignments a ON a.id = au.assignment_id
+	JOIN users u ON u.id = au.user_id`
+
+func EnsureAssignmentSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS assignments (
+			id              BIGSERIAL    PRIMARY KEY,
+			item_type       TEXT         NOT NULL CHECK (item_type IN ('course','exam','path')),
+			item_id         TEXT         NOT NULL,
+			due_at          TIMESTAMPTZ  NOT NULL,
+			recurrence_days INT          NOT NULL DEFAULT 0 CHECK (recurrence_days >= 0),
+			note            TEXT         NOT NULL DEFAULT '',
+			created_by      TEXT         REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS ix_assignments_item ON assignments(item_type, item_id);
+		CREATE TABLE IF NOT EXISTS assignment_targets (
+			assignment_id BIGINT NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+			target_type   TEXT   NOT NULL CHECK (target_type IN ('user','role')),
+			target_value  TEXT   NOT NULL,
+			PRIMARY KEY (assignment_id, target_type, target_value)
+		);
+		CREATE INDEX IF NOT EXISTS ix_assignment_targets_value ON assignment_targets(target_type, target_value);
+	`)
+	return err
+}
+
+// deriveAssignmentStatus works out where a learner stands on an assignment. A
+// recurring assignment stays completed for recurrenceDays after the latest
+// completion; after that it is due again from the later of dueAt and the expiry.
+func deriveAssignmentStatus(ua *UserAssignment, now time.Time) {
+	if ua.CompletedAt != nil {
+		if ua.RecurrenceDays == 0 {
+			ua.Status = AssignmentStatusCompleted
+			return
+		}
+		expiry := ua.CompletedAt.AddDate(0, 0, ua.RecurrenceDays)
+		ua.ExpiresAt = &expiry
+		if expiry.After(ua.DueAt) {
+			ua.DueAt = expiry
+		}
+		if expiry.After(now) {
+			ua.Status = AssignmentStatusCompleted
+			return
+		}
+	}
+
+	started := ua.LastActivityAt != nil && (ua.CompletedAt == nil || ua.LastActivityAt.After(*ua.CompletedAt))
+	switch {
+	case now.After(ua.DueAt):
+		ua.Status = AssignmentStatusOverdue
+	case started:
+		ua.Status = AssignmentStatusInProgress
+	default:
+		ua.Status = AssignmentStatusAssigned
+	}
+}
+
+func assignableItemExists(itemType, itemID string) (bool, error) {
+	table := ""
+	switch itemType {
+	case PathItemCourse:
+		table = "courses"
+	case PathItemExam:
+		table = "exams"
+	case AssignmentItemPath:
+		table = "learning_paths"
+	default:
+		return false, nil
+	}
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id = $1)`, itemID).Scan(&exists)
+	return exists, err
+}
+
+// SaveAssignment creates an assignment (ID 0) or replaces an existing one, including
+// its targets.
+func SaveAssignment(a Assignment, createdBy string) (Assignment, error) {
+	exists, err := assignableItemExists(a.ItemType, a.ItemID)
+	if err != nil {
+		return Assignment{}, err
+	}
+	if !exists {
+		return Assignment{}, ErrAssignmentItemMissing
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return Assignment{}, err
+	}
+	defer tx.Rollback()
+
+	if a.ID == 0 {
+		err = tx.QueryRow(`
+			INSERT INTO assignments (item_type, item_id, due_at, recurrence_days, note, created_by)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+			RETURNING id`,
+			a.ItemType, a.ItemID, a.DueAt, a.RecurrenceDays, a.Note, NormalizeUsername(createdBy),
+		).Scan(&a.ID)
+		if err != nil {
+			return Assignment{}, err
+		}
+	} else {
+		result, err := tx.Exec(`
+			UPDATE assignments
+			SET item_type = $2, item_id = $3, due_at = $4, recurrence_days = $5, note = $6
+			WHERE id = $1`,
+			a.ID, a.ItemType, a.ItemID, a.DueAt, a.RecurrenceDays, a.Note)
+		if err != nil {
+			return Assignment{}, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return Assignment{}, sql.ErrNoRows
+		}
+		if _, err := tx.Exec(`DELETE FROM assignment_targets WHERE assignment_id = $1`, a.ID); err != nil {
+			return Assignment{}, err
+		}
+	}
+
+	for _, t := range a.Targets {
+		if _, err := tx.Exec(`
+			INSERT INTO assignment_targets (assignment_id, target_type, target_value)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`,
+			a.ID, t.Type, t.Value,
+		); err != nil {
+			return Assignment{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Assignment{}, err
+	}
+	return GetAssignment(a.ID)
+}
+
+// removeItemReferences drops path steps and assignments that point at a deleted
+// course, exam or path.
+func removeItemReferences(itemType, itemID string) error {
+	if itemType != AssignmentItemPath {
+		if err := RemovePathSteps(itemType, itemID); err != nil {
+			return err
+		}
+	}
+	_, err := db.Exec(`DELETE FROM assignments WHERE item_type = $1 AND item_id = $2`, itemType, itemID)
+	return err
+}
+
+func DeleteAssignment(id int64) error {
+	result, err := db.Exec(`DELETE FROM assignments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func GetAssignment(id int64) (Assignment, error) {
+	list, _, err := queryAssignments(id, 1, 0)
+	if err != nil {
+		return Assignment{}, err
+	}
+	if len(list) == 0 {
+		return Assignment{}, sql.ErrNoRows
+	}
+	return list[0], nil
+}
+
+func ListAssignments(limit, offset int) ([]Assignment, int, error) {
+	return queryAssignments(0, limit, offset)
+}
+
+func queryAssignments(id int64, limit, offset int) ([]Assignment, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM assignments WHERE ($1 = 0 OR id = $1)`, id).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`
+		SELECT a.id, a.item_type, a.item_id, `+assignmentItemTitleSQL+`, a.due_at, a.recurrence_days,
+		       a.note, COALESCE(a.created_by, ''), a.created_at,
+		       (SELECT COUNT(DISTINCT u.id)
+		        FROM assignment_targets t
+		        JOIN users u ON (t.target_type = 'user' AND u.username = t.target_value)
+		                     OR (t.target_type = 'role' AND u.role_code = t.target_value)
+		        WHERE t.assignment_id = a.id AND u.status = 'active')
+		FROM assignments a
+		WHERE ($1 = 0 OR a.id = $1)
+		ORDER BY a.due_at, a.id
+		LIMIT $2 OFFSET $3`, id, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := make([]Assignment, 0)
+	idx := map[int64]int{}
+	for rows.Next() {
+		var a Assignment
+		if err := rows.Scan(&a.ID, &a.ItemType, &a.ItemID, &a.ItemTitle, &a.DueAt, &a.RecurrenceDays,
+			&a.Note, &a.CreatedBy, &a.CreatedAt, &a.AssigneeCount); err != nil {
+			return nil, 0, err
+		}
+		a.Targets = []AssignmentTarget{}
+		idx[a.ID] = len(list)
+		list = append(list, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(list) == 0 {
+		return list, total, nil
+	}
+
+	ids := make([]int64, 0, len(list))
+	for _, a := range list {
+		ids = append(ids, a.ID)
+	}
+	targetRows, err := db.Query(`
+		SELECT assignment_id, target_type, target_value
+		FROM assignment_targets
+		WHERE assignment_id = ANY($1::bigint[])
+		ORDER BY target_type, target_value`, ids)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot load assignment targets: %w", err)
+	}
+	defer targetRows.Close()
+	for targetRows.Next() {
+		var assignmentID int64
+		var t AssignmentTarget
+		if err := targetRows.Scan(&assignmentID, &t.Type, &t.Value); err != nil {
+			return nil, 0, err
+		}
+		if i, ok := idx[assignmentID]; ok {
+			list[i].Targets = append(list[i].Targets, t)
+		}
+	}
+	return list, total, targetRows.Err()
+}
+
+// ListUserAssignments returns every assignment that applies to the user, soonest due first.
+func ListUserAssignments(username string) ([]UserAssignment, error) {
+	rows, err := queryAssigneeRows(ComplianceFilter{}, []string{NormalizeUsername(username)})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]UserAssignment, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, r.UserAssignment)
+	}
+	// Recertification can push the effective due date past the stored one.
+	sort.SliceStable(result, func(i, j int) bool { return result[i].DueAt.Before(result[j].DueAt) })
+	return result, nil
+}
+
+// GetComplianceReport lists one row per assignment and assignee, filtered and paged,
+// together with status counts over the whole filtered set (ignoring f.Status).
+func GetComplianceReport(f ComplianceFilter, limit, offset int) ([]ComplianceRow, ComplianceSummary, int, error) {
+	rows, err := queryAssigneeRows(f, nil)
+	if err != nil {
+		return nil, ComplianceSummary{}, 0, err
+	}
+
+	var summary ComplianceSummary
+	filtered := make([]ComplianceRow, 0, len(rows))
+	for _, r := range rows {
+		summary.Total++
+		switch r.Status {
+		case AssignmentStatusAssigned:
+			summary.Assigned++
+		case AssignmentStatusInProgress:
+			summary.InProgress++
+		case AssignmentStatusCompleted:
+			summary.Completed++
+		case AssignmentStatusOverdue:
+			summary.Overdue++
+		}
+		if f.Status == "" || r.Status == f.Status {
+			filtered = append(filtered, r)
+		}
+	}
+	if summary.Total > 0 {
+		summary.CompletionRate = float64(summary.Completed) * 100 / float64(summary.Total)
+	}
+
+	total := len(filtered)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return filtered[offset:end], summary, total, nil
+}
+
+// CountOverdueAssignments returns the number of overdue assignments per username.
+func CountOverdueAssignments(usernames []string) (map[string]int, error) {
+	counts := make(map[string]int, len(usernames))
+	if len(usernames) == 0 {
+		return counts, nil
+	}
+	rows, err := queryAssigneeRows(ComplianceFilter{}, usernames)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		if r.Status == AssignmentStatusOverdue {
+			counts[r.Username]++
+		}
+	}
+	return counts, nil
+}
+
+// queryAssigneeRows loads assignment/assignee rows with derived status, optionally
+// narrowed to the given usernames.
+func queryAssigneeRows(f ComplianceFilter, usernames []string) ([]ComplianceRow, error) {
+	fb := newFilterBuilder(` WHERE TRUE`)
+	if len(usernames) > 0 {
+		fb.add(` AND u.username = ANY($%d)`, StringArray(usernames))
+	}
+	if f.AssignmentID > 0 {
+		fb.add(` AND a.id = $%d`, f.AssignmentID)
+	}
+	if f.Role != "" {
+		fb.add(` AND u.role_code = $%d`, f.Role)
+	}
+	if f.Search != "" {
+		pattern := "%" + strings.TrimSpace(f.Search) + "%"
+		fb.add(` AND (u.name ILIKE $%d OR u.username ILIKE $%d OR u.employee_code ILIKE $%d)`, pattern, pattern, pattern)
+	}
+
+	rows, err := db.Query(assigneeRowsSQL+fb.where+` ORDER BY a.due_at, a.id, u.name`, fb.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	result := make([]ComplianceRow, 0)
+	for rows.Next() {
+		var r ComplianceRow
+		if err := rows.Scan(
+			&r.AssignmentID, &r.ItemType, &r.ItemID, &r.ItemTitle, &r.Note,
+			&r.DueAt, &r.RecurrenceDays,
+			&r.Username, &r.Name, &r.EmployeeCode, &r.Role,
+			&r.CompletedAt, &r.LastActivityAt,
+		); err != nil {
+			return nil, err
+		}
+		deriveAssignmentStatus(&r.UserAssignment, now)
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,352 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Badge criteria. Each is evaluated against data the platform already records.
+const (
+	BadgeCoursesCompleted = "courses_completed" // completed at least threshold courses
+	BadgeSkillPoints      = "skill_points"      // at least threshold points in skill
+	BadgeExamScore        = "exam_score"        // scored at least threshold percent in an exam (any exam when examId is empty)
+	BadgeLoginStreak      = "login_streak"      // logged in on threshold consecutive days
+)
+
+var (
+	ErrBadgeNotFound        = errors.New("badge not found")
+	ErrInvalidBadgeCriteria = errors.New("invalid badge criteria")
+)
+
+type Badge struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	ImageURL     string    `json:"imageUrl"`
+	Criteria     string    `json:"criteria"`
+	Threshold    int       `json:"threshold"`
+	Skill        string    `json:"skill,omitempty"`
+	ExamID       string    `json:"examId,omitempty"`
+	Active       bool      `json:"active"`
+	AwardedCount int       `json:"awardedCount"`
+	CreatedBy    string    `json:"createdBy"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// UserBadge is a badge as earned by one user.
+type UserBadge struct {
+	BadgeID     int64     `json:"badgeId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"imageUrl"`
+	AwardedAt   time.Time `json:"awardedAt"`
+}
+
+// BadgeAward is one entry of a badge's award history.
+type BadgeAward struct {
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	AwardedAt time.Time `json:"awardedAt"`
+}
+
+// ValidateBadgeCriteria checks the criteria fields of a badge.
+func ValidateBadgeCriteria(b Badge) error {
+	if b.Threshold <= 0 {
+		return fmt.Errorf("%w: threshold must be positive", ErrInvalidBadgeCriteria)
+	}
+	switch b.Criteria {
+	case BadgeCoursesCompleted, BadgeLoginStreak:
+	case BadgeSkillPoints:
+		if b.Skill == "" {
+			return fmt.Errorf("%w: skill is required", ErrInvalidBadgeCriteria)
+		}
+	case BadgeExamScore:
+		if b.Threshold > 100 {
+			return fmt.Errorf("%w: threshold must be a percentage", ErrInvalidBadgeCriteria)
+		}
+	default:
+		return fmt.Errorf("%w: unknown criteria %q", ErrInvalidBadgeCriteria, b.Criteria)
+	}
+	return nil
+}
+
+func EnsureBadgeSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS badges (
+			id          BIGSERIAL    PRIMARY KEY,
+			name        TEXT         NOT NULL UNIQUE,
+			description TEXT         NOT NULL DEFAULT '',
+			image_url   TEXT         NOT NULL DEFAULT '',
+			criteria    TEXT         NOT NULL CHECK (criteria IN ('courses_completed', 'skill_points', 'exam_score', 'login_streak')),
+			threshold   INT          NOT NULL CHECK (threshold > 0),
+			skill       TEXT         NOT NULL DEFAULT '',
+			exam_id     TEXT         NULL REFERENCES exams(id) ON DELETE CASCADE,
+			active      BOOLEAN      NOT NULL DEFAULT TRUE,
+			created_by  TEXT         NULL REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS user_badges (
+			badge_id   BIGINT       NOT NULL REFERENCES badges(id) ON DELETE CASCADE,
+			username   TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			awarded_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (badge_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_user_badges_user ON user_badges(username, awarded_at);
+	`)
+	return err
+}
+
+const badgeColumns = `
+	b.id, b.name, b.description, b.image_url, b.criteria, b.threshold, b.skill, COALESCE(b.exam_id, ''),
+	b.active, (SELECT COUNT(*) FROM user_badges ub WHERE ub.badge_id = b.id), COALESCE(b.created_by, ''), b.created_at`
+
+func scanBadge(row interface{ Scan(dest ...any) error }) (Badge, error) {
+	var b Badge
+	err := row.Scan(&b.ID, &b.Name, &b.Description, &b.ImageURL, &b.Criteria, &b.Threshold, &b.Skill, &b.ExamID,
+		&b.Active, &b.AwardedCount, &b.CreatedBy, &b.CreatedAt)
+	return b, err
+}
+
+// ListBadges returns badges by name; inactive ones only when includeInactive.
+func ListBadges(includeInactive bool) ([]Badge, error) {
+	rows, err := db.Query(`SELECT `+badgeColumns+` FROM badges b WHERE b.active OR $1 ORDER BY b.name`, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]Badge, 0)
+	for rows.Next() {
+		b, err := scanBadge(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
+
+func GetBadge(id int64) (Badge, error) {
+	b, err := scanBadge(db.QueryRow(`SELECT `+badgeColumns+` FROM badges b WHERE b.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return b, ErrBadgeNotFound
+	}
+	return b, err
+}
+
+// SaveBadge creates (ID 0) or updates a badge. The image is set separately.
+func SaveBadge(b Badge, createdBy string) (Badge, error) {
+	if err := ValidateBadgeCriteria(b); err != nil {
+		return Badge{}, err
+	}
+	var err error
+	if b.Criteria == BadgeSkillPoints {
+		if b.Skill, err = ResolveSkill(b.Skill); err != nil {
+			if errors.Is(err, ErrSkillNotFound) {
+				return Badge{}, fmt.Errorf("%w: %v", ErrInvalidBadgeCriteria, err)
+			}
+			return Badge{}, err
+		}
+	}
+	if b.ID == 0 {
+		err = db.QueryRow(`
+			INSERT INTO badges (name, description, criteria, threshold, skill, exam_id, active, created_by)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+			RETURNING id`,
+			b.Name, b.Description, b.Criteria, b.Threshold, b.Skill, b.ExamID, b.Active, createdBy,
+		).Scan(&b.ID)
+	} else {
+		var result sql.Result
+		result, err = db.Exec(`
+			UPDATE badges
+			SET name = $2, description = $3, criteria = $4, threshold = $5, skill = $6,
+			    exam_id = NULLIF($7, ''), active = $8
+			WHERE id = $1`,
+			b.ID, b.Name, b.Description, b.Criteria, b.Threshold, b.Skill, b.ExamID, b.Active)
+		if err == nil {
+			if n, _ := result.RowsAffected(); n == 0 {
+				return Badge{}, ErrBadgeNotFound
+			}
+		}
+	}
+	if err != nil {
+		if IsForeignKeyViolation(err) {
+			return Badge{}, fmt.Errorf("%w: exam %s not found", ErrInvalidBadgeCriteria, b.ExamID)
+		}
+		return Badge{}, err
+	}
+	return GetBadge(b.ID)
+}
+
+func SetBadgeImage(id int64, url string) error {
+	result, err := db.Exec(`UPDATE badges SET image_url = $2 WHERE id = $1`, id, url)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrBadgeNotFound
+	}
+	return nil
+}
+
+// DeleteBadge removes a badge and its award history.
+func DeleteBadge(id int64) error {
+	result, err := db.Exec(`DELETE FROM badges WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrBadgeNotFound
+	}
+	return nil
+}
+
+// badgeEarnersQuery selects the usernames meeting a badge's criteria. $1 limits
+// the query to one user (empty for everyone); the criteria's own parameters follow.
+func badgeEarnersQuery(b Badge, username string) (string, []any) {
+	switch b.Criteria {
+	case BadgeCoursesCompleted:
+		return `
+			SELECT username FROM user_course_enrollments
+			WHERE completed_at IS NOT NULL AND ($1::text = '' OR username = $1)
+			GROUP BY username HAVING COUNT(*) >= $2`, []any{username, b.Threshold}
+	case BadgeSkillPoints:
+		return `
+			SELECT username FROM user_skill_scores
+			WHERE ($1::text = '' OR username = $1) AND skill = $3 AND points >= $2`, []any{username, b.Threshold, b.Skill}
+	case BadgeExamScore:
+		return `
+			SELECT DISTINCT username FROM exam_attempts
+			WHERE ($1::text = '' OR username = $1) AND score_percent >= $2
+			  AND ($3::text = '' OR exam_id = $3)`, []any{username, b.Threshold, b.ExamID}
+	case BadgeLoginStreak:
+		// Consecutive login days share the same (day - row number).
+		return `
+			WITH days AS (
+				SELECT DISTINCT u.username, (l.logged_in_at AT TIME ZONE 'Asia/Bangkok')::date AS day
+				FROM user_login_logs l JOIN users u ON u.id = l.user_id
+				WHERE ($1::text = '' OR u.username = $1)
+			), runs AS (
+				SELECT username, day - (ROW_NUMBER() OVER (PARTITION BY username ORDER BY day))::int AS run
+				FROM days
+			)
+			SELECT DISTINCT username FROM runs GROUP BY username, run HAVING COUNT(*) >= $2`, []any{username, b.Threshold}
+	}
+	return "", nil
+}
+
+// awardBadge gives the badge to every active user meeting its criteria, or only to
+// username when set, and returns who newly earned it.
+func awardBadge(b Badge, username string) ([]string, error) {
+	earners, args := badgeEarnersQuery(b, username)
+	if earners == "" {
+		return nil, ErrInvalidBadgeCriteria
+	}
+	args = append(args, b.ID)
+	rows, err := db.Query(fmt.Sprintf(`
+		INSERT INTO user_badges (badge_id, username)
+		SELECT $%d::bigint, e.username FROM (%s) e
+		JOIN users u ON u.username = e.username AND u.status = 'active'
+		ON CONFLICT (badge_id, username) DO NOTHING
+		RETURNING username`, len(args), earners), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	awarded := make([]string, 0)
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		awarded = append(awarded, u)
+	}
+	return awarded, rows.Err()
+}
+
+// AwardBadgeRetroactively gives an active badge to everyone who already meets its
+// criteria, e.g. right after it is defined.
+func AwardBadgeRetroactively(b Badge) ([]string, error) {
+	if !b.Active {
+		return []string{}, nil
+	}
+	return awardBadge(b, "")
+}
+
+// AwardEarnedBadges evaluates the user's unearned active badges of the given
+// criteria (all criteria when none are given) and returns the newly earned ones.
+func AwardEarnedBadges(username string, criteria ...string) ([]Badge, error) {
+	badges, err := ListBadges(false)
+	if err != nil {
+		return nil, err
+	}
+	earned := make([]Badge, 0)
+	for _, b := range badges {
+		if len(criteria) > 0 && !slices.Contains(criteria, b.Criteria) {
+			continue
+		}
+		awarded, err := awardBadge(b, username)
+		if err != nil {
+			return earned, fmt.Errorf("cannot evaluate badge %d: %w", b.ID, err)
+		}
+		if len(awarded) > 0 {
+			earned = append(earned, b)
+		}
+	}
+	return earned, nil
+}
+
+// ListUserBadges returns the user's badges, most recent first.
+func ListUserBadges(username string) ([]UserBadge, error) {
+	rows, err := db.Query(`
+		SELECT b.id, b.name, b.description, b.image_url, ub.awarded_at
+		FROM user_badges ub JOIN badges b ON b.id = ub.badge_id
+		WHERE ub.username = $1
+		ORDER BY ub.awarded_at DESC, b.name`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]UserBadge, 0)
+	for rows.Next() {
+		var ub UserBadge
+		if err := rows.Scan(&ub.BadgeID, &ub.Name, &ub.Description, &ub.ImageURL, &ub.AwardedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, ub)
+	}
+	return result, rows.Err()
+}
+
+// ListBadgeAwards returns a page of a badge's award history, most recent first.
+func ListBadgeAwards(id int64, limit, offset int) ([]BadgeAward, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM user_badges WHERE badge_id = $1`, id).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.Query(`
+		SELECT ub.username, u.name, ub.awarded_at
+		FROM user_badges ub JOIN users u ON u.username = ub.username
+		WHERE ub.badge_id = $1
+		ORDER BY ub.awarded_at DESC, ub.username
+		LIMIT $2 OFFSET $3`, id, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	result := make([]BadgeAward, 0)
+	for rows.Next() {
+		var a BadgeAward
+		if err := rows.Scan(&a.Username, &a.Name, &a.AwardedAt); err != nil {
+			return nil, 0, err
+		}
+		result = append(result, a)
+	}
+	return result, total, rows.Err()
+}
@@ -0,0 +1,272 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CertificationValid        = "valid"
+	CertificationExpiringSoon = "expiring_soon"
+	CertificationExpired      = "expired"
+)
+
+var ErrRecertificationNotDue = errors.New("course has no certification that is expired or expiring soon")
+
+// Certification is the latest completion of a course or exam by one user. Its expiry
+// is computed from the item's current validity period.
+type Certification struct {
+	ItemType     string     `json:"itemType"`
+	ItemID       string     `json:"itemId"`
+	ItemTitle    string     `json:"itemTitle"`
+	CompletedAt  time.Time  `json:"completedAt"`
+	ValidityDays int        `json:"validityDays"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+	Status       string     `json:"status"`
+	Completions  int        `json:"completions"`
+}
+
+type ExpiringCertification struct {
+	Certification
+	Username     string `json:"username"`
+	Name         string `json:"name"`
+	EmployeeCode string `json:"employeeCode"`
+	Role         string `json:"role"`
+}
+
+type ExpiringCertificationFilter struct {
+	WithinDays     int
+	IncludeExpired bool
+	ItemType       string
+	ItemID         string
+	Search         string // name, username or employee code
+}
+
+// latestCertificationsSQL keeps the newest completion record per user and item and
+// computes its expiry from the item's validity_days (NULL when it never expires).
+var latestCertificationsSQL = `
+	WITH latest AS (
+		SELECT DISTINCT ON (r.username, r.item_type, r.item_id)
+		       r.username, r.item_type, r.item_id, r.completed_at,
+		       COUNT(*) OVER (PARTITION BY r.username, r.item_type, r.item_id) AS completions
+		FROM completion_records r
+		ORDER BY r.username, r.item_type, r.item_id, r.completed_at DESC
+	), cert AS (
+		SELECT l.username, l.item_type, l.item_id,
+		       COALESCE(c.title, x.title, '') AS item_title,
+		       l.completed_at, l.completions,
+		       COALESCE(c.validity_days, x.validity_days, 0) AS validity_days,
+		       CASE WHEN COALESCE(c.validity_days, x.validity_days, 0) > 0
+		            THEN l.completed_at + make_interval(days => COALESCE(c.validity_days, x.validity_days))
+		       END AS expires_at
+		FROM latest l
+		LEFT JOIN courses c ON l.item_type = 'course' AND c.id = l.item_id
+		LEFT JOIN exams x   ON l.item_type = 'exam'   AND x.id = l.item_id
+	)`
+
+func EnsureCertificationSchema() error {
+	_, err := db.Exec(`
+		ALTER TABLE courses ADD COLUMN IF NOT EXISTS validity_days INT NOT NULL DEFAULT 0;
+		ALTER TABLE exams ADD COLUMN IF NOT EXISTS validity_days INT NOT NULL DEFAULT 0;
+		CREATE TABLE IF NOT EXISTS completion_records (
+			id           BIGSERIAL    PRIMARY KEY,
+			username     TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			item_type    TEXT         NOT NULL CHECK (item_type IN ('course','exam')),
+			item_id      TEXT         NOT NULL,
+			attempt_id   BIGINT       UNIQUE REFERENCES exam_attempts(id) ON DELETE SET NULL,
+			completed_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS ix_completion_records_user_item
+			ON completion_records(username, item_type, item_id, completed_at DESC);
+		CREATE INDEX IF NOT EXISTS ix_completion_records_item ON completion_records(item_type, item_id);
+
+		-- Backfill from completions recorded before completion_records existed.
+		INSERT INTO completion_records (username, item_type, item_id, completed_at)
+		SELECT e.username, 'course', e.course_id, e.completed_at
+		FROM user_course_enrollments e
+		WHERE e.completed_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM completion_records r
+		                  WHERE r.username = e.username AND r.item_type = 'course' AND r.item_id = e.course_id);
+		INSERT INTO completion_records (username, item_type, item_id, attempt_id, completed_at)
+		SELECT a.username, 'exam', a.exam_id, a.id, a.finished_at
+		FROM exam_attempts a
+		WHERE a.finished_at IS NOT NULL AND a.score_percent >= ` + strconv.Itoa(ExamPassPercent) + `
+		ON CONFLICT (attempt_id) DO NOTHING;
+	`)
+	return err
+}
+
+// recordCompletion appends a completion record. attemptID is set for exam passes.
+func recordCompletion(exec interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, username, itemType, itemID string, attemptID *int64) error {
+	_, err := exec.Exec(`
+		INSERT INTO completion_records (username, item_type, item_id, attempt_id)
+		VALUES ($1, $2, $3, $4)`,
+		username, itemType, itemID, attemptID)
+	return err
+}
+
+func certificationStatus(expiresAt *time.Time, soonDays int, now time.Time) string {
+	switch {
+	case expiresAt == nil:
+		return CertificationValid
+	case !expiresAt.After(now):
+		return CertificationExpired
+	case expiresAt.Before(now.AddDate(0, 0, soonDays)):
+		return CertificationExpiringSoon
+	default:
+		return CertificationValid
+	}
+}
+
+// ListUserCertifications returns the user's latest completion of every course and
+// exam, soonest expiry first. Items expiring within soonDays are flagged.
+func ListUserCertifications(username string, soonDays int) ([]Certification, error) {
+	rows, err := db.Query(latestCertificationsSQL+`
+		SELECT item_type, item_id, item_title, completed_at, validity_days, expires_at, completions
+		FROM cert
+		WHERE username = $1
+		ORDER BY expires_at NULLS LAST, completed_at DESC`, NormalizeUsername(username))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	result := make([]Certification, 0)
+	for rows.Next() {
+		var c Certification
+		if err := rows.Scan(&c.ItemType, &c.ItemID, &c.ItemTitle, &c.CompletedAt, &c.ValidityDays, &c.ExpiresAt, &c.Completions); err != nil {
+			return nil, err
+		}
+		c.Status = certificationStatus(c.ExpiresAt, soonDays, now)
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// ListExpiringCertifications returns certifications of active users that expire
+// within f.WithinDays, plus already expired ones when f.IncludeExpired is set.
+// Reminders and the compliance dashboard are driven from this query.
+func ListExpiringCertifications(f ExpiringCertificationFilter, limit, offset int) ([]ExpiringCertification, int, error) {
+	fb := newFilterBuilder(`
+		FROM cert
+		JOIN users u ON u.username = cert.username
+		WHERE u.status = 'active' AND cert.expires_at IS NOT NULL`)
+	fb.add(` AND cert.expires_at < NOW() + make_interval(days => $%d)`, f.WithinDays)
+	if !f.IncludeExpired {
+		fb.add(` AND cert.expires_at > NOW()`)
+	}
+	if f.ItemType != "" {
+		fb.add(` AND cert.item_type = $%d`, f.ItemType)
+	}
+	if f.ItemID != "" {
+		fb.add(` AND cert.item_id = $%d`, f.ItemID)
+	}
+	if f.Search != "" {
+		pattern := "%" + strings.TrimSpace(f.Search) + "%"
+		fb.add(` AND (u.name ILIKE $%d OR u.username ILIKE $%d OR u.employee_code ILIKE $%d)`, pattern, pattern, pattern)
+	}
+
+	var total int
+	if err := db.QueryRow(latestCertificationsSQL+` SELECT COUNT(*)`+fb.where, fb.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limitClause := fb.limitOffset(limit, offset)
+	rows, err := db.Query(latestCertificationsSQL+`
+		SELECT cert.item_type, cert.item_id, cert.item_title, cert.completed_at, cert.validity_days,
+		       cert.expires_at, cert.completions,
+		       u.username, u.name, u.employee_code, u.role_code`+fb.where+`
+		ORDER BY cert.expires_at, u.name`+limitClause, fb.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	result := make([]ExpiringCertification, 0)
+	for rows.Next() {
+		var c ExpiringCertification
+		if err := rows.Scan(
+			&c.ItemType, &c.ItemID, &c.ItemTitle, &c.CompletedAt, &c.ValidityDays,
+			&c.ExpiresAt, &c.Completions,
+			&c.Username, &c.Name, &c.EmployeeCode, &c.Role,
+		); err != nil {
+			return nil, 0, err
+		}
+		c.Status = certificationStatus(c.ExpiresAt, f.WithinDays, now)
+		result = append(result, c)
+	}
+	return result, total, rows.Err()
+}
+
+// StartCourseRecertification reopens a completed course so it can be retaken for
+// credit. It is only allowed once the course's certification has expired or expires
+// within soonDays. Subtopic progress and answers are cleared; the completion history
+// in completion_records is kept. A reopened course takes a seat again, so in a full
+// capacity course the learner joins the waitlist instead and waitlisted is true.
+func StartCourseRecertification(username, courseID string, soonDays int) (waitlisted bool, err error) {
+	username = NormalizeUsername(username)
+
+	var due bool
+	err = db.QueryRow(latestCertificationsSQL+`
+		SELECT expires_at IS NOT NULL AND expires_at < NOW() + make_interval(days => $3)
+		FROM cert
+		WHERE username = $1 AND item_type = 'course' AND item_id = $2`,
+		username, courseID, soonDays,
+	).Scan(&due)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrRecertificationNotDue
+	}
+	if err != nil {
+		return false, err
+	}
+	if !due {
+		return false, ErrRecertificationNotDue
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	mode, capacity, err := lockCourseForEnrollment(tx, courseID)
+	if err != nil {
+		return false, err
+	}
+	if mode == EnrollmentModeCapacity && capacity > 0 {
+		var seatsTaken int
+		if err := tx.QueryRow(`
+			SELECT COUNT(*) FROM user_course_enrollments WHERE course_id = $1 AND completed_at IS NULL`,
+			courseID).Scan(&seatsTaken); err != nil {
+			return false, err
+		}
+		waitlisted = seatsTaken >= capacity
+	}
+
+	statements := []string{
+		`DELETE FROM learning_subtopic_progress WHERE username = $1 AND course_id = $2`,
+		`DELETE FROM learning_subtopic_answers WHERE username = $1 AND course_id = $2`,
+		`UPDATE user_course_enrollments SET completed_at = NULL, enrolled_at = NOW() WHERE username = $1 AND course_id = $2`,
+	}
+	if waitlisted {
+		statements[2] = `DELETE FROM user_course_enrollments WHERE username = $1 AND course_id = $2`
+		statements = append(statements, `
+			INSERT INTO course_enrollment_requests (course_id, username, status)
+			VALUES ($2, $1, 'waitlisted')
+			ON CONFLICT (course_id, username) DO UPDATE
+				SET status = EXCLUDED.status, requested_at = NOW(), decided_by = NULL, decided_at = NULL`)
+	}
+	for _, q := range statements {
+		if _, err := tx.Exec(q, username, courseID); err != nil {
+			return false, err
+		}
+	}
+	return waitlisted, tx.Commit()
+}
@@ -0,0 +1,380 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCohortNotFound     = errors.New("cohort not found")
+	ErrCohortConflict     = errors.New("already in another cohort of this course")
+	ErrInvalidCohortDates = errors.New("endsAt must be after startsAt")
+)
+
+// Cohort is one run of a course with its own schedule, members and instructor.
+// With UnlockIntervalDays set, chapter N unlocks (N-1)×UnlockIntervalDays days after
+// StartsAt; otherwise every chapter unlocks at StartsAt.
+type Cohort struct {
+	ID                 int64     `json:"id"`
+	CourseID           string    `json:"courseId"`
+	Name               string    `json:"name"`
+	InstructorUsername string    `json:"instructorUsername"`
+	InstructorName     string    `json:"instructorName"`
+	StartsAt           time.Time `json:"startsAt"`
+	EndsAt             time.Time `json:"endsAt"`
+	UnlockIntervalDays int       `json:"unlockIntervalDays"` // 0 = all chapters at start
+	MemberCount        int       `json:"memberCount"`
+	CreatedAt          time.Time `json:"createdAt"`
+}
+
+type CohortMember struct {
+	Username    string     `json:"username"`
+	Name        string     `json:"name"`
+	AddedAt     time.Time  `json:"addedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// ChapterUnlockAt returns when the 1-based chapter of the cohort unlocks.
+func (c Cohort) ChapterUnlockAt(chapter int) time.Time {
+	if chapter <= 1 || c.UnlockIntervalDays <= 0 {
+		return c.StartsAt
+	}
+	return c.StartsAt.AddDate(0, 0, (chapter-1)*c.UnlockIntervalDays)
+}
+
+func EnsureCohortSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS course_cohorts (
+			id                   BIGSERIAL    PRIMARY KEY,
+			course_id            TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			name                 TEXT         NOT NULL,
+			instructor_username  TEXT         NULL REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			starts_at            TIMESTAMPTZ  NOT NULL,
+			ends_at              TIMESTAMPTZ  NOT NULL,
+			unlock_interval_days INT          NOT NULL DEFAULT 0 CHECK (unlock_interval_days >= 0),
+			created_at           TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			UNIQUE (course_id, name),
+			CHECK (ends_at > starts_at)
+		);
+		CREATE INDEX IF NOT EXISTS ix_course_cohorts_instructor ON course_cohorts(instructor_username);
+		CREATE TABLE IF NOT EXISTS course_cohort_members (
+			cohort_id BIGINT       NOT NULL REFERENCES course_cohorts(id) ON DELETE CASCADE,
+			course_id TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			username  TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			added_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (cohort_id, username),
+			UNIQUE (course_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_cohort_members_user ON course_cohort_members(username);
+		ALTER TABLE qna_questions ADD COLUMN IF NOT EXISTS cohort_id BIGINT NULL
+			REFERENCES course_cohorts(id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS ix_qna_questions_cohort ON qna_questions(cohort_id);
+	`)
+	return err
+}
+
+// checkCourseOwner allows the course owner and admins.
+func checkCourseOwner(courseID, callerUsername string, isAdmin bool) error {
+	var ownerUsername sql.NullString
+	err := db.QueryRow(`SELECT owner_username FROM courses WHERE id = $1`, courseID).Scan(&ownerUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCourseNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !isAdmin && (!ownerUsername.Valid || ownerUsername.String != callerUsername) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+const cohortColumns = `
+	c.id, c.course_id, c.name, COALESCE(c.instructor_username, ''), COALESCE(u.name, ''),
+	c.starts_at, c.ends_at, c.unlock_interval_days,
+	(SELECT COUNT(*) FROM course_cohort_members m WHERE m.cohort_id = c.id), c.created_at`
+
+func scanCohort(row interface{ Scan(dest ...any) error }) (Cohort, error) {
+	var c Cohort
+	err := row.Scan(
+		&c.ID, &c.CourseID, &c.Name, &c.InstructorUsername, &c.InstructorName,
+		&c.StartsAt, &c.EndsAt, &c.UnlockIntervalDays,
+		&c.MemberCount, &c.CreatedAt,
+	)
+	return c, err
+}
+
+func getCohort(where string, args ...any) (Cohort, error) {
+	c, err := scanCohort(db.QueryRow(`
+		SELECT `+cohortColumns+`
+		FROM course_cohorts c
+		LEFT JOIN users u ON u.username = c.instructor_username
+		WHERE `+where, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, ErrCohortNotFound
+	}
+	return c, err
+}
+
+// GetCohort returns a cohort of the course.
+func GetCohort(courseID string, id int64) (Cohort, error) {
+	return getCohort(`c.id = $1 AND c.course_id = $2`, id, courseID)
+}
+
+// GetLearnerCohort returns the cohort the learner belongs to in a course, or
+// ErrCohortNotFound when they learn outside any cohort.
+func GetLearnerCohort(username, courseID string) (Cohort, error) {
+	return getCohort(`c.id = (SELECT cohort_id FROM course_cohort_members WHERE course_id = $1 AND username = $2)`,
+		courseID, username)
+}
+
+// ListCohorts returns the cohorts of a course, latest start first. The course owner,
+// admins and instructors of one of its cohorts may list them.
+func ListCohorts(courseID, callerUsername string, isAdmin bool) ([]Cohort, error) {
+	if err := checkCourseOwner(courseID, callerUsername, isAdmin); errors.Is(err, ErrForbidden) {
+		var instructs bool
+		if err := db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM course_cohorts WHERE course_id = $1 AND instructor_username = $2)`,
+			courseID, callerUsername).Scan(&instructs); err != nil {
+			return nil, err
+		}
+		if !instructs {
+			return nil, ErrForbidden
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT `+cohortColumns+`
+		FROM course_cohorts c
+		LEFT JOIN users u ON u.username = c.instructor_username
+		WHERE c.course_id = $1
+		ORDER BY c.starts_at DESC, c.id DESC`, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]Cohort, 0)
+	for rows.Next() {
+		c, err := scanCohort(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// SaveCohort creates (ID 0) or updates a cohort. Only the course owner or an admin
+// may save cohorts.
+func SaveCohort(c Cohort, callerUsername string, isAdmin bool) (Cohort, error) {
+	if !c.EndsAt.After(c.StartsAt) {
+		return Cohort{}, ErrInvalidCohortDates
+	}
+	if err := checkCourseOwner(c.CourseID, callerUsername, isAdmin); err != nil {
+		return Cohort{}, err
+	}
+	var instructor *string
+	if c.InstructorUsername != "" {
+		instructor = &c.InstructorUsername
+	}
+
+	var err error
+	if c.ID == 0 {
+		err = db.QueryRow(`
+			INSERT INTO course_cohorts (course_id, name, instructor_username, starts_at, ends_at, unlock_interval_days)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id`,
+			c.CourseID, c.Name, instructor, c.StartsAt, c.EndsAt, c.UnlockIntervalDays,
+		).Scan(&c.ID)
+	} else {
+		var result sql.Result
+		result, err = db.Exec(`
+			UPDATE course_cohorts
+			SET name = $3, instructor_username = $4, starts_at = $5, ends_at = $6, unlock_interval_days = $7
+			WHERE id = $1 AND course_id = $2`,
+			c.ID, c.CourseID, c.Name, instructor, c.StartsAt, c.EndsAt, c.UnlockIntervalDays)
+		if err == nil {
+			if n, _ := result.RowsAffected(); n == 0 {
+				return Cohort{}, ErrCohortNotFound
+			}
+		}
+	}
+	if err != nil {
+		if IsForeignKeyViolation(err) {
+			return Cohort{}, fmt.Errorf("%w: %s", ErrUnknownUsername, c.InstructorUsername)
+		}
+		return Cohort{}, err
+	}
+	return GetCohort(c.CourseID, c.ID)
+}
+
+// DeleteCohort removes a cohort. Members stay enrolled in the course and its Q&A
+// questions become course-wide.
+func DeleteCohort(courseID string, id int64, callerUsername string, isAdmin bool) error {
+	if err := checkCourseOwner(courseID, callerUsername, isAdmin); err != nil {
+		return err
+	}
+	result, err := db.Exec(`DELETE FROM course_cohorts WHERE id = $1 AND course_id = $2`, id, courseID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrCohortNotFound
+	}
+	return nil
+}
+
+// canViewCohort allows the course owner, admins and the cohort's instructor.
+func canViewCohort(c Cohort, callerUsername string, isAdmin bool) error {
+	if c.InstructorUsername != "" && c.InstructorUsername == callerUsername {
+		return nil
+	}
+	return checkCourseOwner(c.CourseID, callerUsername, isAdmin)
+}
+
+// CheckCohortAccess allows the members of a cohort, its instructor, the course
+// owner and admins.
+func CheckCohortAccess(courseID string, id int64, callerUsername string, isAdmin bool) error {
+	cohort, err := GetCohort(courseID, id)
+	if err != nil {
+		return err
+	}
+	var member bool
+	if err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM course_cohort_members WHERE cohort_id = $1 AND username = $2)`,
+		id, callerUsername).Scan(&member); err != nil {
+		return err
+	}
+	if member {
+		return nil
+	}
+	return canViewCohort(cohort, callerUsername, isAdmin)
+}
+
+// ListCohortMembers returns the members of a cohort by name.
+func ListCohortMembers(courseID string, id int64, callerUsername string, isAdmin bool) ([]CohortMember, error) {
+	cohort, err := GetCohort(courseID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := canViewCohort(cohort, callerUsername, isAdmin); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT m.username, u.name, m.added_at, e.completed_at
+		FROM course_cohort_members m
+		JOIN users u ON u.username = m.username
+		LEFT JOIN user_course_enrollments e ON e.username = m.username AND e.course_id = m.course_id
+		WHERE m.cohort_id = $1
+		ORDER BY u.name, m.username`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]CohortMember, 0)
+	for rows.Next() {
+		var m CohortMember
+		if err := rows.Scan(&m.Username, &m.Name, &m.AddedAt, &m.CompletedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// SetCohortMembers replaces the members of a cohort and enrolls new members in the
+// course regardless of its enrollment mode. A learner belongs to at most one cohort
+// per course, so members of another cohort are rejected. It returns the newly
+// added members.
+func SetCohortMembers(courseID string, id int64, usernames []string, callerUsername string, isAdmin bool) ([]string, error) {
+	if err := checkCourseOwner(courseID, callerUsername, isAdmin); err != nil {
+		return nil, err
+	}
+	normalized := StringArray{}
+	for _, u := range usernames {
+		if u = NormalizeUsername(u); u != "" {
+			normalized = append(normalized, u)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(`SELECT id FROM course_cohorts WHERE id = $1 AND course_id = $2 FOR UPDATE`, id, courseID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCohortNotFound
+		}
+		return nil, err
+	}
+	var missing StringArray
+	if err := tx.QueryRow(`
+		SELECT COALESCE(array_agg(u), '{}') FROM unnest($1::text[]) AS u
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE username = u)`, normalized,
+	).Scan(&missing); err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownUsername, strings.Join(missing, ", "))
+	}
+	var taken StringArray
+	if err := tx.QueryRow(`
+		SELECT COALESCE(array_agg(username ORDER BY username), '{}') FROM course_cohort_members
+		WHERE course_id = $1 AND cohort_id <> $2 AND username = ANY($3::text[])`, courseID, id, normalized,
+	).Scan(&taken); err != nil {
+		return nil, err
+	}
+	if len(taken) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCohortConflict, strings.Join(taken, ", "))
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM course_cohort_members WHERE cohort_id = $1 AND NOT (username = ANY($2::text[]))`,
+		id, normalized); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(`
+		INSERT INTO course_cohort_members (cohort_id, course_id, username)
+		SELECT DISTINCT $1::bigint, $2, u FROM unnest($3::text[]) AS u
+		ON CONFLICT DO NOTHING
+		RETURNING username`, id, courseID, normalized)
+	if err != nil {
+		return nil, err
+	}
+	added := make([]string, 0)
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		added = append(added, username)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO user_course_enrollments (username, course_id)
+		SELECT u, $1 FROM unnest($2::text[]) AS u
+		ON CONFLICT (username, course_id) DO NOTHING`, courseID, StringArray(added)); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM course_enrollment_requests
+		WHERE course_id = $1 AND username = ANY($2::text[])`, courseID, StringArray(added)); err != nil {
+		return nil, err
+	}
+	return added, tx.Commit()
+}
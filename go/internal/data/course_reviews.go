@@ -0,0 +1,237 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrCourseNotCompleted = errors.New("complete the course before reviewing it")
+
+// CourseReview is a learner's 1–5 rating of a completed course with an optional
+// written review and instructor reply. Hidden reviews are left out of the public
+// list and of the course rating.
+type CourseReview struct {
+	ID        int64      `json:"id"`
+	CourseID  string     `json:"courseId"`
+	Username  string     `json:"username"`
+	Name      string     `json:"name"`
+	Rating    int        `json:"rating"`
+	Review    string     `json:"review"`
+	Reply     string     `json:"reply"`
+	ReplyBy   string     `json:"replyBy,omitempty"`
+	RepliedAt *time.Time `json:"repliedAt,omitempty"`
+	Hidden    bool       `json:"hidden"`
+	HiddenBy  string     `json:"hiddenBy,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+type CourseRatingSummary struct {
+	Average      float64     `json:"average"`
+	Count        int         `json:"count"`
+	Distribution map[int]int `json:"distribution"` // rating → number of reviews
+}
+
+func EnsureCourseReviewSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS course_reviews (
+			id          BIGSERIAL    PRIMARY KEY,
+			course_id   TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			username    TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			rating      SMALLINT     NOT NULL CHECK (rating BETWEEN 1 AND 5),
+			review      TEXT         NOT NULL DEFAULT '',
+			reply       TEXT         NOT NULL DEFAULT '',
+			reply_by    TEXT         NULL REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			replied_at  TIMESTAMPTZ  NULL,
+			hidden_at   TIMESTAMPTZ  NULL,
+			hidden_by   TEXT         NULL REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			UNIQUE (course_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_course_reviews_user ON course_reviews(username);
+	`)
+	return err
+}
+
+const courseReviewColumns = `
+	r.id, r.course_id, r.username, u.name, r.rating, r.review,
+	r.reply, COALESCE(r.reply_by, ''), r.replied_at,
+	r.hidden_at IS NOT NULL, COALESCE(r.hidden_by, ''), r.created_at, r.updated_at`
+
+func scanCourseReview(row interface{ Scan(dest ...any) error }) (CourseReview, error) {
+	var r CourseReview
+	err := row.Scan(
+		&r.ID, &r.CourseID, &r.Username, &r.Name, &r.Rating, &r.Review,
+		&r.Reply, &r.ReplyBy, &r.RepliedAt,
+		&r.Hidden, &r.HiddenBy, &r.CreatedAt, &r.UpdatedAt,
+	)
+	return r, err
+}
+
+func getCourseReview(where string, args ...any) (CourseReview, error) {
+	return scanCourseReview(db.QueryRow(`
+		SELECT `+courseReviewColumns+`
+		FROM course_reviews r
+		JOIN users u ON u.username = r.username
+		WHERE `+where, args...))
+}
+
+// UpsertCourseReview saves the learner's rating and review of a course they have
+// completed. Editing keeps the moderation state and the instructor reply.
+func UpsertCourseReview(username, courseID string, rating int, review string) (CourseReview, error) {
+	var completedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT completed_at FROM user_course_enrollments
+		WHERE username = $1 AND course_id = $2`, username, courseID,
+	).Scan(&completedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return CourseReview{}, err
+	}
+	if !completedAt.Valid {
+		return CourseReview{}, ErrCourseNotCompleted
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO course_reviews (course_id, username, rating, review)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (course_id, username) DO UPDATE
+			SET rating     = EXCLUDED.rating,
+			    review     = EXCLUDED.review,
+			    updated_at = NOW()`,
+		courseID, username, rating, review); err != nil {
+		return CourseReview{}, err
+	}
+	return GetMyCourseReview(username, courseID)
+}
+
+func GetMyCourseReview(username, courseID string) (CourseReview, error) {
+	return getCourseReview(`r.username = $1 AND r.course_id = $2`, username, courseID)
+}
+
+func DeleteMyCourseReview(username, courseID string) error {
+	result, err := db.Exec(`DELETE FROM course_reviews WHERE username = $1 AND course_id = $2`, username, courseID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListCourseReviews returns reviews of a course, newest first. Hidden reviews are
+// only included for moderators.
+func ListCourseReviews(courseID string, includeHidden bool, limit, offset int) ([]CourseReview, int, error) {
+	fb := newFilterBuilder(`WHERE r.course_id = $1`, courseID)
+	if !includeHidden {
+		fb.add(` AND r.hidden_at IS NULL`)
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM course_reviews r `+fb.where, fb.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where := fb.where
+	pagination := fb.limitOffset(limit, offset)
+	rows, err := db.Query(`
+		SELECT `+courseReviewColumns+`
+		FROM course_reviews r
+		JOIN users u ON u.username = r.username
+		`+where+`
+		ORDER BY r.created_at DESC, r.id DESC`+pagination, fb.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	result := make([]CourseReview, 0)
+	for rows.Next() {
+		r, err := scanCourseReview(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, r)
+	}
+	return result, total, rows.Err()
+}
+
+// GetCourseRatingSummary aggregates the visible ratings of a course.
+func GetCourseRatingSummary(courseID string) (CourseRatingSummary, error) {
+	summary := CourseRatingSummary{Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	rows, err := db.Query(`
+		SELECT rating, COUNT(*) FROM course_reviews
+		WHERE course_id = $1 AND hidden_at IS NULL
+		GROUP BY rating`, courseID)
+	if err != nil {
+		return summary, err
+	}
+	defer rows.Close()
+
+	sum := 0
+	for rows.Next() {
+		var rating, count int
+		if err := rows.Scan(&rating, &count); err != nil {
+			return summary, err
+		}
+		summary.Distribution[rating] = count
+		summary.Count += count
+		sum += rating * count
+	}
+	if summary.Count > 0 {
+		summary.Average = math.Round(float64(sum)/float64(summary.Count)*100) / 100
+	}
+	return summary, rows.Err()
+}
+
+// SetCourseReviewHidden hides or restores a review of the course.
+func SetCourseReviewHidden(courseID string, reviewID int64, hidden bool, moderator string) (CourseReview, error) {
+	query := `UPDATE course_reviews SET hidden_at = NULL, hidden_by = NULL WHERE id = $1 AND course_id = $2`
+	args := []any{reviewID, courseID}
+	if hidden {
+		query = `UPDATE course_reviews SET hidden_at = NOW(), hidden_by = $3 WHERE id = $1 AND course_id = $2`
+		args = append(args, moderator)
+	}
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return CourseReview{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return CourseReview{}, sql.ErrNoRows
+	}
+	return getCourseReview(`r.id = $1`, reviewID)
+}
+
+// ReplyToCourseReview sets the instructor reply of a review; an empty reply removes
+// it. Only the course owner or an admin may reply.
+func ReplyToCourseReview(courseID string, reviewID int64, reply, callerUsername string, isAdmin bool) (CourseReview, error) {
+	var ownerUsername sql.NullString
+	err := db.QueryRow(`SELECT owner_username FROM courses WHERE id = $1`, courseID).Scan(&ownerUsername)
+	if err != nil {
+		return CourseReview{}, err
+	}
+	if !isAdmin && (!ownerUsername.Valid || ownerUsername.String != callerUsername) {
+		return CourseReview{}, ErrForbidden
+	}
+
+	var result sql.Result
+	if reply == "" {
+		result, err = db.Exec(`
+			UPDATE course_reviews SET reply = '', reply_by = NULL, replied_at = NULL
+			WHERE id = $1 AND course_id = $2`, reviewID, courseID)
+	} else {
+		result, err = db.Exec(`
+			UPDATE course_reviews SET reply = $3, reply_by = $4, replied_at = NOW()
+			WHERE id = $1 AND course_id = $2`, reviewID, courseID, reply, callerUsername)
+	}
+	if err != nil {
+		return CourseReview{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return CourseReview{}, sql.ErrNoRows
+	}
+	return getCourseReview(`r.id = $1`, reviewID)
+}
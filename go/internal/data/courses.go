@@ -6,7 +6,33 @@ import (
 	"strings"
 )
 
-func ListCourses(limit, offset int) ([]Course, int, error) {
+// Course catalog sort keys accepted by ListCourses.
+const (
+	CourseSortPopular = "popular"
+	CourseSortRating  = "rating"
+	CourseSortNewest  = "newest"
+)
+
+var courseSortOrders = map[string]string{
+	CourseSortPopular: `learner_count DESC, c.created_at DESC`,
+	CourseSortRating:  `rating_average DESC, rating_count DESC, learner_count DESC, c.created_at DESC`,
+	CourseSortNewest:  `c.created_at DESC`,
+}
+
+// IsValidCourseSort reports whether sort is a ListCourses sort key.
+func IsValidCourseSort(sort string) bool {
+	_, ok := courseSortOrders[sort]
+	return ok
+}
+
+// ListCourses returns a page of the catalog. Unknown sort keys fall back to
+// CourseSortPopular.
+func ListCourses(sort string, limit, offset int) ([]Course, int, error) {
+	orderBy, ok := courseSortOrders[sort]
+	if !ok {
+		orderBy = courseSortOrders[CourseSortPopular]
+	}
+
 	var total int
 	if err := db.QueryRow(`SELECT COUNT(*) FROM courses`).Scan(&total); err != nil {
 		return nil, 0, err
@@ -16,12 +42,20 @@ func ListCourses(limit, offset int) ([]Course, int, error) {
 		SELECT c.id, c.title, c.creator, COALESCE(c.owner_username, ''), c.status,
 		       COALESCE(c.visibility, 'public'), COALESCE(c.allowed_usernames, '{}'),
 		       c.description, c.image, c.content,
-		       c.skill_points, c.subtopic_completion_score, c.course_completion_score, c.created_at,
-		       COUNT(DISTINCT e.username) AS learner_count
+		       c.skill_points, c.subtopic_completion_score, c.course_completion_score, c.validity_days,
+		       c.enrollment_mode, c.capacity, c.created_at,
+		       COUNT(DISTINCT e.username) AS learner_count,
+		       COALESCE(r.rating_average, 0) AS rating_average, COALESCE(r.rating_count, 0) AS rating_count
 		FROM courses c
 		LEFT JOIN user_course_enrollments e ON e.course_id = c.id
-		GROUP BY c.id
-		ORDER BY learner_count DESC, c.created_at DESC
+		LEFT JOIN (
+			SELECT course_id, ROUND(AVG(rating), 2)::float8 AS rating_average, COUNT(*) AS rating_count
+			FROM course_reviews
+			WHERE hidden_at IS NULL
+			GROUP BY course_id
+		) r ON r.course_id = c.id
+		GROUP BY c.id, r.rating_average, r.rating_count
+		ORDER BY `+orderBy+`
 		LIMIT $1 OFFSET $2`, limit, offset)
 	if err != nil {
 		return nil, 0, err
@@ -36,8 +70,9 @@ func ListCourses(limit, offset int) ([]Course, int, error) {
 			&c.ID, &c.Title, &c.Creator, &c.OwnerUsername, &c.Status,
 			&c.Visibility, (*StringArray)(&c.AllowedUsernames),
 			&c.Description, &c.Image, &c.Content,
-			&c.SkillPoints, &c.SubtopicCompletionScore, &c.CourseCompletionScore, &c.CreatedAt,
-			&c.LearnerCount,
+			&c.SkillPoints, &c.SubtopicCompletionScore, &c.CourseCompletionScore, &c.ValidityDays,
+			&c.EnrollmentMode, &c.Capacity, &c.CreatedAt,
+			&c.LearnerCount, &c.RatingAverage, &c.RatingCount,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -77,6 +112,8 @@ func ListCourses(limit, offset int) ([]Course, int, error) {
 	return courses, total, nil
 }
 
+// UpsertCourse creates or updates a course. An empty EnrollmentMode and a negative
+// Capacity keep the course's current settings.
 func UpsertCourse(c Course, callerUsername string, isAdmin bool) (Course, error) {
 	// Check duplicate title
 	title := strings.TrimSpace(c.Title)
@@ -118,6 +155,9 @@ func UpsertCourse(c Course, callerUsername string, isAdmin bool) (Course, error)
 	if c.AllowedUsernames == nil {
 		c.AllowedUsernames = []string{}
 	}
+	if c.EnrollmentMode != "" && !IsValidEnrollmentMode(c.EnrollmentMode) {
+		return Course{}, ErrInvalidEnrollmentMode
+	}
 
 	// Use a transaction for the multi-step upsert
 	tx, err := db.Begin()
@@ -129,8 +169,9 @@ func UpsertCourse(c Course, callerUsername string, isAdmin bool) (Course, error)
 	err = tx.QueryRow(`
 		INSERT INTO courses (id, title, creator, owner_username, status, visibility, allowed_usernames,
 		                     description, image, content,
-		                     skill_points, subtopic_completion_score, course_completion_score)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		                     skill_points, subtopic_completion_score, course_completion_score, validity_days,
+		                     enrollment_mode, capacity)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,COALESCE(NULLIF($15, ''), 'open'),GREATEST($16, 0))
 		ON CONFLICT (id) DO UPDATE SET
 			title                     = EXCLUDED.title,
 			creator                   = EXCLUDED.creator,
@@ -142,19 +183,25 @@ func UpsertCourse(c Course, callerUsername string, isAdmin bool) (Course, error)
 			content                   = EXCLUDED.content,
 			skill_points              = EXCLUDED.skill_points,
 			subtopic_completion_score = EXCLUDED.subtopic_completion_score,
-			course_completion_score   = EXCLUDED.course_completion_score
+			course_completion_score   = EXCLUDED.course_completion_score,
+			validity_days             = EXCLUDED.validity_days,
+			enrollment_mode           = COALESCE(NULLIF($15, ''), courses.enrollment_mode),
+			capacity                  = CASE WHEN $16 < 0 THEN courses.capacity ELSE $16 END
 		RETURNING id, title, creator, COALESCE(owner_username, ''), status,
 		          COALESCE(visibility, 'public'), COALESCE(allowed_usernames, '{}'),
 		          description, image, content,
-		          skill_points, subtopic_completion_score, course_completion_score, created_at`,
+		          skill_points, subtopic_completion_score, course_completion_score, validity_days,
+		          enrollment_mode, capacity, created_at`,
 		c.ID, c.Title, c.Creator, ownerPtr, c.Status, c.Visibility, StringArray(c.AllowedUsernames),
 		c.Description, c.Image, c.Content,
-		c.SkillPoints, c.SubtopicCompletionScore, c.CourseCompletionScore,
+		c.SkillPoints, c.SubtopicCompletionScore, c.CourseCompletionScore, c.ValidityDays,
+		c.EnrollmentMode, c.Capacity,
 	).Scan(
 		&c.ID, &c.Title, &c.Creator, &c.OwnerUsername, &c.Status,
 		&c.Visibility, (*StringArray)(&c.AllowedUsernames),
 		&c.Description, &c.Image, &c.Content,
-		&c.SkillPoints, &c.SubtopicCompletionScore, &c.CourseCompletionScore, &c.CreatedAt,
+		&c.SkillPoints, &c.SubtopicCompletionScore, &c.CourseCompletionScore, &c.ValidityDays,
+		&c.EnrollmentMode, &c.Capacity, &c.CreatedAt,
 	)
 	if err != nil {
 		return Course{}, err
@@ -210,6 +257,8 @@ func DeleteCourse(id, callerUsername string, isAdmin bool) error {
 	if !isAdmin && (!ownerUsername.Valid || ownerUsername.String != callerUsername) {
 		return ErrForbidden
 	}
-	_, err = db.Exec(`DELETE FROM courses WHERE id = $1`, id)
-	return err
+	if _, err := db.Exec(`DELETE FROM courses WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return removeItemReferences(PathItemCourse, id)
 }
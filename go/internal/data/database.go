@@ -23,6 +23,18 @@ func IsDuplicateKey(err error) bool {
 	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
 
+// IsForeignKeyViolation returns true when err is a PostgreSQL foreign-key violation (code 23503).
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// IsCheckViolation returns true when err is a PostgreSQL check-constraint violation (code 23514).
+func IsCheckViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23514"
+}
+
 func ConnectPostgres(databaseURL string) error {
 	if strings.TrimSpace(databaseURL) == "" {
 		return errors.New("DATABASE_URL is required")
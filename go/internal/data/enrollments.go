@@ -0,0 +1,593 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Course enrollment modes.
+const (
+	EnrollmentModeOpen       = "open"
+	EnrollmentModeApproval   = "approval"
+	EnrollmentModeInviteOnly = "invite_only"
+	EnrollmentModeCapacity   = "capacity"
+)
+
+// Enrollment states of a learner in a course. Enrolled learners have a row in
+// user_course_enrollments; the other states live in course_enrollment_requests.
+const (
+	EnrollmentStatusNone       = "none"
+	EnrollmentStatusEnrolled   = "enrolled"
+	EnrollmentStatusPending    = "pending"
+	EnrollmentStatusWaitlisted = "waitlisted"
+	EnrollmentStatusInvited    = "invited"
+	EnrollmentStatusRejected   = "rejected"
+)
+
+var (
+	ErrNotEnrolled             = errors.New("enroll in the course first")
+	ErrInviteOnly              = errors.New("this course is invite only")
+	ErrInvalidEnrollmentMode   = errors.New("enrollmentMode must be open, approval, invite_only or capacity")
+	ErrCompletedEnrollment     = errors.New("a completed course cannot be unenrolled")
+	ErrNoPendingEnrollment     = errors.New("no pending enrollment request")
+	ErrInvalidEnrollmentStatus = errors.New("status must be enrolled, pending, waitlisted, invited or rejected")
+)
+
+// CourseEnrollment is one learner's enrollment state in a course.
+type CourseEnrollment struct {
+	CourseID         string     `json:"courseId"`
+	Mode             string     `json:"mode"`
+	Capacity         int        `json:"capacity"` // seats in capacity mode; 0 = unlimited
+	SeatsTaken       int        `json:"seatsTaken"`
+	Status           string     `json:"status"`
+	EnrolledAt       *time.Time `json:"enrolledAt,omitempty"`
+	CompletedAt      *time.Time `json:"completedAt,omitempty"`
+	RequestedAt      *time.Time `json:"requestedAt,omitempty"`
+	WaitlistPosition int        `json:"waitlistPosition,omitempty"` // 1 = next in line
+}
+
+// EnrollmentEntry is a row of a course roster or of a manager's approval queue.
+type EnrollmentEntry struct {
+	CourseID    string     `json:"courseId"`
+	CourseTitle string     `json:"courseTitle"`
+	Username    string     `json:"username"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Since       time.Time  `json:"since"` // enrolled or requested at
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	DecidedBy   string     `json:"decidedBy,omitempty"`
+}
+
+// IsValidEnrollmentMode reports whether mode is one of the enrollment modes.
+func IsValidEnrollmentMode(mode string) bool {
+	switch mode {
+	case EnrollmentModeOpen, EnrollmentModeApproval, EnrollmentModeInviteOnly, EnrollmentModeCapacity:
+		return true
+	}
+	return false
+}
+
+func EnsureEnrollmentSchema() error {
+	_, err := db.Exec(`
+		ALTER TABLE courses ADD COLUMN IF NOT EXISTS enrollment_mode TEXT NOT NULL DEFAULT 'open'
+			CHECK (enrollment_mode IN ('open', 'approval', 'invite_only', 'capacity'));
+		ALTER TABLE courses ADD COLUMN IF NOT EXISTS capacity INT NOT NULL DEFAULT 0 CHECK (capacity >= 0);
+		CREATE TABLE IF NOT EXISTS course_enrollment_requests (
+			course_id    TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			username     TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			status       TEXT         NOT NULL CHECK (status IN ('pending', 'waitlisted', 'invited', 'rejected')),
+			requested_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			decided_by   TEXT         NULL REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			decided_at   TIMESTAMPTZ  NULL,
+			PRIMARY KEY (course_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_enrollment_requests_user ON course_enrollment_requests(username);
+		CREATE INDEX IF NOT EXISTS ix_enrollment_requests_queue
+			ON course_enrollment_requests(course_id, status, requested_at);
+	`)
+	return err
+}
+
+// EnsureEnrollment checks that the learner may use a course. Open courses enroll
+// the learner on first use; other modes require an explicit enrollment
+// and return ErrNotEnrolled otherwise.
+func EnsureEnrollment(username, courseID string) error {
+	var mode string
+	var enrolled bool
+	err := db.QueryRow(`
+		SELECT c.enrollment_mode,
+		       EXISTS (SELECT 1 FROM user_course_enrollments e WHERE e.username = $1 AND e.course_id = c.id)
+		FROM courses c WHERE c.id = $2`, username, courseID,
+	).Scan(&mode, &enrolled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCourseNotFound
+	}
+	if err != nil || enrolled {
+		return err
+	}
+	if mode != EnrollmentModeOpen {
+		return ErrNotEnrolled
+	}
+	_, err = db.Exec(`
+		INSERT INTO user_course_enrollments (username, course_id)
+		VALUES ($1, $2)
+		ON CONFLICT (username, course_id) DO NOTHING`,
+		username, courseID)
+	return err
+}
+
+func GetEnrollment(username, courseID string) (CourseEnrollment, error) {
+	e := CourseEnrollment{CourseID: courseID, Status: EnrollmentStatusNone}
+	var enrolledAt, requestedAt sql.NullTime
+	var requestStatus sql.NullString
+	err := db.QueryRow(`
+		SELECT c.enrollment_mode, c.capacity,
+		       (SELECT COUNT(*) FROM user_course_enrollments s WHERE s.course_id = c.id AND s.completed_at IS NULL),
+		       e.enrolled_at, e.completed_at, r.status, r.requested_at,
+		       CASE WHEN r.status = 'waitlisted' THEN
+		           (SELECT COUNT(*) FROM course_enrollment_requests w
+		            WHERE w.course_id = c.id AND w.status = 'waitlisted' AND w.requested_at <= r.requested_at)
+		       ELSE 0 END
+		FROM courses c
+		LEFT JOIN user_course_enrollments e ON e.course_id = c.id AND e.username = $1
+		LEFT JOIN course_enrollment_requests r ON r.course_id = c.id AND r.username = $1
+		WHERE c.id = $2`, username, courseID,
+	).Scan(&e.Mode, &e.Capacity, &e.SeatsTaken, &enrolledAt, &e.CompletedAt, &requestStatus, &requestedAt, &e.WaitlistPosition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, ErrCourseNotFound
+	}
+	if err != nil {
+		return e, err
+	}
+	switch {
+	case enrolledAt.Valid:
+		e.Status = EnrollmentStatusEnrolled
+		e.EnrolledAt = &enrolledAt.Time
+	case requestStatus.Valid:
+		e.Status = requestStatus.String
+		e.RequestedAt = &requestedAt.Time
+	}
+	return e, nil
+}
+
+// enrollTx enrolls the learner and drops any open request of theirs.
+func enrollTx(tx *sql.Tx, username, courseID string) error {
+	if _, err := tx.Exec(`
+		INSERT INTO user_course_enrollments (username, course_id)
+		VALUES ($1, $2)
+		ON CONFLICT (username, course_id) DO NOTHING`, username, courseID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM course_enrollment_requests WHERE course_id = $1 AND username = $2`, courseID, username)
+	return err
+}
+
+// lockCourseForEnrollment locks the course row so seat counts stay consistent.
+func lockCourseForEnrollment(tx *sql.Tx, courseID string) (mode string, capacity int, err error) {
+	err = tx.QueryRow(`SELECT enrollment_mode, capacity FROM courses WHERE id = $1 FOR UPDATE`, courseID).Scan(&mode, &capacity)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrCourseNotFound
+	}
+	return mode, capacity, err
+}
+
+// Enroll handles a learner's enrollment request according to the course mode:
+// open courses enroll at once, approval courses create a pending request, capacity
+// courses enroll while seats remain and waitlist afterwards, and invite-only courses
+// accept invited learners only. An invitation enrolls in every mode.
+func Enroll(username, courseID string) (CourseEnrollment, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return CourseEnrollment{}, err
+	}
+	defer tx.Rollback()
+
+	mode, capacity, err := lockCourseForEnrollment(tx, courseID)
+	if err != nil {
+		return CourseEnrollment{}, err
+	}
+	var enrolled bool
+	var requestStatus sql.NullString
+	if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM user_course_enrollments WHERE username = $1 AND course_id = $2),
+		       (SELECT status FROM course_enrollment_requests WHERE username = $1 AND course_id = $2)`,
+		username, courseID,
+	).Scan(&enrolled, &requestStatus); err != nil {
+		return CourseEnrollment{}, err
+	}
+
+	if !enrolled {
+		queue := ""
+		switch {
+		case requestStatus.String == EnrollmentStatusInvited, mode == EnrollmentModeOpen:
+		case mode == EnrollmentModeApproval:
+			queue = EnrollmentStatusPending
+		case mode == EnrollmentModeInviteOnly:
+			return CourseEnrollment{}, ErrInviteOnly
+		case mode == EnrollmentModeCapacity:
+			var seatsTaken int
+			if err := tx.QueryRow(`
+				SELECT COUNT(*) FROM user_course_enrollments WHERE course_id = $1 AND completed_at IS NULL`,
+				courseID).Scan(&seatsTaken); err != nil {
+				return CourseEnrollment{}, err
+			}
+			if capacity > 0 && seatsTaken >= capacity {
+				queue = EnrollmentStatusWaitlisted
+			}
+		}
+
+		if queue == "" {
+			err = enrollTx(tx, username, courseID)
+		} else if requestStatus.String != queue {
+			// A new or rejected request joins the back of the queue.
+			_, err = tx.Exec(`
+				INSERT INTO course_enrollment_requests (course_id, username, status)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (course_id, username) DO UPDATE
+					SET status = EXCLUDED.status, requested_at = NOW(), decided_by = NULL, decided_at = NULL`,
+				courseID, username, queue)
+		}
+		if err != nil {
+			return CourseEnrollment{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return CourseEnrollment{}, err
+	}
+	return GetEnrollment(username, courseID)
+}
+
+// Unenroll removes the learner from a course, or withdraws their open request or
+// invitation. A seat freed in a capacity course goes to the head of the waitlist;
+// the promoted usernames are returned.
+func Unenroll(username, courseID string) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockCourseForEnrollment(tx, courseID); err != nil {
+		return nil, err
+	}
+	var completedAt sql.NullTime
+	err = tx.QueryRow(`
+		SELECT completed_at FROM user_course_enrollments WHERE username = $1 AND course_id = $2`,
+		username, courseID).Scan(&completedAt)
+	switch {
+	case err == nil:
+		if completedAt.Valid {
+			return nil, ErrCompletedEnrollment
+		}
+		if _, err := tx.Exec(`DELETE FROM user_course_enrollments WHERE username = $1 AND course_id = $2`, username, courseID); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		result, err := tx.Exec(`
+			DELETE FROM course_enrollment_requests
+			WHERE username = $1 AND course_id = $2 AND status <> 'rejected'`, username, courseID)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil, ErrNotEnrolled
+		}
+	default:
+		return nil, err
+	}
+
+	promoted, err := promoteWaitlist(tx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return promoted, tx.Commit()
+}
+
+// promoteWaitlist fills free seats of a capacity course from its waitlist, first
+// come first served. A course switched to open mode takes the whole waitlist. The
+// course row must be locked by the caller.
+func promoteWaitlist(tx *sql.Tx, courseID string) ([]string, error) {
+	var free int
+	err := tx.QueryRow(`
+		SELECT CASE WHEN c.enrollment_mode = 'open' OR (c.enrollment_mode = 'capacity' AND c.capacity = 0)
+		                 THEN 2147483647
+		            WHEN c.enrollment_mode = 'capacity'
+		                 THEN c.capacity - (SELECT COUNT(*) FROM user_course_enrollments e
+		                                    WHERE e.course_id = c.id AND e.completed_at IS NULL)
+		            ELSE 0 END
+		FROM courses c WHERE c.id = $1`, courseID,
+	).Scan(&free)
+	if err != nil || free <= 0 {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT username FROM course_enrollment_requests
+		WHERE course_id = $1 AND status = 'waitlisted'
+		ORDER BY requested_at
+		LIMIT $2`, courseID, free)
+	if err != nil {
+		return nil, err
+	}
+	var promoted []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		promoted = append(promoted, username)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, username := range promoted {
+		if err := enrollTx(tx, username, courseID); err != nil {
+			return nil, err
+		}
+	}
+	return promoted, nil
+}
+
+// PromoteWaitlist fills seats freed by a course change, such as a higher capacity.
+func PromoteWaitlist(courseID string) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockCourseForEnrollment(tx, courseID); err != nil {
+		return nil, err
+	}
+	promoted, err := promoteWaitlist(tx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return promoted, tx.Commit()
+}
+
+// canDecideEnrollment reports whether decider may approve requests of username for
+// the course: its owner or one of the learner's (indirect) managers.
+func canDecideEnrollment(tx *sql.Tx, courseID, username, decider string) (bool, error) {
+	var allowed bool
+	err := tx.QueryRow(teamCTE+`
+		SELECT EXISTS (SELECT 1 FROM courses WHERE id = $3 AND owner_username = $1)
+		    OR EXISTS (SELECT 1 FROM team WHERE username = $2)`,
+		decider, username, courseID,
+	).Scan(&allowed)
+	return allowed, err
+}
+
+// DecideEnrollmentRequest approves or rejects a pending request. Admins, the course
+// owner and the learner's managers may decide.
+func DecideEnrollmentRequest(courseID, username string, approve bool, decider string, isAdmin bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockCourseForEnrollment(tx, courseID); err != nil {
+		return err
+	}
+	var status string
+	err = tx.QueryRow(`
+		SELECT status FROM course_enrollment_requests
+		WHERE course_id = $1 AND username = $2 FOR UPDATE`, courseID, username).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && status != EnrollmentStatusPending) {
+		return ErrNoPendingEnrollment
+	}
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		allowed, err := canDecideEnrollment(tx, courseID, username, decider)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrForbidden
+		}
+	}
+
+	if approve {
+		err = enrollTx(tx, username, courseID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE course_enrollment_requests
+			SET status = 'rejected', decided_by = $3, decided_at = NOW()
+			WHERE course_id = $1 AND username = $2`, courseID, username, decider)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const enrollmentEntrySelect = `
+	SELECT * FROM (
+		SELECT e.course_id, c.title, e.username, u.name, 'enrolled' AS status, e.enrolled_at AS since,
+		       e.completed_at, '' AS decided_by
+		FROM user_course_enrollments e
+		JOIN courses c ON c.id = e.course_id
+		JOIN users u ON u.username = e.username
+		UNION ALL
+		SELECT r.course_id, c.title, r.username, u.name, r.status, r.requested_at,
+		       NULL, COALESCE(r.decided_by, '')
+		FROM course_enrollment_requests r
+		JOIN courses c ON c.id = r.course_id
+		JOIN users u ON u.username = r.username
+	) x`
+
+func scanEnrollmentEntries(rows *sql.Rows) ([]EnrollmentEntry, error) {
+	defer rows.Close()
+	result := make([]EnrollmentEntry, 0)
+	for rows.Next() {
+		var e EnrollmentEntry
+		if err := rows.Scan(&e.CourseID, &e.CourseTitle, &e.Username, &e.Name, &e.Status, &e.Since, &e.CompletedAt, &e.DecidedBy); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// ListCourseEnrollments returns the roster of a course, optionally narrowed to one
+// status, oldest first. Only the course owner or an admin may list it.
+func ListCourseEnrollments(courseID, status, callerUsername string, isAdmin bool, limit, offset int) ([]EnrollmentEntry, int, error) {
+	if status != "" && status != EnrollmentStatusEnrolled && status != EnrollmentStatusPending &&
+		status != EnrollmentStatusWaitlisted && status != EnrollmentStatusInvited && status != EnrollmentStatusRejected {
+		return nil, 0, ErrInvalidEnrollmentStatus
+	}
+	var ownerUsername sql.NullString
+	if err := db.QueryRow(`SELECT owner_username FROM courses WHERE id = $1`, courseID).Scan(&ownerUsername); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrCourseNotFound
+		}
+		return nil, 0, err
+	}
+	if !isAdmin && (!ownerUsername.Valid || ownerUsername.String != callerUsername) {
+		return nil, 0, ErrForbidden
+	}
+
+	fb := newFilterBuilder(`WHERE x.course_id = $1`, courseID)
+	if status != "" {
+		fb.add(` AND x.status = $%d`, status)
+	}
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM (`+enrollmentEntrySelect+` `+fb.where+`) t`, fb.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	where := fb.where
+	pagination := fb.limitOffset(limit, offset)
+	rows, err := db.Query(enrollmentEntrySelect+` `+where+` ORDER BY x.since, x.username`+pagination, fb.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries, err := scanEnrollmentEntries(rows)
+	return entries, total, err
+}
+
+// ListTeamEnrollmentRequests returns pending requests of the manager's direct and
+// indirect reports, oldest first.
+func ListTeamEnrollmentRequests(manager string) ([]EnrollmentEntry, error) {
+	rows, err := db.Query(teamCTE+enrollmentEntrySelect+`
+		WHERE x.status = 'pending' AND x.username IN (SELECT username FROM team)
+		ORDER BY x.since, x.username`, manager)
+	if err != nil {
+		return nil, err
+	}
+	return scanEnrollmentEntries(rows)
+}
+
+// BulkEnroll enrolls the users in a course regardless of its mode and capacity, or
+// with invite set, invites those not enrolled yet. Unknown usernames reject the
+// whole call. It returns the users whose state changed.
+func BulkEnroll(courseID string, usernames []string, invite bool) ([]string, error) {
+	normalized := StringArray{}
+	for _, u := range usernames {
+		if u = NormalizeUsername(u); u != "" {
+			normalized = append(normalized, u)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockCourseForEnrollment(tx, courseID); err != nil {
+		return nil, err
+	}
+	var missing StringArray
+	if err := tx.QueryRow(`
+		SELECT COALESCE(array_agg(u), '{}') FROM unnest($1::text[]) AS u
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE username = u)`, normalized,
+	).Scan(&missing); err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownUsername, strings.Join(missing, ", "))
+	}
+
+	query := `
+		INSERT INTO user_course_enrollments (username, course_id)
+		SELECT DISTINCT u, $1 FROM unnest($2::text[]) AS u
+		ON CONFLICT (username, course_id) DO NOTHING
+		RETURNING username`
+	if invite {
+		query = `
+			INSERT INTO course_enrollment_requests (course_id, username, status)
+			SELECT DISTINCT $1, u, 'invited' FROM unnest($2::text[]) AS u
+			WHERE NOT EXISTS (SELECT 1 FROM user_course_enrollments e WHERE e.course_id = $1 AND e.username = u)
+			ON CONFLICT (course_id, username) DO UPDATE
+				SET status = 'invited', requested_at = NOW(), decided_by = NULL, decided_at = NULL
+				WHERE course_enrollment_requests.status <> 'invited'
+			RETURNING username`
+	}
+	rows, err := tx.Query(query, courseID, normalized)
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0)
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		changed = append(changed, username)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !invite {
+		if _, err := tx.Exec(`
+			DELETE FROM course_enrollment_requests
+			WHERE course_id = $1 AND username = ANY($2::text[])`, courseID, normalized); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// UnenrollUser removes a user from a course on behalf of an admin, completed or not,
+// and returns the users promoted from the waitlist.
+func UnenrollUser(username, courseID string) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockCourseForEnrollment(tx, courseID); err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec(`DELETE FROM user_course_enrollments WHERE username = $1 AND course_id = $2`, username, courseID)
+	if err != nil {
+		return nil, err
+	}
+	removed, _ := result.RowsAffected()
+	result, err = tx.Exec(`DELETE FROM course_enrollment_requests WHERE username = $1 AND course_id = $2`, username, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); removed+n == 0 {
+		return nil, ErrNotEnrolled
+	}
+	promoted, err := promoteWaitlist(tx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	return promoted, tx.Commit()
+}
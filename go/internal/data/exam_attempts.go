@@ -4,9 +4,13 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
 )
 
+// ExamPassPercent is the score at or above which an attempt counts as passed.
+const ExamPassPercent = 60
+
 // ExamAttemptFilter holds optional filter parameters for exam attempt queries.
 type ExamAttemptFilter struct {
 	Search    string // search by name or employee code (admin) or exam title (self)
@@ -62,11 +66,20 @@ func (fb *filterBuilder) limitOffset(limit, offset int) string {
 	return clause
 }
 
+// CheckExamAttemptLimit returns the exam's attempt limit and how many attempts count
+// against it. For exams with a validity period, once the latest pass has expired only
+// attempts started after the expiry count, so the exam can be retaken to recertify.
 func CheckExamAttemptLimit(examID, username string) (maxAttempts int, currentCount int, err error) {
 	err = db.QueryRow(`
 		SELECT e.max_attempts, COUNT(ea.id)
 		FROM exams e
+		LEFT JOIN LATERAL (
+			SELECT MAX(r.completed_at) + make_interval(days => e.validity_days) AS expired_at
+			FROM completion_records r
+			WHERE r.username = $2 AND r.item_type = 'exam' AND r.item_id = e.id
+		) pass ON e.validity_days > 0
 		LEFT JOIN exam_attempts ea ON ea.exam_id = e.id AND ea.username = $2
+		     AND (pass.expired_at IS NULL OR pass.expired_at > NOW() OR ea.started_at > pass.expired_at)
 		WHERE e.id = $1
 		GROUP BY e.max_attempts`, examID, username,
 	).Scan(&maxAttempts, &currentCount)
@@ -131,9 +144,23 @@ func SaveExamAttempt(examID, username string, correctCount, totalQuestions int,
 		}
 	}
 
+	if attempt.ScorePercent >= ExamPassPercent {
+		if err = recordCompletion(tx, username, "exam", examID, &attempt.ID); err != nil {
+			return ExamAttempt{}, err
+		}
+		if attempt.Reward, err = awardExamPass(tx, username, examID, attempt.ID, attempt.DomainStats); err != nil {
+			return ExamAttempt{}, fmt.Errorf("cannot award exam rewards: %w", err)
+		}
+	}
+
 	if err = tx.Commit(); err != nil {
 		return ExamAttempt{}, err
 	}
+	if attempt.ScorePercent >= ExamPassPercent {
+		if err := NotifyCertificateIssued(username, PathItemExam, examID); err != nil {
+			log.Printf("notify certificate for %s/%s: %v", username, examID, err)
+		}
+	}
 
 	attempt.Details = []ExamAttemptAnswer{}
 	return attempt, nil
@@ -0,0 +1,219 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidExamReward = errors.New("invalid exam reward")
+
+// ExamSkillReward grants skill points for passing an exam. When Domain is set the
+// points are scaled by the share of that domain's questions the passing attempt
+// answered correctly.
+type ExamSkillReward struct {
+	Skill  string `json:"skill"`
+	Domain string `json:"domain"`
+	Points int    `json:"points"`
+}
+
+// ExamReward is what the first passing attempt of an exam earned.
+type ExamReward struct {
+	Points       int           `json:"points"`
+	SkillRewards []SkillReward `json:"skillRewards"`
+}
+
+// EnsureExamRewardSchema adds point and skill rewards to exams. Rewards are paid
+// once per user and exam, marked in exam_pass_awards; users who passed before
+// rewards existed are marked as already paid so that a retake earns nothing.
+func EnsureExamRewardSchema() error {
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('exam_pass_awards') IS NOT NULL`).Scan(&exists); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		ALTER TABLE exams ADD COLUMN IF NOT EXISTS completion_score INT NOT NULL DEFAULT 0 CHECK (completion_score >= 0);
+		ALTER TABLE user_score_events ADD COLUMN IF NOT EXISTS exam_id TEXT NULL;
+		CREATE TABLE IF NOT EXISTS exam_skill_rewards (
+			exam_id TEXT  NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+			skill   TEXT  NOT NULL,
+			domain  TEXT  NOT NULL DEFAULT '',
+			points  INT   NOT NULL CHECK (points > 0),
+			PRIMARY KEY (exam_id, skill, domain)
+		);
+		CREATE TABLE IF NOT EXISTS exam_pass_awards (
+			username   TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			exam_id    TEXT         NOT NULL REFERENCES exams(id) ON DELETE CASCADE,
+			attempt_id BIGINT       NULL REFERENCES exam_attempts(id) ON DELETE SET NULL,
+			awarded_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (username, exam_id)
+		);
+	`); err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := tx.Exec(`
+			INSERT INTO exam_pass_awards (username, exam_id, attempt_id, awarded_at)
+			SELECT DISTINCT ON (username, item_id) username, item_id, attempt_id, completed_at
+			FROM completion_records
+			WHERE item_type = 'exam'
+			ORDER BY username, item_id, completed_at
+			ON CONFLICT DO NOTHING`); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ResolveExamSkillRewards resolves reward skills like ResolveSkillRewards. A
+// skill may appear once per domain; domains must be ones the exam has questions
+// or a percentage for.
+func ResolveExamSkillRewards(rewards []ExamSkillReward, domains map[string]bool) ([]ExamSkillReward, error) {
+	result := make([]ExamSkillReward, 0, len(rewards))
+	seen := make(map[[2]string]bool)
+	for _, r := range rewards {
+		if r.Points <= 0 {
+			return nil, fmt.Errorf("%w: points for %s must be > 0", ErrInvalidExamReward, r.Skill)
+		}
+		domain := strings.TrimSpace(r.Domain)
+		if domain != "" && !domains[domain] {
+			return nil, fmt.Errorf("%w: the exam has no domain %s", ErrInvalidExamReward, domain)
+		}
+		name, err := ResolveSkill(r.Skill)
+		if err != nil {
+			return nil, err
+		}
+		key := [2]string{skillKey(name), domain}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s is listed more than once for the same domain", ErrInvalidExamReward, name)
+		}
+		seen[key] = true
+		result = append(result, ExamSkillReward{Skill: name, Domain: domain, Points: r.Points})
+	}
+	return result, nil
+}
+
+// loadExamSkillRewards returns the skill rewards of the given exams keyed by exam.
+func loadExamSkillRewards(examIDs []string) (map[string][]ExamSkillReward, error) {
+	rows, err := db.Query(`
+		SELECT exam_id, skill, domain, points FROM exam_skill_rewards
+		WHERE exam_id = ANY($1)
+		ORDER BY exam_id, skill, domain`, StringArray(examIDs))
+	if err != nil {
+		return nil, fmt.Errorf("cannot load exam skill rewards: %w", err)
+	}
+	defer rows.Close()
+	rewards := make(map[string][]ExamSkillReward)
+	for rows.Next() {
+		var examID string
+		var r ExamSkillReward
+		if err := rows.Scan(&examID, &r.Skill, &r.Domain, &r.Points); err != nil {
+			return nil, fmt.Errorf("cannot scan exam skill reward: %w", err)
+		}
+		rewards[examID] = append(rewards[examID], r)
+	}
+	return rewards, rows.Err()
+}
+
+// examSkillRewards returns one exam's skill rewards.
+func examSkillRewards(examID string) ([]ExamSkillReward, error) {
+	rewards, err := loadExamSkillRewards([]string{examID})
+	if err != nil {
+		return nil, err
+	}
+	if rewards[examID] == nil {
+		return []ExamSkillReward{}, nil
+	}
+	return rewards[examID], nil
+}
+
+// replaceExamSkillRewards stores the exam's skill rewards in place of the old ones.
+func replaceExamSkillRewards(tx *sql.Tx, examID string, rewards []ExamSkillReward) error {
+	if _, err := tx.Exec(`DELETE FROM exam_skill_rewards WHERE exam_id = $1`, examID); err != nil {
+		return err
+	}
+	for _, r := range rewards {
+		if _, err := tx.Exec(
+			`INSERT INTO exam_skill_rewards (exam_id, skill, domain, points) VALUES ($1,$2,$3,$4)`,
+			examID, r.Skill, r.Domain, r.Points,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// awardExamPass pays the exam's rewards for a passing attempt, unless the user
+// already passed it before. It returns nil when nothing was paid.
+func awardExamPass(tx *sql.Tx, username, examID string, attemptID int64, domainStats map[string]ExamDomainStat) (*ExamReward, error) {
+	result, err := tx.Exec(`
+		INSERT INTO exam_pass_awards (username, exam_id, attempt_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (username, exam_id) DO NOTHING`,
+		username, examID, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, nil
+	}
+
+	reward := ExamReward{SkillRewards: []SkillReward{}}
+	if err := tx.QueryRow(`SELECT completion_score FROM exams WHERE id = $1`, examID).Scan(&reward.Points); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(`SELECT skill, domain, points FROM exam_skill_rewards WHERE exam_id = $1 ORDER BY skill, domain`, examID)
+	if err != nil {
+		return nil, err
+	}
+	// A skill rewarded for several domains is paid as one entry.
+	index := make(map[string]int)
+	for rows.Next() {
+		var r ExamSkillReward
+		if err := rows.Scan(&r.Skill, &r.Domain, &r.Points); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("cannot scan exam skill reward: %w", err)
+		}
+		points := scaledExamReward(r, domainStats)
+		if points <= 0 {
+			continue
+		}
+		if i, ok := index[r.Skill]; ok {
+			reward.SkillRewards[i].Points += points
+			continue
+		}
+		index[r.Skill] = len(reward.SkillRewards)
+		reward.SkillRewards = append(reward.SkillRewards, SkillReward{Skill: r.Skill, Points: points})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	source := ScoreEvent{Username: username, Reason: ScoreReasonExam, ExamID: examID}
+	if err := awardScores(tx, source, reward.Points, reward.SkillRewards); err != nil {
+		return nil, err
+	}
+	return &reward, nil
+}
+
+// scaledExamReward is the reward's points, scaled by the domain result when the
+// reward is tied to a domain. A domain without graded questions earns nothing.
+func scaledExamReward(r ExamSkillReward, domainStats map[string]ExamDomainStat) int {
+	if r.Domain == "" {
+		return r.Points
+	}
+	ds := domainStats[r.Domain]
+	if ds.Total <= 0 {
+		return 0
+	}
+	return (r.Points*ds.Correct + ds.Total/2) / ds.Total
+}
@@ -19,7 +19,7 @@ func ListExams(limit, offset int) ([]Exam, int, error) {
 		SELECT ex.id, ex.title, ex.creator, COALESCE(ex.owner_username, ''), ex.status,
 		       COALESCE(ex.visibility, 'public'), COALESCE(ex.allowed_usernames, '{}'),
 		       ex.description, ex.instructions, ex.image,
-		       ex.number_of_questions, ex.default_time, ex.max_attempts, ex.created_at,
+		       ex.number_of_questions, ex.default_time, ex.max_attempts, ex.validity_days, ex.completion_score, ex.created_at,
 		       COUNT(DISTINCT ea.username) AS attempt_count
 		FROM exams ex
 		LEFT JOIN exam_attempts ea ON ea.exam_id = ex.id
@@ -39,7 +39,7 @@ func ListExams(limit, offset int) ([]Exam, int, error) {
 			&e.ID, &e.Title, &e.Creator, &e.OwnerUsername, &e.Status,
 			&e.Visibility, (*StringArray)(&e.AllowedUsernames),
 			&e.Description, &e.Instructions, &e.Image,
-			&e.NumberOfQuestions, &e.DefaultTime, &e.MaxAttempts, &e.CreatedAt,
+			&e.NumberOfQuestions, &e.DefaultTime, &e.MaxAttempts, &e.ValidityDays, &e.CompletionScore, &e.CreatedAt,
 			&e.AttemptCount,
 		); err != nil {
 			return nil, 0, err
@@ -76,6 +76,18 @@ func ListExams(limit, offset int) ([]Exam, int, error) {
 				exams[idx].DomainPercentages[domain] = pct
 			}
 		}
+		rewards, err := loadExamSkillRewards(ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range exams {
+			exams[i].SkillRewards = rewards[exams[i].ID]
+		}
+	}
+	for i := range exams {
+		if exams[i].SkillRewards == nil {
+			exams[i].SkillRewards = []ExamSkillReward{}
+		}
 	}
 
 	return exams, total, nil
@@ -87,13 +99,13 @@ func GetExam(id string) (*Exam, error) {
 		SELECT id, title, creator, COALESCE(owner_username, ''), status,
 		       COALESCE(visibility, 'public'), COALESCE(allowed_usernames, '{}'),
 		       description, instructions, image,
-		       number_of_questions, default_time, max_attempts, created_at
+		       number_of_questions, default_time, max_attempts, validity_days, completion_score, created_at
 		FROM exams WHERE id = $1`, id,
 	).Scan(
 		&e.ID, &e.Title, &e.Creator, &e.OwnerUsername, &e.Status,
 		&e.Visibility, (*StringArray)(&e.AllowedUsernames),
 		&e.Description, &e.Instructions, &e.Image,
-		&e.NumberOfQuestions, &e.DefaultTime, &e.MaxAttempts, &e.CreatedAt,
+		&e.NumberOfQuestions, &e.DefaultTime, &e.MaxAttempts, &e.ValidityDays, &e.CompletionScore, &e.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -113,6 +125,9 @@ func GetExam(id string) (*Exam, error) {
 		}
 		e.DomainPercentages[domain] = pct
 	}
+	if e.SkillRewards, err = examSkillRewards(id); err != nil {
+		return nil, err
+	}
 
 	e.Questions = []ExamQuestion{}
 	qRows, err := db.Query(`
@@ -147,13 +162,13 @@ func GetExamPublic(id string) (*PublicExam, error) {
 		SELECT id, title, creator, status,
 		       COALESCE(visibility, 'public'), COALESCE(allowed_usernames, '{}'),
 		       description, instructions, image,
-		       number_of_questions, default_time, max_attempts, created_at
+		       number_of_questions, default_time, max_attempts, validity_days, completion_score, created_at
 		FROM exams WHERE id = $1`, id,
 	).Scan(
 		&e.ID, &e.Title, &e.Creator, &e.Status,
 		&e.Visibility, (*StringArray)(&e.AllowedUsernames),
 		&e.Description, &e.Instructions, &e.Image,
-		&e.NumberOfQuestions, &e.DefaultTime, &e.MaxAttempts, &e.CreatedAt,
+		&e.NumberOfQuestions, &e.DefaultTime, &e.MaxAttempts, &e.ValidityDays, &e.CompletionScore, &e.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -173,6 +188,9 @@ func GetExamPublic(id string) (*PublicExam, error) {
 		}
 		e.DomainPercentages[domain] = pct
 	}
+	if e.SkillRewards, err = examSkillRewards(id); err != nil {
+		return nil, err
+	}
 
 	return &e, nil
 }
@@ -270,8 +288,8 @@ func UpsertExam(exam Exam, callerUsername string, isAdmin bool) (Exam, error) {
 	err = tx.QueryRow(`
 		INSERT INTO exams (id, title, creator, owner_username, status, visibility, allowed_usernames,
 		                   description, instructions, image,
-		                   number_of_questions, default_time, max_attempts)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		                   number_of_questions, default_time, max_attempts, validity_days, completion_score)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
 		ON CONFLICT (id) DO UPDATE SET
 			title               = EXCLUDED.title,
 			creator             = EXCLUDED.creator,
@@ -283,19 +301,21 @@ func UpsertExam(exam Exam, callerUsername string, isAdmin bool) (Exam, error) {
 			image               = EXCLUDED.image,
 			number_of_questions = EXCLUDED.number_of_questions,
 			default_time        = EXCLUDED.default_time,
-			max_attempts        = EXCLUDED.max_attempts
+			max_attempts        = EXCLUDED.max_attempts,
+			validity_days       = EXCLUDED.validity_days,
+			completion_score    = EXCLUDED.completion_score
 		RETURNING id, title, creator, COALESCE(owner_username, ''), status,
 		          COALESCE(visibility, 'public'), COALESCE(allowed_usernames, '{}'),
 		          description, instructions, image,
-		          number_of_questions, default_time, max_attempts, created_at`,
+		          number_of_questions, default_time, max_attempts, validity_days, completion_score, created_at`,
 		exam.ID, exam.Title, exam.Creator, ownerPtr, exam.Status, exam.Visibility, StringArray(exam.AllowedUsernames),
 		exam.Description, exam.Instructions, exam.Image,
-		exam.NumberOfQuestions, exam.DefaultTime, exam.MaxAttempts,
+		exam.NumberOfQuestions, exam.DefaultTime, exam.MaxAttempts, exam.ValidityDays, exam.CompletionScore,
 	).Scan(
 		&exam.ID, &exam.Title, &exam.Creator, &exam.OwnerUsername, &exam.Status,
 		&exam.Visibility, (*StringArray)(&exam.AllowedUsernames),
 		&exam.Description, &exam.Instructions, &exam.Image,
-		&exam.NumberOfQuestions, &exam.DefaultTime, &exam.MaxAttempts, &exam.CreatedAt,
+		&exam.NumberOfQuestions, &exam.DefaultTime, &exam.MaxAttempts, &exam.ValidityDays, &exam.CompletionScore, &exam.CreatedAt,
 	)
 	if err != nil {
 		return Exam{}, err
@@ -317,6 +337,13 @@ func UpsertExam(exam Exam, callerUsername string, isAdmin bool) (Exam, error) {
 		}
 	}
 
+	if exam.SkillRewards == nil {
+		exam.SkillRewards = []ExamSkillReward{}
+	}
+	if err := replaceExamSkillRewards(tx, exam.ID, exam.SkillRewards); err != nil {
+		return Exam{}, err
+	}
+
 	// Replace questions
 	if _, err := tx.Exec(`DELETE FROM exam_questions WHERE exam_id = $1`, exam.ID); err != nil {
 		return Exam{}, err
@@ -399,6 +426,8 @@ func DeleteExam(id, callerUsername string, isAdmin bool) error {
 	if !isAdmin && (!ownerUsername.Valid || ownerUsername.String != callerUsername) {
 		return ErrForbidden
 	}
-	_, err = db.Exec(`DELETE FROM exams WHERE id = $1`, id)
-	return err
+	if _, err := db.Exec(`DELETE FROM exams WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return removeItemReferences(PathItemExam, id)
 }
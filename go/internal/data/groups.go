@@ -0,0 +1,167 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrGroupNotFound   = errors.New("group not found")
+	ErrUnknownUsername = errors.New("unknown username")
+)
+
+// UserGroup is an admin-managed set of users, independent of roles.
+type UserGroup struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type GroupMember struct {
+	Username string    `json:"username"`
+	Name     string    `json:"name"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+func EnsureGroupSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_groups (
+			id         BIGSERIAL    PRIMARY KEY,
+			name       TEXT         NOT NULL UNIQUE,
+			created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS user_group_members (
+			group_id BIGINT       NOT NULL REFERENCES user_groups(id) ON DELETE CASCADE,
+			username TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			added_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (group_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_user_group_members_user ON user_group_members(username);
+	`)
+	return err
+}
+
+func ListGroups() ([]UserGroup, error) {
+	rows, err := db.Query(`
+		SELECT g.id, g.name, COUNT(m.username), g.created_at
+		FROM user_groups g
+		LEFT JOIN user_group_members m ON m.group_id = g.id
+		GROUP BY g.id
+		ORDER BY g.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]UserGroup, 0)
+	for rows.Next() {
+		var g UserGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.MemberCount, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, g)
+	}
+	return result, rows.Err()
+}
+
+func CreateGroup(name string) (UserGroup, error) {
+	var g UserGroup
+	err := db.QueryRow(`
+		INSERT INTO user_groups (name) VALUES ($1)
+		RETURNING id, name, created_at`, strings.TrimSpace(name),
+	).Scan(&g.ID, &g.Name, &g.CreatedAt)
+	return g, err
+}
+
+func DeleteGroup(id int64) error {
+	result, err := db.Exec(`DELETE FROM user_groups WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func ListGroupMembers(id int64) ([]GroupMember, error) {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_groups WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	rows, err := db.Query(`
+		SELECT u.username, u.name, m.added_at
+		FROM user_group_members m
+		JOIN users u ON u.username = m.username
+		WHERE m.group_id = $1
+		ORDER BY u.name, u.username`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]GroupMember, 0)
+	for rows.Next() {
+		var m GroupMember
+		if err := rows.Scan(&m.Username, &m.Name, &m.AddedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// SetGroupMembers replaces the members of a group. Unknown usernames are rejected
+// as a whole; members who stay keep their original added_at.
+func SetGroupMembers(id int64, usernames []string) error {
+	normalized := StringArray{}
+	for _, u := range usernames {
+		if u = NormalizeUsername(u); u != "" {
+			normalized = append(normalized, u)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(`SELECT id FROM user_groups WHERE id = $1 FOR UPDATE`, id).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrGroupNotFound
+		}
+		return err
+	}
+	var missing StringArray
+	if err := tx.QueryRow(`
+		SELECT COALESCE(array_agg(u), '{}') FROM unnest($1::text[]) AS u
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE username = u)`, normalized,
+	).Scan(&missing); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownUsername, strings.Join(missing, ", "))
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM user_group_members WHERE group_id = $1 AND NOT (username = ANY($2::text[]))`,
+		id, normalized); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO user_group_members (group_id, username)
+		SELECT $1, u FROM unnest($2::text[]) AS u
+		ON CONFLICT DO NOTHING`, id, normalized); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,94 @@
+package data
+
+import (
+	"database/sql"
+	"time"
+)
+
+type ImpersonationLog struct {
+	ID             int64      `json:"id"`
+	AdminUsername  string     `json:"adminUsername"`
+	TargetUsername string     `json:"targetUsername"`
+	Reason         string     `json:"reason"`
+	IPAddress      string     `json:"ipAddress"`
+	StartedAt      time.Time  `json:"startedAt"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	EndedAt        *time.Time `json:"endedAt"`
+}
+
+func EnsureImpersonationSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS impersonation_logs (
+			id              BIGSERIAL    PRIMARY KEY,
+			admin_username  TEXT         NOT NULL,
+			target_username TEXT         NOT NULL,
+			reason          TEXT         NOT NULL DEFAULT '',
+			ip_address      TEXT         NOT NULL DEFAULT '',
+			started_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			expires_at      TIMESTAMPTZ  NOT NULL,
+			ended_at        TIMESTAMPTZ
+		);
+		CREATE INDEX IF NOT EXISTS ix_impersonation_logs_admin ON impersonation_logs(admin_username);
+		CREATE INDEX IF NOT EXISTS ix_impersonation_logs_target ON impersonation_logs(target_username);
+	`)
+	return err
+}
+
+// CreateImpersonationLog records the start of an impersonation session and returns its id.
+func CreateImpersonationLog(adminUsername, targetUsername, reason, ipAddress string, expiresAt time.Time) (int64, error) {
+	var id int64
+	err := db.QueryRow(
+		`INSERT INTO impersonation_logs (admin_username, target_username, reason, ip_address, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id`,
+		NormalizeUsername(adminUsername),
+		NormalizeUsername(targetUsername),
+		reason,
+		ipAddress,
+		expiresAt,
+	).Scan(&id)
+	return id, err
+}
+
+// EndImpersonationLog stamps ended_at on an open session; ending twice is a no-op.
+func EndImpersonationLog(id int64) error {
+	_, err := db.Exec(
+		`UPDATE impersonation_logs
+		 SET ended_at = NOW()
+		 WHERE id = $1
+		   AND ended_at IS NULL`,
+		id,
+	)
+	return err
+}
+
+func ListImpersonationLogs(limit, offset int) ([]ImpersonationLog, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM impersonation_logs`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, admin_username, target_username, reason, ip_address, started_at, expires_at, ended_at
+		FROM impersonation_logs
+		ORDER BY started_at DESC
+		LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	logs := make([]ImpersonationLog, 0)
+	for rows.Next() {
+		var l ImpersonationLog
+		var endedAt sql.NullTime
+		if err := rows.Scan(&l.ID, &l.AdminUsername, &l.TargetUsername, &l.Reason, &l.IPAddress, &l.StartedAt, &l.ExpiresAt, &endedAt); err != nil {
+			return nil, 0, err
+		}
+		if endedAt.Valid {
+			l.EndedAt = &endedAt.Time
+		}
+		logs = append(logs, l)
+	}
+	return logs, total, rows.Err()
+}
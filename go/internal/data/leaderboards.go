@@ -0,0 +1,345 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Leaderboard periods. Week and month are the current calendar week (from Monday)
+// and month in Asia/Bangkok; custom is an explicit range.
+const (
+	LeaderboardAllTime = "all"
+	LeaderboardWeek    = "week"
+	LeaderboardMonth   = "month"
+	LeaderboardCustom  = "custom"
+)
+
+var ErrInvalidLeaderboard = errors.New("invalid leaderboard")
+
+// LeaderboardFilter selects a board and an optional segment of it. Ranks are
+// computed within the segment.
+type LeaderboardFilter struct {
+	Period  string
+	From    time.Time // custom period only, inclusive
+	To      time.Time // custom period only, exclusive
+	Skill   string    // rank by points in one skill
+	Role    string
+	GroupID int64
+}
+
+// LeaderboardInfo describes the board a page was read from.
+type LeaderboardInfo struct {
+	Period     string     `json:"period"`
+	From       *time.Time `json:"from,omitempty"`
+	To         *time.Time `json:"to,omitempty"`
+	ComputedAt *time.Time `json:"computed_at,omitempty"` // snapshot time; nil for live custom ranges
+}
+
+func EnsureLeaderboardSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS leaderboard_boards (
+			board       TEXT         PRIMARY KEY,
+			period      TEXT         NOT NULL,
+			starts_at   TIMESTAMPTZ  NULL,
+			ends_at     TIMESTAMPTZ  NULL,
+			computed_at TIMESTAMPTZ  NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS leaderboard_snapshots (
+			board    TEXT  NOT NULL REFERENCES leaderboard_boards(board) ON DELETE CASCADE,
+			username TEXT  NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			score    INT   NOT NULL,
+			rank     INT   NOT NULL,
+			PRIMARY KEY (board, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_leaderboard_snapshots_rank ON leaderboard_snapshots(board, rank);
+	`)
+	return err
+}
+
+// ValidateLeaderboardFilter checks the period and segment combination.
+func ValidateLeaderboardFilter(f LeaderboardFilter) error {
+	switch f.Period {
+	case LeaderboardAllTime, LeaderboardWeek, LeaderboardMonth:
+	case LeaderboardCustom:
+		if f.From.IsZero() || !f.To.After(f.From) {
+			return fmt.Errorf("%w: custom period needs from before to", ErrInvalidLeaderboard)
+		}
+	default:
+		return fmt.Errorf("%w: unknown period %q", ErrInvalidLeaderboard, f.Period)
+	}
+	if f.Role != "" && f.GroupID != 0 {
+		return fmt.Errorf("%w: filter by role or by group, not both", ErrInvalidLeaderboard)
+	}
+	return nil
+}
+
+// leaderboardBoard names the snapshot of a standard board: the period, or
+// "skill:<skill>" prefixed by the period for windowed skill boards.
+func leaderboardBoard(f LeaderboardFilter) string {
+	switch {
+	case f.Skill == "":
+		return f.Period
+	case f.Period == LeaderboardAllTime:
+		return "skill:" + f.Skill
+	}
+	return f.Period + ":skill:" + f.Skill
+}
+
+// Period bounds in Asia/Bangkok, as SQL expressions.
+const (
+	weekStartSQL  = `(date_trunc('week', NOW() AT TIME ZONE 'Asia/Bangkok') AT TIME ZONE 'Asia/Bangkok')`
+	monthStartSQL = `(date_trunc('month', NOW() AT TIME ZONE 'Asia/Bangkok') AT TIME ZONE 'Asia/Bangkok')`
+)
+
+// leaderboardScoresQuery selects (username, score) of a board. All-time boards
+// read the balances; windowed boards sum the score ledger and only list users who
+// scored in the window.
+func leaderboardScoresQuery(f LeaderboardFilter) (string, []any) {
+	if f.Period == LeaderboardAllTime {
+		if f.Skill != "" {
+			return `SELECT username, points AS score FROM user_skill_scores WHERE skill = $1`, []any{f.Skill}
+		}
+		// All-time lists every active user, as the original leaderboard did.
+		return `
+			SELECT u.username, COALESCE(s.total, 0) AS score
+			FROM users u LEFT JOIN user_scores s ON s.username = u.username`, nil
+	}
+
+	args := []any{f.Skill}
+	var window string
+	switch f.Period {
+	case LeaderboardWeek:
+		window = `earned_at >= ` + weekStartSQL
+	case LeaderboardMonth:
+		window = `earned_at >= ` + monthStartSQL
+	default:
+		args = append(args, f.From, f.To)
+		window = `earned_at >= $2 AND earned_at < $3`
+	}
+	return `
+		SELECT username, SUM(score)::int AS score FROM user_score_events
+		WHERE skill = $1 AND ` + window + `
+		GROUP BY username HAVING SUM(score) > 0`, args
+}
+
+// refreshLeaderboardBoard recomputes one standard board's snapshot.
+func refreshLeaderboardBoard(f LeaderboardFilter) error {
+	board := leaderboardBoard(f)
+	scores, args := leaderboardScoresQuery(f)
+	n := len(args)
+
+	var startsAt string
+	switch f.Period {
+	case LeaderboardWeek:
+		startsAt = weekStartSQL
+	case LeaderboardMonth:
+		startsAt = monthStartSQL
+	default:
+		startsAt = "NULL::timestamptz"
+	}
+	endsAt := "NULL::timestamptz"
+	if f.Period == LeaderboardWeek {
+		endsAt = weekStartSQL + ` + INTERVAL '7 days'`
+	} else if f.Period == LeaderboardMonth {
+		endsAt = monthStartSQL + ` + INTERVAL '1 month'`
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf(`
+		INSERT INTO leaderboard_boards (board, period, starts_at, ends_at, computed_at)
+		VALUES ($1, $2, %s, %s, NOW())
+		ON CONFLICT (board) DO UPDATE
+			SET starts_at = EXCLUDED.starts_at, ends_at = EXCLUDED.ends_at, computed_at = EXCLUDED.computed_at`,
+		startsAt, endsAt), board, f.Period); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM leaderboard_snapshots WHERE board = $1`, board); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`
+		INSERT INTO leaderboard_snapshots (board, username, score, rank)
+		SELECT $%d::text, s.username, s.score, RANK() OVER (ORDER BY s.score DESC)
+		FROM (%s) s
+		JOIN users u ON u.username = s.username AND u.status = 'active'`, n+1, scores),
+		append(args, board)...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RefreshLeaderboardSnapshots recomputes the all-time, weekly and monthly boards,
+// overall and per skill, and drops boards of skills no longer scored.
+func RefreshLeaderboardSnapshots() error {
+	boards := []LeaderboardFilter{{Period: LeaderboardAllTime}, {Period: LeaderboardWeek}, {Period: LeaderboardMonth}}
+
+	rows, err := db.Query(`SELECT DISTINCT skill FROM user_skill_scores ORDER BY skill`)
+	if err != nil {
+		return err
+	}
+	skillBoards := StringArray{}
+	for rows.Next() {
+		var skill string
+		if err := rows.Scan(&skill); err != nil {
+			rows.Close()
+			return err
+		}
+		for _, period := range []string{LeaderboardAllTime, LeaderboardWeek, LeaderboardMonth} {
+			f := LeaderboardFilter{Period: period, Skill: skill}
+			boards = append(boards, f)
+			skillBoards = append(skillBoards, leaderboardBoard(f))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, f := range boards {
+		if err := refreshLeaderboardBoard(f); err != nil {
+			return fmt.Errorf("refresh leaderboard %s: %w", leaderboardBoard(f), err)
+		}
+	}
+	_, err = db.Exec(`
+		DELETE FROM leaderboard_boards
+		WHERE board LIKE '%skill:%' AND board <> ALL($1::text[])`, skillBoards)
+	return err
+}
+
+// leaderboardSource returns a query of (username, score, rank) for the filter and
+// the board information. Standard boards read their snapshot, computing it first
+// if it has never been computed; custom ranges are computed live. Skill boards are
+// only computed by RefreshLeaderboardSnapshots.
+func leaderboardSource(f LeaderboardFilter) (string, []any, LeaderboardInfo, error) {
+	info := LeaderboardInfo{Period: f.Period}
+	if f.Period == LeaderboardCustom {
+		from, to := f.From, f.To
+		info.From, info.To = &from, &to
+		scores, args := leaderboardScoresQuery(f)
+		return `SELECT s.username, s.score FROM (` + scores + `) s`, args, info, nil
+	}
+
+	board := leaderboardBoard(f)
+	var computedAt time.Time
+	err := db.QueryRow(`SELECT starts_at, ends_at, computed_at FROM leaderboard_boards WHERE board = $1`, board).
+		Scan(&info.From, &info.To, &computedAt)
+	if errors.Is(err, sql.ErrNoRows) && f.Skill == "" {
+		if err := refreshLeaderboardBoard(f); err != nil {
+			return "", nil, info, err
+		}
+		err = db.QueryRow(`SELECT starts_at, ends_at, computed_at FROM leaderboard_boards WHERE board = $1`, board).
+			Scan(&info.From, &info.To, &computedAt)
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// A skill nobody has points in: an empty board.
+	case err != nil:
+		return "", nil, info, err
+	default:
+		info.ComputedAt = &computedAt
+	}
+	return `SELECT username, score, rank FROM leaderboard_snapshots WHERE board = $1`, []any{board}, info, nil
+}
+
+// rankedLeaderboard wraps the source in a query of (username, score, rank) limited
+// to active users of the segment. Unsegmented snapshots keep their stored ranks.
+func rankedLeaderboard(f LeaderboardFilter, source string, args []any) (string, []any) {
+	var segment []string
+	if f.Role != "" {
+		args = append(args, f.Role)
+		segment = append(segment, fmt.Sprintf("u.role_code = $%d", len(args)))
+	}
+	if f.GroupID != 0 {
+		args = append(args, f.GroupID)
+		segment = append(segment, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM user_group_members gm WHERE gm.group_id = $%d AND gm.username = u.username)", len(args)))
+	}
+	if len(segment) == 0 && f.Period != LeaderboardCustom {
+		return source, args
+	}
+	where := "u.status = 'active'"
+	if len(segment) > 0 {
+		where += " AND " + strings.Join(segment, " AND ")
+	}
+	return `
+		SELECT s.username, s.score, RANK() OVER (ORDER BY s.score DESC)::int AS rank
+		FROM (` + source + `) s
+		JOIN users u ON u.username = s.username
+		WHERE ` + where, args
+}
+
+// queryLeaderboardEntries reads the entries of the ranked rows matching where.
+func queryLeaderboardEntries(ranked, where, tail string, args ...any) ([]LeaderboardEntry, error) {
+	rows, err := db.Query(`
+		SELECT p.rank, p.score, u.username, u.name, u.role_code,
+		       COALESCE((SELECT total FROM user_scores t WHERE t.username = u.username), 0),
+		       (SELECT COUNT(*) FROM user_course_enrollments e WHERE e.username = u.username AND e.completed_at IS NOT NULL),
+		       (SELECT COUNT(*) FROM learning_subtopic_answers a WHERE a.username = u.username AND a.is_correct),
+		       EXISTS (SELECT 1 FROM user_avatars av WHERE av.username = u.username)
+		FROM (SELECT * FROM (`+ranked+`) r WHERE `+where+` ORDER BY r.rank, r.username `+tail+`) p
+		JOIN users u ON u.username = p.username
+		ORDER BY p.rank, u.username`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]LeaderboardEntry, 0)
+	for rows.Next() {
+		var e LeaderboardEntry
+		var hasAvatar bool
+		if err := rows.Scan(&e.Rank, &e.Score, &e.Username, &e.Name, &e.Role,
+			&e.TotalScore, &e.CompletedCourses, &e.SolvedQuestions, &hasAvatar); err != nil {
+			return nil, err
+		}
+		e.AvatarURL = avatarURL(e.Username, hasAvatar)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetLeaderboard returns a page of a leaderboard and the number of ranked users.
+func GetLeaderboard(f LeaderboardFilter, limit, offset int) ([]LeaderboardEntry, int, LeaderboardInfo, error) {
+	if err := ValidateLeaderboardFilter(f); err != nil {
+		return nil, 0, LeaderboardInfo{}, err
+	}
+	source, args, info, err := leaderboardSource(f)
+	if err != nil {
+		return nil, 0, info, err
+	}
+	ranked, args := rankedLeaderboard(f, source, args)
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM (`+ranked+`) r`, args...).Scan(&total); err != nil {
+		return nil, 0, info, err
+	}
+	n := len(args)
+	entries, err := queryLeaderboardEntries(ranked, "TRUE",
+		fmt.Sprintf("LIMIT $%d OFFSET $%d", n+1, n+2), append(args, limit, offset)...)
+	return entries, total, info, err
+}
+
+// GetLeaderboardEntry returns the user's entry on a leaderboard, or nil when they
+// are not ranked on it.
+func GetLeaderboardEntry(f LeaderboardFilter, username string) (*LeaderboardEntry, error) {
+	if err := ValidateLeaderboardFilter(f); err != nil {
+		return nil, err
+	}
+	source, args, _, err := leaderboardSource(f)
+	if err != nil {
+		return nil, err
+	}
+	ranked, args := rankedLeaderboard(f, source, args)
+	entries, err := queryLeaderboardEntries(ranked, fmt.Sprintf("r.username = $%d", len(args)+1), "", append(args, username)...)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
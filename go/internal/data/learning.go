@@ -3,37 +3,9 @@ package data
 import (
 	"database/sql"
 	"fmt"
+	"log"
 )
 
-func RecordScoreEvent(username, reason, courseID string, score int) error {
-	_, err := db.Exec(
-		`INSERT INTO user_score_events (username, score, reason, course_id) VALUES ($1, $2, $3, NULLIF($4, ''))`,
-		username, score, reason, courseID,
-	)
-	return err
-}
-
-func AddTotalScore(username string, delta int) error {
-	_, err := db.Exec(`
-		INSERT INTO user_scores (username, total, updated_at)
-		VALUES ($1, $2, NOW())
-		ON CONFLICT (username) DO UPDATE
-			SET total      = user_scores.total + EXCLUDED.total,
-			    updated_at = NOW()`,
-		username, delta)
-	return err
-}
-
-func AddSkillScore(username, skill string, delta int) error {
-	_, err := db.Exec(`
-		INSERT INTO user_skill_scores (username, skill, points)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (username, skill) DO UPDATE
-			SET points = user_skill_scores.points + EXCLUDED.points`,
-		username, skill, delta)
-	return err
-}
-
 func GetUserScores(username string) (total int, skills map[string]int, err error) {
 	skills = make(map[string]int)
 	if err := db.QueryRow(`SELECT total FROM user_scores WHERE username = $1`, username).Scan(&total); err != nil && err != sql.ErrNoRows {
@@ -60,65 +32,103 @@ func AwardCourseCompletion(username, courseID string) (int, []SkillReward, error
 	if err := EnsureEnrollment(username, courseID); err != nil {
 		return 0, nil, err
 	}
-	result, err := db.Exec(`
-		UPDATE user_course_enrollments
-		SET completed_at = NOW()
-		WHERE username = $1 AND course_id = $2 AND completed_at IS NULL`,
-		username, courseID)
-	if err != nil {
+	var completed bool
+	if err := db.QueryRow(`
+		SELECT completed_at IS NOT NULL FROM user_course_enrollments WHERE username = $1 AND course_id = $2`,
+		username, courseID).Scan(&completed); err != nil {
 		return 0, nil, err
 	}
-	affected, _ := result.RowsAffected()
-	if affected == 0 {
+	if completed {
 		return 0, nil, nil // already awarded
 	}
-
+	if err := checkRequiredAttendance(username, courseID); err != nil {
+		return 0, nil, err
+	}
 	var courseScore int
 	if err := db.QueryRow(`SELECT course_completion_score FROM courses WHERE id = $1`, courseID).Scan(&courseScore); err != nil && err != sql.ErrNoRows {
 		return 0, nil, fmt.Errorf("cannot get course score: %w", err)
 	}
-	if courseScore > 0 {
-		if err := AddTotalScore(username, courseScore); err != nil {
-			return 0, nil, fmt.Errorf("cannot add total score: %w", err)
-		}
-		if err := RecordScoreEvent(username, "course_complete", courseID, courseScore); err != nil {
-			return 0, nil, fmt.Errorf("cannot record score event: %w", err)
-		}
-	}
-
 	rows, err := db.Query(`SELECT skill, points FROM course_skill_rewards WHERE course_id = $1`, courseID)
 	if err != nil {
-		return courseScore, nil, err
+		return 0, nil, err
 	}
-	defer rows.Close()
 	var rewards []SkillReward
 	for rows.Next() {
 		var r SkillReward
 		if err := rows.Scan(&r.Skill, &r.Points); err != nil {
-			return courseScore, nil, fmt.Errorf("cannot scan skill reward: %w", err)
-		}
-		if err := AddSkillScore(username, r.Skill, r.Points); err != nil {
-			return courseScore, nil, fmt.Errorf("cannot add skill score for %s: %w", r.Skill, err)
+			rows.Close()
+			return 0, nil, fmt.Errorf("cannot scan skill reward: %w", err)
 		}
 		rewards = append(rewards, r)
 	}
-	return courseScore, rewards, rows.Err()
-}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
 
-func EnsureEnrollment(username, courseID string) error {
-	_, err := db.Exec(`
-		INSERT INTO user_course_enrollments (username, course_id)
-		VALUES ($1, $2)
-		ON CONFLICT (username, course_id) DO NOTHING`,
+	// Completion, certificate record, points and the freed seat are written together.
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+	if _, _, err := lockCourseForEnrollment(tx, courseID); err != nil {
+		return 0, nil, err
+	}
+	result, err := tx.Exec(`
+		UPDATE user_course_enrollments
+		SET completed_at = NOW()
+		WHERE username = $1 AND course_id = $2 AND completed_at IS NULL`,
 		username, courseID)
-	return err
+	if err != nil {
+		return 0, nil, err
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return 0, nil, nil // already awarded
+	}
+	if err := recordCompletion(tx, username, "course", courseID, nil); err != nil {
+		return 0, nil, fmt.Errorf("cannot record completion: %w", err)
+	}
+	if err := awardScores(tx, ScoreEvent{Username: username, Reason: ScoreReasonCourse, CourseID: courseID}, courseScore, rewards); err != nil {
+		return 0, nil, err
+	}
+	// A completed enrollment no longer takes a seat of a capacity course.
+	promoted, err := promoteWaitlist(tx, courseID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+
+	if len(promoted) > 0 {
+		if err := NotifyEnrollmentChanged(promoted, courseID, EnrollmentStatusEnrolled); err != nil {
+			log.Printf("notify waitlist promotion in course %s: %v", courseID, err)
+		}
+	}
+
+	if err := NotifyCertificateIssued(username, PathItemCourse, courseID); err != nil {
+		log.Printf("notify certificate for %s/%s: %v", username, courseID, err)
+	}
+	return courseScore, rewards, nil
 }
 
 func MarkSubtopicComplete(username, courseID, subtopicID string) (awardedScore int, err error) {
 	if err := EnsureEnrollment(username, courseID); err != nil {
 		return 0, err
 	}
-	result, err := db.Exec(`
+	var score int
+	if err := db.QueryRow(`SELECT subtopic_completion_score FROM courses WHERE id = $1`, courseID).Scan(&score); err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("cannot get subtopic score: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(`
 		INSERT INTO learning_subtopic_progress (username, course_id, subtopic_id)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (username, course_id, subtopic_id) DO NOTHING`,
@@ -130,36 +140,15 @@ func MarkSubtopicComplete(username, courseID, subtopicID string) (awardedScore i
 	if affected == 0 {
 		return 0, nil // already completed before, no score
 	}
-
-	var score int
-	if err := db.QueryRow(`SELECT subtopic_completion_score FROM courses WHERE id = $1`, courseID).Scan(&score); err != nil && err != sql.ErrNoRows {
-		return 0, fmt.Errorf("cannot get subtopic score: %w", err)
+	if err := awardScores(tx, ScoreEvent{Username: username, Reason: ScoreReasonSubtopic, CourseID: courseID}, score, nil); err != nil {
+		return 0, err
 	}
-	if score > 0 {
-		if err := AddTotalScore(username, score); err != nil {
-			return 0, fmt.Errorf("cannot add total score: %w", err)
-		}
-		if err := RecordScoreEvent(username, "subtopic_complete", courseID, score); err != nil {
-			return 0, fmt.Errorf("cannot record score event: %w", err)
-		}
+	if err := tx.Commit(); err != nil {
+		return 0, err
 	}
 	return score, nil
 }
 
-func UpsertSubtopicTime(username, courseID, subtopicID string, seconds int) error {
-	if err := EnsureEnrollment(username, courseID); err != nil {
-		return err
-	}
-	_, err := db.Exec(`
-		INSERT INTO learning_subtopic_time (username, course_id, subtopic_id, seconds_spent, updated_at)
-		VALUES ($1, $2, $3, $4, NOW())
-		ON CONFLICT (username, course_id, subtopic_id) DO UPDATE
-			SET seconds_spent = learning_subtopic_time.seconds_spent + EXCLUDED.seconds_spent,
-			    updated_at    = NOW()`,
-		username, courseID, subtopicID, seconds)
-	return err
-}
-
 func UpsertSubtopicAnswer(username, courseID, subtopicID, questionID, typedAnswer string, isCorrect bool) error {
 	if err := EnsureEnrollment(username, courseID); err != nil {
 		return err
@@ -237,17 +226,36 @@ func GetLearningProgress(username string) (map[string]CourseProgress, error) {
 		result[courseID] = cp
 	}
 
+	positionRows, err := db.Query(`
+		SELECT course_id, subtopic_id
+		FROM learning_course_positions
+		WHERE username = $1`, username)
+	if err != nil {
+		return result, err
+	}
+	defer positionRows.Close()
+	for positionRows.Next() {
+		var courseID, subtopicID string
+		if err := positionRows.Scan(&courseID, &subtopicID); err != nil {
+			return nil, fmt.Errorf("cannot scan course position: %w", err)
+		}
+		cp := getOrCreateCourseProgress(result, courseID)
+		cp.LastSubtopicID = subtopicID
+		result[courseID] = cp
+	}
+
 	return result, nil
 }
 
 func GetPublicUserProfile(username string) (*PublicUserProfile, error) {
 	var p PublicUserProfile
+	var hasAvatar bool
 	err := db.QueryRow(`
 		SELECT u.username, u.name, u.role_code,
 		       COALESCE(s.total, 0),
 		       COALESCE(ec.cnt, 0),
 		       COALESCE(aq.cnt, 0),
-		       COALESCE(av.data_url, '')
+		       av.username IS NOT NULL
 		FROM users u
 		LEFT JOIN user_scores s ON s.username = u.username
 		LEFT JOIN (
@@ -263,54 +271,16 @@ func GetPublicUserProfile(username string) (*PublicUserProfile, error) {
 		LEFT JOIN user_avatars av ON av.username = u.username
 		WHERE u.username = $1 AND u.status = 'active'`,
 		username,
-	).Scan(&p.Username, &p.Name, &p.Role, &p.TotalScore, &p.CompletedCourses, &p.SolvedQuestions, &p.AvatarURL)
+	).Scan(&p.Username, &p.Name, &p.Role, &p.TotalScore, &p.CompletedCourses, &p.SolvedQuestions, &hasAvatar)
 	if err != nil {
 		return nil, err
 	}
-	_, p.SkillScores, err = GetUserScores(username)
-	return &p, err
-}
-
-func GetLeaderboard() ([]LeaderboardEntry, error) {
-	rows, err := db.Query(`
-		SELECT
-			u.username,
-			u.name,
-			u.role_code,
-			COALESCE(s.total, 0)   AS total_score,
-			COALESCE(ec.cnt, 0)    AS completed_courses,
-			COALESCE(aq.cnt, 0)    AS solved_questions,
-			COALESCE(av.data_url, '') AS avatar_url
-		FROM users u
-		LEFT JOIN user_scores s ON s.username = u.username
-		LEFT JOIN (
-			SELECT username, COUNT(*) AS cnt
-			FROM user_course_enrollments
-			WHERE completed_at IS NOT NULL
-			GROUP BY username
-		) ec ON ec.username = u.username
-		LEFT JOIN (
-			SELECT username, COUNT(*) AS cnt
-			FROM learning_subtopic_answers
-			WHERE is_correct = true
-			GROUP BY username
-		) aq ON aq.username = u.username
-		LEFT JOIN user_avatars av ON av.username = u.username
-		WHERE u.status = 'active'
-		ORDER BY total_score DESC, completed_courses DESC`)
-	if err != nil {
-		return nil, err
+	p.AvatarURL = avatarURL(p.Username, hasAvatar)
+	if _, p.SkillScores, err = GetUserScores(username); err != nil {
+		return &p, err
 	}
-	defer rows.Close()
-	var entries []LeaderboardEntry
-	for rows.Next() {
-		var e LeaderboardEntry
-		if err := rows.Scan(&e.Username, &e.Name, &e.Role, &e.TotalScore, &e.CompletedCourses, &e.SolvedQuestions, &e.AvatarURL); err != nil {
-			return nil, err
-		}
-		entries = append(entries, e)
-	}
-	return entries, rows.Err()
+	p.Badges, err = ListUserBadges(username)
+	return &p, err
 }
 
 func getOrCreateCourseProgress(result map[string]CourseProgress, courseID string) CourseProgress {
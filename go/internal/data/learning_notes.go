@@ -0,0 +1,215 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrCourseNotFound = errors.New("course not found")
+
+// LearningBookmark marks a subtopic the learner wants to come back to.
+type LearningBookmark struct {
+	CourseID    string    `json:"courseId"`
+	CourseTitle string    `json:"courseTitle"`
+	SubtopicID  string    `json:"subtopicId"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// LearningNote is a private markdown note anchored to a subtopic. Only its author
+// can read it.
+type LearningNote struct {
+	ID         int64     `json:"id"`
+	CourseID   string    `json:"courseId"`
+	SubtopicID string    `json:"subtopicId"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+func EnsureLearningNotesSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS learning_course_positions (
+			username    TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			course_id   TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			subtopic_id TEXT         NOT NULL,
+			visited_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (username, course_id)
+		);
+		CREATE TABLE IF NOT EXISTS learning_bookmarks (
+			username    TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			course_id   TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			subtopic_id TEXT         NOT NULL,
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (username, course_id, subtopic_id)
+		);
+		CREATE TABLE IF NOT EXISTS learning_notes (
+			id          BIGSERIAL    PRIMARY KEY,
+			username    TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			course_id   TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			subtopic_id TEXT         NOT NULL,
+			body        TEXT         NOT NULL,
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS ix_learning_notes_user_course ON learning_notes(username, course_id, subtopic_id);
+	`)
+	return err
+}
+
+// courseRefError turns a foreign key violation on course_id into ErrCourseNotFound.
+func courseRefError(err error) error {
+	if IsForeignKeyViolation(err) {
+		return ErrCourseNotFound
+	}
+	return err
+}
+
+// SaveLastVisitedSubtopic remembers where the learner stopped in a course.
+func SaveLastVisitedSubtopic(username, courseID, subtopicID string) error {
+	_, err := db.Exec(`
+		INSERT INTO learning_course_positions (username, course_id, subtopic_id, visited_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (username, course_id) DO UPDATE
+			SET subtopic_id = EXCLUDED.subtopic_id,
+			    visited_at  = NOW()`,
+		username, courseID, subtopicID)
+	return courseRefError(err)
+}
+
+func AddBookmark(username, courseID, subtopicID string) (LearningBookmark, error) {
+	if _, err := db.Exec(`
+		INSERT INTO learning_bookmarks (username, course_id, subtopic_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		username, courseID, subtopicID); err != nil {
+		return LearningBookmark{}, courseRefError(err)
+	}
+	var b LearningBookmark
+	err := db.QueryRow(`
+		SELECT b.course_id, c.title, b.subtopic_id, b.created_at
+		FROM learning_bookmarks b
+		JOIN courses c ON c.id = b.course_id
+		WHERE b.username = $1 AND b.course_id = $2 AND b.subtopic_id = $3`,
+		username, courseID, subtopicID,
+	).Scan(&b.CourseID, &b.CourseTitle, &b.SubtopicID, &b.CreatedAt)
+	return b, err
+}
+
+func RemoveBookmark(username, courseID, subtopicID string) error {
+	result, err := db.Exec(`
+		DELETE FROM learning_bookmarks
+		WHERE username = $1 AND course_id = $2 AND subtopic_id = $3`,
+		username, courseID, subtopicID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListBookmarks returns the learner's bookmarks, newest first. An empty courseID
+// lists bookmarks across all courses.
+func ListBookmarks(username, courseID string) ([]LearningBookmark, error) {
+	fb := newFilterBuilder(`WHERE b.username = $1`, username)
+	if courseID != "" {
+		fb.add(` AND b.course_id = $%d`, courseID)
+	}
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT b.course_id, c.title, b.subtopic_id, b.created_at
+		FROM learning_bookmarks b
+		JOIN courses c ON c.id = b.course_id
+		%s
+		ORDER BY b.created_at DESC`, fb.where), fb.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]LearningBookmark, 0)
+	for rows.Next() {
+		var b LearningBookmark
+		if err := rows.Scan(&b.CourseID, &b.CourseTitle, &b.SubtopicID, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
+
+// ListNotes returns the learner's notes in a course, oldest first. An empty
+// subtopicID lists notes of every subtopic.
+func ListNotes(username, courseID, subtopicID string) ([]LearningNote, error) {
+	fb := newFilterBuilder(`WHERE username = $1 AND course_id = $2`, username, courseID)
+	if subtopicID != "" {
+		fb.add(` AND subtopic_id = $%d`, subtopicID)
+	}
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, course_id, subtopic_id, body, created_at, updated_at
+		FROM learning_notes
+		%s
+		ORDER BY created_at, id`, fb.where), fb.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]LearningNote, 0)
+	for rows.Next() {
+		var n LearningNote
+		if err := rows.Scan(&n.ID, &n.CourseID, &n.SubtopicID, &n.Body, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, n)
+	}
+	return result, rows.Err()
+}
+
+func CreateNote(username, courseID, subtopicID, body string) (LearningNote, error) {
+	var n LearningNote
+	err := db.QueryRow(`
+		INSERT INTO learning_notes (username, course_id, subtopic_id, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, course_id, subtopic_id, body, created_at, updated_at`,
+		username, courseID, subtopicID, body,
+	).Scan(&n.ID, &n.CourseID, &n.SubtopicID, &n.Body, &n.CreatedAt, &n.UpdatedAt)
+	return n, courseRefError(err)
+}
+
+// UpdateNote replaces the body of one of the learner's notes. Notes of other users
+// are reported as sql.ErrNoRows.
+func UpdateNote(id int64, username, body string) (LearningNote, error) {
+	var n LearningNote
+	err := db.QueryRow(`
+		UPDATE learning_notes SET body = $3, updated_at = NOW()
+		WHERE id = $1 AND username = $2
+		RETURNING id, course_id, subtopic_id, body, created_at, updated_at`,
+		id, username, body,
+	).Scan(&n.ID, &n.CourseID, &n.SubtopicID, &n.Body, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+func DeleteNote(id int64, username string) error {
+	result, err := db.Exec(`DELETE FROM learning_notes WHERE id = $1 AND username = $2`, id, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetCourseOutline returns the title and markdown content of a course, used to
+// label and order exported notes.
+func GetCourseOutline(courseID string) (title, content string, err error) {
+	err = db.QueryRow(`SELECT title, content FROM courses WHERE id = $1`, strings.TrimSpace(courseID)).Scan(&title, &content)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrCourseNotFound
+	}
+	return title, content, err
+}
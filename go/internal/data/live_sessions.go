@@ -0,0 +1,568 @@
+package data
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CheckinOpensBefore is how long before a live session starts learners may check in.
+const CheckinOpensBefore = 30 * time.Minute
+
+var (
+	ErrLiveSessionNotFound     = errors.New("live session not found")
+	ErrLiveSessionFull         = errors.New("live session is full")
+	ErrLiveSessionOver         = errors.New("live session has already ended")
+	ErrLiveSessionOtherCohort  = errors.New("this live session is for another cohort")
+	ErrInvalidLiveSessionTimes = errors.New("endsAt must be after startsAt")
+	ErrCheckinClosed           = errors.New("check-in is not open for this live session")
+	ErrInvalidCheckinCode      = errors.New("invalid or expired check-in code")
+	ErrAlreadyAttended         = errors.New("attendance is already recorded")
+	ErrAttendanceRequired      = errors.New("attend the required live sessions first")
+	ErrNotLiveSessionLearner   = errors.New("not enrolled in the live session's course or cohort")
+)
+
+// LiveSession is a classroom or online session of a course, optionally limited to
+// one cohort. Attendance of required sessions counts toward course completion.
+type LiveSession struct {
+	ID                 int64      `json:"id"`
+	CourseID           string     `json:"courseId"`
+	CourseTitle        string     `json:"courseTitle"`
+	CohortID           *int64     `json:"cohortId,omitempty"` // nil = every learner of the course
+	Title              string     `json:"title"`
+	Description        string     `json:"description"`
+	StartsAt           time.Time  `json:"startsAt"`
+	EndsAt             time.Time  `json:"endsAt"`
+	Location           string     `json:"location"`
+	MeetingURL         string     `json:"meetingUrl"`
+	Capacity           int        `json:"capacity"` // 0 = unlimited
+	Required           bool       `json:"required"`
+	InstructorUsername string     `json:"instructorUsername"`
+	InstructorName     string     `json:"instructorName"`
+	RegisteredCount    int        `json:"registeredCount"`
+	AttendedCount      int        `json:"attendedCount"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	Registered         bool       `json:"registered"`           // the viewing learner
+	AttendedAt         *time.Time `json:"attendedAt,omitempty"` // the viewing learner
+	checkinSecret      string
+}
+
+type LiveSessionAttendee struct {
+	Username     string     `json:"username"`
+	Name         string     `json:"name"`
+	RegisteredAt time.Time  `json:"registeredAt"`
+	AttendedAt   *time.Time `json:"attendedAt,omitempty"`
+	Method       string     `json:"method,omitempty"` // instructor or code
+	MarkedBy     string     `json:"markedBy,omitempty"`
+}
+
+// CheckinCode returns the six-digit check-in code valid in the window of length
+// period that contains at. Codes rotate every period.
+func (s LiveSession) CheckinCode(at time.Time, period time.Duration) string {
+	window := at.Unix() / int64(period/time.Second)
+	mac := hmac.New(sha256.New, []byte(s.checkinSecret))
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(window))
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	return fmt.Sprintf("%06d", binary.BigEndian.Uint32(sum[:4])%1000000)
+}
+
+// CheckinOpen reports whether learners may check in at the given time.
+func (s LiveSession) CheckinOpen(at time.Time) bool {
+	return !at.Before(s.StartsAt.Add(-CheckinOpensBefore)) && at.Before(s.EndsAt)
+}
+
+func EnsureLiveSessionSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS course_live_sessions (
+			id                  BIGSERIAL    PRIMARY KEY,
+			course_id           TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			cohort_id           BIGINT       NULL REFERENCES course_cohorts(id) ON DELETE CASCADE,
+			title               TEXT         NOT NULL,
+			description         TEXT         NOT NULL DEFAULT '',
+			starts_at           TIMESTAMPTZ  NOT NULL,
+			ends_at             TIMESTAMPTZ  NOT NULL,
+			location            TEXT         NOT NULL DEFAULT '',
+			meeting_url         TEXT         NOT NULL DEFAULT '',
+			capacity            INT          NOT NULL DEFAULT 0 CHECK (capacity >= 0),
+			required            BOOLEAN      NOT NULL DEFAULT FALSE,
+			instructor_username TEXT         NULL REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			checkin_secret      TEXT         NOT NULL,
+			created_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			CHECK (ends_at > starts_at)
+		);
+		CREATE INDEX IF NOT EXISTS ix_live_sessions_course ON course_live_sessions(course_id, starts_at);
+		CREATE INDEX IF NOT EXISTS ix_live_sessions_instructor ON course_live_sessions(instructor_username);
+		CREATE TABLE IF NOT EXISTS course_live_session_attendees (
+			session_id        BIGINT       NOT NULL REFERENCES course_live_sessions(id) ON DELETE CASCADE,
+			username          TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			registered_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			attended_at       TIMESTAMPTZ  NULL,
+			attendance_method TEXT         NULL CHECK (attendance_method IN ('instructor', 'code')),
+			marked_by         TEXT         NULL REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			PRIMARY KEY (session_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_live_session_attendees_user ON course_live_session_attendees(username);
+		CREATE TABLE IF NOT EXISTS user_calendar_feeds (
+			username   TEXT         PRIMARY KEY REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			token_hash TEXT         NOT NULL UNIQUE,
+			created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+	`)
+	return err
+}
+
+// liveSessionColumns reads a session for the viewer given as $1 (empty for none).
+const liveSessionColumns = `
+	s.id, s.course_id, c.title, s.cohort_id, s.title, s.description, s.starts_at, s.ends_at,
+	s.location, s.meeting_url, s.capacity, s.required,
+	COALESCE(s.instructor_username, ''), COALESCE(u.name, ''),
+	(SELECT COUNT(*) FROM course_live_session_attendees a WHERE a.session_id = s.id),
+	(SELECT COUNT(*) FROM course_live_session_attendees a WHERE a.session_id = s.id AND a.attended_at IS NOT NULL),
+	s.created_at, me.username IS NOT NULL, me.attended_at, s.checkin_secret`
+
+const liveSessionFrom = `
+	FROM course_live_sessions s
+	JOIN courses c ON c.id = s.course_id
+	LEFT JOIN users u ON u.username = s.instructor_username
+	LEFT JOIN course_live_session_attendees me ON me.session_id = s.id AND me.username = $1`
+
+func scanLiveSession(row interface{ Scan(dest ...any) error }) (LiveSession, error) {
+	var s LiveSession
+	err := row.Scan(
+		&s.ID, &s.CourseID, &s.CourseTitle, &s.CohortID, &s.Title, &s.Description, &s.StartsAt, &s.EndsAt,
+		&s.Location, &s.MeetingURL, &s.Capacity, &s.Required,
+		&s.InstructorUsername, &s.InstructorName,
+		&s.RegisteredCount, &s.AttendedCount,
+		&s.CreatedAt, &s.Registered, &s.AttendedAt, &s.checkinSecret,
+	)
+	return s, err
+}
+
+func queryLiveSessions(where string, args ...any) ([]LiveSession, error) {
+	rows, err := db.Query(`SELECT `+liveSessionColumns+liveSessionFrom+` WHERE `+where+` ORDER BY s.starts_at, s.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]LiveSession, 0)
+	for rows.Next() {
+		s, err := scanLiveSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+// GetLiveSession returns a session of the course as seen by viewer (empty for none).
+func GetLiveSession(courseID string, id int64, viewer string) (LiveSession, error) {
+	s, err := scanLiveSession(db.QueryRow(`SELECT `+liveSessionColumns+liveSessionFrom+`
+		WHERE s.id = $2 AND s.course_id = $3`, viewer, id, courseID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, ErrLiveSessionNotFound
+	}
+	return s, err
+}
+
+// ListCourseLiveSessions returns the sessions of a course the learner may attend:
+// course-wide ones and those of their cohort.
+func ListCourseLiveSessions(courseID, username string) ([]LiveSession, error) {
+	return queryLiveSessions(`s.course_id = $2 AND (s.cohort_id IS NULL OR s.cohort_id =
+		(SELECT cohort_id FROM course_cohort_members WHERE course_id = $2 AND username = $1))`, username, courseID)
+}
+
+// ListManagedLiveSessions returns every session of a course. The course owner,
+// admins and instructors of one of its sessions may list them.
+func ListManagedLiveSessions(courseID, callerUsername string, isAdmin bool) ([]LiveSession, error) {
+	if err := checkCourseOwner(courseID, callerUsername, isAdmin); errors.Is(err, ErrForbidden) {
+		var instructs bool
+		if err := db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM course_live_sessions WHERE course_id = $1 AND instructor_username = $2)`,
+			courseID, callerUsername).Scan(&instructs); err != nil {
+			return nil, err
+		}
+		if !instructs {
+			return nil, ErrForbidden
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return queryLiveSessions(`s.course_id = $2`, "", courseID)
+}
+
+// SaveLiveSession creates (ID 0) or updates a session. Only the course owner or an
+// admin may save sessions.
+func SaveLiveSession(s LiveSession, callerUsername string, isAdmin bool) (LiveSession, error) {
+	if !s.EndsAt.After(s.StartsAt) {
+		return LiveSession{}, ErrInvalidLiveSessionTimes
+	}
+	if err := checkCourseOwner(s.CourseID, callerUsername, isAdmin); err != nil {
+		return LiveSession{}, err
+	}
+	if s.CohortID != nil {
+		if _, err := GetCohort(s.CourseID, *s.CohortID); err != nil {
+			return LiveSession{}, err
+		}
+	}
+	var instructor *string
+	if s.InstructorUsername != "" {
+		instructor = &s.InstructorUsername
+	}
+
+	var err error
+	if s.ID == 0 {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return LiveSession{}, err
+		}
+		err = db.QueryRow(`
+			INSERT INTO course_live_sessions (course_id, cohort_id, title, description, starts_at, ends_at,
+			                                  location, meeting_url, capacity, required, instructor_username, checkin_secret)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING id`,
+			s.CourseID, s.CohortID, s.Title, s.Description, s.StartsAt, s.EndsAt,
+			s.Location, s.MeetingURL, s.Capacity, s.Required, instructor, hex.EncodeToString(secret),
+		).Scan(&s.ID)
+	} else {
+		var result sql.Result
+		result, err = db.Exec(`
+			UPDATE course_live_sessions
+			SET cohort_id = $3, title = $4, description = $5, starts_at = $6, ends_at = $7,
+			    location = $8, meeting_url = $9, capacity = $10, required = $11, instructor_username = $12
+			WHERE id = $1 AND course_id = $2`,
+			s.ID, s.CourseID, s.CohortID, s.Title, s.Description, s.StartsAt, s.EndsAt,
+			s.Location, s.MeetingURL, s.Capacity, s.Required, instructor)
+		if err == nil {
+			if n, _ := result.RowsAffected(); n == 0 {
+				return LiveSession{}, ErrLiveSessionNotFound
+			}
+		}
+	}
+	if err != nil {
+		if IsForeignKeyViolation(err) {
+			return LiveSession{}, fmt.Errorf("%w: %s", ErrUnknownUsername, s.InstructorUsername)
+		}
+		return LiveSession{}, err
+	}
+	return GetLiveSession(s.CourseID, s.ID, "")
+}
+
+func DeleteLiveSession(courseID string, id int64, callerUsername string, isAdmin bool) error {
+	if err := checkCourseOwner(courseID, callerUsername, isAdmin); err != nil {
+		return err
+	}
+	result, err := db.Exec(`DELETE FROM course_live_sessions WHERE id = $1 AND course_id = $2`, id, courseID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrLiveSessionNotFound
+	}
+	return nil
+}
+
+// CanRunLiveSession allows the course owner, admins and the session's instructor to
+// take attendance and show the check-in code.
+func CanRunLiveSession(s LiveSession, callerUsername string, isAdmin bool) error {
+	if s.InstructorUsername != "" && s.InstructorUsername == callerUsername {
+		return nil
+	}
+	return checkCourseOwner(s.CourseID, callerUsername, isAdmin)
+}
+
+// getLearnerLiveSession loads a session by id and checks the learner may attend it:
+// they must be enrolled in the course and, for a cohort session, in that cohort.
+func getLearnerLiveSession(username string, id int64) (LiveSession, error) {
+	var courseID string
+	if err := db.QueryRow(`SELECT course_id FROM course_live_sessions WHERE id = $1`, id).Scan(&courseID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return LiveSession{}, ErrLiveSessionNotFound
+		}
+		return LiveSession{}, err
+	}
+	s, err := GetLiveSession(courseID, id, username)
+	if err != nil {
+		return s, err
+	}
+	if s.CohortID != nil {
+		cohort, err := GetLearnerCohort(username, courseID)
+		if errors.Is(err, ErrCohortNotFound) || (err == nil && cohort.ID != *s.CohortID) {
+			return s, ErrLiveSessionOtherCohort
+		}
+		if err != nil {
+			return s, err
+		}
+	}
+	return s, EnsureEnrollment(username, courseID)
+}
+
+// RegisterForLiveSession reserves a seat for the learner.
+func RegisterForLiveSession(username string, id int64) (LiveSession, error) {
+	s, err := getLearnerLiveSession(username, id)
+	if err != nil {
+		return s, err
+	}
+	if !time.Now().Before(s.EndsAt) {
+		return s, ErrLiveSessionOver
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return s, err
+	}
+	defer tx.Rollback()
+
+	if err := checkLiveSessionSeat(tx, id, s.Registered); err != nil {
+		return s, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO course_live_session_attendees (session_id, username)
+		VALUES ($1, $2)
+		ON CONFLICT (session_id, username) DO NOTHING`, id, username); err != nil {
+		return s, err
+	}
+	if err := tx.Commit(); err != nil {
+		return s, err
+	}
+	return GetLiveSession(s.CourseID, id, username)
+}
+
+// checkLiveSessionSeat locks the session and returns ErrLiveSessionFull when a
+// learner who is not registered yet would go over its capacity.
+func checkLiveSessionSeat(tx *sql.Tx, id int64, registered bool) error {
+	var capacity, taken int
+	if err := tx.QueryRow(`SELECT capacity FROM course_live_sessions WHERE id = $1 FOR UPDATE`, id).Scan(&capacity); err != nil {
+		return err
+	}
+	if registered || capacity == 0 {
+		return nil
+	}
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM course_live_session_attendees WHERE session_id = $1`, id).Scan(&taken); err != nil {
+		return err
+	}
+	if taken >= capacity {
+		return ErrLiveSessionFull
+	}
+	return nil
+}
+
+// CancelLiveSessionRegistration frees the learner's seat unless they attended.
+func CancelLiveSessionRegistration(username string, id int64) error {
+	var attendedAt sql.NullTime
+	err := db.QueryRow(`
+		DELETE FROM course_live_session_attendees
+		WHERE session_id = $1 AND username = $2 AND attended_at IS NULL
+		RETURNING attended_at`, id, username).Scan(&attendedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		var attended bool
+		if err := db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM course_live_session_attendees WHERE session_id = $1 AND username = $2)`,
+			id, username).Scan(&attended); err != nil {
+			return err
+		}
+		if attended {
+			return ErrAlreadyAttended
+		}
+		return ErrLiveSessionNotFound
+	}
+	return err
+}
+
+// CheckInToLiveSession records the learner's attendance with the code shown by the
+// instructor. The current and the previous code are accepted so a code that rotates
+// while being typed still works. Learners who did not register are registered if a
+// seat is free.
+func CheckInToLiveSession(username string, id int64, code string, period time.Duration) (LiveSession, error) {
+	s, err := getLearnerLiveSession(username, id)
+	if err != nil {
+		return s, err
+	}
+	now := time.Now()
+	if !s.CheckinOpen(now) {
+		return s, ErrCheckinClosed
+	}
+	code = strings.TrimSpace(code)
+	if !hmac.Equal([]byte(code), []byte(s.CheckinCode(now, period))) &&
+		!hmac.Equal([]byte(code), []byte(s.CheckinCode(now.Add(-period), period))) {
+		return s, ErrInvalidCheckinCode
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return s, err
+	}
+	defer tx.Rollback()
+
+	if err := checkLiveSessionSeat(tx, id, s.Registered); err != nil {
+		return s, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO course_live_session_attendees (session_id, username, attended_at, attendance_method)
+		VALUES ($1, $2, NOW(), 'code')
+		ON CONFLICT (session_id, username) DO UPDATE
+			SET attended_at = NOW(), attendance_method = 'code', marked_by = NULL
+			WHERE course_live_session_attendees.attended_at IS NULL`, id, username); err != nil {
+		return s, err
+	}
+	if err := tx.Commit(); err != nil {
+		return s, err
+	}
+	return GetLiveSession(s.CourseID, id, username)
+}
+
+// ListLiveSessionAttendees returns the registered and attending learners by name.
+func ListLiveSessionAttendees(id int64) ([]LiveSessionAttendee, error) {
+	rows, err := db.Query(`
+		SELECT a.username, u.name, a.registered_at, a.attended_at,
+		       COALESCE(a.attendance_method, ''), COALESCE(a.marked_by, '')
+		FROM course_live_session_attendees a
+		JOIN users u ON u.username = a.username
+		WHERE a.session_id = $1
+		ORDER BY u.name, a.username`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]LiveSessionAttendee, 0)
+	for rows.Next() {
+		var a LiveSessionAttendee
+		if err := rows.Scan(&a.Username, &a.Name, &a.RegisteredAt, &a.AttendedAt, &a.Method, &a.MarkedBy); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// SetLiveSessionAttendance marks the users as attended, registering them when
+// needed, or clears their attendance. Unknown usernames reject the whole call, as
+// do, when marking, users not enrolled in the session's cohort or, for a
+// course-wide session, its course.
+func SetLiveSessionAttendance(id int64, usernames []string, attended bool, marker string) error {
+	normalized := StringArray{}
+	for _, u := range usernames {
+		if u = NormalizeUsername(u); u != "" {
+			normalized = append(normalized, u)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var missing StringArray
+	if err := tx.QueryRow(`
+		SELECT COALESCE(array_agg(u), '{}') FROM unnest($1::text[]) AS u
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE username = u)`, normalized,
+	).Scan(&missing); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownUsername, strings.Join(missing, ", "))
+	}
+
+	if attended {
+		var outsiders StringArray
+		if err := tx.QueryRow(`
+			SELECT COALESCE(array_agg(u), '{}')
+			FROM unnest($2::text[]) AS u
+			JOIN course_live_sessions s ON s.id = $1
+			WHERE NOT CASE
+				WHEN s.cohort_id IS NULL THEN EXISTS (
+					SELECT 1 FROM user_course_enrollments e WHERE e.course_id = s.course_id AND e.username = u)
+				ELSE EXISTS (
+					SELECT 1 FROM course_cohort_members m WHERE m.cohort_id = s.cohort_id AND m.username = u)
+			END`, id, normalized,
+		).Scan(&outsiders); err != nil {
+			return err
+		}
+		if len(outsiders) > 0 {
+			return fmt.Errorf("%w: %s", ErrNotLiveSessionLearner, strings.Join(outsiders, ", "))
+		}
+		_, err = tx.Exec(`
+			INSERT INTO course_live_session_attendees (session_id, username, attended_at, attendance_method, marked_by)
+			SELECT DISTINCT $1::bigint, u, NOW(), 'instructor', $3 FROM unnest($2::text[]) AS u
+			ON CONFLICT (session_id, username) DO UPDATE
+				SET attended_at = NOW(), attendance_method = 'instructor', marked_by = EXCLUDED.marked_by
+				WHERE course_live_session_attendees.attended_at IS NULL`, id, normalized, marker)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE course_live_session_attendees
+			SET attended_at = NULL, attendance_method = NULL, marked_by = NULL
+			WHERE session_id = $1 AND username = ANY($2::text[])`, id, normalized)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkRequiredAttendance returns ErrAttendanceRequired while the learner has not
+// attended every required session of the course open to them.
+func checkRequiredAttendance(username, courseID string) error {
+	var missing int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM course_live_sessions s
+		WHERE s.course_id = $1 AND s.required
+		  AND (s.cohort_id IS NULL OR s.cohort_id =
+		       (SELECT cohort_id FROM course_cohort_members WHERE course_id = $1 AND username = $2))
+		  AND NOT EXISTS (
+		      SELECT 1 FROM course_live_session_attendees a
+		      WHERE a.session_id = s.id AND a.username = $2 AND a.attended_at IS NOT NULL)`,
+		courseID, username).Scan(&missing)
+	if err != nil {
+		return err
+	}
+	if missing > 0 {
+		return fmt.Errorf("%w (%d remaining)", ErrAttendanceRequired, missing)
+	}
+	return nil
+}
+
+// ListCalendarLiveSessions returns the sessions on a learner's calendar: those they
+// registered for and the required sessions of their enrolled courses, from 30 days
+// ago onward.
+func ListCalendarLiveSessions(username string) ([]LiveSession, error) {
+	return queryLiveSessions(`s.ends_at > NOW() - INTERVAL '30 days' AND (
+		me.username IS NOT NULL OR (
+			s.required
+			AND EXISTS (SELECT 1 FROM user_course_enrollments e WHERE e.course_id = s.course_id AND e.username = $1)
+			AND (s.cohort_id IS NULL OR s.cohort_id =
+			     (SELECT cohort_id FROM course_cohort_members m WHERE m.course_id = s.course_id AND m.username = $1))))`,
+		username)
+}
+
+// SetCalendarFeedToken stores the hash of the user's calendar feed token, replacing
+// the previous one.
+func SetCalendarFeedToken(username, tokenHash string) error {
+	_, err := db.Exec(`
+		INSERT INTO user_calendar_feeds (username, token_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (username) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW()`,
+		username, tokenHash)
+	return err
+}
+
+// FindCalendarFeedUser returns the active user owning a calendar feed token hash.
+func FindCalendarFeedUser(tokenHash string) (string, error) {
+	var username string
+	err := db.QueryRow(`
+		SELECT f.username FROM user_calendar_feeds f
+		JOIN users u ON u.username = f.username
+		WHERE f.token_hash = $1 AND u.status = 'active'`, tokenHash).Scan(&username)
+	return username, err
+}
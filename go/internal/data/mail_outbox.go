@@ -0,0 +1,260 @@
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MailStatusPending = "pending"
+	MailStatusSent    = "sent"
+	MailStatusFailed  = "failed"
+
+	// MailMaxAttempts is how many deliveries are tried before a mail is marked failed.
+	MailMaxAttempts = 8
+)
+
+// Mail templates. The account mails (password reset, email verification) are always
+// sent; the others can be switched off by the user.
+const (
+	MailPasswordReset        = "password_reset"
+	MailPasswordResetRequest = "password_reset_request"
+	MailEmailVerification    = "email_verification"
+	MailAssignmentNew        = "assignment_new"
+	MailAssignmentDue        = "assignment_due"
+	MailCertificateIssued    = "certificate_issued"
+)
+
+// OptionalMailTypes lists the mails a user may opt out of, in display order.
+var OptionalMailTypes = []string{MailAssignmentNew, MailAssignmentDue, MailCertificateIssued}
+
+var ErrInvalidLocale = errors.New("locale must be th or en")
+
+type OutboxMail struct {
+	ID        int64
+	Username  string
+	ToAddress string
+	Template  string
+	Locale    string
+	Payload   map[string]string
+	Attempts  int
+}
+
+type NotificationPreferences struct {
+	Locale string          `json:"locale"`
+	Email  map[string]bool `json:"email"`
+}
+
+func EnsureMailSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS mail_outbox (
+			id              BIGSERIAL    PRIMARY KEY,
+			username        TEXT         REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			to_address      TEXT         NOT NULL,
+			template        TEXT         NOT NULL,
+			locale          TEXT         NOT NULL DEFAULT 'th',
+			payload         JSONB        NOT NULL DEFAULT '{}',
+			status          TEXT         NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','sent','failed')),
+			attempts        INT          NOT NULL DEFAULT 0,
+			last_error      TEXT         NOT NULL DEFAULT '',
+			next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			sent_at         TIMESTAMPTZ
+		);
+		CREATE INDEX IF NOT EXISTS ix_mail_outbox_pending ON mail_outbox(next_attempt_at) WHERE status = 'pending';
+		CREATE INDEX IF NOT EXISTS ix_mail_outbox_user ON mail_outbox(username);
+		-- Account mails used to store their one-time link; it is now minted at delivery.
+		UPDATE mail_outbox
+		SET payload = payload - 'link',
+		    status = CASE WHEN status = 'pending' THEN 'failed' ELSE status END
+		WHERE template IN ('password_reset_request','email_verification') AND payload ? 'link';
+		CREATE TABLE IF NOT EXISTS notification_preferences (
+			username      TEXT         PRIMARY KEY REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			locale        TEXT         NOT NULL DEFAULT 'th' CHECK (locale IN ('th','en')),
+			email_opt_out TEXT[]       NOT NULL DEFAULT '{}',
+			updated_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+	`)
+	return err
+}
+
+func GetNotificationPreferences(username string) (NotificationPreferences, error) {
+	locale := "th"
+	var optOut StringArray
+	err := db.QueryRow(`SELECT locale, email_opt_out FROM notification_preferences WHERE username = $1`,
+		NormalizeUsername(username)).Scan(&locale, &optOut)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return NotificationPreferences{}, err
+	}
+	prefs := NotificationPreferences{Locale: locale, Email: map[string]bool{}}
+	for _, t := range OptionalMailTypes {
+		prefs.Email[t] = !slices.Contains(optOut, t)
+	}
+	return prefs, nil
+}
+
+// SaveNotificationPreferences stores the user's mail language and opt-outs. An empty
+// locale and mail types missing from prefs.Email keep their current setting.
+func SaveNotificationPreferences(username string, prefs NotificationPreferences) (NotificationPreferences, error) {
+	current, err := GetNotificationPreferences(username)
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+	if prefs.Locale == "" {
+		prefs.Locale = current.Locale
+	}
+	if prefs.Locale != "th" && prefs.Locale != "en" {
+		return NotificationPreferences{}, ErrInvalidLocale
+	}
+	optOut := StringArray{}
+	for _, t := range OptionalMailTypes {
+		enabled, ok := prefs.Email[t]
+		if !ok {
+			enabled = current.Email[t]
+		}
+		if !enabled {
+			optOut = append(optOut, t)
+		}
+	}
+	if _, err := db.Exec(`
+		INSERT INTO notification_preferences (username, locale, email_opt_out, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (username) DO UPDATE
+		SET locale = EXCLUDED.locale, email_opt_out = EXCLUDED.email_opt_out, updated_at = NOW()`,
+		NormalizeUsername(username), prefs.Locale, optOut,
+	); err != nil {
+		return NotificationPreferences{}, err
+	}
+	return GetNotificationPreferences(username)
+}
+
+// EnqueueMail adds a mail for the user to the outbox, sent to their verified email in
+// their language. It is skipped when the user has no verified email, is inactive, or
+// opted out of the template. The user's name and username are added to the payload.
+func EnqueueMail(username, template string, payload map[string]string) error {
+	e, err := GetUserEmail(username)
+	if err != nil {
+		return err
+	}
+	if e.Email == "" || e.VerifiedAt == nil {
+		return nil
+	}
+	return EnqueueMailTo(username, e.Email, template, payload)
+}
+
+// EnqueueMailTo is EnqueueMail with an explicit address, for mails that must reach an
+// address before it is verified.
+func EnqueueMailTo(username, to, template string, payload map[string]string) error {
+	username = NormalizeUsername(username)
+	var name, status, locale string
+	var activationPending bool
+	var optOut StringArray
+	err := db.QueryRow(`
+		SELECT u.name, u.status, u.activation_pending, COALESCE(p.locale, 'th'), COALESCE(p.email_opt_out, '{}')
+		FROM users u
+		LEFT JOIN notification_preferences p ON p.username = u.username
+		WHERE u.username = $1`, username,
+	).Scan(&name, &status, &activationPending, &locale, &optOut)
+	if err != nil {
+		return err
+	}
+	// An account awaiting activation still needs its verification mail.
+	active := status == "active" || (activationPending && template == MailEmailVerification)
+	if !active || slices.Contains(optOut, template) {
+		return nil
+	}
+
+	full := map[string]string{"name": name, "username": username}
+	for k, v := range payload {
+		full[k] = v
+	}
+	raw, err := json.Marshal(full)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO mail_outbox (username, to_address, template, locale, payload)
+		VALUES ($1, $2, $3, $4, $5)`,
+		username, to, template, locale, raw)
+	return err
+}
+
+// ClaimOutboxMails leases up to limit due mails for delivery. A leased mail is not
+// handed out again until lease has passed, so a crashed worker only delays it.
+func ClaimOutboxMails(limit int, lease time.Duration) ([]OutboxMail, error) {
+	rows, err := db.Query(`
+		UPDATE mail_outbox SET next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM mail_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, COALESCE(username, ''), to_address, template, locale, payload, attempts`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]OutboxMail, 0)
+	for rows.Next() {
+		var m OutboxMail
+		var raw []byte
+		if err := rows.Scan(&m.ID, &m.Username, &m.ToAddress, &m.Template, &m.Locale, &raw, &m.Attempts); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &m.Payload); err != nil {
+			m.Payload = map[string]string{}
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// MarkMailSent records a delivered mail.
+func MarkMailSent(id int64) error {
+	_, err := db.Exec(`
+		UPDATE mail_outbox
+		SET status = 'sent', sent_at = NOW(), attempts = attempts + 1, last_error = ''
+		WHERE id = $1`, id)
+	return err
+}
+
+// DiscardMail marks a mail failed without retrying it, e.g. when the token it would
+// deliver is no longer valid.
+func DiscardMail(id int64, reason string) error {
+	_, err := db.Exec(`
+		UPDATE mail_outbox SET status = 'failed', last_error = $2
+		WHERE id = $1`, id, reason)
+	return err
+}
+
+// MarkMailFailed records a failed delivery and schedules a retry with exponential
+// backoff (1 minute doubling, at most 6 hours). After MailMaxAttempts the mail is
+// marked failed and no longer retried.
+func MarkMailFailed(id int64, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > 500 {
+		// Cut on a rune boundary; Postgres rejects a split UTF-8 sequence.
+		cut := 500
+		for cut > 0 && !utf8.RuneStart(reason[cut]) {
+			cut--
+		}
+		reason = reason[:cut]
+	}
+	_, err := db.Exec(`
+		UPDATE mail_outbox
+		SET attempts = attempts + 1,
+		    last_error = $2,
+		    status = CASE WHEN attempts + 1 >= $3 THEN 'failed' ELSE 'pending' END,
+		    next_attempt_at = NOW() + LEAST(make_interval(mins => 1) * POWER(2, attempts), INTERVAL '6 hours')
+		WHERE id = $1`, id, reason, MailMaxAttempts)
+	return err
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"database/sql"
 	"errors"
+	"net/url"
 )
 
 func GetAvatar(username string) (string, error) {
@@ -14,6 +15,28 @@ func GetAvatar(username string) (string, error) {
 	return url, err
 }
 
+// GetActiveUserAvatar returns the stored avatar of an active user, or "" when
+// there is none.
+func GetActiveUserAvatar(username string) (string, error) {
+	var stored string
+	err := db.QueryRow(`
+		SELECT av.data_url FROM user_avatars av
+		JOIN users u ON u.username = av.username
+		WHERE av.username = $1 AND u.status = 'active'`, username).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return stored, err
+}
+
+// avatarURL is the public URL of a user's avatar, or "" when they have none.
+func avatarURL(username string, hasAvatar bool) string {
+	if !hasAvatar {
+		return ""
+	}
+	return "/api/users/" + url.PathEscape(username) + "/avatar"
+}
+
 func SaveAvatar(username, url string) error {
 	_, err := db.Exec(`
 		INSERT INTO user_avatars (username, data_url, updated_at)
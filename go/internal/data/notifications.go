@@ -0,0 +1,517 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	NotificationQnAReply          = "qna_reply"
+	NotificationQnAQuestion       = "qna_question"
+	NotificationAssignmentNew     = "assignment_new"
+	NotificationAssignmentDue     = "assignment_due"
+	NotificationAssignmentOverdue = "assignment_overdue"
+	NotificationExamGraded        = "exam_graded"
+	NotificationRoleChanged       = "role_changed"
+	NotificationCertificateIssued = "certificate_issued"
+	NotificationReviewReply       = "review_reply"
+	NotificationEnrollmentRequest = "enrollment_request"
+	NotificationEnrollmentUpdate  = "enrollment_update"
+	NotificationBadgeAwarded      = "badge_awarded"
+)
+
+// notificationSubscriberQueue is how many events a slow live stream may buffer.
+const notificationSubscriberQueue = 16
+
+// maxNotificationStreams is how many live streams one user may hold open at once.
+const maxNotificationStreams = 5
+
+var ErrTooManyNotificationStreams = errors.New("too many open notification streams")
+
+type Notification struct {
+	ID        int64      `json:"id"`
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Link      string     `json:"link"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ReadAt    *time.Time `json:"readAt"`
+}
+
+// NotificationEvent is pushed to live streams: a new notification, or a change in
+// read state (Notification is nil).
+type NotificationEvent struct {
+	Notification *Notification
+}
+
+// notificationHub fans notification events out to the live streams of this process.
+// Subscribers that fall behind drop events; they still see them on the next list.
+type notificationHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan NotificationEvent]struct{}
+}
+
+var notificationStreams = &notificationHub{subs: map[string]map[chan NotificationEvent]struct{}{}}
+
+// SubscribeNotifications registers a live stream for the user. The returned func
+// must be called to unsubscribe. A user already holding maxNotificationStreams
+// streams gets ErrTooManyNotificationStreams.
+func SubscribeNotifications(username string) (<-chan NotificationEvent, func(), error) {
+	username = NormalizeUsername(username)
+	ch := make(chan NotificationEvent, notificationSubscriberQueue)
+
+	notificationStreams.mu.Lock()
+	if len(notificationStreams.subs[username]) >= maxNotificationStreams {
+		notificationStreams.mu.Unlock()
+		return nil, nil, ErrTooManyNotificationStreams
+	}
+	if notificationStreams.subs[username] == nil {
+		notificationStreams.subs[username] = map[chan NotificationEvent]struct{}{}
+	}
+	notificationStreams.subs[username][ch] = struct{}{}
+	notificationStreams.mu.Unlock()
+
+	return ch, func() {
+		notificationStreams.mu.Lock()
+		delete(notificationStreams.subs[username], ch)
+		if len(notificationStreams.subs[username]) == 0 {
+			delete(notificationStreams.subs, username)
+		}
+		notificationStreams.mu.Unlock()
+	}, nil
+}
+
+func (h *notificationHub) publish(username string, ev NotificationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[username] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func EnsureNotificationSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS notifications (
+			id         BIGSERIAL    PRIMARY KEY,
+			username   TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			type       TEXT         NOT NULL,
+			title      TEXT         NOT NULL,
+			body       TEXT         NOT NULL DEFAULT '',
+			link       TEXT         NOT NULL DEFAULT '',
+			dedupe_key TEXT,
+			created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			read_at    TIMESTAMPTZ,
+			UNIQUE (username, dedupe_key)
+		);
+		CREATE INDEX IF NOT EXISTS ix_notifications_user ON notifications(username, created_at DESC);
+		CREATE INDEX IF NOT EXISTS ix_notifications_unread ON notifications(username) WHERE read_at IS NULL;
+	`)
+	return err
+}
+
+// CreateNotification stores a notification and pushes it to the user's live streams.
+// A non-empty dedupeKey makes the call idempotent per user: repeats are ignored and
+// reported with created == false.
+func CreateNotification(username, notifType, title, body, link, dedupeKey string) (n Notification, created bool, err error) {
+	username = NormalizeUsername(username)
+	var key any
+	if dedupeKey != "" {
+		key = dedupeKey
+	}
+	err = db.QueryRow(`
+		INSERT INTO notifications (username, type, title, body, link, dedupe_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (username, dedupe_key) DO NOTHING
+		RETURNING id, type, title, body, link, created_at, read_at`,
+		username, notifType, title, body, link, key,
+	).Scan(&n.ID, &n.Type, &n.Title, &n.Body, &n.Link, &n.CreatedAt, &n.ReadAt)
+	if err == sql.ErrNoRows {
+		return Notification{}, false, nil
+	}
+	if err != nil {
+		return Notification{}, false, err
+	}
+	notificationStreams.publish(username, NotificationEvent{Notification: &n})
+	return n, true, nil
+}
+
+// ListNotifications returns the user's notifications, newest first.
+func ListNotifications(username string, unreadOnly bool, limit, offset int) ([]Notification, int, error) {
+	fb := newFilterBuilder(` FROM notifications WHERE username = $1`, NormalizeUsername(username))
+	if unreadOnly {
+		fb.add(` AND read_at IS NULL`)
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*)`+fb.where, fb.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	limitClause := fb.limitOffset(limit, offset)
+	rows, err := db.Query(`SELECT id, type, title, body, link, created_at, read_at`+fb.where+
+		` ORDER BY created_at DESC, id DESC`+limitClause, fb.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	result := make([]Notification, 0)
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.Type, &n.Title, &n.Body, &n.Link, &n.CreatedAt, &n.ReadAt); err != nil {
+			return nil, 0, err
+		}
+		result = append(result, n)
+	}
+	return result, total, rows.Err()
+}
+
+func CountUnreadNotifications(username string) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE username = $1 AND read_at IS NULL`,
+		NormalizeUsername(username)).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationRead marks one of the user's notifications as read. It returns
+// sql.ErrNoRows when the notification does not belong to the user.
+func MarkNotificationRead(username string, id int64) error {
+	username = NormalizeUsername(username)
+	result, err := db.Exec(`
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND username = $2`, id, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	notificationStreams.publish(username, NotificationEvent{})
+	return nil
+}
+
+func MarkAllNotificationsRead(username string) (int64, error) {
+	username = NormalizeUsername(username)
+	result, err := db.Exec(`UPDATE notifications SET read_at = NOW() WHERE username = $1 AND read_at IS NULL`, username)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if n > 0 {
+		notificationStreams.publish(username, NotificationEvent{})
+	}
+	return n, err
+}
+
+// ── Producers ────────────────────────────────────────────────────────────────
+
+// NotifyQnAReply tells the question author about a reply from someone else.
+func NotifyQnAReply(questionID int64, replier string) error {
+	var author, courseID, courseTitle, replierName string
+	err := db.QueryRow(`
+		SELECT q.username, q.course_id, COALESCE(c.title, ''), COALESCE(u.name, $2)
+		FROM qna_questions q
+		LEFT JOIN courses c ON c.id = q.course_id
+		LEFT JOIN users u ON u.username = $2
+		WHERE q.id = $1`, questionID, NormalizeUsername(replier),
+	).Scan(&author, &courseID, &courseTitle, &replierName)
+	if err != nil {
+		return err
+	}
+	if author == NormalizeUsername(replier) {
+		return nil
+	}
+	_, _, err = CreateNotification(author, NotificationQnAReply,
+		"New reply to your question",
+		fmt.Sprintf("%s replied to your question in %s", replierName, courseTitle),
+		"/content/"+courseID+"#qna-"+strconv.FormatInt(questionID, 10), "")
+	return err
+}
+
+// NotifyQnAQuestion tells the course owner and, for a cohort question, the cohort
+// instructor about a new question from someone else.
+func NotifyQnAQuestion(q QnAQuestion) error {
+	var owner, instructor, courseTitle string
+	err := db.QueryRow(`
+		SELECT COALESCE(c.owner_username, ''), COALESCE(h.instructor_username, ''), c.title
+		FROM courses c
+		LEFT JOIN course_cohorts h ON h.id = $2
+		WHERE c.id = $1`, q.CourseID, q.CohortID).
+		Scan(&owner, &instructor, &courseTitle)
+	if err != nil {
+		return err
+	}
+	notified := map[string]bool{"": true, q.Username: true}
+	for _, recipient := range []string{owner, instructor} {
+		if notified[recipient] {
+			continue
+		}
+		notified[recipient] = true
+		if _, _, err := CreateNotification(recipient, NotificationQnAQuestion,
+			"New question on your course",
+			fmt.Sprintf("%s asked a question in %s", q.Name, courseTitle),
+			"/content/"+q.CourseID+"#qna-"+strconv.FormatInt(q.ID, 10), ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyReviewReply tells the reviewer the instructor replied to their course review.
+func NotifyReviewReply(r CourseReview) error {
+	if r.Reply == "" || r.ReplyBy == r.Username {
+		return nil
+	}
+	var courseTitle string
+	if err := db.QueryRow(`SELECT title FROM courses WHERE id = $1`, r.CourseID).Scan(&courseTitle); err != nil {
+		return err
+	}
+	_, _, err := CreateNotification(r.Username, NotificationReviewReply,
+		"The instructor replied to your review",
+		fmt.Sprintf("Your review of %s has a reply", courseTitle),
+		"/content/"+r.CourseID+"#review-"+strconv.FormatInt(r.ID, 10), "")
+	return err
+}
+
+// NotifyEnrollmentRequested tells the course owner and the learner's manager about
+// a pending enrollment request.
+func NotifyEnrollmentRequested(username, courseID string) error {
+	var owner, manager, courseTitle, name string
+	var requestedAt time.Time
+	err := db.QueryRow(`
+		SELECT COALESCE(c.owner_username, ''), COALESCE(u.manager_username, ''), c.title, u.name, r.requested_at
+		FROM course_enrollment_requests r
+		JOIN courses c ON c.id = r.course_id
+		JOIN users u ON u.username = r.username
+		WHERE r.course_id = $1 AND r.username = $2 AND r.status = 'pending'`, courseID, username,
+	).Scan(&owner, &manager, &courseTitle, &name, &requestedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	dedupeKey := fmt.Sprintf("enrollment:%s:%s:%d", courseID, username, requestedAt.Unix())
+	notified := map[string]bool{"": true, username: true}
+	for _, recipient := range []string{owner, manager} {
+		if notified[recipient] {
+			continue
+		}
+		notified[recipient] = true
+		if _, _, err := CreateNotification(recipient, NotificationEnrollmentRequest,
+			"Enrollment request",
+			fmt.Sprintf("%s asked to enroll in %s", name, courseTitle),
+			"/content/"+courseID+"#enrollments", dedupeKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyEnrollmentChanged tells learners their enrollment in a course is now status:
+// enrolled (approved, promoted or enrolled by an admin), rejected or invited.
+func NotifyEnrollmentChanged(usernames []string, courseID, status string) error {
+	var courseTitle string
+	if err := db.QueryRow(`SELECT title FROM courses WHERE id = $1`, courseID).Scan(&courseTitle); err != nil {
+		return err
+	}
+	var title, body string
+	switch status {
+	case EnrollmentStatusEnrolled:
+		title, body = "You're enrolled", "You can now start "+courseTitle
+	case EnrollmentStatusRejected:
+		title, body = "Enrollment request declined", "Your request to enroll in "+courseTitle+" was declined"
+	case EnrollmentStatusInvited:
+		title, body = "Course invitation", "You're invited to enroll in "+courseTitle
+	default:
+		return nil
+	}
+	for _, u := range usernames {
+		if _, _, err := CreateNotification(u, NotificationEnrollmentUpdate, title, body, "/content/"+courseID, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyBadgeAwarded tells the users they earned a badge.
+func NotifyBadgeAwarded(usernames []string, b Badge) error {
+	for _, u := range usernames {
+		if _, _, err := CreateNotification(u, NotificationBadgeAwarded,
+			"Badge earned", "You earned the "+b.Name+" badge", "/profile",
+			"badge:"+strconv.FormatInt(b.ID, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyExamGraded tells the examinee their attempt has been graded.
+func NotifyExamGraded(username string, attempt ExamAttempt) error {
+	var title string
+	if err := db.QueryRow(`SELECT title FROM exams WHERE id = $1`, attempt.ExamID).Scan(&title); err != nil {
+		return err
+	}
+	result := "not passed"
+	if attempt.ScorePercent >= ExamPassPercent {
+		result = "passed"
+	}
+	_, _, err := CreateNotification(username, NotificationExamGraded,
+		"Exam graded",
+		fmt.Sprintf("%s: %.0f%% (%d/%d), %s", title, attempt.ScorePercent, attempt.CorrectCount, attempt.TotalQuestions, result),
+		"/exam/"+attempt.ExamID+"/result", "")
+	return err
+}
+
+// NotifyRoleChanged tells users their role has changed.
+func NotifyRoleChanged(usernames []string, roleCode string) error {
+	var roleName string
+	if err := db.QueryRow(`SELECT name FROM roles WHERE code = $1`, NormalizeRoleName(roleCode)).Scan(&roleName); err != nil {
+		if err != sql.ErrNoRows {
+			return err
+		}
+		roleName = roleCode
+	}
+	for _, u := range usernames {
+		if _, _, err := CreateNotification(u, NotificationRoleChanged,
+			"Your role has changed",
+			"Your role is now "+roleName+". Available menus and permissions may have changed.",
+			"", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyAssignmentCreated tells every current assignee about a new assignment.
+func NotifyAssignmentCreated(assignmentID int64) error {
+	rows, err := queryAssigneeRows(ComplianceFilter{AssignmentID: assignmentID}, nil)
+	if err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if r.Status == AssignmentStatusCompleted {
+			continue
+		}
+		due := r.DueAt.Format("2006-01-02")
+		_, created, err := CreateNotification(r.Username, NotificationAssignmentNew,
+			"New assignment",
+			fmt.Sprintf("%s is due on %s", r.ItemTitle, due),
+			assignmentLink(r.UserAssignment),
+			fmt.Sprintf("assignment_new:%d", r.AssignmentID))
+		if err != nil {
+			return err
+		}
+		if !created {
+			continue
+		}
+		if err := EnqueueMail(r.Username, MailAssignmentNew, map[string]string{
+			"itemTitle": r.ItemTitle,
+			"dueDate":   due,
+			"note":      r.Note,
+			"link":      assignmentLink(r.UserAssignment),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyAssignmentsDue sends one reminder per assignee and due date for assignments
+// due within withinDays, and one more once they become overdue. It is safe to run
+// repeatedly; it returns how many notifications were created.
+func NotifyAssignmentsDue(withinDays int) (int, error) {
+	rows, err := queryAssigneeRows(ComplianceFilter{}, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	horizon := time.Now().AddDate(0, 0, withinDays)
+	sent := 0
+	for _, r := range rows {
+		due := r.DueAt.Format("2006-01-02")
+		var notifType, title, body string
+		switch {
+		case r.Status == AssignmentStatusOverdue:
+			notifType, title = NotificationAssignmentOverdue, "Assignment overdue"
+			body = fmt.Sprintf("%s was due on %s", r.ItemTitle, due)
+		case r.Status != AssignmentStatusCompleted && r.DueAt.Before(horizon):
+			notifType, title = NotificationAssignmentDue, "Assignment due soon"
+			body = fmt.Sprintf("%s is due on %s", r.ItemTitle, due)
+		default:
+			continue
+		}
+		_, created, err := CreateNotification(r.Username, notifType, title, body,
+			assignmentLink(r.UserAssignment),
+			fmt.Sprintf("%s:%d:%s", notifType, r.AssignmentID, due))
+		if err != nil {
+			return sent, err
+		}
+		if !created {
+			continue
+		}
+		sent++
+		if notifType == NotificationAssignmentDue {
+			if err := EnqueueMail(r.Username, MailAssignmentDue, map[string]string{
+				"itemTitle": r.ItemTitle,
+				"dueDate":   due,
+				"link":      assignmentLink(r.UserAssignment),
+			}); err != nil {
+				return sent, err
+			}
+		}
+	}
+	return sent, nil
+}
+
+// NotifyCertificateIssued tells the user they completed a course or passed an exam,
+// including when the certification expires if the item has a validity period.
+func NotifyCertificateIssued(username, itemType, itemID string) error {
+	var title string
+	var validityDays int
+	query := `SELECT title, validity_days FROM courses WHERE id = $1`
+	link := "/content/" + itemID
+	if itemType == PathItemExam {
+		query = `SELECT title, validity_days FROM exams WHERE id = $1`
+		link = "/exam/" + itemID
+	}
+	if err := db.QueryRow(query, itemID).Scan(&title, &validityDays); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	body := fmt.Sprintf("You completed %s", title)
+	payload := map[string]string{
+		"itemTitle":   title,
+		"completedAt": now.Format("2006-01-02"),
+		"link":        link,
+	}
+	if validityDays > 0 {
+		expires := now.AddDate(0, 0, validityDays).Format("2006-01-02")
+		body += ", valid until " + expires
+		payload["expiresAt"] = expires
+	}
+	if _, _, err := CreateNotification(username, NotificationCertificateIssued,
+		"Certificate issued", body, link, ""); err != nil {
+		return err
+	}
+	return EnqueueMail(username, MailCertificateIssued, payload)
+}
+
+func assignmentLink(ua UserAssignment) string {
+	switch ua.ItemType {
+	case PathItemCourse:
+		return "/content/" + ua.ItemID
+	case PathItemExam:
+		return "/exam/" + ua.ItemID
+	default:
+		return "/paths/" + ua.ItemID
+	}
+}
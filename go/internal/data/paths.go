@@ -0,0 +1,667 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	PathItemCourse = "course"
+	PathItemExam   = "exam"
+)
+
+var (
+	ErrItemLocked      = errors.New("item is locked by a learning path prerequisite")
+	ErrPathItemMissing = errors.New("learning path step references a missing course or exam")
+)
+
+type LearningPathStep struct {
+	Position int    `json:"position"`
+	ItemType string `json:"itemType"`
+	ItemID   string `json:"itemId"`
+	Title    string `json:"title"`
+}
+
+type LearningPath struct {
+	ID              string             `json:"id"`
+	Title           string             `json:"title"`
+	Description     string             `json:"description"`
+	Image           string             `json:"image"`
+	Status          string             `json:"status"`
+	OwnerUsername   string             `json:"ownerUsername"`
+	CompletionScore int                `json:"completionScore"`
+	CreatedAt       time.Time          `json:"createdAt"`
+	Steps           []LearningPathStep `json:"steps"`
+	SkillRewards    []SkillReward      `json:"skillRewards"`
+}
+
+type PathStepProgress struct {
+	LearningPathStep
+	Done     bool `json:"done"`
+	Unlocked bool `json:"unlocked"`
+}
+
+type PathProgress struct {
+	PathID         string             `json:"pathId"`
+	Title          string             `json:"title"`
+	Enrolled       bool               `json:"enrolled"`
+	Assigned       bool               `json:"assigned"`
+	StepsTotal     int                `json:"stepsTotal"`
+	StepsCompleted int                `json:"stepsCompleted"`
+	Percent        float64            `json:"percent"`
+	CompletedAt    *time.Time         `json:"completedAt"`
+	Steps          []PathStepProgress `json:"steps"`
+}
+
+type PathCompletion struct {
+	PathID       string        `json:"pathId"`
+	Title        string        `json:"title"`
+	AwardedScore int           `json:"awardedScore"`
+	SkillRewards []SkillReward `json:"skillRewards"`
+}
+
+type PathStepStat struct {
+	Position       int    `json:"position"`
+	ItemType       string `json:"itemType"`
+	ItemID         string `json:"itemId"`
+	Title          string `json:"title"`
+	CompletedCount int    `json:"completedCount"`
+}
+
+type PathStats struct {
+	PathID            string         `json:"pathId"`
+	Title             string         `json:"title"`
+	Status            string         `json:"status"`
+	Learners          int            `json:"learners"`
+	Completions       int            `json:"completions"`
+	CompletionRate    float64        `json:"completionRate"`
+	AvgDaysToComplete float64        `json:"avgDaysToComplete"`
+	Steps             []PathStepStat `json:"steps"`
+}
+
+// stepDoneSQL is true when the user ($1) has ever finished step s: the course was
+// completed or the exam passed. Expired certifications still count, so retaking a
+// course for recertification does not relock later steps.
+var stepDoneSQL = `
+	EXISTS (
+		SELECT 1 FROM completion_records r
+		WHERE r.username = $1 AND r.item_type = s.item_type AND r.item_id = s.item_id)`
+
+// pathEnrolledSQL and pathAssignedSQL are true when the user ($1) enrolled in path
+// p or was assigned it directly or through their role. Only such learners are held
+// to the order of its steps.
+var pathEnrolledSQL = `
+	EXISTS (SELECT 1 FROM learning_path_enrollments pe WHERE pe.path_id = p.id AND pe.username = $1)`
+
+var pathAssignedSQL = `
+	EXISTS (
+		SELECT 1
+		FROM assignments a
+		JOIN assignment_targets t ON t.assignment_id = a.id
+		JOIN users u ON u.username = $1
+		WHERE a.item_type = 'path' AND a.item_id = p.id
+		  AND ((t.target_type = 'user' AND t.target_value = u.username)
+		    OR (t.target_type = 'role' AND t.target_value = u.role_code)))`
+
+// stepTitleSQL resolves the title of step s from its course or exam.
+var stepTitleSQL = `
+	COALESCE(CASE s.item_type
+		WHEN 'course' THEN (SELECT title FROM courses WHERE id = s.item_id)
+		ELSE (SELECT title FROM exams WHERE id = s.item_id)
+	END, '')`
+
+func EnsurePathSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS learning_paths (
+			id               TEXT         PRIMARY KEY,
+			title            TEXT         NOT NULL,
+			description      TEXT         NOT NULL DEFAULT '',
+			image            TEXT         NOT NULL DEFAULT '',
+			status           TEXT         NOT NULL DEFAULT 'inactive' CHECK (status IN ('active','inactive')),
+			owner_username   TEXT         REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			completion_score INT          NOT NULL DEFAULT 0 CHECK (completion_score >= 0),
+			created_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS learning_path_steps (
+			path_id   TEXT  NOT NULL REFERENCES learning_paths(id) ON DELETE CASCADE,
+			position  INT   NOT NULL,
+			item_type TEXT  NOT NULL CHECK (item_type IN ('course','exam')),
+			item_id   TEXT  NOT NULL,
+			PRIMARY KEY (path_id, position),
+			UNIQUE (path_id, item_type, item_id)
+		);
+		CREATE INDEX IF NOT EXISTS ix_learning_path_steps_item ON learning_path_steps(item_type, item_id);
+		CREATE TABLE IF NOT EXISTS learning_path_skill_rewards (
+			path_id TEXT NOT NULL REFERENCES learning_paths(id) ON DELETE CASCADE,
+			skill   TEXT NOT NULL,
+			points  INT  NOT NULL DEFAULT 0 CHECK (points >= 0),
+			PRIMARY KEY (path_id, skill)
+		);
+		CREATE TABLE IF NOT EXISTS learning_path_completions (
+			path_id       TEXT         NOT NULL REFERENCES learning_paths(id) ON DELETE CASCADE,
+			username      TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			awarded_score INT          NOT NULL DEFAULT 0,
+			completed_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (path_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_learning_path_completions_user ON learning_path_completions(username);
+		CREATE TABLE IF NOT EXISTS learning_path_enrollments (
+			path_id     TEXT         NOT NULL REFERENCES learning_paths(id) ON DELETE CASCADE,
+			username    TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			enrolled_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (path_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_learning_path_enrollments_user ON learning_path_enrollments(username);
+	`)
+	return err
+}
+
+// ListLearningPaths returns paths with their steps and rewards. When activeOnly is
+// set, inactive paths are left out.
+func ListLearningPaths(activeOnly bool) ([]LearningPath, error) {
+	rows, err := db.Query(`
+		SELECT id, title, description, image, status, COALESCE(owner_username, ''), completion_score, created_at
+		FROM learning_paths
+		WHERE (NOT $1 OR status = 'active')
+		ORDER BY created_at DESC`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := make([]LearningPath, 0)
+	for rows.Next() {
+		var p LearningPath
+		if err := rows.Scan(&p.ID, &p.Title, &p.Description, &p.Image, &p.Status, &p.OwnerUsername, &p.CompletionScore, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range paths {
+		if err := loadPathDetails(&paths[i]); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+func GetLearningPath(id string) (LearningPath, error) {
+	var p LearningPath
+	err := db.QueryRow(`
+		SELECT id, title, description, image, status, COALESCE(owner_username, ''), completion_score, created_at
+		FROM learning_paths WHERE id = $1`, id,
+	).Scan(&p.ID, &p.Title, &p.Description, &p.Image, &p.Status, &p.OwnerUsername, &p.CompletionScore, &p.CreatedAt)
+	if err != nil {
+		return LearningPath{}, err
+	}
+	if err := loadPathDetails(&p); err != nil {
+		return LearningPath{}, err
+	}
+	return p, nil
+}
+
+func loadPathDetails(p *LearningPath) error {
+	p.Steps = []LearningPathStep{}
+	p.SkillRewards = []SkillReward{}
+
+	rows, err := db.Query(`
+		SELECT s.position, s.item_type, s.item_id, `+stepTitleSQL+`
+		FROM learning_path_steps s
+		WHERE s.path_id = $1
+		ORDER BY s.position`, p.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s LearningPathStep
+		if err := rows.Scan(&s.Position, &s.ItemType, &s.ItemID, &s.Title); err != nil {
+			return fmt.Errorf("cannot scan path step: %w", err)
+		}
+		p.Steps = append(p.Steps, s)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	srRows, err := db.Query(`SELECT skill, points FROM learning_path_skill_rewards WHERE path_id = $1 ORDER BY skill`, p.ID)
+	if err != nil {
+		return err
+	}
+	defer srRows.Close()
+	for srRows.Next() {
+		var sr SkillReward
+		if err := srRows.Scan(&sr.Skill, &sr.Points); err != nil {
+			return fmt.Errorf("cannot scan path skill reward: %w", err)
+		}
+		p.SkillRewards = append(p.SkillRewards, sr)
+	}
+	return srRows.Err()
+}
+
+// UpsertLearningPath creates or replaces a path. Steps are renumbered from 1 in the
+// given order. Non-admins may only edit paths they own.
+func UpsertLearningPath(p LearningPath, callerUsername string, isAdmin bool) (LearningPath, error) {
+	var existingOwner sql.NullString
+	err := db.QueryRow(`SELECT owner_username FROM learning_paths WHERE id = $1`, p.ID).Scan(&existingOwner)
+	if err != nil && err != sql.ErrNoRows {
+		return LearningPath{}, err
+	}
+	if err == nil {
+		if !isAdmin && (!existingOwner.Valid || existingOwner.String != callerUsername) {
+			return LearningPath{}, ErrForbidden
+		}
+	} else {
+		p.OwnerUsername = callerUsername
+	}
+
+	var ownerPtr *string
+	if p.OwnerUsername != "" {
+		ownerPtr = &p.OwnerUsername
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return LearningPath{}, err
+	}
+	defer tx.Rollback()
+
+	for _, s := range p.Steps {
+		table := "courses"
+		if s.ItemType == PathItemExam {
+			table = "exams"
+		}
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id = $1)`, s.ItemID).Scan(&exists); err != nil {
+			return LearningPath{}, err
+		}
+		if !exists {
+			return LearningPath{}, fmt.Errorf("%w: %s %s", ErrPathItemMissing, s.ItemType, s.ItemID)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO learning_paths (id, title, description, image, status, owner_username, completion_score)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (id) DO UPDATE SET
+			title            = EXCLUDED.title,
+			description      = EXCLUDED.description,
+			image            = EXCLUDED.image,
+			status           = EXCLUDED.status,
+			completion_score = EXCLUDED.completion_score`,
+		p.ID, p.Title, p.Description, p.Image, p.Status, ownerPtr, p.CompletionScore,
+	); err != nil {
+		return LearningPath{}, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM learning_path_steps WHERE path_id = $1`, p.ID); err != nil {
+		return LearningPath{}, err
+	}
+	for i, s := range p.Steps {
+		if _, err := tx.Exec(
+			`INSERT INTO learning_path_steps (path_id, position, item_type, item_id) VALUES ($1,$2,$3,$4)`,
+			p.ID, i+1, s.ItemType, s.ItemID,
+		); err != nil {
+			return LearningPath{}, err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM learning_path_skill_rewards WHERE path_id = $1`, p.ID); err != nil {
+		return LearningPath{}, err
+	}
+	for _, sr := range p.SkillRewards {
+		if strings.TrimSpace(sr.Skill) == "" {
+			continue
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO learning_path_skill_rewards (path_id, skill, points) VALUES ($1,$2,$3)
+			 ON CONFLICT (path_id, skill) DO UPDATE SET points = EXCLUDED.points`,
+			p.ID, sr.Skill, sr.Points,
+		); err != nil {
+			return LearningPath{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return LearningPath{}, err
+	}
+	return GetLearningPath(p.ID)
+}
+
+func DeleteLearningPath(id, callerUsername string, isAdmin bool) error {
+	var ownerUsername sql.NullString
+	err := db.QueryRow(`SELECT owner_username FROM learning_paths WHERE id = $1`, id).Scan(&ownerUsername)
+	if err != nil {
+		return err
+	}
+	if !isAdmin && (!ownerUsername.Valid || ownerUsername.String != callerUsername) {
+		return ErrForbidden
+	}
+	if _, err := db.Exec(`DELETE FROM learning_paths WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return removeItemReferences(AssignmentItemPath, id)
+}
+
+// CheckItemUnlocked returns ErrItemLocked when the course or exam is a step of an
+// active path the user is enrolled in or assigned, and they have not finished every
+// earlier step of that path. Learners not on the path take its items freely.
+func CheckItemUnlocked(username, itemType, itemID string) error {
+	var locked bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM learning_path_steps cur
+			JOIN learning_paths p ON p.id = cur.path_id AND p.status = 'active'
+			JOIN learning_path_steps s ON s.path_id = cur.path_id AND s.position < cur.position
+			WHERE cur.item_type = $2 AND cur.item_id = $3
+			  AND (`+pathEnrolledSQL+` OR `+pathAssignedSQL+`)
+			  AND NOT (`+stepDoneSQL+`)
+		)`, username, itemType, itemID,
+	).Scan(&locked)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrItemLocked
+	}
+	return nil
+}
+
+// GetPathProgress returns the user's step-by-step progress through a path. For a
+// learner enrolled in or assigned the path, a step is unlocked when every step
+// before it is done; otherwise every step is unlocked.
+func GetPathProgress(username, pathID string) (PathProgress, error) {
+	progress := PathProgress{PathID: pathID, Steps: []PathStepProgress{}}
+	err := db.QueryRow(`
+		SELECT p.title, c.completed_at, `+pathEnrolledSQL+`, `+pathAssignedSQL+`
+		FROM learning_paths p
+		LEFT JOIN learning_path_completions c ON c.path_id = p.id AND c.username = $1
+		WHERE p.id = $2`, username, pathID,
+	).Scan(&progress.Title, &progress.CompletedAt, &progress.Enrolled, &progress.Assigned)
+	if err != nil {
+		return PathProgress{}, err
+	}
+
+	rows, err := db.Query(`
+		SELECT s.position, s.item_type, s.item_id, `+stepTitleSQL+`, `+stepDoneSQL+`
+		FROM learning_path_steps s
+		WHERE s.path_id = $2
+		ORDER BY s.position`, username, pathID)
+	if err != nil {
+		return PathProgress{}, err
+	}
+	defer rows.Close()
+
+	unlocked := true
+	for rows.Next() {
+		var sp PathStepProgress
+		if err := rows.Scan(&sp.Position, &sp.ItemType, &sp.ItemID, &sp.Title, &sp.Done); err != nil {
+			return PathProgress{}, fmt.Errorf("cannot scan path step progress: %w", err)
+		}
+		sp.Unlocked = unlocked
+		unlocked = unlocked && (sp.Done || !(progress.Enrolled || progress.Assigned))
+		if sp.Done {
+			progress.StepsCompleted++
+		}
+		progress.Steps = append(progress.Steps, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return PathProgress{}, err
+	}
+	progress.StepsTotal = len(progress.Steps)
+	if progress.StepsTotal > 0 {
+		progress.Percent = float64(progress.StepsCompleted) * 100 / float64(progress.StepsTotal)
+	}
+	return progress, nil
+}
+
+// EnrollInPath puts the user on an active path, so its steps unlock in order.
+// It returns sql.ErrNoRows when the path does not exist or is inactive.
+func EnrollInPath(username, pathID string) error {
+	result, err := db.Exec(`
+		INSERT INTO learning_path_enrollments (path_id, username)
+		SELECT id, $1 FROM learning_paths WHERE id = $2 AND status = 'active'
+		ON CONFLICT (path_id, username) DO NOTHING`, username, pathID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		var exists bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM learning_paths WHERE id = $1 AND status = 'active')`,
+			pathID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+	}
+	return nil
+}
+
+// LeavePath removes the user's enrollment in a path. An assigned path stays locked
+// in order. It returns sql.ErrNoRows when the user was not enrolled.
+func LeavePath(username, pathID string) error {
+	result, err := db.Exec(`DELETE FROM learning_path_enrollments WHERE path_id = $1 AND username = $2`, pathID, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AwardPathCompletions grants the completion reward of every active path containing
+// the given item that the user has now fully finished. Each path is rewarded once.
+func AwardPathCompletions(username, itemType, itemID string) ([]PathCompletion, error) {
+	rows, err := db.Query(`
+		SELECT p.id, p.title, p.completion_score
+		FROM learning_paths p
+		WHERE p.status = 'active'
+		  AND EXISTS (SELECT 1 FROM learning_path_steps x
+		              WHERE x.path_id = p.id AND x.item_type = $2 AND x.item_id = $3)
+		  AND NOT EXISTS (SELECT 1 FROM learning_path_completions c
+		                  WHERE c.path_id = p.id AND c.username = $1)
+		  AND NOT EXISTS (SELECT 1 FROM learning_path_steps s
+		                  WHERE s.path_id = p.id AND NOT (`+stepDoneSQL+`))`,
+		username, itemType, itemID)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []PathCompletion
+	for rows.Next() {
+		var pc PathCompletion
+		if err := rows.Scan(&pc.PathID, &pc.Title, &pc.AwardedScore); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		candidates = append(candidates, pc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	completions := make([]PathCompletion, 0, len(candidates))
+	for _, pc := range candidates {
+		pc.SkillRewards = []SkillReward{}
+		srRows, err := db.Query(`SELECT skill, points FROM learning_path_skill_rewards WHERE path_id = $1`, pc.PathID)
+		if err != nil {
+			return completions, err
+		}
+		for srRows.Next() {
+			var sr SkillReward
+			if err := srRows.Scan(&sr.Skill, &sr.Points); err != nil {
+				srRows.Close()
+				return completions, fmt.Errorf("cannot scan path skill reward: %w", err)
+			}
+			pc.SkillRewards = append(pc.SkillRewards, sr)
+		}
+		srRows.Close()
+		if err := srRows.Err(); err != nil {
+			return completions, err
+		}
+
+		awarded, err := awardPathCompletion(username, pc)
+		if err != nil {
+			return completions, err
+		}
+		if awarded {
+			completions = append(completions, pc)
+		}
+	}
+	return completions, nil
+}
+
+// awardPathCompletion records a path completion and its rewards in one
+// transaction. It reports false when the path was awarded concurrently.
+func awardPathCompletion(username string, pc PathCompletion) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(`
+		INSERT INTO learning_path_completions (path_id, username, awarded_score)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (path_id, username) DO NOTHING`,
+		pc.PathID, username, pc.AwardedScore)
+	if err != nil {
+		return false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, nil
+	}
+	if err := awardScores(tx, ScoreEvent{Username: username, Reason: ScoreReasonPath}, pc.AwardedScore, pc.SkillRewards); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RemovePathSteps drops a deleted course or exam from every path.
+func RemovePathSteps(itemType, itemID string) error {
+	_, err := db.Exec(`DELETE FROM learning_path_steps WHERE item_type = $1 AND item_id = $2`, itemType, itemID)
+	return err
+}
+
+// GetPathStats reports, per path, how many learners started it (enrolled in or attempted
+// any step), how many completed it, and how many finished each step.
+func GetPathStats(ownerUsername string) ([]PathStats, error) {
+	rows, err := db.Query(`
+		SELECT p.id, p.title, p.status,
+		       COALESCE(c.completions, 0),
+		       COALESCE(c.avg_days, 0)
+		FROM learning_paths p
+		LEFT JOIN (
+			SELECT lpc.path_id,
+			       COUNT(*) AS completions,
+			       AVG(EXTRACT(EPOCH FROM lpc.completed_at - first_step.started_at) / 86400)::float8 AS avg_days
+			FROM learning_path_completions lpc
+			LEFT JOIN LATERAL (
+				SELECT MIN(started.at) AS started_at
+				FROM learning_path_steps s
+				JOIN LATERAL (
+					SELECT e.enrolled_at AS at FROM user_course_enrollments e
+					WHERE s.item_type = 'course' AND e.course_id = s.item_id AND e.username = lpc.username
+					UNION ALL
+					SELECT a.started_at FROM exam_attempts a
+					WHERE s.item_type = 'exam' AND a.exam_id = s.item_id AND a.username = lpc.username
+				) started ON TRUE
+				WHERE s.path_id = lpc.path_id
+			) first_step ON TRUE
+			GROUP BY lpc.path_id
+		) c ON c.path_id = p.id
+		WHERE ($1 = '' OR p.owner_username = $1)
+		ORDER BY p.created_at DESC`, ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]PathStats, 0)
+	idx := map[string]int{}
+	for rows.Next() {
+		var ps PathStats
+		if err := rows.Scan(&ps.PathID, &ps.Title, &ps.Status, &ps.Completions, &ps.AvgDaysToComplete); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ps.Steps = []PathStepStat{}
+		idx[ps.PathID] = len(stats)
+		stats = append(stats, ps)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return stats, nil
+	}
+
+	ids := make([]string, 0, len(stats))
+	for _, ps := range stats {
+		ids = append(ids, ps.PathID)
+	}
+
+	stepRows, err := db.Query(`
+		SELECT s.path_id, s.position, s.item_type, s.item_id, `+stepTitleSQL+`,
+		       (SELECT COUNT(DISTINCT r.username) FROM completion_records r
+		        WHERE r.item_type = s.item_type AND r.item_id = s.item_id)
+		FROM learning_path_steps s
+		WHERE s.path_id = ANY($1)
+		ORDER BY s.path_id, s.position`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer stepRows.Close()
+	for stepRows.Next() {
+		var pathID string
+		var st PathStepStat
+		if err := stepRows.Scan(&pathID, &st.Position, &st.ItemType, &st.ItemID, &st.Title, &st.CompletedCount); err != nil {
+			return nil, err
+		}
+		if i, ok := idx[pathID]; ok {
+			stats[i].Steps = append(stats[i].Steps, st)
+		}
+	}
+	if err := stepRows.Err(); err != nil {
+		return nil, err
+	}
+
+	learnerRows, err := db.Query(`
+		SELECT s.path_id, COUNT(DISTINCT u.username)
+		FROM learning_path_steps s
+		JOIN LATERAL (
+			SELECT e.username FROM user_course_enrollments e
+			WHERE s.item_type = 'course' AND e.course_id = s.item_id
+			UNION
+			SELECT a.username FROM exam_attempts a
+			WHERE s.item_type = 'exam' AND a.exam_id = s.item_id
+		) u ON TRUE
+		WHERE s.path_id = ANY($1)
+		GROUP BY s.path_id`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer learnerRows.Close()
+	for learnerRows.Next() {
+		var pathID string
+		var learners int
+		if err := learnerRows.Scan(&pathID, &learners); err != nil {
+			return nil, err
+		}
+		if i, ok := idx[pathID]; ok {
+			stats[i].Learners = learners
+			if learners > 0 {
+				stats[i].CompletionRate = float64(stats[i].Completions) * 100 / float64(learners)
+			}
+		}
+	}
+	return stats, learnerRows.Err()
+}
@@ -33,11 +33,14 @@ var defaultPermissions = []Permission{
 	{Code: "exam.take", Module: "exam", Action: "take", Description: "เข้าทำข้อสอบ"},
 	{Code: "exam.manage", Module: "exam", Action: "manage", Description: "สร้าง / แก้ไขข้อสอบ"},
 	{Code: "exam.view_all", Module: "exam", Action: "view_all", Description: "ดูข้อสอบทั้งหมด (รวม Private)"},
+	{Code: "qna.moderate", Module: "qna", Action: "moderate", Description: "ดูแลกระทู้ถามตอบ (ซ่อน / แสดงโพสต์)"},
 	{Code: "system.report.view", Module: "system", Action: "report.view", Description: "ดูรายงานสรุปผล"},
 	{Code: "system.exam_history.view", Module: "system", Action: "exam_history.view", Description: "ดูประวัติการสอบของตัวเอง"},
 	{Code: "management.users.manage", Module: "management", Action: "users.manage", Description: "จัดการผู้ใช้"},
+	{Code: "management.users.impersonate", Module: "management", Action: "users.impersonate", Description: "ดูระบบในมุมมองของผู้ใช้ (อ่านอย่างเดียว)"},
 	{Code: "management.roles.manage", Module: "management", Action: "roles.manage", Description: "จัดการสิทธิ์การใช้งาน"},
 	{Code: "management.exam_history.view", Module: "management", Action: "exam_history.view", Description: "ดูประวัติการสอบของทุกคน"},
+	{Code: "management.assignments.manage", Module: "management", Action: "assignments.manage", Description: "มอบหมายการเรียน / ดูรายงานการปฏิบัติตาม"},
 }
 
 var defaultRoles = []Role{
@@ -71,6 +74,7 @@ var defaultRolePermissions = map[string][]string{
 		"exam.take",
 		"exam.manage",
 		"exam.view_all",
+		"qna.moderate",
 		"system.report.view",
 		"system.exam_history.view",
 	},
@@ -81,11 +85,14 @@ var defaultRolePermissions = map[string][]string{
 		"exam.take",
 		"exam.manage",
 		"exam.view_all",
+		"qna.moderate",
 		"system.report.view",
 		"system.exam_history.view",
 		"management.users.manage",
+		"management.users.impersonate",
 		"management.roles.manage",
 		"management.exam_history.view",
+		"management.assignments.manage",
 	},
 }
 
@@ -0,0 +1,315 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AnonymisedUserName replaces the display name of an anonymised account.
+const AnonymisedUserName = "ผู้ใช้ที่ไม่ระบุตัวตน"
+
+var ErrAlreadyAnonymised = errors.New("user is already anonymised")
+
+// EnsurePrivacySchema adds users.anonymised_at and makes every foreign key to
+// users(username) cascade on update, so anonymisation can rename a user in one
+// statement. It inspects pg_constraint, so it must run after every other schema
+// function that adds tables referencing users(username).
+func EnsurePrivacySchema() error {
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymised_at TIMESTAMPTZ`); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT c.conrelid::regclass::text, c.conname, pg_get_constraintdef(c.oid)
+		FROM pg_constraint c
+		WHERE c.contype = 'f'
+		  AND c.confrelid = 'users'::regclass
+		  AND c.confupdtype <> 'c'
+		  AND c.confkey = ARRAY[(
+		        SELECT attnum FROM pg_attribute
+		        WHERE attrelid = 'users'::regclass AND attname = 'username'
+		      )]`)
+	if err != nil {
+		return err
+	}
+	type fk struct{ table, name, def string }
+	var fks []fk
+	for rows.Next() {
+		var f fk
+		if err := rows.Scan(&f.table, &f.name, &f.def); err != nil {
+			rows.Close()
+			return err
+		}
+		fks = append(fks, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, f := range fks {
+		name := `"` + strings.ReplaceAll(f.name, `"`, `""`) + `"`
+		if _, err := db.Exec(fmt.Sprintf(
+			`ALTER TABLE %s DROP CONSTRAINT %s, ADD CONSTRAINT %s %s ON UPDATE CASCADE`,
+			f.table, name, name, f.def,
+		)); err != nil {
+			return fmt.Errorf("cascade %s.%s: %w", f.table, f.name, err)
+		}
+	}
+	return nil
+}
+
+// PersonalDataTable is one dataset of a user's personal data export.
+type PersonalDataTable struct {
+	Name    string
+	Columns []string
+	Rows    [][]any
+}
+
+// personalDataQueries lists every dataset included in a personal data export.
+// Each query takes the username as $1. The avatar image is added by the handler.
+var personalDataQueries = []struct {
+	name  string
+	query string
+}{
+	{"profile", `
+		SELECT id, name, username, employee_code, role_code, status, created_at,
+		       COALESCE(manager_username, '') AS manager_username, email, email_verified_at
+		FROM users WHERE username = $1`},
+	{"scores", `
+		SELECT total, updated_at FROM user_scores WHERE username = $1`},
+	{"skill_scores", `
+		SELECT skill, points FROM user_skill_scores WHERE username = $1 ORDER BY skill`},
+	{"score_events", `
+		SELECT score, skill, reason, COALESCE(course_id, '') AS course_id, COALESCE(exam_id, '') AS exam_id, note, earned_at
+		FROM user_score_events WHERE username = $1 ORDER BY earned_at`},
+	{"course_enrollments", `
+		SELECT e.course_id, c.title AS course_title, e.enrolled_at, e.completed_at
+		FROM user_course_enrollments e
+		JOIN courses c ON c.id = e.course_id
+		WHERE e.username = $1 ORDER BY e.enrolled_at`},
+	{"subtopic_progress", `
+		SELECT course_id, subtopic_id, completed_at
+		FROM learning_subtopic_progress WHERE username = $1 ORDER BY completed_at`},
+	{"subtopic_answers", `
+		SELECT course_id, subtopic_id, question_id, typed_answer, is_correct, answered_at
+		FROM learning_subtopic_answers WHERE username = $1 ORDER BY answered_at`},
+	{"subtopic_time", `
+		SELECT course_id, subtopic_id, seconds_spent, updated_at
+		FROM learning_subtopic_time WHERE username = $1 ORDER BY course_id, subtopic_id`},
+	{"exam_attempts", `
+		SELECT a.id, a.exam_id, e.title AS exam_title, a.correct_count, a.total_questions,
+		       a.score_percent::float8 AS score_percent, a.domain_stats::text AS domain_stats,
+		       a.started_at, a.finished_at
+		FROM exam_attempts a
+		JOIN exams e ON e.id = a.exam_id
+		WHERE a.username = $1 ORDER BY a.started_at`},
+	{"exam_attempt_answers", `
+		SELECT aa.attempt_id, aa.question_id, q.question, aa.selected, aa.is_correct
+		FROM exam_attempt_answers aa
+		JOIN exam_attempts a ON a.id = aa.attempt_id
+		JOIN exam_questions q ON q.id = aa.question_id
+		WHERE a.username = $1 ORDER BY aa.attempt_id, aa.question_id`},
+	{"learning_path_completions", `
+		SELECT c.path_id, p.title AS path_title, c.awarded_score, c.completed_at
+		FROM learning_path_completions c
+		JOIN learning_paths p ON p.id = c.path_id
+		WHERE c.username = $1 ORDER BY c.completed_at`},
+	{"assignments", `
+		SELECT a.id, a.item_type, a.item_id, a.due_at, a.recurrence_days, a.note, a.created_at
+		FROM assignments a
+		JOIN assignment_targets t ON t.assignment_id = a.id AND t.target_type = 'user'
+		WHERE t.target_value = $1 ORDER BY a.due_at`},
+	{"notifications", `
+		SELECT type, title, body, link, created_at, read_at
+		FROM notifications WHERE username = $1 ORDER BY created_at`},
+	{"notification_preferences", `
+		SELECT locale, email_opt_out, updated_at
+		FROM notification_preferences WHERE username = $1`},
+	{"mail_outbox", `
+		SELECT to_address, template, locale, status, created_at, sent_at
+		FROM mail_outbox WHERE username = $1 ORDER BY created_at`},
+	{"groups", `
+		SELECT g.name, m.added_at
+		FROM user_group_members m JOIN user_groups g ON g.id = m.group_id
+		WHERE m.username = $1 ORDER BY g.name`},
+	{"invite_redemptions", `
+		SELECT i.label, i.role_code, r.redeemed_at
+		FROM user_invite_redemptions r JOIN user_invites i ON i.id = r.invite_id
+		WHERE r.username = $1 ORDER BY r.redeemed_at`},
+	{"reading_sessions", `
+		SELECT course_id, subtopic_id, started_at, last_beat_at, ended_at, credited_seconds
+		FROM learning_reading_sessions WHERE username = $1 ORDER BY started_at`},
+	{"course_positions", `
+		SELECT course_id, subtopic_id, visited_at
+		FROM learning_course_positions WHERE username = $1 ORDER BY course_id`},
+	{"bookmarks", `
+		SELECT course_id, subtopic_id, created_at
+		FROM learning_bookmarks WHERE username = $1 ORDER BY created_at`},
+	{"notes", `
+		SELECT id, course_id, subtopic_id, body, created_at, updated_at
+		FROM learning_notes WHERE username = $1 ORDER BY created_at`},
+	{"course_reviews", `
+		SELECT r.course_id, c.title AS course_title, r.rating, r.review, r.hidden_at, r.created_at, r.updated_at
+		FROM course_reviews r JOIN courses c ON c.id = r.course_id
+		WHERE r.username = $1 ORDER BY r.created_at`},
+	{"enrollment_requests", `
+		SELECT r.course_id, c.title AS course_title, r.status, r.requested_at, r.decided_at
+		FROM course_enrollment_requests r JOIN courses c ON c.id = r.course_id
+		WHERE r.username = $1 ORDER BY r.requested_at`},
+	{"cohorts", `
+		SELECT m.course_id, h.name AS cohort_name, h.starts_at, h.ends_at, m.added_at
+		FROM course_cohort_members m JOIN course_cohorts h ON h.id = m.cohort_id
+		WHERE m.username = $1 ORDER BY h.starts_at`},
+	{"badges", `
+		SELECT b.name, ub.awarded_at
+		FROM user_badges ub JOIN badges b ON b.id = ub.badge_id
+		WHERE ub.username = $1 ORDER BY ub.awarded_at`},
+	{"live_session_attendance", `
+		SELECT s.course_id, s.title, s.starts_at, a.registered_at, a.attended_at, a.attendance_method
+		FROM course_live_session_attendees a JOIN course_live_sessions s ON s.id = a.session_id
+		WHERE a.username = $1 ORDER BY s.starts_at`},
+	{"completion_records", `
+		SELECT item_type, item_id, attempt_id, completed_at
+		FROM completion_records WHERE username = $1 ORDER BY completed_at`},
+	{"qna_questions", `
+		SELECT id, course_id, subtopic_id, cohort_id, question, status, accepted_reply_id, edited_at, created_at
+		FROM qna_questions WHERE username = $1 ORDER BY created_at`},
+	{"qna_replies", `
+		SELECT id, question_id, reply, edited_at, created_at
+		FROM qna_replies WHERE username = $1 ORDER BY created_at`},
+	{"login_logs", `
+		SELECT l.logged_in_at
+		FROM user_login_logs l
+		JOIN users u ON u.id = l.user_id
+		WHERE u.username = $1 ORDER BY l.logged_in_at`},
+	{"impersonation_logs", `
+		SELECT admin_username, reason, started_at, ended_at
+		FROM impersonation_logs WHERE target_username = $1 ORDER BY started_at`},
+}
+
+// ExportPersonalData collects every dataset that holds the user's personal data.
+func ExportPersonalData(username string) ([]PersonalDataTable, error) {
+	normalized := NormalizeUsername(username)
+	tables := make([]PersonalDataTable, 0, len(personalDataQueries))
+	for _, q := range personalDataQueries {
+		table, err := queryPersonalDataTable(q.name, q.query, normalized)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", q.name, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func queryPersonalDataTable(name, query, username string) (PersonalDataTable, error) {
+	rows, err := db.Query(query, username)
+	if err != nil {
+		return PersonalDataTable{}, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return PersonalDataTable{}, err
+	}
+	table := PersonalDataTable{Name: name, Columns: columns, Rows: make([][]any, 0)}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return PersonalDataTable{}, err
+		}
+		table.Rows = append(table.Rows, values)
+	}
+	return table, rows.Err()
+}
+
+// AnonymiseUser replaces the user's identity with a random pseudonym while keeping
+// every learning, score and exam record attached to it, so aggregate statistics
+// stay intact. The account is deactivated, its avatar and sessions are removed,
+// and the new username is returned.
+//
+// Private content (notes, bookmarks, last positions) is deleted. Free text about
+// the user that only they or admins wrote is blanked: review texts keep their
+// rating and manual score adjustments keep their points. Q&A posts are kept as
+// written, since other learners' replies and accepted answers build on them;
+// moderators can hide a post that identifies its author.
+func AnonymiseUser(username string) (string, error) {
+	normalized := NormalizeUsername(username)
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	pseudonym := "anon-" + hex.EncodeToString(suffix)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var oldName string
+	var anonymised bool
+	err = tx.QueryRow(
+		`SELECT id, name, anonymised_at IS NOT NULL FROM users WHERE username = $1 FOR UPDATE`,
+		normalized,
+	).Scan(&userID, &oldName, &anonymised)
+	if err != nil {
+		return "", err
+	}
+	if anonymised {
+		return "", ErrAlreadyAnonymised
+	}
+
+	// Foreign keys to users(username) cascade, so this renames every related row.
+	// The password hash is not a valid bcrypt hash, so the account can never log in.
+	if _, err := tx.Exec(
+		`UPDATE users
+		 SET username = $2, name = $3, employee_code = '', password_hash = '!',
+		     email = '', email_verified_at = NULL, status = 'inactive', anonymised_at = NOW()
+		 WHERE id = $1`,
+		userID, pseudonym, AnonymisedUserName,
+	); err != nil {
+		return "", err
+	}
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`DELETE FROM user_avatars WHERE username = $1`, []any{pseudonym}},
+		{`DELETE FROM refresh_tokens WHERE user_id = $1`, []any{userID}},
+		{`DELETE FROM account_tokens WHERE user_id = $1`, []any{userID}},
+		{`DELETE FROM mail_outbox WHERE username = $1`, []any{pseudonym}},
+		{`DELETE FROM learning_notes WHERE username = $1`, []any{pseudonym}},
+		{`DELETE FROM learning_bookmarks WHERE username = $1`, []any{pseudonym}},
+		{`DELETE FROM learning_course_positions WHERE username = $1`, []any{pseudonym}},
+		{`UPDATE course_reviews SET review = '' WHERE username = $1`, []any{pseudonym}},
+		{`UPDATE user_score_events SET note = '' WHERE username = $1`, []any{pseudonym}},
+		{`UPDATE courses SET allowed_usernames = array_replace(allowed_usernames, $1, $2) WHERE $1 = ANY(allowed_usernames)`, []any{normalized, pseudonym}},
+		{`UPDATE exams SET allowed_usernames = array_replace(allowed_usernames, $1, $2) WHERE $1 = ANY(allowed_usernames)`, []any{normalized, pseudonym}},
+		{`UPDATE courses SET creator = $2 WHERE owner_username = $1 AND creator = $3`, []any{pseudonym, AnonymisedUserName, oldName}},
+		{`UPDATE exams SET creator = $2 WHERE owner_username = $1 AND creator = $3`, []any{pseudonym, AnonymisedUserName, oldName}},
+		{`UPDATE impersonation_logs SET target_username = $2 WHERE target_username = $1`, []any{normalized, pseudonym}},
+		{`UPDATE impersonation_logs SET admin_username = $2 WHERE admin_username = $1`, []any{normalized, pseudonym}},
+		{`UPDATE assignment_targets SET target_value = $2 WHERE target_type = 'user' AND target_value = $1`, []any{normalized, pseudonym}},
+	}
+	for _, s := range statements {
+		if _, err := tx.Exec(s.query, s.args...); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return pseudonym, nil
+}
@@ -1,28 +1,154 @@
 package data
 
-import "fmt"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const (
+	QnAStatusOpen     = "open"
+	QnAStatusResolved = "resolved"
+)
+
+var (
+	ErrInvalidQnAStatus    = errors.New("status must be open or resolved")
+	ErrQnAReplyNotQuestion = errors.New("the reply does not belong to this question")
+)
+
+// EnsureQnASchema adds editing, moderation and resolution to Q&A. When the status
+// column is first added, questions that already have a reply from the course
+// owner or their cohort instructor are marked resolved.
+func EnsureQnASchema() error {
+	var migrated bool
+	if err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		               WHERE table_name = 'qna_questions' AND column_name = 'status')`).Scan(&migrated); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		ALTER TABLE qna_questions ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open'
+			CHECK (status IN ('open', 'resolved'));
+		ALTER TABLE qna_questions ADD COLUMN IF NOT EXISTS accepted_reply_id BIGINT NULL
+			REFERENCES qna_replies(id) ON DELETE SET NULL;
+		ALTER TABLE qna_questions ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ NULL;
+		ALTER TABLE qna_questions ADD COLUMN IF NOT EXISTS hidden_by TEXT NULL
+			REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE;
+		ALTER TABLE qna_questions ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ NULL;
+		ALTER TABLE qna_replies ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ NULL;
+		ALTER TABLE qna_replies ADD COLUMN IF NOT EXISTS hidden_by TEXT NULL
+			REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE;
+		ALTER TABLE qna_replies ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ NULL;
+	`); err != nil {
+		return err
+	}
+	if !migrated {
+		if _, err := tx.Exec(`
+			UPDATE qna_questions q SET status = 'resolved'
+			WHERE EXISTS (
+				SELECT 1 FROM qna_replies r
+				JOIN courses c ON c.id = q.course_id
+				LEFT JOIN course_cohorts h ON h.id = q.cohort_id
+				WHERE r.question_id = q.id
+				  AND r.username IN (c.owner_username, h.instructor_username))`); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// qnaCohortScope narrows questions to one cohort, or to course-wide questions when
+// cohortID is 0. The cohort id is the second query parameter.
+const qnaCohortScope = `(($2::bigint = 0 AND q.cohort_id IS NULL) OR q.cohort_id = $2)`
+
+const qnaQuestionColumns = `
+	q.id, q.course_id, q.subtopic_id, q.cohort_id, q.username, u.name, q.question,
+	q.status, q.accepted_reply_id, q.hidden_at IS NOT NULL, COALESCE(q.hidden_by, ''),
+	q.edited_at, q.created_at`
+
+// qnaReplyFrom joins what a reply needs to tell whether its author instructs the
+// question's course or cohort.
+const qnaReplyFrom = `
+	FROM qna_replies r
+	JOIN users u ON u.username = r.username
+	JOIN qna_questions q ON q.id = r.question_id
+	JOIN courses c ON c.id = q.course_id
+	LEFT JOIN course_cohorts h ON h.id = q.cohort_id`
+
+const qnaReplyColumns = `
+	r.id, r.question_id, r.username, u.name, r.reply,
+	COALESCE(r.username IN (c.owner_username, h.instructor_username), false),
+	r.hidden_at IS NOT NULL, COALESCE(r.hidden_by, ''), r.edited_at, r.created_at`
+
+func scanQnAQuestion(row interface{ Scan(dest ...any) error }) (QnAQuestion, error) {
+	var q QnAQuestion
+	err := row.Scan(&q.ID, &q.CourseID, &q.SubtopicID, &q.CohortID, &q.Username, &q.Name, &q.Question,
+		&q.Status, &q.AcceptedReplyID, &q.Hidden, &q.HiddenBy, &q.EditedAt, &q.CreatedAt)
+	q.Replies = []QnAReply{}
+	return q, err
+}
+
+func scanQnAReply(row interface{ Scan(dest ...any) error }) (QnAReply, error) {
+	var r QnAReply
+	err := row.Scan(&r.ID, &r.QuestionID, &r.Username, &r.Name, &r.Reply,
+		&r.IsInstructor, &r.Hidden, &r.HiddenBy, &r.EditedAt, &r.CreatedAt)
+	return r, err
+}
+
+// GetCourseQnA returns the Q&A of one cohort of a course, or its course-wide Q&A
+// when cohortID is 0. Hidden questions and replies are only included for
+// moderators.
+func GetCourseQnA(courseID string, cohortID int64, includeHidden bool) ([]QnAQuestion, error) {
+	return loadQnA(`q.course_id = $1 AND `+qnaCohortScope, includeHidden, courseID, cohortID)
+}
+
+// getQnAQuestion returns one question with its replies.
+func getQnAQuestion(questionID int64, includeHidden bool) (QnAQuestion, error) {
+	questions, err := loadQnA(`q.id = $1`, includeHidden, questionID)
+	if err != nil {
+		return QnAQuestion{}, err
+	}
+	if len(questions) == 0 {
+		return QnAQuestion{}, sql.ErrNoRows
+	}
+	return questions[0], nil
+}
+
+// loadQnA returns the questions matching the condition on q, oldest first, with
+// their replies.
+func loadQnA(where string, includeHidden bool, args ...any) ([]QnAQuestion, error) {
+	questionFilter, replyFilter := "", ""
+	if !includeHidden {
+		questionFilter = ` AND q.hidden_at IS NULL`
+		replyFilter = ` AND r.hidden_at IS NULL`
+	}
 
-func GetCourseQnA(courseID string) ([]QnAQuestion, error) {
 	rows, err := db.Query(`
-		SELECT q.id, q.course_id, q.subtopic_id, q.username, u.name, q.question, q.created_at
+		SELECT `+qnaQuestionColumns+`
 		FROM qna_questions q
 		JOIN users u ON u.username = q.username
-		WHERE q.course_id = $1
-		ORDER BY q.created_at ASC`, courseID)
+		WHERE `+where+questionFilter+`
+		ORDER BY q.created_at ASC`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var questions []QnAQuestion
+	questions := []QnAQuestion{}
 	questionMap := make(map[int64]int) // id → index
 
 	for rows.Next() {
-		var q QnAQuestion
-		if err := rows.Scan(&q.ID, &q.CourseID, &q.SubtopicID, &q.Username, &q.Name, &q.Question, &q.CreatedAt); err != nil {
-			continue
+		q, err := scanQnAQuestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan question: %w", err)
 		}
-		q.Replies = []QnAReply{}
 		questionMap[q.ID] = len(questions)
 		questions = append(questions, q)
 	}
@@ -35,51 +161,256 @@ func GetCourseQnA(courseID string) ([]QnAQuestion, error) {
 	}
 
 	replyRows, err := db.Query(`
-		SELECT r.id, r.question_id, r.username, u.name, r.reply, r.created_at
-		FROM qna_replies r
-		JOIN users u ON u.username = r.username
-		INNER JOIN qna_questions q ON q.id = r.question_id
-		WHERE q.course_id = $1
-		ORDER BY r.created_at ASC`, courseID)
+		SELECT `+qnaReplyColumns+qnaReplyFrom+`
+		WHERE `+where+questionFilter+replyFilter+`
+		ORDER BY r.created_at ASC`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("cannot load replies: %w", err)
 	}
 	defer replyRows.Close()
 
 	for replyRows.Next() {
-		var r QnAReply
-		if err := replyRows.Scan(&r.ID, &r.QuestionID, &r.Username, &r.Name, &r.Reply, &r.CreatedAt); err != nil {
-			continue
+		r, err := scanQnAReply(replyRows)
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan reply: %w", err)
 		}
 		if idx, ok := questionMap[r.QuestionID]; ok {
 			questions[idx].Replies = append(questions[idx].Replies, r)
 		}
 	}
 
-	return questions, nil
+	return questions, replyRows.Err()
 }
 
+func getQnAReply(replyID int64) (QnAReply, error) {
+	return scanQnAReply(db.QueryRow(`SELECT `+qnaReplyColumns+qnaReplyFrom+` WHERE r.id = $1`, replyID))
+}
+
+// CreateQnAQuestion posts a question to the asker's cohort of the course, or
+// course-wide when they learn outside any cohort.
 func CreateQnAQuestion(courseID, subtopicID, username, question string) (QnAQuestion, error) {
-	var q QnAQuestion
+	var questionID int64
 	err := db.QueryRow(`
-		INSERT INTO qna_questions (course_id, subtopic_id, username, question)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, course_id, subtopic_id, username,
-			(SELECT name FROM users WHERE username = $3), question, created_at`,
+		INSERT INTO qna_questions (course_id, subtopic_id, username, question, cohort_id)
+		VALUES ($1, $2, $3, $4,
+			(SELECT cohort_id FROM course_cohort_members WHERE course_id = $1 AND username = $3))
+		RETURNING id`,
 		courseID, subtopicID, username, question,
-	).Scan(&q.ID, &q.CourseID, &q.SubtopicID, &q.Username, &q.Name, &q.Question, &q.CreatedAt)
-	q.Replies = []QnAReply{}
-	return q, err
+	).Scan(&questionID)
+	if err != nil {
+		return QnAQuestion{}, err
+	}
+	return getQnAQuestion(questionID, false)
 }
 
-func CreateQnAReply(questionID int64, username, reply string) (QnAReply, error) {
-	var r QnAReply
+// checkQnAQuestionCohort allows anyone on a course-wide question and, on a cohort's
+// question, only its members, its instructor, the course owner and admins. It
+// returns sql.ErrNoRows for a missing or hidden question.
+func checkQnAQuestionCohort(questionID int64, callerUsername string, isAdmin bool) error {
+	var courseID string
+	var cohortID sql.NullInt64
+	if err := db.QueryRow(`
+		SELECT course_id, cohort_id FROM qna_questions WHERE id = $1 AND hidden_at IS NULL`,
+		questionID).Scan(&courseID, &cohortID); err != nil {
+		return err
+	}
+	if !cohortID.Valid {
+		return nil
+	}
+	return CheckCohortAccess(courseID, cohortID.Int64, callerUsername, isAdmin)
+}
+
+// CreateQnAReply answers a visible question; it returns sql.ErrNoRows when the
+// question does not exist or is hidden and ErrForbidden when the caller may not
+// take part in the question's cohort.
+func CreateQnAReply(questionID int64, username, reply string, isAdmin bool) (QnAReply, error) {
+	if err := checkQnAQuestionCohort(questionID, username, isAdmin); err != nil {
+		return QnAReply{}, err
+	}
+	var replyID int64
 	err := db.QueryRow(`
 		INSERT INTO qna_replies (question_id, username, reply)
-		VALUES ($1, $2, $3)
-		RETURNING id, question_id, username,
-			(SELECT name FROM users WHERE username = $2), reply, created_at`,
+		SELECT id, $2, $3 FROM qna_questions WHERE id = $1 AND hidden_at IS NULL
+		RETURNING id`,
 		questionID, username, reply,
-	).Scan(&r.ID, &r.QuestionID, &r.Username, &r.Name, &r.Reply, &r.CreatedAt)
-	return r, err
+	).Scan(&replyID)
+	if err != nil {
+		return QnAReply{}, err
+	}
+	return getQnAReply(replyID)
+}
+
+// UpdateQnAQuestion edits the text of the caller's own visible question.
+func UpdateQnAQuestion(questionID int64, username, question string) (QnAQuestion, error) {
+	result, err := db.Exec(`
+		UPDATE qna_questions SET question = $3, edited_at = NOW()
+		WHERE id = $1 AND username = $2 AND hidden_at IS NULL`, questionID, username, question)
+	if err != nil {
+		return QnAQuestion{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return QnAQuestion{}, sql.ErrNoRows
+	}
+	return getQnAQuestion(questionID, false)
+}
+
+// UpdateQnAReply edits the text of the caller's own visible reply. As in
+// CreateQnAReply, an author no longer in the question's cohort gets ErrForbidden.
+func UpdateQnAReply(replyID int64, username, reply string, isAdmin bool) (QnAReply, error) {
+	var questionID int64
+	if err := db.QueryRow(`SELECT question_id FROM qna_replies WHERE id = $1 AND username = $2`,
+		replyID, username).Scan(&questionID); err != nil {
+		return QnAReply{}, err
+	}
+	if err := checkQnAQuestionCohort(questionID, username, isAdmin); err != nil {
+		return QnAReply{}, err
+	}
+	result, err := db.Exec(`
+		UPDATE qna_replies SET reply = $3, edited_at = NOW()
+		WHERE id = $1 AND username = $2 AND hidden_at IS NULL`, replyID, username, reply)
+	if err != nil {
+		return QnAReply{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return QnAReply{}, sql.ErrNoRows
+	}
+	return getQnAReply(replyID)
+}
+
+// DeleteQnAQuestion deletes the caller's own question together with its replies.
+func DeleteQnAQuestion(questionID int64, username string) error {
+	result, err := db.Exec(`DELETE FROM qna_questions WHERE id = $1 AND username = $2`, questionID, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteQnAReply deletes the caller's own reply; a question that accepted it
+// keeps its status but no longer has an accepted answer.
+func DeleteQnAReply(replyID int64, username string) error {
+	result, err := db.Exec(`DELETE FROM qna_replies WHERE id = $1 AND username = $2`, replyID, username)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetQnAQuestionHidden hides or restores a question and, with it, its replies.
+func SetQnAQuestionHidden(questionID int64, hidden bool, moderator string) (QnAQuestion, error) {
+	query := `UPDATE qna_questions SET hidden_at = NULL, hidden_by = NULL WHERE id = $1`
+	args := []any{questionID}
+	if hidden {
+		query = `UPDATE qna_questions SET hidden_at = NOW(), hidden_by = $2 WHERE id = $1`
+		args = append(args, moderator)
+	}
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return QnAQuestion{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return QnAQuestion{}, sql.ErrNoRows
+	}
+	return getQnAQuestion(questionID, true)
+}
+
+// SetQnAReplyHidden hides or restores a reply. A hidden reply stops being the
+// question's accepted answer.
+func SetQnAReplyHidden(replyID int64, hidden bool, moderator string) (QnAReply, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return QnAReply{}, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE qna_replies SET hidden_at = NULL, hidden_by = NULL WHERE id = $1`
+	args := []any{replyID}
+	if hidden {
+		query = `UPDATE qna_replies SET hidden_at = NOW(), hidden_by = $2 WHERE id = $1`
+		args = append(args, moderator)
+	}
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return QnAReply{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return QnAReply{}, sql.ErrNoRows
+	}
+	if hidden {
+		if _, err := tx.Exec(`UPDATE qna_questions SET accepted_reply_id = NULL WHERE accepted_reply_id = $1`, replyID); err != nil {
+			return QnAReply{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return QnAReply{}, err
+	}
+	return getQnAReply(replyID)
+}
+
+// checkQnAResolver allows the question author, the course owner, the instructor
+// of the question's cohort and admins. It returns sql.ErrNoRows for a missing or
+// hidden question.
+func checkQnAResolver(questionID int64, callerUsername string, isAdmin bool) error {
+	var author, owner, instructor string
+	err := db.QueryRow(`
+		SELECT q.username, COALESCE(c.owner_username, ''), COALESCE(h.instructor_username, '')
+		FROM qna_questions q
+		JOIN courses c ON c.id = q.course_id
+		LEFT JOIN course_cohorts h ON h.id = q.cohort_id
+		WHERE q.id = $1 AND q.hidden_at IS NULL`, questionID).Scan(&author, &owner, &instructor)
+	if err != nil {
+		return err
+	}
+	if isAdmin || callerUsername == author || callerUsername == owner || callerUsername == instructor {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// AcceptQnAReply marks a visible reply as the question's accepted answer and
+// resolves the question. A replyID of 0 clears the accepted answer and leaves the
+// status as it is.
+func AcceptQnAReply(questionID, replyID int64, callerUsername string, isAdmin bool) (QnAQuestion, error) {
+	if err := checkQnAResolver(questionID, callerUsername, isAdmin); err != nil {
+		return QnAQuestion{}, err
+	}
+	if replyID == 0 {
+		if _, err := db.Exec(`UPDATE qna_questions SET accepted_reply_id = NULL WHERE id = $1`, questionID); err != nil {
+			return QnAQuestion{}, err
+		}
+		return getQnAQuestion(questionID, false)
+	}
+
+	result, err := db.Exec(`
+		UPDATE qna_questions SET accepted_reply_id = $2, status = 'resolved'
+		WHERE id = $1
+		  AND EXISTS (SELECT 1 FROM qna_replies WHERE id = $2 AND question_id = $1 AND hidden_at IS NULL)`,
+		questionID, replyID)
+	if err != nil {
+		return QnAQuestion{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return QnAQuestion{}, ErrQnAReplyNotQuestion
+	}
+	return getQnAQuestion(questionID, false)
+}
+
+// SetQnAStatus opens or resolves a question.
+func SetQnAStatus(questionID int64, status, callerUsername string, isAdmin bool) (QnAQuestion, error) {
+	if status != QnAStatusOpen && status != QnAStatusResolved {
+		return QnAQuestion{}, ErrInvalidQnAStatus
+	}
+	if err := checkQnAResolver(questionID, callerUsername, isAdmin); err != nil {
+		return QnAQuestion{}, err
+	}
+	if _, err := db.Exec(`UPDATE qna_questions SET status = $2 WHERE id = $1`, questionID, status); err != nil {
+		return QnAQuestion{}, err
+	}
+	return getQnAQuestion(questionID, false)
 }
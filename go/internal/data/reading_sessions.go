@@ -0,0 +1,171 @@
+package data
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrReadingSessionEnded = errors.New("reading session has ended")
+
+// ReadingSession is a server-issued session for one open subtopic. Reading time is
+// credited from its heartbeats instead of client-reported totals.
+type ReadingSession struct {
+	ID              int64      `json:"id"`
+	CourseID        string     `json:"courseId"`
+	SubtopicID      string     `json:"subtopicId"`
+	StartedAt       time.Time  `json:"startedAt"`
+	LastBeatAt      time.Time  `json:"lastBeatAt"`
+	EndedAt         *time.Time `json:"endedAt,omitempty"`
+	CreditedSeconds int        `json:"creditedSeconds"`
+}
+
+// HeartbeatLimits bounds how much time a heartbeat can credit. MaxCredit caps the
+// interval since the previous heartbeat; a session silent for longer than
+// IdleTimeout is closed without credit.
+type HeartbeatLimits struct {
+	MaxCredit   time.Duration
+	IdleTimeout time.Duration
+}
+
+// EnsureReadingSessionSchema creates reading sessions and the per-user credit cursor.
+// The cursor records up to when a user's reading time has been credited, so
+// sessions open in several tabs never credit the same wall-clock time twice.
+func EnsureReadingSessionSchema() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS learning_reading_sessions (
+			id               BIGSERIAL    PRIMARY KEY,
+			username         TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			course_id        TEXT         NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+			subtopic_id      TEXT         NOT NULL,
+			started_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			last_beat_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			ended_at         TIMESTAMPTZ  NULL,
+			credited_seconds INT          NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS ix_reading_sessions_open ON learning_reading_sessions(username) WHERE ended_at IS NULL;
+		CREATE TABLE IF NOT EXISTS learning_time_cursors (
+			username       TEXT         PRIMARY KEY REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			credited_until TIMESTAMPTZ  NOT NULL
+		);
+	`)
+	return err
+}
+
+// StartReadingSession opens a session on a subtopic. Open sessions of the user that
+// went idle are closed first.
+func StartReadingSession(username, courseID, subtopicID string, idleTimeout time.Duration) (ReadingSession, error) {
+	if err := EnsureEnrollment(username, courseID); err != nil {
+		return ReadingSession{}, courseRefError(err)
+	}
+	if _, err := db.Exec(`
+		UPDATE learning_reading_sessions SET ended_at = last_beat_at
+		WHERE username = $1 AND ended_at IS NULL
+		  AND last_beat_at < NOW() - make_interval(secs => $2)`,
+		username, idleTimeout.Seconds()); err != nil {
+		return ReadingSession{}, err
+	}
+
+	var s ReadingSession
+	err := db.QueryRow(`
+		INSERT INTO learning_reading_sessions (username, course_id, subtopic_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, course_id, subtopic_id, started_at, last_beat_at, credited_seconds`,
+		username, courseID, subtopicID,
+	).Scan(&s.ID, &s.CourseID, &s.SubtopicID, &s.StartedAt, &s.LastBeatAt, &s.CreditedSeconds)
+	return s, courseRefError(err)
+}
+
+// RecordHeartbeat credits the time since the previous heartbeat of the session and
+// returns the updated session. The interval is capped at limits.MaxCredit, is not
+// credited when the client reports the learner idle, and skips time already
+// credited to another session of the user. With end set the session is closed.
+// A session of another user is reported as sql.ErrNoRows.
+func RecordHeartbeat(sessionID int64, username string, idle, end bool, limits HeartbeatLimits) (ReadingSession, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return ReadingSession{}, err
+	}
+	defer tx.Rollback()
+
+	var s ReadingSession
+	var now time.Time
+	if err := tx.QueryRow(`
+		SELECT id, course_id, subtopic_id, started_at, last_beat_at, ended_at, credited_seconds, NOW()
+		FROM learning_reading_sessions
+		WHERE id = $1 AND username = $2
+		FOR UPDATE`, sessionID, username,
+	).Scan(&s.ID, &s.CourseID, &s.SubtopicID, &s.StartedAt, &s.LastBeatAt, &s.EndedAt, &s.CreditedSeconds, &now); err != nil {
+		return ReadingSession{}, err
+	}
+	if s.EndedAt != nil {
+		return s, ErrReadingSessionEnded
+	}
+	if now.Sub(s.LastBeatAt) > limits.IdleTimeout {
+		if _, err := tx.Exec(`UPDATE learning_reading_sessions SET ended_at = last_beat_at WHERE id = $1`, s.ID); err != nil {
+			return ReadingSession{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return ReadingSession{}, err
+		}
+		s.EndedAt = &s.LastBeatAt
+		return s, ErrReadingSessionEnded
+	}
+
+	credited := 0
+	if !idle {
+		if _, err := tx.Exec(`
+			INSERT INTO learning_time_cursors (username, credited_until) VALUES ($1, 'epoch')
+			ON CONFLICT (username) DO NOTHING`, username); err != nil {
+			return ReadingSession{}, err
+		}
+		var creditedUntil time.Time
+		if err := tx.QueryRow(`
+			SELECT credited_until FROM learning_time_cursors WHERE username = $1 FOR UPDATE`, username,
+		).Scan(&creditedUntil); err != nil {
+			return ReadingSession{}, err
+		}
+
+		from := s.LastBeatAt
+		if creditedUntil.After(from) {
+			from = creditedUntil
+		}
+		to := now
+		if limit := s.LastBeatAt.Add(limits.MaxCredit); limit.Before(to) {
+			to = limit
+		}
+		if to.After(from) {
+			credited = int(to.Sub(from) / time.Second)
+		}
+		if credited > 0 {
+			until := from.Add(time.Duration(credited) * time.Second)
+			if _, err := tx.Exec(`UPDATE learning_time_cursors SET credited_until = $2 WHERE username = $1`, username, until); err != nil {
+				return ReadingSession{}, err
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO learning_subtopic_time (username, course_id, subtopic_id, seconds_spent, updated_at)
+				VALUES ($1, $2, $3, $4, NOW())
+				ON CONFLICT (username, course_id, subtopic_id) DO UPDATE
+					SET seconds_spent = learning_subtopic_time.seconds_spent + EXCLUDED.seconds_spent,
+					    updated_at    = NOW()`,
+				username, s.CourseID, s.SubtopicID, credited); err != nil {
+				return ReadingSession{}, err
+			}
+		}
+	}
+
+	s.LastBeatAt = now
+	s.CreditedSeconds += credited
+	if end {
+		s.EndedAt = &now
+	}
+	if _, err := tx.Exec(`
+		UPDATE learning_reading_sessions
+		SET last_beat_at = $2, credited_seconds = $3, ended_at = $4
+		WHERE id = $1`, s.ID, s.LastBeatAt, s.CreditedSeconds, s.EndedAt); err != nil {
+		return ReadingSession{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ReadingSession{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,401 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Weights of the recommendation signals. Skill gaps count once per missing skill
+// an item rewards; the other signals at most once per item.
+const (
+	recommendSkillGapWeight   = 3.0
+	recommendWeakDomainWeight = 2.5
+	recommendPeerWeight       = 2.0
+	recommendPopularWeight    = 1.0
+
+	// Co-enrollment counts below this are too thin to be a pattern.
+	recommendMinPeers = 2
+	// Only the learners sharing the most courses with the user count as peers, so
+	// the co-enrollment query stays bounded on large catalogues.
+	recommendMaxPeers = 500
+)
+
+// Recommendation is a course or exam suggested to a learner. Reasons explain the
+// signals behind Score, strongest first; Explanation is the strongest one.
+type Recommendation struct {
+	ItemType    string   `json:"itemType"` // course | exam
+	ItemID      string   `json:"itemId"`
+	Title       string   `json:"title"`
+	Image       string   `json:"image"`
+	Score       float64  `json:"score"`
+	Explanation string   `json:"explanation"`
+	Reasons     []string `json:"reasons"`
+}
+
+type recommendReason struct {
+	weight float64
+	text   string
+}
+
+type recommendCandidate struct {
+	rec      Recommendation
+	learners int
+	skills   []string // skills the item rewards
+	domains  []string // exam domains covered
+	reasons  []recommendReason
+}
+
+func (c *recommendCandidate) add(weight float64, text string) {
+	c.reasons = append(c.reasons, recommendReason{weight, text})
+	c.rec.Score += weight
+}
+
+// domainResult is a learner's share of correct answers in one exam domain.
+type domainResult struct {
+	correct, total int
+}
+
+func (d domainResult) percent() int {
+	return d.correct * 100 / d.total
+}
+
+// RecommendFor ranks the active courses and exams the user can see and has not
+// taken yet. Courses the user is enrolled in, exams with a valid pass and exams
+// with no attempts left are left out, as are items still locked behind a learning
+// path step.
+func RecommendFor(username string, limit int) ([]Recommendation, error) {
+	candidates, err := recommendCandidates(username)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return []Recommendation{}, nil
+	}
+	if err := scoreSkillGaps(username, candidates); err != nil {
+		return nil, err
+	}
+	if err := scoreWeakDomains(username, candidates); err != nil {
+		return nil, err
+	}
+	if err := scoreCoEnrollment(username, candidates); err != nil {
+		return nil, err
+	}
+	scorePopularity(candidates)
+
+	ranked := make([]*recommendCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.rec.Score > 0 {
+			ranked = append(ranked, c)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.rec.Score != b.rec.Score {
+			return a.rec.Score > b.rec.Score
+		}
+		if a.learners != b.learners {
+			return a.learners > b.learners
+		}
+		if a.rec.Title != b.rec.Title {
+			return a.rec.Title < b.rec.Title
+		}
+		return a.rec.ItemID < b.rec.ItemID
+	})
+
+	recs := make([]Recommendation, 0, limit)
+	for _, c := range ranked {
+		if len(recs) == limit {
+			break
+		}
+		if err := CheckItemUnlocked(username, c.rec.ItemType, c.rec.ItemID); err != nil {
+			if errors.Is(err, ErrItemLocked) {
+				continue
+			}
+			return nil, err
+		}
+		sort.SliceStable(c.reasons, func(i, j int) bool { return c.reasons[i].weight > c.reasons[j].weight })
+		c.rec.Reasons = make([]string, 0, len(c.reasons))
+		for _, r := range c.reasons {
+			c.rec.Reasons = append(c.rec.Reasons, r.text)
+		}
+		c.rec.Explanation = c.rec.Reasons[0]
+		c.rec.Score = math.Round(c.rec.Score*100) / 100
+		recs = append(recs, c.rec)
+	}
+	return recs, nil
+}
+
+// recommendCandidates loads the items the user could take next, keyed by
+// "type:id", with the skills they reward and, for exams, the domains they cover.
+func recommendCandidates(username string) (map[string]*recommendCandidate, error) {
+	rows, err := db.Query(`
+		SELECT 'course', c.id, c.title, c.image,
+		       (SELECT COUNT(*) FROM user_course_enrollments e WHERE e.course_id = c.id)
+		FROM courses c
+		WHERE c.status = 'active'
+		  AND (COALESCE(c.visibility, 'public') = 'public' OR $1 = ANY(c.allowed_usernames))
+		  AND NOT EXISTS (SELECT 1 FROM user_course_enrollments e WHERE e.course_id = c.id AND e.username = $1)
+		UNION ALL
+		SELECT 'exam', x.id, x.title, x.image,
+		       (SELECT COUNT(DISTINCT a.username) FROM exam_attempts a WHERE a.exam_id = x.id)
+		FROM exams x
+		WHERE x.status = 'active'
+		  AND (COALESCE(x.visibility, 'public') = 'public' OR $1 = ANY(x.allowed_usernames))
+		  AND NOT EXISTS (SELECT 1 FROM completion_records r
+		                  WHERE r.username = $1 AND r.item_type = 'exam' AND r.item_id = x.id
+		                    AND (x.validity_days = 0 OR r.completed_at + make_interval(days => x.validity_days) > NOW()))
+		  -- Any pass left here has expired, and only attempts after it count (see CheckExamAttemptLimit).
+		  AND (x.max_attempts = 0 OR x.max_attempts > (
+		       SELECT COUNT(*) FROM exam_attempts a
+		       WHERE a.exam_id = x.id AND a.username = $1
+		         AND a.started_at > COALESCE((SELECT MAX(r.completed_at) + make_interval(days => x.validity_days)
+		                                      FROM completion_records r
+		                                      WHERE r.username = $1 AND r.item_type = 'exam' AND r.item_id = x.id),
+		                                     '-infinity')))`,
+		username)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make(map[string]*recommendCandidate)
+	for rows.Next() {
+		c := &recommendCandidate{}
+		if err := rows.Scan(&c.rec.ItemType, &c.rec.ItemID, &c.rec.Title, &c.rec.Image, &c.learners); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("cannot scan recommendation candidate: %w", err)
+		}
+		candidates[c.rec.ItemType+":"+c.rec.ItemID] = c
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`
+		SELECT 'course', course_id, skill FROM course_skill_rewards WHERE points > 0
+		UNION
+		SELECT 'exam', exam_id, skill FROM exam_skill_rewards`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var itemType, itemID, skill string
+		if err := rows.Scan(&itemType, &itemID, &skill); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("cannot scan item skill: %w", err)
+		}
+		if c, ok := candidates[itemType+":"+itemID]; ok {
+			c.skills = append(c.skills, skill)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`
+		SELECT exam_id, domain FROM exam_domain_percentages
+		UNION
+		SELECT exam_id, domain FROM exam_questions WHERE domain <> ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var examID, domain string
+		if err := rows.Scan(&examID, &domain); err != nil {
+			return nil, fmt.Errorf("cannot scan exam domain: %w", err)
+		}
+		if c, ok := candidates[PathItemExam+":"+examID]; ok {
+			c.domains = append(c.domains, domain)
+		}
+	}
+	return candidates, rows.Err()
+}
+
+// scoreSkillGaps favours items that reward a skill the user's role requires at a
+// level the user has not reached.
+func scoreSkillGaps(username string, candidates map[string]*recommendCandidate) error {
+	report, err := GetSkillGap(username)
+	if err != nil {
+		return err
+	}
+	gaps := make(map[string]SkillGap)
+	for _, g := range report.Skills {
+		if !g.Met {
+			gaps[g.Skill] = g
+		}
+	}
+	if len(gaps) == 0 {
+		return nil
+	}
+	for _, c := range candidates {
+		for _, skill := range c.skills {
+			g, ok := gaps[skill]
+			if !ok {
+				continue
+			}
+			c.add(recommendSkillGapWeight, fmt.Sprintf(
+				"Builds %s toward the %s level your role requires (%d points to go)", skill, g.LevelName, g.PointsNeeded))
+		}
+	}
+	return nil
+}
+
+// scoreWeakDomains favours exams covering a domain where the user's answers fall
+// below the pass mark, and items rewarding a skill tied to such a domain.
+func scoreWeakDomains(username string, candidates map[string]*recommendCandidate) error {
+	rows, err := db.Query(`
+		SELECT d.key, SUM((d.value->>'correct')::int), SUM((d.value->>'total')::int)
+		FROM exam_attempts a, jsonb_each(a.domain_stats) d
+		WHERE a.username = $1
+		GROUP BY d.key`, username)
+	if err != nil {
+		return err
+	}
+	weak := make(map[string]domainResult)
+	for rows.Next() {
+		var domain string
+		var r domainResult
+		if err := rows.Scan(&domain, &r.correct, &r.total); err != nil {
+			rows.Close()
+			return fmt.Errorf("cannot scan domain result: %w", err)
+		}
+		if r.total > 0 && r.correct*100 < ExamPassPercent*r.total {
+			weak[domain] = r
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(weak) == 0 {
+		return nil
+	}
+
+	// Exam skill rewards tie skills to domains.
+	rows, err = db.Query(`SELECT DISTINCT domain, skill FROM exam_skill_rewards WHERE domain <> ''`)
+	if err != nil {
+		return err
+	}
+	domainSkills := make(map[string][]string)
+	for rows.Next() {
+		var domain, skill string
+		if err := rows.Scan(&domain, &skill); err != nil {
+			rows.Close()
+			return fmt.Errorf("cannot scan domain skill: %w", err)
+		}
+		if _, ok := weak[domain]; ok {
+			domainSkills[skill] = append(domainSkills[skill], domain)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range candidates {
+		// One reason per item, for its weakest domain.
+		best, bestDomain, bestSkill := -1, "", ""
+		consider := func(domain, skill string) {
+			r := weak[domain]
+			if best < 0 || r.percent() < best {
+				best, bestDomain, bestSkill = r.percent(), domain, skill
+			}
+		}
+		for _, domain := range c.domains {
+			if _, ok := weak[domain]; ok {
+				consider(domain, "")
+			}
+		}
+		for _, skill := range c.skills {
+			for _, domain := range domainSkills[skill] {
+				consider(domain, skill)
+			}
+		}
+		switch {
+		case best < 0:
+		case bestSkill == "":
+			c.add(recommendWeakDomainWeight, fmt.Sprintf(
+				"Practises %s, where you have answered %d%% of exam questions correctly", bestDomain, best))
+		default:
+			c.add(recommendWeakDomainWeight, fmt.Sprintf(
+				"Builds %s for %s, where you have answered %d%% of exam questions correctly", bestSkill, bestDomain, best))
+		}
+	}
+	return nil
+}
+
+// scoreCoEnrollment favours courses taken by learners who share a course with the
+// user, relative to the most shared one. Peers are capped at recommendMaxPeers,
+// those sharing the most courses first.
+func scoreCoEnrollment(username string, candidates map[string]*recommendCandidate) error {
+	rows, err := db.Query(`
+		WITH peers AS (
+			SELECT peer.username
+			FROM user_course_enrollments mine
+			JOIN user_course_enrollments peer ON peer.course_id = mine.course_id AND peer.username <> mine.username
+			WHERE mine.username = $1
+			GROUP BY peer.username
+			ORDER BY COUNT(*) DESC, peer.username
+			LIMIT $3
+		)
+		SELECT other.course_id, COUNT(*)
+		FROM peers
+		JOIN user_course_enrollments other ON other.username = peers.username
+		GROUP BY other.course_id
+		HAVING COUNT(*) >= $2`, username, recommendMinPeers, recommendMaxPeers)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	peers := make(map[*recommendCandidate]int)
+	top := 0
+	for rows.Next() {
+		var courseID string
+		var n int
+		if err := rows.Scan(&courseID, &n); err != nil {
+			return fmt.Errorf("cannot scan co-enrollment: %w", err)
+		}
+		if c, ok := candidates[PathItemCourse+":"+courseID]; ok {
+			peers[c] = n
+			top = max(top, n)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for c, n := range peers {
+		c.add(recommendPeerWeight*float64(n)/float64(top), fmt.Sprintf(
+			"Taken by %d learners who share a course with you", n))
+	}
+	return nil
+}
+
+// scorePopularity adds a small boost for items many learners have taken, on a log
+// scale relative to the most popular candidate.
+func scorePopularity(candidates map[string]*recommendCandidate) {
+	top := 0
+	for _, c := range candidates {
+		top = max(top, c.learners)
+	}
+	if top == 0 {
+		return
+	}
+	for _, c := range candidates {
+		if c.learners == 0 {
+			continue
+		}
+		weight := recommendPopularWeight * math.Log1p(float64(c.learners)) / math.Log1p(float64(top))
+		noun := "learners"
+		if c.learners == 1 {
+			noun = "learner"
+		}
+		if c.rec.ItemType == PathItemExam {
+			c.add(weight, fmt.Sprintf("Popular: taken by %d %s", c.learners, noun))
+		} else {
+			c.add(weight, fmt.Sprintf("Popular: %d %s enrolled", c.learners, noun))
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Registration modes, stored in app_settings. RegistrationOpen is the default so
+// existing deployments keep working until an admin changes it.
+const (
+	RegistrationClosed     = "closed"      // no self-registration, not even with an invite
+	RegistrationInviteOnly = "invite_only" // a valid invite is required
+	RegistrationDomain     = "domain"      // an email in an allowed domain, or an invite
+	RegistrationOpen       = "open"
+)
+
+const (
+	registrationModeSettingKey    = "registration_mode"
+	registrationDomainsSettingKey = "registration_allowed_domains"
+)
+
+// Invite states, derived when an invite is loaded.
+const (
+	InviteActive  = "active"
+	InviteExpired = "expired"
+	InviteUsedUp  = "used_up"
+	InviteRevoked = "revoked"
+)
+
+var (
+	ErrInvalidRegistrationMode = errors.New("mode must be closed, invite_only, domain or open")
+	ErrNoAllowedDomains        = errors.New("domain mode needs at least one allowed domain")
+	ErrInvalidInvite           = errors.New("invite is invalid, expired, revoked or used up")
+)
+
+type RegistrationSettings struct {
+	Mode           string   `json:"mode"`
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
+// AllowsEmail reports whether the email's domain is one of the allowed domains.
+// Subdomains are not matched implicitly.
+func (s RegistrationSettings) AllowsEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	return at >= 0 && slices.Contains(s.AllowedDomains, NormalizeEmail(email[at+1:]))
+}
+
+type Invite struct {
+	ID        int64       `json:"id"`
+	Label     string      `json:"label"`
+	Role      string      `json:"role"`
+	Groups    []UserGroup `json:"groups"`
+	MaxUses   int         `json:"max_uses"`
+	UseCount  int         `json:"use_count"`
+	Status    string      `json:"status"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	RevokedAt *time.Time  `json:"revoked_at"`
+	CreatedBy string      `json:"created_by"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewUser is a self-registration. Email may be empty.
+type NewUser struct {
+	Name         string
+	Username     string
+	EmployeeCode string
+	Email        string
+	Password     string
+}
+
+func EnsureRegistrationSchema() error {
+	_, err := db.Exec(`
+		-- Set for domain-mode registrations: the account is inactive until the email is verified.
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS activation_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+		CREATE TABLE IF NOT EXISTS user_invites (
+			id         BIGSERIAL    PRIMARY KEY,
+			token_hash TEXT         NOT NULL UNIQUE,
+			label      TEXT         NOT NULL DEFAULT '',
+			role_code  TEXT         NOT NULL REFERENCES roles(code) ON DELETE CASCADE ON UPDATE CASCADE,
+			max_uses   INT          NOT NULL CHECK (max_uses > 0),
+			use_count  INT          NOT NULL DEFAULT 0,
+			expires_at TIMESTAMPTZ  NOT NULL,
+			revoked_at TIMESTAMPTZ,
+			created_by TEXT         REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE,
+			created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE TABLE IF NOT EXISTS user_invite_groups (
+			invite_id BIGINT NOT NULL REFERENCES user_invites(id) ON DELETE CASCADE,
+			group_id  BIGINT NOT NULL REFERENCES user_groups(id) ON DELETE CASCADE,
+			PRIMARY KEY (invite_id, group_id)
+		);
+		CREATE TABLE IF NOT EXISTS user_invite_redemptions (
+			invite_id   BIGINT       NOT NULL REFERENCES user_invites(id) ON DELETE CASCADE,
+			username    TEXT         NOT NULL REFERENCES users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+			redeemed_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (invite_id, username)
+		);
+		CREATE INDEX IF NOT EXISTS ix_user_invite_redemptions_user ON user_invite_redemptions(username);
+	`)
+	return err
+}
+
+func GetRegistrationSettings() (RegistrationSettings, error) {
+	mode, err := getSetting(registrationModeSettingKey, RegistrationOpen)
+	if err != nil {
+		return RegistrationSettings{}, err
+	}
+	domains, err := getSetting(registrationDomainsSettingKey, "")
+	if err != nil {
+		return RegistrationSettings{}, err
+	}
+	settings := RegistrationSettings{Mode: mode, AllowedDomains: []string{}}
+	for _, d := range strings.Split(domains, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			settings.AllowedDomains = append(settings.AllowedDomains, d)
+		}
+	}
+	return settings, nil
+}
+
+// SaveRegistrationSettings validates and stores the mode and allowed domains.
+// Domains are lower-cased and a leading "@" is dropped.
+func SaveRegistrationSettings(settings RegistrationSettings) (RegistrationSettings, error) {
+	switch settings.Mode {
+	case RegistrationClosed, RegistrationInviteOnly, RegistrationDomain, RegistrationOpen:
+	default:
+		return RegistrationSettings{}, ErrInvalidRegistrationMode
+	}
+	domains := make([]string, 0, len(settings.AllowedDomains))
+	for _, d := range settings.AllowedDomains {
+		d = strings.TrimPrefix(NormalizeEmail(d), "@")
+		if d != "" && !slices.Contains(domains, d) {
+			domains = append(domains, d)
+		}
+	}
+	if settings.Mode == RegistrationDomain && len(domains) == 0 {
+		return RegistrationSettings{}, ErrNoAllowedDomains
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return RegistrationSettings{}, err
+	}
+	defer tx.Rollback()
+	if err := setSetting(tx, registrationModeSettingKey, settings.Mode); err != nil {
+		return RegistrationSettings{}, err
+	}
+	if err := setSetting(tx, registrationDomainsSettingKey, strings.Join(domains, ",")); err != nil {
+		return RegistrationSettings{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return RegistrationSettings{}, err
+	}
+	return RegistrationSettings{Mode: settings.Mode, AllowedDomains: domains}, nil
+}
+
+const inviteColumns = `
+	i.id, i.label, i.role_code, i.max_uses, i.use_count, i.expires_at, i.revoked_at,
+	COALESCE(i.created_by, ''), i.created_at`
+
+func scanInvite(row interface{ Scan(...any) error }) (Invite, error) {
+	var inv Invite
+	err := row.Scan(&inv.ID, &inv.Label, &inv.Role, &inv.MaxUses, &inv.UseCount, &inv.ExpiresAt,
+		&inv.RevokedAt, &inv.CreatedBy, &inv.CreatedAt)
+	if err != nil {
+		return Invite{}, err
+	}
+	switch {
+	case inv.RevokedAt != nil:
+		inv.Status = InviteRevoked
+	case inv.UseCount >= inv.MaxUses:
+		inv.Status = InviteUsedUp
+	case !inv.ExpiresAt.After(time.Now()):
+		inv.Status = InviteExpired
+	default:
+		inv.Status = InviteActive
+	}
+	inv.Groups = []UserGroup{}
+	return inv, nil
+}
+
+// loadInviteGroups fills Groups (without member counts) for the given invites.
+func loadInviteGroups(invites []Invite) error {
+	if len(invites) == 0 {
+		return nil
+	}
+	ids := make(StringArray, len(invites))
+	byID := make(map[int64]*Invite, len(invites))
+	for i := range invites {
+		ids[i] = strconv.FormatInt(invites[i].ID, 10)
+		byID[invites[i].ID] = &invites[i]
+	}
+	rows, err := db.Query(`
+		SELECT ig.invite_id, g.id, g.name, g.created_at
+		FROM user_invite_groups ig
+		JOIN user_groups g ON g.id = ig.group_id
+		WHERE ig.invite_id = ANY($1::bigint[])
+		ORDER BY g.name`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var inviteID int64
+		var g UserGroup
+		if err := rows.Scan(&inviteID, &g.ID, &g.Name, &g.CreatedAt); err != nil {
+			return err
+		}
+		if inv := byID[inviteID]; inv != nil {
+			inv.Groups = append(inv.Groups, g)
+		}
+	}
+	return rows.Err()
+}
+
+// CreateInvite stores an invite by the hash of its token. Every group must exist.
+func CreateInvite(tokenHash, label, role string, groupIDs []int64, maxUses int, expiresAt time.Time, createdBy string) (Invite, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Invite{}, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	if err := tx.QueryRow(`
+		INSERT INTO user_invites (token_hash, label, role_code, max_uses, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		tokenHash, strings.TrimSpace(label), NormalizeRoleName(role), maxUses, expiresAt, NormalizeUsername(createdBy),
+	).Scan(&id); err != nil {
+		return Invite{}, err
+	}
+	for _, groupID := range groupIDs {
+		if _, err := tx.Exec(`
+			INSERT INTO user_invite_groups (invite_id, group_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING`, id, groupID); err != nil {
+			if IsForeignKeyViolation(err) {
+				return Invite{}, ErrGroupNotFound
+			}
+			return Invite{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return Invite{}, err
+	}
+	return GetInvite(id)
+}
+
+func GetInvite(id int64) (Invite, error) {
+	inv, err := scanInvite(db.QueryRow(`SELECT `+inviteColumns+` FROM user_invites i WHERE i.id = $1`, id))
+	if err != nil {
+		return Invite{}, err
+	}
+	invites := []Invite{inv}
+	if err := loadInviteGroups(invites); err != nil {
+		return Invite{}, err
+	}
+	return invites[0], nil
+}
+
+// GetActiveInviteByToken returns the invite for a token if it can still be redeemed.
+func GetActiveInviteByToken(tokenHash string) (Invite, error) {
+	inv, err := scanInvite(db.QueryRow(`SELECT `+inviteColumns+` FROM user_invites i WHERE i.token_hash = $1`, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Invite{}, ErrInvalidInvite
+		}
+		return Invite{}, err
+	}
+	if inv.Status != InviteActive {
+		return Invite{}, ErrInvalidInvite
+	}
+	invites := []Invite{inv}
+	if err := loadInviteGroups(invites); err != nil {
+		return Invite{}, err
+	}
+	return invites[0], nil
+}
+
+// ListInvites returns invites newest first. activeOnly hides expired, used up and
+// revoked invites.
+func ListInvites(activeOnly bool, limit, offset int) ([]Invite, int, error) {
+	where := `WHERE 1=1`
+	if activeOnly {
+		where = `WHERE i.revoked_at IS NULL AND i.use_count < i.max_uses AND i.expires_at > NOW()`
+	}
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM user_invites i ` + where).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.Query(`
+		SELECT `+inviteColumns+`
+		FROM user_invites i `+where+`
+		ORDER BY i.created_at DESC, i.id DESC
+		LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	result := make([]Invite, 0)
+	for rows.Next() {
+		inv, err := scanInvite(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := loadInviteGroups(result); err != nil {
+		return nil, 0, err
+	}
+	return result, total, nil
+}
+
+// RevokeInvite stops an invite from being redeemed. Accounts already created with it
+// are not affected. Revoking twice keeps the first revocation time.
+func RevokeInvite(id int64) (Invite, error) {
+	result, err := db.Exec(`UPDATE user_invites SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`, id)
+	if err != nil {
+		return Invite{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return Invite{}, sql.ErrNoRows
+	}
+	return GetInvite(id)
+}
+
+// RegisterUser creates a self-registered account in one transaction. With an invite
+// token hash, the invite is redeemed: the user gets its role and groups and its use
+// count goes up. Without one the role is "user". activationPending creates the
+// account inactive until its email is verified.
+func RegisterUser(u NewUser, inviteTokenHash string, activationPending bool) (AuthUser, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return AuthUser{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return AuthUser{}, err
+	}
+	defer tx.Rollback()
+
+	role := "user"
+	var inviteID int64
+	if inviteTokenHash != "" {
+		err := tx.QueryRow(`
+			SELECT id, role_code FROM user_invites
+			WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW() AND use_count < max_uses
+			FOR UPDATE`, inviteTokenHash).Scan(&inviteID, &role)
+		if errors.Is(err, sql.ErrNoRows) {
+			return AuthUser{}, ErrInvalidInvite
+		}
+		if err != nil {
+			return AuthUser{}, err
+		}
+	}
+
+	// Domain-mode accounts whose activation link expired unused give up their
+	// username and address.
+	if _, err := tx.Exec(`
+		DELETE FROM users u
+		WHERE u.activation_pending AND u.email_verified_at IS NULL
+		  AND (u.username = $1 OR ($2 <> '' AND u.email = $2))
+		  AND NOT EXISTS (SELECT 1 FROM account_tokens t
+		                  WHERE t.user_id = u.id AND t.purpose = 'email_verify'
+		                    AND t.used_at IS NULL AND t.expires_at > NOW())`,
+		NormalizeUsername(u.Username), NormalizeEmail(u.Email)); err != nil {
+		return AuthUser{}, err
+	}
+
+	status := "active"
+	if activationPending {
+		status = "inactive"
+	}
+	var user AuthUser
+	err = tx.QueryRow(`
+		INSERT INTO users (name, username, employee_code, password_hash, role_code, status, email, activation_pending)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, name, username, employee_code, role_code, status, created_at, email`,
+		strings.TrimSpace(u.Name), NormalizeUsername(u.Username), NormalizeEmployeeCode(u.EmployeeCode),
+		string(hashed), role, status, NormalizeEmail(u.Email), activationPending,
+	).Scan(&user.ID, &user.Name, &user.Username, &user.EmployeeCode, &user.Role, &user.Status, &user.CreatedAt, &user.Email)
+	if err != nil {
+		return AuthUser{}, err
+	}
+
+	if inviteID != 0 {
+		statements := []struct {
+			query string
+			args  []any
+		}{
+			{`UPDATE user_invites SET use_count = use_count + 1 WHERE id = $1`, []any{inviteID}},
+			{`INSERT INTO user_group_members (group_id, username)
+			  SELECT group_id, $2 FROM user_invite_groups WHERE invite_id = $1
+			  ON CONFLICT DO NOTHING`, []any{inviteID, user.Username}},
+			{`INSERT INTO user_invite_redemptions (invite_id, username) VALUES ($1, $2)`, []any{inviteID, user.Username}},
+		}
+		for _, st := range statements {
+			if _, err := tx.Exec(st.query, st.args...); err != nil {
+				return AuthUser{}, err
+			}
+		}
+	}
+	return user, tx.Commit()
+}
+
+// IsActivationPending reports whether an inactive account is only waiting for its
+// email to be verified.
+func IsActivationPending(userID int64) (bool, error) {
+	var pending bool
+	err := db.QueryRow(`SELECT activation_pending FROM users WHERE id = $1`, userID).Scan(&pending)
+	return pending, err
+}
@@ -0,0 +1,288 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Score ledger reasons. Every change to user_scores or user_skill_scores is
+// recorded in user_score_events with one of these.
+const (
+	ScoreReasonSubtopic   = "subtopic_complete"
+	ScoreReasonCourse     = "course_complete"
+	ScoreReasonPath       = "path_complete"
+	ScoreReasonExam       = "exam_pass"
+	ScoreReasonAdjustment = "adjustment"
+	// Skill points that existed before skill awards were recorded in the ledger.
+	ScoreReasonOpeningBalance = "opening_balance"
+)
+
+var ErrInvalidScoreAdjustment = errors.New("invalid score adjustment")
+
+// ScoreEvent is one ledger entry. Skill is empty for overall points.
+type ScoreEvent struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Skill     string    `json:"skill,omitempty"`
+	Points    int       `json:"points"`
+	Reason    string    `json:"reason"`
+	CourseID  string    `json:"courseId,omitempty"`
+	ExamID    string    `json:"examId,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	EarnedAt  time.Time `json:"earnedAt"`
+}
+
+// ScoreDrift is a balance that differs from the sum of its ledger entries.
+type ScoreDrift struct {
+	Username string `json:"username"`
+	Skill    string `json:"skill,omitempty"` // empty for the overall total
+	Stored   int    `json:"stored"`
+	Ledger   int    `json:"ledger"`
+}
+
+// EnsureScoreLedgerSchema extends user_score_events to record skill awards and
+// manual adjustments. When the skill column is first added, existing skill
+// points are carried over as opening-balance entries so that the ledger matches
+// the balances.
+func EnsureScoreLedgerSchema() error {
+	var migrated bool
+	if err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM information_schema.columns
+		               WHERE table_name = 'user_score_events' AND column_name = 'skill')`).Scan(&migrated); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		ALTER TABLE user_score_events ADD COLUMN IF NOT EXISTS skill TEXT NOT NULL DEFAULT '';
+		ALTER TABLE user_score_events ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+		ALTER TABLE user_score_events ADD COLUMN IF NOT EXISTS created_by TEXT NULL
+			REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE;
+		CREATE INDEX IF NOT EXISTS ix_score_events_skill ON user_score_events(skill, earned_at) WHERE skill <> '';
+	`); err != nil {
+		return err
+	}
+	if !migrated {
+		if _, err := tx.Exec(`
+			INSERT INTO user_score_events (username, skill, score, reason, earned_at)
+			SELECT s.username, s.skill, s.points, $1, u.created_at
+			FROM user_skill_scores s
+			JOIN users u ON u.username = s.username
+			WHERE s.points <> 0`, ScoreReasonOpeningBalance); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// postScore records one ledger entry, filling in its ID and time, and applies it
+// to the matching balance. Callers run it in the transaction that makes the award.
+func postScore(tx *sql.Tx, e *ScoreEvent) error {
+	if err := tx.QueryRow(`
+		INSERT INTO user_score_events (username, skill, score, reason, course_id, exam_id, note, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''))
+		RETURNING id, earned_at`,
+		e.Username, e.Skill, e.Points, e.Reason, e.CourseID, e.ExamID, e.Note, e.CreatedBy).Scan(&e.ID, &e.EarnedAt); err != nil {
+		return fmt.Errorf("cannot record score event: %w", err)
+	}
+	if e.Skill == "" {
+		if _, err := tx.Exec(`
+			INSERT INTO user_scores (username, total, updated_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (username) DO UPDATE
+				SET total      = user_scores.total + EXCLUDED.total,
+				    updated_at = NOW()`,
+			e.Username, e.Points); err != nil {
+			return fmt.Errorf("cannot add total score: %w", err)
+		}
+		return nil
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO user_skill_scores (username, skill, points)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (username, skill) DO UPDATE
+			SET points = user_skill_scores.points + EXCLUDED.points`,
+		e.Username, e.Skill, e.Points); err != nil {
+		return fmt.Errorf("cannot add skill score for %s: %w", e.Skill, err)
+	}
+	return nil
+}
+
+// awardScores posts the points and skill rewards of one award. source carries the
+// user, reason and the course or exam that earned them. Zero amounts are skipped.
+func awardScores(tx *sql.Tx, source ScoreEvent, points int, skills []SkillReward) error {
+	if points > 0 {
+		e := source
+		e.Points = points
+		if err := postScore(tx, &e); err != nil {
+			return err
+		}
+	}
+	for _, r := range skills {
+		if r.Points <= 0 {
+			continue
+		}
+		e := source
+		e.Skill, e.Points = r.Skill, r.Points
+		if err := postScore(tx, &e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AdjustScore records a manual change to a user's overall points, or to one skill
+// when skill is set. A note explaining the change is required, and the balance
+// may not drop below zero.
+func AdjustScore(username, skill string, points int, note, by string) (ScoreEvent, error) {
+	e := ScoreEvent{
+		Username:  NormalizeUsername(username),
+		Skill:     strings.TrimSpace(skill),
+		Points:    points,
+		Reason:    ScoreReasonAdjustment,
+		Note:      strings.TrimSpace(note),
+		CreatedBy: by,
+	}
+	if e.Points == 0 {
+		return e, fmt.Errorf("%w: points must not be zero", ErrInvalidScoreAdjustment)
+	}
+	if e.Note == "" {
+		return e, fmt.Errorf("%w: a reason is required", ErrInvalidScoreAdjustment)
+	}
+	if e.Skill != "" {
+		skill, err := ResolveSkill(e.Skill)
+		if errors.Is(err, ErrSkillNotFound) {
+			return e, fmt.Errorf("%w: %v", ErrInvalidScoreAdjustment, err)
+		}
+		if err != nil {
+			return e, err
+		}
+		e.Skill = skill
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return e, err
+	}
+	defer tx.Rollback()
+	if err := postScore(tx, &e); err != nil {
+		if IsCheckViolation(err) {
+			return e, fmt.Errorf("%w: the balance cannot drop below zero", ErrInvalidScoreAdjustment)
+		}
+		return e, err
+	}
+	return e, tx.Commit()
+}
+
+// ListScoreEvents returns a page of a user's ledger, newest first, and the number
+// of entries.
+func ListScoreEvents(username string, limit, offset int) ([]ScoreEvent, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM user_score_events WHERE username = $1`, username).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.Query(`
+		SELECT id, username, skill, score, reason, COALESCE(course_id, ''), COALESCE(exam_id, ''), note, COALESCE(created_by, ''), earned_at
+		FROM user_score_events
+		WHERE username = $1
+		ORDER BY earned_at DESC, id DESC
+		LIMIT $2 OFFSET $3`, username, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := make([]ScoreEvent, 0)
+	for rows.Next() {
+		var e ScoreEvent
+		if err := rows.Scan(&e.ID, &e.Username, &e.Skill, &e.Points, &e.Reason, &e.CourseID, &e.ExamID, &e.Note, &e.CreatedBy, &e.EarnedAt); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+	return events, total, rows.Err()
+}
+
+// scoreDriftQuery compares every balance with the sum of its ledger entries.
+const scoreDriftQuery = `
+	SELECT COALESCE(s.username, l.username), '', COALESCE(s.total, 0), COALESCE(l.total, 0)
+	FROM user_scores s
+	FULL JOIN (SELECT username, SUM(score)::int AS total FROM user_score_events
+	           WHERE skill = '' GROUP BY username) l ON l.username = s.username
+	WHERE COALESCE(s.total, 0) <> COALESCE(l.total, 0)
+	UNION ALL
+	SELECT COALESCE(s.username, l.username), COALESCE(s.skill, l.skill), COALESCE(s.points, 0), COALESCE(l.points, 0)
+	FROM user_skill_scores s
+	FULL JOIN (SELECT username, skill, SUM(score)::int AS points FROM user_score_events
+	           WHERE skill <> '' GROUP BY username, skill) l ON l.username = s.username AND l.skill = s.skill
+	WHERE COALESCE(s.points, 0) <> COALESCE(l.points, 0)
+	ORDER BY 1, 2`
+
+// ReconcileScores reports balances that drifted from the ledger and, when apply is
+// set, rebuilds them from it. Balances are locked while they are rebuilt so that
+// concurrent awards wait; a ledger sum below zero is stored as zero.
+func ReconcileScores(apply bool) ([]ScoreDrift, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if apply {
+		if _, err := tx.Exec(`LOCK TABLE user_scores, user_skill_scores IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.Query(scoreDriftQuery)
+	if err != nil {
+		return nil, err
+	}
+	drift := make([]ScoreDrift, 0)
+	for rows.Next() {
+		var d ScoreDrift
+		if err := rows.Scan(&d.Username, &d.Skill, &d.Stored, &d.Ledger); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		drift = append(drift, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !apply || len(drift) == 0 {
+		return drift, nil
+	}
+
+	for _, d := range drift {
+		ledger := max(d.Ledger, 0)
+		if d.Skill == "" {
+			_, err = tx.Exec(`
+				INSERT INTO user_scores (username, total, updated_at)
+				VALUES ($1, $2, NOW())
+				ON CONFLICT (username) DO UPDATE SET total = EXCLUDED.total, updated_at = NOW()`,
+				d.Username, ledger)
+		} else if ledger == 0 {
+			_, err = tx.Exec(`DELETE FROM user_skill_scores WHERE username = $1 AND skill = $2`, d.Username, d.Skill)
+		} else {
+			_, err = tx.Exec(`
+				INSERT INTO user_skill_scores (username, skill, points)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (username, skill) DO UPDATE SET points = EXCLUDED.points`,
+				d.Username, d.Skill, ledger)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reconcile %s/%s: %w", d.Username, d.Skill, err)
+		}
+	}
+	return drift, tx.Commit()
+}
@@ -0,0 +1,663 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrSkillNotFound      = errors.New("skill not found")
+	ErrSkillConflict      = errors.New("skill name or alias is already used by another skill")
+	ErrSkillInUse         = errors.New("skill is still used by courses, paths, exams, badges or scores")
+	ErrInvalidSkill       = errors.New("invalid skill")
+	ErrInvalidSkillLevels = errors.New("invalid skill levels")
+)
+
+// Skill is an entry of the managed skills catalog. Courses, paths, badges and
+// scores refer to skills by their canonical name; aliases resolve to it.
+type Skill struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases"`
+}
+
+// SkillLevel is a proficiency level reached at MinPoints points in a skill.
+// Levels are numbered from 1 in order of MinPoints; below the first, a user has
+// level 0.
+type SkillLevel struct {
+	Level     int    `json:"level"`
+	Name      string `json:"name"`
+	MinPoints int    `json:"minPoints"`
+}
+
+// Competency is a skill level required by a job role.
+type Competency struct {
+	SkillID   int64  `json:"skillId"`
+	Skill     string `json:"skill"`
+	Category  string `json:"category"`
+	Level     int    `json:"level"`
+	LevelName string `json:"levelName"`
+}
+
+// SkillGap compares one required competency with the user's points.
+type SkillGap struct {
+	Competency
+	Points        int    `json:"points"`
+	UserLevel     int    `json:"userLevel"`
+	UserLevelName string `json:"userLevelName"`
+	PointsNeeded  int    `json:"pointsNeeded"`
+	Met           bool   `json:"met"`
+}
+
+type SkillGapReport struct {
+	Username string     `json:"username"`
+	Role     string     `json:"role"`
+	Skills   []SkillGap `json:"skills"`
+	Met      int        `json:"met"`
+	Total    int        `json:"total"`
+}
+
+var defaultSkillLevels = []SkillLevel{
+	{Level: 1, Name: "Beginner", MinPoints: 10},
+	{Level: 2, Name: "Intermediate", MinPoints: 50},
+	{Level: 3, Name: "Advanced", MinPoints: 150},
+	{Level: 4, Name: "Expert", MinPoints: 300},
+}
+
+// skillKeySQL normalises a skill column the way skillKey normalises a string.
+const skillKeySQL = `LOWER(REGEXP_REPLACE(TRIM(skill), '\s+', ' ', 'g'))`
+
+// normalizeSkillName trims a skill name and collapses inner whitespace.
+func normalizeSkillName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// skillKey is the case-insensitive form skill names and aliases are matched on.
+func skillKey(name string) string {
+	return strings.ToLower(normalizeSkillName(name))
+}
+
+// EnsureSkillSchema creates the skills catalog, proficiency levels and role
+// competency profiles. On first run the catalog is seeded from the free-text
+// skills already in use: spellings that differ only in case or spacing are
+// merged into the most used one.
+func EnsureSkillSchema() error {
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('skills') IS NOT NULL`).Scan(&exists); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS skills (
+			id          BIGSERIAL    PRIMARY KEY,
+			name        TEXT         NOT NULL,
+			category    TEXT         NOT NULL DEFAULT '',
+			description TEXT         NOT NULL DEFAULT '',
+			created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS ux_skills_name ON skills(LOWER(name));
+		CREATE TABLE IF NOT EXISTS skill_aliases (
+			alias_key TEXT    PRIMARY KEY,
+			alias     TEXT    NOT NULL,
+			skill_id  BIGINT  NOT NULL REFERENCES skills(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS ix_skill_aliases_skill ON skill_aliases(skill_id);
+		CREATE TABLE IF NOT EXISTS skill_levels (
+			level      INT   PRIMARY KEY CHECK (level > 0),
+			name       TEXT  NOT NULL,
+			min_points INT   NOT NULL UNIQUE CHECK (min_points > 0)
+		);
+		CREATE TABLE IF NOT EXISTS role_competencies (
+			role_code TEXT    NOT NULL REFERENCES roles(code) ON DELETE CASCADE ON UPDATE CASCADE,
+			skill_id  BIGINT  NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+			level     INT     NOT NULL CHECK (level > 0),
+			PRIMARY KEY (role_code, skill_id)
+		);
+	`); err != nil {
+		return err
+	}
+	var levels int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM skill_levels`).Scan(&levels); err != nil {
+		return err
+	}
+	if levels == 0 {
+		if err := insertSkillLevels(tx, defaultSkillLevels); err != nil {
+			return err
+		}
+	}
+	if !exists {
+		if err := seedSkillCatalog(tx); err != nil {
+			return fmt.Errorf("seed skills catalog: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func seedSkillCatalog(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		SELECT skill, COUNT(*) FROM (
+			SELECT skill FROM user_skill_scores
+			UNION ALL SELECT skill FROM course_skill_rewards
+			UNION ALL SELECT skill FROM learning_path_skill_rewards
+			UNION ALL SELECT skill FROM badges WHERE skill <> ''
+		) s
+		WHERE TRIM(skill) <> ''
+		GROUP BY skill
+		ORDER BY COUNT(*) DESC, skill`)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		var uses int
+		if err := rows.Scan(&name, &uses); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// The first spelling of each key is the most used one.
+	seen := make(map[string]bool)
+	for _, name := range names {
+		key := skillKey(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		canonical := normalizeSkillName(name)
+		if _, err := tx.Exec(`INSERT INTO skills (name) VALUES ($1)`, canonical); err != nil {
+			return err
+		}
+		if err := renameSkillRefs(tx, []string{key}, canonical); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameSkillRefs points every reference to a skill whose key is in keys at the
+// canonical name. Rewards listed under two spellings keep the larger one; scores
+// are added up, matching the renamed ledger entries.
+func renameSkillRefs(tx *sql.Tx, keys []string, name string) error {
+	match := skillKeySQL + ` = ANY($1) AND skill <> $2`
+	stmts := []string{
+		`INSERT INTO course_skill_rewards (course_id, skill, points)
+		 SELECT course_id, $2::text, MAX(points) FROM course_skill_rewards WHERE ` + match + ` GROUP BY course_id
+		 ON CONFLICT (course_id, skill) DO UPDATE
+			SET points = GREATEST(course_skill_rewards.points, EXCLUDED.points)`,
+		`DELETE FROM course_skill_rewards WHERE ` + match,
+		`INSERT INTO learning_path_skill_rewards (path_id, skill, points)
+		 SELECT path_id, $2::text, MAX(points) FROM learning_path_skill_rewards WHERE ` + match + ` GROUP BY path_id
+		 ON CONFLICT (path_id, skill) DO UPDATE
+			SET points = GREATEST(learning_path_skill_rewards.points, EXCLUDED.points)`,
+		`DELETE FROM learning_path_skill_rewards WHERE ` + match,
+		`INSERT INTO exam_skill_rewards (exam_id, skill, domain, points)
+		 SELECT exam_id, $2::text, domain, MAX(points) FROM exam_skill_rewards WHERE ` + match + ` GROUP BY exam_id, domain
+		 ON CONFLICT (exam_id, skill, domain) DO UPDATE
+			SET points = GREATEST(exam_skill_rewards.points, EXCLUDED.points)`,
+		`DELETE FROM exam_skill_rewards WHERE ` + match,
+		`UPDATE badges SET skill = $2 WHERE ` + match,
+		`UPDATE user_score_events SET skill = $2 WHERE ` + match,
+		`INSERT INTO user_skill_scores (username, skill, points)
+		 SELECT username, $2::text, SUM(points) FROM user_skill_scores WHERE ` + match + ` GROUP BY username
+		 ON CONFLICT (username, skill) DO UPDATE
+			SET points = user_skill_scores.points + EXCLUDED.points`,
+		`DELETE FROM user_skill_scores WHERE ` + match,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt, StringArray(keys), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const skillSelect = `
+	SELECT s.id, s.name, s.category, s.description,
+	       COALESCE((SELECT array_agg(a.alias ORDER BY a.alias) FROM skill_aliases a WHERE a.skill_id = s.id), '{}')
+	FROM skills s`
+
+func scanSkill(row interface{ Scan(...any) error }) (Skill, error) {
+	var s Skill
+	var aliases StringArray
+	if err := row.Scan(&s.ID, &s.Name, &s.Category, &s.Description, &aliases); err != nil {
+		return Skill{}, err
+	}
+	s.Aliases = []string(aliases)
+	if s.Aliases == nil {
+		s.Aliases = []string{}
+	}
+	return s, nil
+}
+
+// ListSkills returns the catalog ordered by category and name.
+func ListSkills() ([]Skill, error) {
+	rows, err := db.Query(skillSelect + ` ORDER BY s.category, LOWER(s.name)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	skills := make([]Skill, 0)
+	for rows.Next() {
+		s, err := scanSkill(rows)
+		if err != nil {
+			return nil, err
+		}
+		skills = append(skills, s)
+	}
+	return skills, rows.Err()
+}
+
+func GetSkill(id int64) (Skill, error) {
+	s, err := scanSkill(db.QueryRow(skillSelect+` WHERE s.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Skill{}, ErrSkillNotFound
+	}
+	return s, err
+}
+
+// skillKeyTaken reports whether key names or aliases a skill other than id.
+func skillKeyTaken(tx *sql.Tx, key string, id int64) (bool, error) {
+	var taken bool
+	err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM skills WHERE LOWER(name) = $1 AND id <> $2)
+		    OR EXISTS (SELECT 1 FROM skill_aliases WHERE alias_key = $1 AND skill_id <> $2)`,
+		key, id).Scan(&taken)
+	return taken, err
+}
+
+// SaveSkill creates (ID 0) or updates a skill and replaces its aliases. References
+// under a previous name or under any alias are renamed to the skill's name.
+func SaveSkill(s Skill) (Skill, error) {
+	s.Name = normalizeSkillName(s.Name)
+	s.Category = strings.TrimSpace(s.Category)
+	s.Description = strings.TrimSpace(s.Description)
+	if s.Name == "" {
+		return Skill{}, fmt.Errorf("%w: name is required", ErrInvalidSkill)
+	}
+	keys := []string{skillKey(s.Name)}
+	aliases := make(map[string]string)
+	for _, alias := range s.Aliases {
+		alias = normalizeSkillName(alias)
+		if key := skillKey(alias); alias != "" && key != keys[0] && aliases[key] == "" {
+			aliases[key] = alias
+			keys = append(keys, key)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return Skill{}, err
+	}
+	defer tx.Rollback()
+
+	for _, key := range keys {
+		taken, err := skillKeyTaken(tx, key, s.ID)
+		if err != nil {
+			return Skill{}, err
+		}
+		if taken {
+			return Skill{}, fmt.Errorf("%w: %s", ErrSkillConflict, key)
+		}
+	}
+	if s.ID == 0 {
+		err = tx.QueryRow(`
+			INSERT INTO skills (name, category, description) VALUES ($1, $2, $3) RETURNING id`,
+			s.Name, s.Category, s.Description).Scan(&s.ID)
+	} else {
+		var oldName string
+		err = tx.QueryRow(`
+			UPDATE skills s SET name = $2, category = $3, description = $4
+			FROM skills old WHERE s.id = $1 AND old.id = s.id
+			RETURNING old.name`,
+			s.ID, s.Name, s.Category, s.Description).Scan(&oldName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Skill{}, ErrSkillNotFound
+		}
+		keys = append(keys, skillKey(oldName))
+	}
+	if err != nil {
+		return Skill{}, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM skill_aliases WHERE skill_id = $1`, s.ID); err != nil {
+		return Skill{}, err
+	}
+	for key, alias := range aliases {
+		if _, err := tx.Exec(`INSERT INTO skill_aliases (alias_key, alias, skill_id) VALUES ($1, $2, $3)`, key, alias, s.ID); err != nil {
+			return Skill{}, err
+		}
+	}
+	if err := renameSkillRefs(tx, keys, s.Name); err != nil {
+		return Skill{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Skill{}, err
+	}
+	return GetSkill(s.ID)
+}
+
+// DeleteSkill removes a skill that nothing refers to. Role competencies on it
+// are removed with it.
+func DeleteSkill(id int64) error {
+	var inUse bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM course_skill_rewards r WHERE r.skill = s.name)
+		    OR EXISTS (SELECT 1 FROM learning_path_skill_rewards r WHERE r.skill = s.name)
+		    OR EXISTS (SELECT 1 FROM exam_skill_rewards r WHERE r.skill = s.name)
+		    OR EXISTS (SELECT 1 FROM badges b WHERE b.skill = s.name)
+		    OR EXISTS (SELECT 1 FROM user_skill_scores u WHERE u.skill = s.name)
+		FROM skills s WHERE s.id = $1`, id).Scan(&inUse)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSkillNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrSkillInUse
+	}
+	_, err = db.Exec(`DELETE FROM skills WHERE id = $1`, id)
+	return err
+}
+
+// MergeSkill folds the source skill into the target: the source name and aliases
+// become target aliases, all references move to the target, and role
+// competencies keep the higher required level.
+func MergeSkill(targetID, sourceID int64) (Skill, error) {
+	if targetID == sourceID {
+		return Skill{}, fmt.Errorf("%w: cannot merge a skill into itself", ErrInvalidSkill)
+	}
+	target, err := GetSkill(targetID)
+	if err != nil {
+		return Skill{}, err
+	}
+	source, err := GetSkill(sourceID)
+	if err != nil {
+		return Skill{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return Skill{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO role_competencies (role_code, skill_id, level)
+		SELECT role_code, $1, level FROM role_competencies WHERE skill_id = $2
+		ON CONFLICT (role_code, skill_id) DO UPDATE
+			SET level = GREATEST(role_competencies.level, EXCLUDED.level)`, targetID, sourceID); err != nil {
+		return Skill{}, err
+	}
+	if _, err := tx.Exec(`DELETE FROM skills WHERE id = $1`, sourceID); err != nil {
+		return Skill{}, err
+	}
+	keys := make([]string, 0, len(source.Aliases)+1)
+	for _, alias := range append([]string{source.Name}, source.Aliases...) {
+		key := skillKey(alias)
+		keys = append(keys, key)
+		if _, err := tx.Exec(`
+			INSERT INTO skill_aliases (alias_key, alias, skill_id) VALUES ($1, $2, $3)
+			ON CONFLICT (alias_key) DO NOTHING`, key, alias, targetID); err != nil {
+			return Skill{}, err
+		}
+	}
+	if err := renameSkillRefs(tx, keys, target.Name); err != nil {
+		return Skill{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Skill{}, err
+	}
+	return GetSkill(targetID)
+}
+
+// ResolveSkill returns the canonical name of a skill name or alias.
+func ResolveSkill(name string) (string, error) {
+	var canonical string
+	err := db.QueryRow(`
+		SELECT s.name FROM skills s WHERE LOWER(s.name) = $1
+		UNION ALL
+		SELECT s.name FROM skill_aliases a JOIN skills s ON s.id = a.skill_id WHERE a.alias_key = $1
+		LIMIT 1`, skillKey(name)).Scan(&canonical)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", ErrSkillNotFound, normalizeSkillName(name))
+	}
+	return canonical, err
+}
+
+// ResolveSkillRewards resolves reward skills to their canonical names. A skill the
+// catalog does not know is rejected with ErrSkillNotFound, so the catalog is only
+// extended by admins. A skill listed twice, under any spelling, is rejected.
+func ResolveSkillRewards(rewards []SkillReward) ([]SkillReward, error) {
+	result := make([]SkillReward, 0, len(rewards))
+	seen := make(map[string]bool)
+	for _, r := range rewards {
+		name, err := ResolveSkill(r.Skill)
+		if err != nil {
+			return nil, err
+		}
+		if seen[skillKey(name)] {
+			return nil, fmt.Errorf("%w: %s is listed more than once", ErrInvalidSkill, name)
+		}
+		seen[skillKey(name)] = true
+		result = append(result, SkillReward{Skill: name, Points: r.Points})
+	}
+	return result, nil
+}
+
+func ListSkillLevels() ([]SkillLevel, error) {
+	rows, err := db.Query(`SELECT level, name, min_points FROM skill_levels ORDER BY level`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	levels := make([]SkillLevel, 0)
+	for rows.Next() {
+		var l SkillLevel
+		if err := rows.Scan(&l.Level, &l.Name, &l.MinPoints); err != nil {
+			return nil, err
+		}
+		levels = append(levels, l)
+	}
+	return levels, rows.Err()
+}
+
+func insertSkillLevels(tx *sql.Tx, levels []SkillLevel) error {
+	for _, l := range levels {
+		if _, err := tx.Exec(`INSERT INTO skill_levels (level, name, min_points) VALUES ($1, $2, $3)`,
+			l.Level, l.Name, l.MinPoints); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetSkillLevels replaces the proficiency levels. Levels are renumbered from 1 in
+// order of their minimum points. Levels still required by a role cannot be removed.
+func SetSkillLevels(levels []SkillLevel) ([]SkillLevel, error) {
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("%w: at least one level is required", ErrInvalidSkillLevels)
+	}
+	sorted := make([]SkillLevel, len(levels))
+	copy(sorted, levels)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinPoints < sorted[j].MinPoints })
+	for i := range sorted {
+		sorted[i].Level = i + 1
+		sorted[i].Name = strings.TrimSpace(sorted[i].Name)
+		switch {
+		case sorted[i].Name == "":
+			return nil, fmt.Errorf("%w: level names are required", ErrInvalidSkillLevels)
+		case sorted[i].MinPoints <= 0:
+			return nil, fmt.Errorf("%w: minimum points must be positive", ErrInvalidSkillLevels)
+		case i > 0 && sorted[i].MinPoints == sorted[i-1].MinPoints:
+			return nil, fmt.Errorf("%w: minimum points must differ", ErrInvalidSkillLevels)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var required int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(level), 0) FROM role_competencies`).Scan(&required); err != nil {
+		return nil, err
+	}
+	if required > len(sorted) {
+		return nil, fmt.Errorf("%w: role competencies require level %d", ErrInvalidSkillLevels, required)
+	}
+	if _, err := tx.Exec(`DELETE FROM skill_levels`); err != nil {
+		return nil, err
+	}
+	if err := insertSkillLevels(tx, sorted); err != nil {
+		return nil, err
+	}
+	return sorted, tx.Commit()
+}
+
+// skillLevelFor returns the highest level reached with points, or level 0.
+func skillLevelFor(levels []SkillLevel, points int) SkillLevel {
+	reached := SkillLevel{}
+	for _, l := range levels {
+		if points >= l.MinPoints {
+			reached = l
+		}
+	}
+	return reached
+}
+
+func levelName(levels []SkillLevel, level int) string {
+	for _, l := range levels {
+		if l.Level == level {
+			return l.Name
+		}
+	}
+	return ""
+}
+
+// GetRoleCompetencies returns the competency profile of a role.
+func GetRoleCompetencies(roleCode string) ([]Competency, error) {
+	levels, err := ListSkillLevels()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT s.id, s.name, s.category, c.level
+		FROM role_competencies c
+		JOIN skills s ON s.id = c.skill_id
+		WHERE c.role_code = $1
+		ORDER BY s.category, LOWER(s.name)`, NormalizeRoleName(roleCode))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	competencies := make([]Competency, 0)
+	for rows.Next() {
+		var c Competency
+		if err := rows.Scan(&c.SkillID, &c.Skill, &c.Category, &c.Level); err != nil {
+			return nil, err
+		}
+		c.LevelName = levelName(levels, c.Level)
+		competencies = append(competencies, c)
+	}
+	return competencies, rows.Err()
+}
+
+// SetRoleCompetencies replaces the competency profile of a role.
+func SetRoleCompetencies(roleCode string, competencies []Competency) ([]Competency, error) {
+	roleCode = NormalizeRoleName(roleCode)
+	levels, err := ListSkillLevels()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int64]bool)
+	for _, c := range competencies {
+		if c.Level < 1 || c.Level > len(levels) {
+			return nil, fmt.Errorf("%w: level must be between 1 and %d", ErrInvalidSkill, len(levels))
+		}
+		if seen[c.SkillID] {
+			return nil, fmt.Errorf("%w: skill %d is listed more than once", ErrInvalidSkill, c.SkillID)
+		}
+		seen[c.SkillID] = true
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM role_competencies WHERE role_code = $1`, roleCode); err != nil {
+		return nil, err
+	}
+	for _, c := range competencies {
+		if _, err := tx.Exec(`INSERT INTO role_competencies (role_code, skill_id, level) VALUES ($1, $2, $3)`,
+			roleCode, c.SkillID, c.Level); err != nil {
+			if IsForeignKeyViolation(err) {
+				return nil, fmt.Errorf("%w: unknown role or skill %d", ErrSkillNotFound, c.SkillID)
+			}
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetRoleCompetencies(roleCode)
+}
+
+// GetSkillGap compares the user's skill points with the competency profile of
+// their role.
+func GetSkillGap(username string) (SkillGapReport, error) {
+	report := SkillGapReport{Username: username, Skills: []SkillGap{}}
+	if err := db.QueryRow(`SELECT role_code FROM users WHERE username = $1`, username).Scan(&report.Role); err != nil {
+		return report, err
+	}
+	levels, err := ListSkillLevels()
+	if err != nil {
+		return report, err
+	}
+	competencies, err := GetRoleCompetencies(report.Role)
+	if err != nil {
+		return report, err
+	}
+	_, points, err := GetUserScores(username)
+	if err != nil {
+		return report, err
+	}
+
+	for _, c := range competencies {
+		gap := SkillGap{Competency: c, Points: points[c.Skill]}
+		reached := skillLevelFor(levels, gap.Points)
+		gap.UserLevel, gap.UserLevelName = reached.Level, reached.Name
+		gap.Met = reached.Level >= c.Level
+		for _, l := range levels {
+			if l.Level == c.Level && !gap.Met {
+				gap.PointsNeeded = l.MinPoints - gap.Points
+			}
+		}
+		if gap.Met {
+			report.Met++
+		}
+		report.Skills = append(report.Skills, gap)
+	}
+	report.Total = len(report.Skills)
+	return report, nil
+}
@@ -0,0 +1,269 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrManagerCycle = errors.New("manager would create a reporting cycle")
+
+// maxTeamDepth bounds the reporting-line recursion.
+const maxTeamDepth = 50
+
+// teamCTE selects the caller's direct and indirect reports as team(username, depth).
+// $1 is the manager's username.
+var teamCTE = `
+	WITH RECURSIVE team AS (
+		SELECT username, 1 AS depth FROM users WHERE manager_username = $1
+		UNION ALL
+		SELECT u.username, t.depth + 1
+		FROM users u
+		JOIN team t ON u.manager_username = t.username
+		WHERE t.depth < ` + strconv.Itoa(maxTeamDepth) + `
+	)`
+
+type TeamMemberProgress struct {
+	Username           string     `json:"username"`
+	Name               string     `json:"name"`
+	EmployeeCode       string     `json:"employeeCode"`
+	Role               string     `json:"role"`
+	Status             string     `json:"status"`
+	ManagerUsername    string     `json:"managerUsername"`
+	Depth              int        `json:"depth"` // 1 = direct report
+	CoursesEnrolled    int        `json:"coursesEnrolled"`
+	CoursesCompleted   int        `json:"coursesCompleted"`
+	CoursesOverdue     int        `json:"coursesOverdue"`
+	AssignmentsOverdue int        `json:"assignmentsOverdue"`
+	ExamAttempts       int        `json:"examAttempts"`
+	ExamsPassed        int        `json:"examsPassed"`
+	AvgExamScore       float64    `json:"avgExamScore"`
+	SecondsSpent       int64      `json:"secondsSpent"`
+	LastLoginAt        *time.Time `json:"lastLoginAt"`
+}
+
+type TeamCourseProgress struct {
+	CourseID     string     `json:"courseId"`
+	Title        string     `json:"title"`
+	EnrolledAt   time.Time  `json:"enrolledAt"`
+	CompletedAt  *time.Time `json:"completedAt"`
+	Overdue      bool       `json:"overdue"`
+	SecondsSpent int64      `json:"secondsSpent"`
+}
+
+type TeamExamResult struct {
+	ExamID        string    `json:"examId"`
+	Title         string    `json:"title"`
+	Attempts      int       `json:"attempts"`
+	BestScore     float64   `json:"bestScore"`
+	LastScore     float64   `json:"lastScore"`
+	Passed        bool      `json:"passed"`
+	LastAttemptAt time.Time `json:"lastAttemptAt"`
+}
+
+type TeamMemberDetail struct {
+	Member  TeamMemberProgress   `json:"member"`
+	Courses []TeamCourseProgress `json:"courses"`
+	Exams   []TeamExamResult     `json:"exams"`
+}
+
+func EnsureTeamSchema() error {
+	_, err := db.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS manager_username TEXT
+			REFERENCES users(username) ON DELETE SET NULL ON UPDATE CASCADE;
+		CREATE INDEX IF NOT EXISTS ix_users_manager ON users(manager_username);
+	`)
+	return err
+}
+
+// setUserManager sets (or with an empty manager clears) the user's line manager.
+// It refuses assignments that would make a user report to themselves, directly or not.
+func setUserManager(tx *sql.Tx, username, managerUsername string) error {
+	username = NormalizeUsername(username)
+	managerUsername = NormalizeUsername(managerUsername)
+	if managerUsername == "" {
+		_, err := tx.Exec(`UPDATE users SET manager_username = NULL WHERE username = $1`, username)
+		return err
+	}
+	if managerUsername == username {
+		return ErrManagerCycle
+	}
+
+	var cycle bool
+	if err := tx.QueryRow(`
+		WITH RECURSIVE chain AS (
+			SELECT username, manager_username, 1 AS depth FROM users WHERE username = $1
+			UNION ALL
+			SELECT u.username, u.manager_username, c.depth + 1
+			FROM users u
+			JOIN chain c ON u.username = c.manager_username
+			WHERE c.depth < $3
+		)
+		SELECT EXISTS(SELECT 1 FROM chain WHERE username = $2)`,
+		managerUsername, username, maxTeamDepth,
+	).Scan(&cycle); err != nil {
+		return err
+	}
+	if cycle {
+		return ErrManagerCycle
+	}
+
+	result, err := tx.Exec(`UPDATE users SET manager_username = $2 WHERE username = $1`, username, managerUsername)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetTeamProgress summarises learning progress for every direct and indirect report
+// of manager. Enrollments still open after overdueDays count as overdue courses;
+// assignments past their due date are counted separately.
+func GetTeamProgress(manager string, overdueDays int) ([]TeamMemberProgress, error) {
+	return queryTeamProgress(manager, overdueDays, "")
+}
+
+// GetTeamMemberDetail returns per-course and per-exam progress for one report.
+// It returns sql.ErrNoRows when username is not in manager's subtree.
+func GetTeamMemberDetail(manager, username string, overdueDays int) (TeamMemberDetail, error) {
+	members, err := queryTeamProgress(manager, overdueDays, NormalizeUsername(username))
+	if err != nil {
+		return TeamMemberDetail{}, err
+	}
+	if len(members) == 0 {
+		return TeamMemberDetail{}, sql.ErrNoRows
+	}
+	detail := TeamMemberDetail{Member: members[0], Courses: []TeamCourseProgress{}, Exams: []TeamExamResult{}}
+
+	rows, err := db.Query(`
+		SELECT e.course_id, c.title, e.enrolled_at, e.completed_at,
+		       (e.completed_at IS NULL AND e.enrolled_at < NOW() - make_interval(days => $2)) AS overdue,
+		       COALESCE((SELECT SUM(t.seconds_spent) FROM learning_subtopic_time t
+		                 WHERE t.username = e.username AND t.course_id = e.course_id), 0)
+		FROM user_course_enrollments e
+		JOIN courses c ON c.id = e.course_id
+		WHERE e.username = $1
+		ORDER BY e.enrolled_at DESC`, detail.Member.Username, overdueDays)
+	if err != nil {
+		return TeamMemberDetail{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cp TeamCourseProgress
+		if err := rows.Scan(&cp.CourseID, &cp.Title, &cp.EnrolledAt, &cp.CompletedAt, &cp.Overdue, &cp.SecondsSpent); err != nil {
+			return TeamMemberDetail{}, err
+		}
+		detail.Courses = append(detail.Courses, cp)
+	}
+	if err := rows.Err(); err != nil {
+		return TeamMemberDetail{}, err
+	}
+
+	examRows, err := db.Query(`
+		SELECT a.exam_id, e.title, COUNT(*),
+		       MAX(a.score_percent)::float8,
+		       (ARRAY_AGG(a.score_percent ORDER BY a.started_at DESC))[1]::float8,
+		       MAX(a.score_percent) >= $2,
+		       MAX(a.started_at)
+		FROM exam_attempts a
+		JOIN exams e ON e.id = a.exam_id
+		WHERE a.username = $1 AND a.finished_at IS NOT NULL
+		GROUP BY a.exam_id, e.title
+		ORDER BY MAX(a.started_at) DESC`, detail.Member.Username, ExamPassPercent)
+	if err != nil {
+		return TeamMemberDetail{}, err
+	}
+	defer examRows.Close()
+	for examRows.Next() {
+		var er TeamExamResult
+		if err := examRows.Scan(&er.ExamID, &er.Title, &er.Attempts, &er.BestScore, &er.LastScore, &er.Passed, &er.LastAttemptAt); err != nil {
+			return TeamMemberDetail{}, err
+		}
+		detail.Exams = append(detail.Exams, er)
+	}
+	return detail, examRows.Err()
+}
+
+// queryTeamProgress loads the manager's subtree, optionally narrowed to one member.
+func queryTeamProgress(manager string, overdueDays int, only string) ([]TeamMemberProgress, error) {
+	rows, err := db.Query(teamCTE+`
+		SELECT
+			u.username, u.name, u.employee_code, u.role_code, u.status,
+			COALESCE(u.manager_username, ''), MIN(t.depth),
+			COALESCE(en.enrolled, 0), COALESCE(en.completed, 0), COALESCE(en.overdue, 0),
+			COALESCE(ex.attempts, 0), COALESCE(ex.passed, 0), COALESCE(ex.avg_score, 0),
+			COALESCE(tm.seconds, 0),
+			ll.last_login
+		FROM team t
+		JOIN users u ON u.username = t.username
+		LEFT JOIN (
+			SELECT username,
+			       COUNT(*) AS enrolled,
+			       COUNT(completed_at) AS completed,
+			       COUNT(*) FILTER (WHERE completed_at IS NULL AND enrolled_at < NOW() - make_interval(days => $2)) AS overdue
+			FROM user_course_enrollments
+			GROUP BY username
+		) en ON en.username = u.username
+		LEFT JOIN (
+			SELECT username,
+			       COUNT(*) AS attempts,
+			       COUNT(DISTINCT exam_id) FILTER (WHERE score_percent >= $3) AS passed,
+			       AVG(score_percent)::float8 AS avg_score
+			FROM exam_attempts
+			WHERE finished_at IS NOT NULL
+			GROUP BY username
+		) ex ON ex.username = u.username
+		LEFT JOIN (
+			SELECT username, SUM(seconds_spent) AS seconds
+			FROM learning_subtopic_time
+			GROUP BY username
+		) tm ON tm.username = u.username
+		LEFT JOIN (
+			SELECT user_id, MAX(logged_in_at) AS last_login
+			FROM user_login_logs
+			GROUP BY user_id
+		) ll ON ll.user_id = u.id
+		WHERE ($4 = '' OR u.username = $4)
+		GROUP BY u.id, en.enrolled, en.completed, en.overdue, ex.attempts, ex.passed, ex.avg_score, tm.seconds, ll.last_login
+		ORDER BY MIN(t.depth), u.name`,
+		NormalizeUsername(manager), overdueDays, ExamPassPercent, only)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]TeamMemberProgress, 0)
+	for rows.Next() {
+		var m TeamMemberProgress
+		if err := rows.Scan(
+			&m.Username, &m.Name, &m.EmployeeCode, &m.Role, &m.Status,
+			&m.ManagerUsername, &m.Depth,
+			&m.CoursesEnrolled, &m.CoursesCompleted, &m.CoursesOverdue,
+			&m.ExamAttempts, &m.ExamsPassed, &m.AvgExamScore,
+			&m.SecondsSpent,
+			&m.LastLoginAt,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	usernames := make([]string, 0, len(result))
+	for _, m := range result {
+		usernames = append(usernames, m.Username)
+	}
+	overdue, err := CountOverdueAssignments(usernames)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		result[i].AssignmentsOverdue = overdue[result[i].Username]
+	}
+	return result, nil
+}
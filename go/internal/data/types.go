@@ -30,29 +30,37 @@ type Course struct {
 	SkillPoints             int           `json:"skillPoints"`
 	SubtopicCompletionScore int           `json:"subtopicCompletionScore"`
 	CourseCompletionScore   int           `json:"courseCompletionScore"`
+	ValidityDays            int           `json:"validityDays"` // 0 = completion never expires
+	EnrollmentMode          string        `json:"enrollmentMode"`
+	Capacity                int           `json:"capacity"` // seats in capacity mode; 0 = unlimited
 	CreatedAt               time.Time     `json:"createdAt"`
 	SkillRewards            []SkillReward `json:"skillRewards"`
 	LearnerCount            int           `json:"learnerCount"`
+	RatingAverage           float64       `json:"ratingAverage"` // visible reviews only; 0 = not rated yet
+	RatingCount             int           `json:"ratingCount"`
 }
 
 type Exam struct {
-	ID                string         `json:"id"`
-	Title             string         `json:"title"`
-	Creator           string         `json:"creator"`
-	OwnerUsername     string         `json:"ownerUsername"`
-	Status            string         `json:"status"`
-	Visibility        string         `json:"visibility"`
-	AllowedUsernames  []string       `json:"allowedUsernames"`
-	Description       string         `json:"description"`
-	Instructions      string         `json:"instructions"`
-	Image             string         `json:"image"`
-	NumberOfQuestions int            `json:"numberOfQuestions"`
-	DefaultTime       int            `json:"defaultTime"`
-	MaxAttempts       int            `json:"maxAttempts"`
-	CreatedAt         time.Time      `json:"createdAt"`
-	DomainPercentages map[string]int `json:"domainPercentages"`
-	Questions         []ExamQuestion `json:"questions"`
-	AttemptCount      int            `json:"attemptCount"`
+	ID                string            `json:"id"`
+	Title             string            `json:"title"`
+	Creator           string            `json:"creator"`
+	OwnerUsername     string            `json:"ownerUsername"`
+	Status            string            `json:"status"`
+	Visibility        string            `json:"visibility"`
+	AllowedUsernames  []string          `json:"allowedUsernames"`
+	Description       string            `json:"description"`
+	Instructions      string            `json:"instructions"`
+	Image             string            `json:"image"`
+	NumberOfQuestions int               `json:"numberOfQuestions"`
+	DefaultTime       int               `json:"defaultTime"`
+	MaxAttempts       int               `json:"maxAttempts"`
+	ValidityDays      int               `json:"validityDays"`    // 0 = pass never expires
+	CompletionScore   int               `json:"completionScore"` // points for the first pass
+	SkillRewards      []ExamSkillReward `json:"skillRewards"`
+	CreatedAt         time.Time         `json:"createdAt"`
+	DomainPercentages map[string]int    `json:"domainPercentages"`
+	Questions         []ExamQuestion    `json:"questions"`
+	AttemptCount      int               `json:"attemptCount"`
 }
 
 type ExamQuestion struct {
@@ -78,20 +86,23 @@ type PublicExamQuestion struct {
 
 // PublicExam is returned by the public GET /exams/:id endpoint (metadata only, no questions).
 type PublicExam struct {
-	ID                string         `json:"id"`
-	Title             string         `json:"title"`
-	Creator           string         `json:"creator"`
-	Status            string         `json:"status"`
-	Visibility        string         `json:"visibility"`
-	AllowedUsernames  []string       `json:"allowedUsernames"`
-	Description       string         `json:"description"`
-	Instructions      string         `json:"instructions"`
-	Image             string         `json:"image"`
-	NumberOfQuestions int            `json:"numberOfQuestions"`
-	DefaultTime       int            `json:"defaultTime"`
-	MaxAttempts       int            `json:"maxAttempts"`
-	CreatedAt         time.Time      `json:"createdAt"`
-	DomainPercentages map[string]int `json:"domainPercentages"`
+	ID                string            `json:"id"`
+	Title             string            `json:"title"`
+	Creator           string            `json:"creator"`
+	Status            string            `json:"status"`
+	Visibility        string            `json:"visibility"`
+	AllowedUsernames  []string          `json:"allowedUsernames"`
+	Description       string            `json:"description"`
+	Instructions      string            `json:"instructions"`
+	Image             string            `json:"image"`
+	NumberOfQuestions int               `json:"numberOfQuestions"`
+	DefaultTime       int               `json:"defaultTime"`
+	MaxAttempts       int               `json:"maxAttempts"`
+	ValidityDays      int               `json:"validityDays"`    // 0 = pass never expires
+	CompletionScore   int               `json:"completionScore"` // points for the first pass
+	SkillRewards      []ExamSkillReward `json:"skillRewards"`
+	CreatedAt         time.Time         `json:"createdAt"`
+	DomainPercentages map[string]int    `json:"domainPercentages"`
 }
 
 type ExamDomainStat struct {
@@ -112,16 +123,17 @@ type ExamAttemptAnswer struct {
 }
 
 type ExamAttempt struct {
-	ID             int64                      `json:"id"`
-	Username       string                     `json:"username,omitempty"`
-	ExamID         string                     `json:"examId,omitempty"`
-	CorrectCount   int                        `json:"correctCount"`
-	TotalQuestions int                        `json:"totalQuestions"`
-	ScorePercent   float64                    `json:"scorePercent"`
-	StartedAt      time.Time                  `json:"startedAt"`
-	FinishedAt     *time.Time                 `json:"finishedAt"`
-	DomainStats    map[string]ExamDomainStat  `json:"domainStats"`
-	Details        []ExamAttemptAnswer        `json:"details"`
+	ID             int64                     `json:"id"`
+	Username       string                    `json:"username,omitempty"`
+	ExamID         string                    `json:"examId,omitempty"`
+	CorrectCount   int                       `json:"correctCount"`
+	TotalQuestions int                       `json:"totalQuestions"`
+	ScorePercent   float64                   `json:"scorePercent"`
+	StartedAt      time.Time                 `json:"startedAt"`
+	FinishedAt     *time.Time                `json:"finishedAt"`
+	DomainStats    map[string]ExamDomainStat `json:"domainStats"`
+	Reward         *ExamReward               `json:"reward,omitempty"` // set on the first passing attempt
+	Details        []ExamAttemptAnswer       `json:"details"`
 }
 
 // AdminExamAttempt extends ExamAttempt with user and exam display fields for admin view.
@@ -162,9 +174,12 @@ type CourseProgress struct {
 	CompletedSubtopics map[string]bool                      `json:"completedSubtopics"`
 	Answers            map[string]map[string]AnswerProgress `json:"answers"`
 	TimeSpent          map[string]int                       `json:"timeSpent"`
+	LastSubtopicID     string                               `json:"lastSubtopicId,omitempty"`
 }
 
 type LeaderboardEntry struct {
+	Rank             int    `json:"rank"`
+	Score            int    `json:"score"` // points on the requested board
 	Username         string `json:"username"`
 	Name             string `json:"name"`
 	Role             string `json:"role"`
@@ -183,36 +198,50 @@ type PublicUserProfile struct {
 	CompletedCourses int            `json:"completedCourses"`
 	SolvedQuestions  int            `json:"solvedQuestions"`
 	SkillScores      map[string]int `json:"skillScores"`
+	Badges           []UserBadge    `json:"badges"`
 }
 
 type QnAQuestion struct {
-	ID         int64      `json:"id"`
-	CourseID   string     `json:"courseId"`
-	SubtopicID string     `json:"subtopicId"`
-	Username   string     `json:"username"`
-	Name       string     `json:"name"`
-	Question   string     `json:"question"`
-	CreatedAt  time.Time  `json:"createdAt"`
-	Replies    []QnAReply `json:"replies"`
+	ID              int64      `json:"id"`
+	CourseID        string     `json:"courseId"`
+	SubtopicID      string     `json:"subtopicId"`
+	CohortID        *int64     `json:"cohortId,omitempty"` // nil = course-wide
+	Username        string     `json:"username"`
+	Name            string     `json:"name"`
+	Question        string     `json:"question"`
+	Status          string     `json:"status"` // open | resolved
+	AcceptedReplyID *int64     `json:"acceptedReplyId"`
+	Hidden          bool       `json:"hidden"`
+	HiddenBy        string     `json:"hiddenBy,omitempty"`
+	EditedAt        *time.Time `json:"editedAt"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	Replies         []QnAReply `json:"replies"`
 }
 
 type QnAReply struct {
-	ID         int64     `json:"id"`
-	QuestionID int64     `json:"questionId"`
-	Username   string    `json:"username"`
-	Name       string    `json:"name"`
-	Reply      string    `json:"reply"`
-	CreatedAt  time.Time `json:"createdAt"`
+	ID           int64      `json:"id"`
+	QuestionID   int64      `json:"questionId"`
+	Username     string     `json:"username"`
+	Name         string     `json:"name"`
+	Reply        string     `json:"reply"`
+	IsInstructor bool       `json:"isInstructor"` // course owner or instructor of the question's cohort
+	Hidden       bool       `json:"hidden"`
+	HiddenBy     string     `json:"hiddenBy,omitempty"`
+	EditedAt     *time.Time `json:"editedAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
 }
 
 type AuthUser struct {
-	ID           int64     `json:"id"`
-	Name         string    `json:"name"`
-	Username     string    `json:"username"`
-	EmployeeCode string    `json:"employee_code"`
-	Role         string    `json:"role"`
-	Status       string    `json:"status"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	Username        string    `json:"username"`
+	EmployeeCode    string    `json:"employee_code"`
+	Role            string    `json:"role"`
+	Status          string    `json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
+	ManagerUsername string    `json:"manager_username"` // only loaded by ListUsers and SearchUsers
+	Email           string    `json:"email"`            // only loaded by ListUsers and SearchUsers
+	EmailVerified   bool      `json:"email_verified"`   // only loaded by ListUsers and SearchUsers
 }
 
 type AuthUserRecord struct {
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UserImportRow is one validated row of a bulk user import.
+// Password is only used when the row creates a new user.
+type UserImportRow struct {
+	Name         string
+	Username     string
+	EmployeeCode string
+	Role         string
+	Status       string
+	Password     string
+}
+
+// FindUsersByUsernames returns the existing users among usernames, keyed by username.
+func FindUsersByUsernames(usernames []string) (map[string]AuthUserRecord, error) {
+	result := make(map[string]AuthUserRecord)
+	if len(usernames) == 0 {
+		return result, nil
+	}
+	rows, err := db.Query(
+		`SELECT id, name, username, employee_code, password_hash, role_code, status, created_at
+		 FROM users
+		 WHERE username = ANY($1)`,
+		StringArray(usernames),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user AuthUserRecord
+		if err := rows.Scan(&user.ID, &user.Name, &user.Username, &user.EmployeeCode, &user.PasswordHash, &user.Role, &user.Status, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		result[user.Username] = user
+	}
+	return result, rows.Err()
+}
+
+// FindEmployeeCodeOwners returns, for each of codes held by an existing user, the
+// username of one user holding it.
+func FindEmployeeCodeOwners(codes []string) (map[string]string, error) {
+	result := make(map[string]string)
+	if len(codes) == 0 {
+		return result, nil
+	}
+	rows, err := db.Query(
+		`SELECT DISTINCT ON (employee_code) employee_code, username
+		 FROM users
+		 WHERE employee_code = ANY($1)
+		 ORDER BY employee_code, username`,
+		StringArray(codes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code, username string
+		if err := rows.Scan(&code, &username); err != nil {
+			return nil, err
+		}
+		result[code] = username
+	}
+	return result, rows.Err()
+}
+
+// ApplyUserImport creates or updates every row inside a single transaction.
+// Rows without a password get defaultPassword when they are created.
+func ApplyUserImport(rows []UserImportRow, defaultPassword string) (created, updated int, err error) {
+	passwordFor := func(row UserImportRow) string {
+		if row.Password == "" {
+			return defaultPassword
+		}
+		return row.Password
+	}
+
+	// bcrypt is slow by design; hash each distinct password of the rows that create
+	// a user once, before the transaction holds any locks.
+	usernames := make([]string, 0, len(rows))
+	for _, row := range rows {
+		usernames = append(usernames, NormalizeUsername(row.Username))
+	}
+	existing, err := FindUsersByUsernames(usernames)
+	if err != nil {
+		return 0, 0, err
+	}
+	hashes := make(map[string]string)
+	for _, row := range rows {
+		password := passwordFor(row)
+		if _, ok := existing[NormalizeUsername(row.Username)]; ok {
+			continue
+		}
+		if _, ok := hashes[password]; ok {
+			continue
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return 0, 0, err
+		}
+		hashes[password] = string(hashed)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for _, row := range rows {
+		var result sql.Result
+		result, err = tx.Exec(
+			`UPDATE users
+			 SET name = $2, employee_code = $3, role_code = $4, status = $5
+			 WHERE username = $1 AND role_code <> 'admin'`,
+			NormalizeUsername(row.Username), row.Name, NormalizeEmployeeCode(row.EmployeeCode), NormalizeRoleName(row.Role), row.Status,
+		)
+		if err != nil {
+			return 0, 0, err
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			updated++
+			continue
+		}
+
+		hashed, ok := hashes[passwordFor(row)]
+		if !ok {
+			err = fmt.Errorf("user %s was removed during the import", row.Username)
+			return 0, 0, err
+		}
+		if _, err = tx.Exec(
+			`INSERT INTO users (name, username, employee_code, password_hash, role_code, status)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			row.Name, NormalizeUsername(row.Username), NormalizeEmployeeCode(row.EmployeeCode), hashed, NormalizeRoleName(row.Role), row.Status,
+		); err != nil {
+			return 0, 0, err
+		}
+		created++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
@@ -87,7 +88,8 @@ func ListUsers(limit, offset int) ([]AuthUser, int, error) {
 	}
 
 	rows, err := db.Query(`
-SELECT id, name, username, employee_code, role_code, status, created_at
+SELECT id, name, username, employee_code, role_code, status, created_at, COALESCE(manager_username, ''),
+       email, email_verified_at IS NOT NULL
 FROM users
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2`, limit, offset)
@@ -99,7 +101,7 @@ LIMIT $1 OFFSET $2`, limit, offset)
 	result := make([]AuthUser, 0)
 	for rows.Next() {
 		var user AuthUser
-		if err := rows.Scan(&user.ID, &user.Name, &user.Username, &user.EmployeeCode, &user.Role, &user.Status, &user.CreatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Name, &user.Username, &user.EmployeeCode, &user.Role, &user.Status, &user.CreatedAt, &user.ManagerUsername, &user.Email, &user.EmailVerified); err != nil {
 			return nil, 0, err
 		}
 		result = append(result, user)
@@ -121,7 +123,27 @@ func UpdateUserName(userID int64, name string) (AuthUserRecord, error) {
 }
 
 func UpdateUserByUsername(username, name, role, status, employeeCode string) (AuthUserRecord, error) {
-	target, err := FindUserByUsername(username)
+	return UpdateUserWithManager(username, name, role, status, employeeCode, nil)
+}
+
+// UpdateUserWithManager is UpdateUserByUsername that also sets the user's line
+// manager when managerUsername is not nil (empty clears it). Both changes are
+// written in one transaction.
+func UpdateUserWithManager(username, name, role, status, employeeCode string, managerUsername *string) (AuthUserRecord, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return AuthUserRecord{}, err
+	}
+	defer tx.Rollback()
+
+	var target AuthUserRecord
+	err = tx.QueryRow(
+		`SELECT id, name, username, employee_code, password_hash, role_code, status, created_at
+		 FROM users
+		 WHERE username = $1
+		 FOR UPDATE`,
+		NormalizeUsername(username),
+	).Scan(&target.ID, &target.Name, &target.Username, &target.EmployeeCode, &target.PasswordHash, &target.Role, &target.Status, &target.CreatedAt)
 	if err != nil {
 		return AuthUserRecord{}, err
 	}
@@ -150,19 +172,37 @@ func UpdateUserByUsername(username, name, role, status, employeeCode string) (Au
 		nextEmployeeCode = normalizedEmployeeCode
 	}
 
+	if managerUsername != nil {
+		if err := setUserManager(tx, target.Username, *managerUsername); err != nil {
+			return AuthUserRecord{}, err
+		}
+	}
+
 	var updated AuthUserRecord
-	err = db.QueryRow(
+	err = tx.QueryRow(
 		`UPDATE users
-		 SET name = $2, role_code = $3, status = $4, employee_code = $5
+		 SET name = $2, role_code = $3, status = $4, employee_code = $5,
+		     activation_pending = activation_pending AND status = $4
 		 WHERE username = $1
 		 RETURNING id, name, username, employee_code, password_hash, role_code, status, created_at`,
-		NormalizeUsername(username),
+		target.Username,
 		nextName,
 		nextRole,
 		nextStatus,
 		nextEmployeeCode,
 	).Scan(&updated.ID, &updated.Name, &updated.Username, &updated.EmployeeCode, &updated.PasswordHash, &updated.Role, &updated.Status, &updated.CreatedAt)
-	return updated, err
+	if err != nil {
+		return AuthUserRecord{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return AuthUserRecord{}, err
+	}
+	if updated.Role != target.Role {
+		if nerr := NotifyRoleChanged([]string{updated.Username}, updated.Role); nerr != nil {
+			log.Printf("notify role change for %s: %v", updated.Username, nerr)
+		}
+	}
+	return updated, nil
 }
 
 func VerifyUserPassword(userID int64, rawPassword string) (bool, error) {
@@ -212,3 +252,149 @@ func GetLoginDatesByUserID(userID int64) ([]string, error) {
 	return dates, rows.Err()
 }
 
+
+// UserFilter narrows SearchUsers; empty fields are ignored. NameMatch selects how
+// Name is compared: "eq" (default), "co" (contains) or "sw" (starts with).
+type UserFilter struct {
+	Username     string
+	EmployeeCode string
+	Name         string
+	NameMatch    string
+	Role         string
+	ExcludeRole  string
+	Status       string
+}
+
+func SearchUsers(f UserFilter, limit, offset int) ([]AuthUser, int, error) {
+	fb := newFilterBuilder(`WHERE 1=1`)
+	if f.Username != "" {
+		fb.add(` AND username = $%d`, NormalizeUsername(f.Username))
+	}
+	if f.EmployeeCode != "" {
+		fb.add(` AND employee_code = $%d`, NormalizeEmployeeCode(f.EmployeeCode))
+	}
+	if f.Name != "" {
+		switch f.NameMatch {
+		case "co":
+			fb.add(` AND name ILIKE $%d`, "%"+f.Name+"%")
+		case "sw":
+			fb.add(` AND name ILIKE $%d`, f.Name+"%")
+		default:
+			fb.add(` AND name = $%d`, f.Name)
+		}
+	}
+	if f.Role != "" {
+		fb.add(` AND role_code = $%d`, NormalizeRoleName(f.Role))
+	}
+	if f.ExcludeRole != "" {
+		fb.add(` AND role_code <> $%d`, NormalizeRoleName(f.ExcludeRole))
+	}
+	if f.Status != "" {
+		fb.add(` AND status = $%d`, f.Status)
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users `+fb.where, fb.args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	lo := fb.limitOffset(limit, offset)
+	rows, err := db.Query(`
+SELECT id, name, username, employee_code, role_code, status, created_at, COALESCE(manager_username, ''),
+       email, email_verified_at IS NOT NULL
+FROM users `+fb.where+`
+ORDER BY id`+lo, fb.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	result := make([]AuthUser, 0)
+	for rows.Next() {
+		var user AuthUser
+		if err := rows.Scan(&user.ID, &user.Name, &user.Username, &user.EmployeeCode, &user.Role, &user.Status, &user.CreatedAt, &user.ManagerUsername, &user.Email, &user.EmailVerified); err != nil {
+			return nil, 0, err
+		}
+		result = append(result, user)
+	}
+	return result, total, rows.Err()
+}
+
+// MoveUsersToRole assigns toRole to the listed user ids (decimal strings). When
+// fromRole is non-empty only users currently holding fromRole are changed.
+// Admin accounts are never touched. It returns the number of users updated.
+func MoveUsersToRole(userIDs []string, fromRole, toRole string) (int64, error) {
+	moved, err := moveUsersToRole(db, userIDs, false, fromRole, toRole)
+	if err != nil {
+		return 0, err
+	}
+	if err := NotifyRoleChanged(moved, toRole); err != nil {
+		log.Printf("notify role change to %s: %v", toRole, err)
+	}
+	return int64(len(moved)), nil
+}
+
+// ReplaceRoleMembers makes userIDs the exact membership of role: other holders of
+// the role move back to "user" and the listed users move into it, both in one
+// transaction. Admin accounts are never touched.
+func ReplaceRoleMembers(role string, userIDs []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	left, err := moveUsersToRole(tx, userIDs, true, role, "user")
+	if err != nil {
+		return err
+	}
+	joined, err := moveUsersToRole(tx, userIDs, false, "", role)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := NotifyRoleChanged(left, "user"); err != nil {
+		log.Printf("notify role change to user: %v", err)
+	}
+	if err := NotifyRoleChanged(joined, role); err != nil {
+		log.Printf("notify role change to %s: %v", role, err)
+	}
+	return nil
+}
+
+// moveUsersToRole is MoveUsersToRole without notifications, returning the moved
+// usernames. With except it moves every holder of fromRole outside userIDs instead.
+func moveUsersToRole(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, userIDs []string, except bool, fromRole, toRole string) ([]string, error) {
+	if len(userIDs) == 0 && !except {
+		return nil, nil
+	}
+	where := `WHERE id = ANY($2::bigint[]) AND role_code <> 'admin'`
+	if except {
+		where = `WHERE NOT (id = ANY($2::bigint[])) AND role_code <> 'admin'`
+	}
+	fb := newFilterBuilder(where, NormalizeRoleName(toRole), StringArray(userIDs))
+	if fromRole != "" {
+		fb.add(` AND role_code = $%d`, NormalizeRoleName(fromRole))
+	}
+	fb.add(` AND role_code <> $1`)
+	rows, err := q.Query(`UPDATE users SET role_code = $1 `+fb.where+` RETURNING username`, fb.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moved []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		moved = append(moved, username)
+	}
+	return moved, rows.Err()
+}
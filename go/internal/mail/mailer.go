@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the SMTP settings. An empty Host disables sending.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // plain text, UTF-8
+}
+
+type Mailer struct {
+	cfg Config
+}
+
+func NewMailer(cfg Config) *Mailer {
+	return &Mailer{cfg: cfg}
+}
+
+func (m *Mailer) Enabled() bool {
+	return m.cfg.Host != ""
+}
+
+// Send delivers one message. STARTTLS is used when the server offers it; credentials
+// are only sent when a username is configured, so a local SMTP sink works without auth.
+func (m *Mailer) Send(msg Message) error {
+	if !m.Enabled() {
+		return fmt.Errorf("smtp is not configured")
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	return smtp.SendMail(addr, auth, envelopeAddress(m.cfg.From), []string{msg.To}, m.build(msg))
+}
+
+func (m *Mailer) build(msg Message) []byte {
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", m.cfg.From)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "base64")
+	b.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
+
+// envelopeAddress strips a display name: "LMS <noreply@example.com>" → noreply@example.com.
+func envelopeAddress(from string) string {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		return strings.TrimSuffix(strings.TrimSpace(from[i+1:]), ">")
+	}
+	return strings.TrimSpace(from)
+}
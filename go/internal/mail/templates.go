@@ -0,0 +1,196 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+const (
+	LocaleThai    = "th"
+	LocaleEnglish = "en"
+)
+
+type mailTemplate struct {
+	subject string
+	body    string
+}
+
+// templates maps template name → locale → subject and body. Both are text/template
+// strings rendered with the payload stored in the outbox plus "link" made absolute;
+// account mails get their link minted at delivery from "tokenId".
+var templates = map[string]map[string]mailTemplate{
+	"password_reset": {
+		LocaleThai: {
+			subject: "รหัสผ่านของคุณถูกรีเซ็ตโดยผู้ดูแลระบบ",
+			body: `สวัสดี {{.name}}
+
+ผู้ดูแลระบบได้รีเซ็ตรหัสผ่านของบัญชี {{.username}} เมื่อ {{.at}}
+ทุกอุปกรณ์ที่เคยเข้าสู่ระบบไว้ถูกออกจากระบบแล้ว กรุณาติดต่อผู้ดูแลระบบเพื่อรับรหัสผ่านใหม่
+
+หากคุณไม่ได้ร้องขอ กรุณาแจ้งผู้ดูแลระบบทันที
+`,
+		},
+		LocaleEnglish: {
+			subject: "Your password was reset by an administrator",
+			body: `Hello {{.name}},
+
+An administrator reset the password of account {{.username}} at {{.at}}.
+All devices have been signed out. Please contact your administrator for the new password.
+
+If you did not request this, tell your administrator immediately.
+`,
+		},
+	},
+	"password_reset_request": {
+		LocaleThai: {
+			subject: "ตั้งรหัสผ่านใหม่",
+			body: `สวัสดี {{.name}}
+
+มีการขอตั้งรหัสผ่านใหม่สำหรับบัญชี {{.username}}
+ตั้งรหัสผ่านใหม่ได้ที่ลิงก์ด้านล่าง ลิงก์นี้ใช้ได้ครั้งเดียวและหมดอายุใน {{.expiresInMinutes}} นาที
+
+{{.link}}
+
+หากคุณไม่ได้ร้องขอ ไม่ต้องดำเนินการใด ๆ รหัสผ่านเดิมยังใช้งานได้ตามปกติ
+`,
+		},
+		LocaleEnglish: {
+			subject: "Reset your password",
+			body: `Hello {{.name}},
+
+Someone asked to reset the password of account {{.username}}.
+Choose a new password with the link below. It works once and expires in {{.expiresInMinutes}} minutes.
+
+{{.link}}
+
+If you did not ask for this, you can ignore this mail; your password stays the same.
+`,
+		},
+	},
+	"email_verification": {
+		LocaleThai: {
+			subject: "ยืนยันอีเมลของคุณ",
+			body: `สวัสดี {{.name}}
+
+กรุณายืนยันว่า {{.email}} เป็นอีเมลของบัญชี {{.username}} โดยเปิดลิงก์ด้านล่าง
+ลิงก์นี้หมดอายุใน {{.expiresInHours}} ชั่วโมง
+
+{{.link}}
+`,
+		},
+		LocaleEnglish: {
+			subject: "Verify your email address",
+			body: `Hello {{.name}},
+
+Please confirm that {{.email}} belongs to account {{.username}} by opening the link below.
+The link expires in {{.expiresInHours}} hours.
+
+{{.link}}
+`,
+		},
+	},
+	"assignment_new": {
+		LocaleThai: {
+			subject: "งานที่ได้รับมอบหมายใหม่: {{.itemTitle}}",
+			body: `สวัสดี {{.name}}
+
+คุณได้รับมอบหมายให้ทำ "{{.itemTitle}}" กำหนดส่ง {{.dueDate}}
+{{if .note}}
+หมายเหตุ: {{.note}}
+{{end}}
+เริ่มได้ที่: {{.link}}
+`,
+		},
+		LocaleEnglish: {
+			subject: "New assignment: {{.itemTitle}}",
+			body: `Hello {{.name}},
+
+You have been assigned "{{.itemTitle}}", due {{.dueDate}}.
+{{if .note}}
+Note: {{.note}}
+{{end}}
+Start here: {{.link}}
+`,
+		},
+	},
+	"assignment_due": {
+		LocaleThai: {
+			subject: "ใกล้ถึงกำหนดส่ง: {{.itemTitle}}",
+			body: `สวัสดี {{.name}}
+
+"{{.itemTitle}}" จะถึงกำหนดส่งในวันที่ {{.dueDate}} และยังไม่เสร็จสิ้น
+
+ทำต่อได้ที่: {{.link}}
+`,
+		},
+		LocaleEnglish: {
+			subject: "Deadline approaching: {{.itemTitle}}",
+			body: `Hello {{.name}},
+
+"{{.itemTitle}}" is due on {{.dueDate}} and is not finished yet.
+
+Continue here: {{.link}}
+`,
+		},
+	},
+	"certificate_issued": {
+		LocaleThai: {
+			subject: "ยินดีด้วย! คุณผ่าน {{.itemTitle}}",
+			body: `สวัสดี {{.name}}
+
+คุณผ่าน "{{.itemTitle}}" เรียบร้อยแล้วเมื่อ {{.completedAt}}
+{{if .expiresAt}}ใบรับรองนี้มีอายุถึงวันที่ {{.expiresAt}}
+{{end}}
+ดูรายละเอียด: {{.link}}
+`,
+		},
+		LocaleEnglish: {
+			subject: "Congratulations! You completed {{.itemTitle}}",
+			body: `Hello {{.name}},
+
+You completed "{{.itemTitle}}" on {{.completedAt}}.
+{{if .expiresAt}}This certification is valid until {{.expiresAt}}.
+{{end}}
+Details: {{.link}}
+`,
+		},
+	},
+}
+
+// HasTemplate reports whether a template with this name exists.
+func HasTemplate(name string) bool {
+	_, ok := templates[name]
+	return ok
+}
+
+// Render fills the named template in the given locale, falling back to Thai.
+func Render(name, locale string, data map[string]string) (subject, body string, err error) {
+	byLocale, ok := templates[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown mail template %q", name)
+	}
+	t, ok := byLocale[locale]
+	if !ok {
+		t = byLocale[LocaleThai]
+	}
+	if subject, err = execute(name+".subject", t.subject, data); err != nil {
+		return "", "", err
+	}
+	if body, err = execute(name+".body", t.body, data); err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+func execute(name, text string, data map[string]string) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"backend/internal/auth"
+	"backend/internal/data"
+	"backend/internal/mail"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const assignmentReminderInterval = time.Hour
+
+// runAssignmentReminders periodically notifies assignees about assignments that are
+// due soon or overdue. Reminders are de-duplicated in the database, so running it on
+// several instances only costs extra queries.
+func runAssignmentReminders(withinDays int) {
+	ticker := time.NewTicker(assignmentReminderInterval)
+	defer ticker.Stop()
+	for {
+		sent, err := data.NotifyAssignmentsDue(withinDays)
+		if err != nil {
+			log.Printf("assignment reminders: %v", err)
+		} else if sent > 0 {
+			log.Printf("assignment reminders: sent %d", sent)
+		}
+		<-ticker.C
+	}
+}
+
+// runLeaderboardSnapshots periodically recomputes the leaderboard snapshots that
+// the leaderboard endpoints read.
+func runLeaderboardSnapshots(intervalMinutes int) {
+	if intervalMinutes <= 0 {
+		intervalMinutes = 10
+	}
+	ticker := time.NewTicker(time.Duration(intervalMinutes) * time.Minute)
+	defer ticker.Stop()
+	for {
+		if err := data.RefreshLeaderboardSnapshots(); err != nil {
+			log.Printf("leaderboard snapshots: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+const (
+	mailOutboxInterval = 15 * time.Second
+	mailOutboxBatch    = 20
+	mailOutboxLease    = 5 * time.Minute
+)
+
+// runMailOutbox delivers queued mails. Failed deliveries are retried with backoff by
+// the outbox itself; see data.MarkMailFailed.
+func runMailOutbox(mailer *mail.Mailer, baseURL string) {
+	ticker := time.NewTicker(mailOutboxInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			batch, err := data.ClaimOutboxMails(mailOutboxBatch, mailOutboxLease)
+			if err != nil {
+				log.Printf("mail outbox: %v", err)
+				break
+			}
+			for _, m := range batch {
+				deliverOutboxMail(mailer, baseURL, m)
+			}
+			if len(batch) < mailOutboxBatch {
+				break
+			}
+		}
+		<-ticker.C
+	}
+}
+
+func deliverOutboxMail(mailer *mail.Mailer, baseURL string, m data.OutboxMail) {
+	payload := m.Payload
+	if tokenID := payload["tokenId"]; tokenID != "" {
+		link, err := mintAccountTokenLink(tokenID)
+		if errors.Is(err, data.ErrInvalidToken) {
+			if err := data.DiscardMail(m.ID, err.Error()); err != nil {
+				log.Printf("mail outbox: discard %d: %v", m.ID, err)
+			}
+			return
+		}
+		if err != nil {
+			log.Printf("mail outbox: mail %d (%s): mint token: %v", m.ID, m.Template, err)
+			return
+		}
+		payload["link"] = link
+	}
+	if link := payload["link"]; strings.HasPrefix(link, "/") {
+		payload["link"] = baseURL + link
+	}
+	subject, body, err := mail.Render(m.Template, m.Locale, payload)
+	if err == nil {
+		err = mailer.Send(mail.Message{To: m.ToAddress, Subject: subject, Body: body})
+	}
+	if err != nil {
+		log.Printf("mail outbox: mail %d (%s) attempt %d: %v", m.ID, m.Template, m.Attempts+1, err)
+		if err := data.MarkMailFailed(m.ID, err.Error()); err != nil {
+			log.Printf("mail outbox: mark %d failed: %v", m.ID, err)
+		}
+		return
+	}
+	if err := data.MarkMailSent(m.ID); err != nil {
+		log.Printf("mail outbox: mark %d sent: %v", m.ID, err)
+	}
+}
+
+// mintAccountTokenLink issues the raw token of an account mail and returns the
+// relative link that redeems it. Only the token's hash is stored.
+func mintAccountTokenLink(tokenID string) (string, error) {
+	id, err := strconv.ParseInt(tokenID, 10, 64)
+	if err != nil {
+		return "", data.ErrInvalidToken
+	}
+	rawToken, tokenHash, err := auth.GenerateOneTimeToken()
+	if err != nil {
+		return "", err
+	}
+	path, err := data.MintAccountToken(id, tokenHash)
+	if err != nil {
+		return "", err
+	}
+	return path + "?token=" + rawToken, nil
+}
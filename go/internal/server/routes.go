@@ -30,8 +30,9 @@ func csrfProtection() fiber.Handler {
 func registerRoutes(app *fiber.App, cfg config.AppConfig) {
 	handler := api.NewHandler(cfg)
 
-	// Only serve course files statically; avatars are served via authenticated API
+	// Only serve course files and badge images statically; avatars are served via authenticated API
 	app.Static("/uploads/courses", "./uploads/courses")
+	app.Static("/uploads/badges", "./uploads/badges")
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
@@ -42,6 +43,22 @@ func registerRoutes(app *fiber.App, cfg config.AppConfig) {
 	app.Get("/swagger", swaggerUIHandler)
 	app.Get("/swagger/", swaggerUIHandler)
 
+	// SCIM 2.0 provisioning — bearer token instead of the session cookie, so no CSRF
+	scim := app.Group("/scim/v2", handler.SCIMAuth)
+	scim.Get("/ServiceProviderConfig", handler.GetSCIMServiceProviderConfig)
+	scim.Get("/Users", handler.ListSCIMUsers)
+	scim.Post("/Users", handler.CreateSCIMUser)
+	scim.Get("/Users/:id", handler.GetSCIMUser)
+	scim.Put("/Users/:id", handler.ReplaceSCIMUser)
+	scim.Patch("/Users/:id", handler.PatchSCIMUser)
+	scim.Delete("/Users/:id", handler.DeleteSCIMUser)
+	scim.Get("/Groups", handler.ListSCIMGroups)
+	scim.Post("/Groups", handler.CreateSCIMGroup)
+	scim.Get("/Groups/:id", handler.GetSCIMGroup)
+	scim.Put("/Groups/:id", handler.ReplaceSCIMGroup)
+	scim.Patch("/Groups/:id", handler.PatchSCIMGroup)
+	scim.Delete("/Groups/:id", handler.DeleteSCIMGroup)
+
 	api := app.Group("/api")
 
 	authGroup := api.Group("/auth")
@@ -60,6 +77,22 @@ func registerRoutes(app *fiber.App, cfg config.AppConfig) {
 	authGroup.Post("/refresh", handler.Refresh)
 	authGroup.Post("/logout", handler.Logout)
 
+	// Account recovery endpoints: limited much tighter than login to curb mail flooding and token guessing.
+	accountLimiter := limiter.New(limiter.Config{
+		Max:        cfg.RateLimitAccount,
+		Expiration: 15 * time.Minute,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return fiber.NewError(fiber.StatusTooManyRequests, "too many requests, please try again later")
+		},
+	})
+	authGroup.Post("/forgot-password", accountLimiter, handler.ForgotPassword)
+	authGroup.Post("/reset-password", accountLimiter, handler.ResetPassword)
+	authGroup.Post("/verify-email", accountLimiter, handler.VerifyEmail)
+	authGroup.Post("/resend-activation", accountLimiter, handler.ResendActivation)
+
 	publicLimiter := limiter.New(limiter.Config{
 		Max:        cfg.RateLimitPublic,
 		Expiration: 1 * time.Minute,
@@ -72,21 +105,35 @@ func registerRoutes(app *fiber.App, cfg config.AppConfig) {
 	})
 
 	// Courses, Exams & Leaderboard — GET is public (register before JWT middleware)
+	authGroup.Get("/registration", publicLimiter, handler.GetRegistrationSettings)
+	authGroup.Get("/invites/:token", publicLimiter, handler.GetInviteByToken)
 	api.Get("/courses", publicLimiter, handler.ListCourses)
 	api.Get("/courses/:id/images", publicLimiter, handler.GetCourseImages)
 	api.Get("/courses/:id/attachments", publicLimiter, handler.GetCourseAttachments)
+	api.Get("/courses/:id/reviews", publicLimiter, handler.ListCourseReviews)
 	api.Get("/exams", publicLimiter, handler.ListExams)
 	api.Get("/exams/:id", publicLimiter, handler.GetExam)
 	api.Get("/learning/leaderboard", publicLimiter, handler.GetLeaderboard)
+	api.Get("/paths", publicLimiter, handler.ListLearningPaths)
+	api.Get("/paths/:id", publicLimiter, handler.GetLearningPath)
 	api.Get("/courses/:courseId/qna", publicLimiter, handler.GetCourseQnA)
 	api.Get("/users/:username/profile", publicLimiter, handler.GetUserPublicProfile)
+	api.Get("/users/:username/avatar", publicLimiter, handler.GetUserAvatar)
+	api.Get("/calendar/:token", publicLimiter, handler.GetCalendarFeed)
 
-	protected := api.Group("")
-	protected.Use(jwtware.New(jwtware.Config{
+	requireJWT := jwtware.New(jwtware.Config{
 		SigningKey:   []byte(cfg.JWTSecret),
 		TokenLookup: "cookie:access_token",
-	}))
+	})
+
+	// Ending impersonation is the one write allowed inside an impersonation session,
+	// so it is registered before the read-only guard below.
+	authGroup.Post("/impersonation/end", requireJWT, csrfProtection(), handler.EndImpersonation)
+
+	protected := api.Group("")
+	protected.Use(requireJWT)
 	protected.Use(csrfProtection())
+	protected.Use(auth.RejectImpersonatedWrites)
 
 	authProtected := protected.Group("/auth")
 	authProtected.Get("/me", handler.Me)
@@ -97,29 +144,96 @@ func registerRoutes(app *fiber.App, cfg config.AppConfig) {
 	protected.Patch("/role/:code", auth.RequireAnyPermission(auth.PermissionRoleManage), handler.UpdateRole)
 	protected.Delete("/role/:code", auth.RequireAnyPermission(auth.PermissionRoleManage), handler.DeleteRole)
 	protected.Put("/role/:code/permissions", auth.RequireAnyPermission(auth.PermissionRoleManage), handler.UpdateRolePermissions)
+	protected.Get("/role/:code/competencies", auth.RequireAnyPermission(auth.PermissionRoleManage, auth.PermissionUserManage), handler.GetRoleCompetencies)
+	protected.Put("/role/:code/competencies", auth.RequireAnyPermission(auth.PermissionRoleManage), handler.UpdateRoleCompetencies)
+	protected.Get("/skills", handler.ListSkills)
 
 	profile := protected.Group("/profile")
 	profile.Patch("", handler.UpdateProfileName)
 	profile.Post("/change-password", handler.ChangePassword)
+	profile.Put("/email", handler.UpdateMyEmail)
+	profile.Post("/email/verification", accountLimiter, handler.ResendEmailVerification)
 	profile.Get("/avatar", handler.GetAvatar)
 	profile.Put("/avatar", handler.UpdateAvatar)
+	profile.Get("/export", handler.ExportMyData)
+	profile.Get("/notification-preferences", handler.GetNotificationPreferences)
+	profile.Put("/notification-preferences", handler.UpdateNotificationPreferences)
 
 	admin := protected.Group("/users", auth.RequireAnyPermission(auth.PermissionUserManage))
 	admin.Get("/options", handler.UserOptions)
 	admin.Get("/default-password", handler.GetDefaultResetPassword)
 	admin.Put("/default-password", handler.UpdateDefaultResetPassword)
+	admin.Get("/registration-settings", handler.GetRegistrationSettings)
+	admin.Put("/registration-settings", handler.UpdateRegistrationSettings)
 	admin.Get("", handler.ListUsers)
 	admin.Post("", handler.CreateUserByAdmin)
+	admin.Post("/import", handler.ImportUsers)
 	admin.Patch("/:username", handler.UpdateUserByAdmin)
 	admin.Post("/:username/reset-password", handler.ResetUserPasswordByAdmin)
+	admin.Post("/:username/anonymise", handler.AnonymiseUser)
+	admin.Get("/:username/score-events", handler.ListUserScoreEvents)
+	admin.Post("/:username/score-adjustments", handler.AdjustUserScore)
+	admin.Get("/:username/skill-gap", handler.GetUserSkillGap)
+
+	// Team progress — scoped to the caller's reporting subtree, no extra permission
+	team := protected.Group("/team")
+	team.Get("", handler.GetMyTeam)
+	team.Get("/enrollment-requests", handler.ListTeamEnrollmentRequests)
+	team.Get("/:username", handler.GetTeamMember)
+
+	protected.Get("/me/assignments", handler.GetMyAssignments)
+	protected.Get("/me/certifications", handler.GetMyCertifications)
+	protected.Get("/me/skill-gap", handler.GetMySkillGap)
+	protected.Get("/me/recommendations", handler.GetMyRecommendations)
+
+	notifications := protected.Group("/notifications")
+	notifications.Get("", handler.ListNotifications)
+	notifications.Get("/unread-count", handler.GetUnreadNotificationCount)
+	notifications.Get("/stream", handler.StreamNotifications)
+	notifications.Post("/read-all", handler.MarkAllNotificationsRead)
+	notifications.Post("/:id/read", handler.MarkNotificationRead)
+
+	// Impersonation has its own permission, so it lives outside the /users group middleware
+	protected.Post("/impersonate/:username", auth.RequireAnyPermission(auth.PermissionUserImpersonate), handler.StartImpersonation)
 
 	adminExams := protected.Group("/admin")
 	adminExams.Get("/exam-attempts", auth.RequireAnyPermission(auth.PermissionManagementExamHistory), handler.GetAllExamAttemptsAdmin)
 	adminExams.Get("/exam-attempts/:id", auth.RequireAnyPermission(auth.PermissionManagementExamHistory), handler.GetExamAttemptDetailsAdmin)
+	adminExams.Get("/impersonations", auth.RequireAnyPermission(auth.PermissionUserImpersonate), handler.ListImpersonationLogs)
+	adminExams.Get("/invites", auth.RequireAnyPermission(auth.PermissionUserManage), handler.ListInvites)
+	adminExams.Post("/invites", auth.RequireAnyPermission(auth.PermissionUserManage), handler.CreateInvite)
+	adminExams.Post("/invites/:id/revoke", auth.RequireAnyPermission(auth.PermissionUserManage), handler.RevokeInvite)
+	adminExams.Get("/groups", auth.RequireAnyPermission(auth.PermissionUserManage), handler.ListGroups)
+	adminExams.Post("/groups", auth.RequireAnyPermission(auth.PermissionUserManage), handler.CreateGroup)
+	adminExams.Delete("/groups/:id", auth.RequireAnyPermission(auth.PermissionUserManage), handler.DeleteGroup)
+	adminExams.Get("/groups/:id/members", auth.RequireAnyPermission(auth.PermissionUserManage), handler.ListGroupMembers)
+	adminExams.Put("/groups/:id/members", auth.RequireAnyPermission(auth.PermissionUserManage), handler.SetGroupMembers)
+	adminExams.Get("/assignments", auth.RequireAnyPermission(auth.PermissionAssignmentManage), handler.ListAssignments)
+	adminExams.Post("/assignments", auth.RequireAnyPermission(auth.PermissionAssignmentManage), handler.CreateAssignment)
+	adminExams.Get("/assignments/compliance", auth.RequireAnyPermission(auth.PermissionAssignmentManage), handler.GetComplianceReport)
+	adminExams.Put("/assignments/:id", auth.RequireAnyPermission(auth.PermissionAssignmentManage), handler.UpdateAssignment)
+	adminExams.Delete("/assignments/:id", auth.RequireAnyPermission(auth.PermissionAssignmentManage), handler.DeleteAssignment)
+	adminExams.Post("/courses/:id/enrollments", auth.RequireAnyPermission(auth.PermissionAssignmentManage), handler.BulkEnroll)
+	adminExams.Delete("/courses/:id/enrollments/:username", auth.RequireAnyPermission(auth.PermissionAssignmentManage), handler.AdminUnenroll)
+	adminExams.Get("/certifications/expiring", auth.RequireAnyPermission(auth.PermissionAssignmentManage), handler.ListExpiringCertifications)
+	adminExams.Post("/scores/reconcile", auth.RequireAnyPermission(auth.PermissionUserManage), handler.ReconcileScores)
 	adminExams.Get("/analytics", auth.RequireAnyPermission(auth.PermissionSystemReport), handler.GetAnalytics)
 	adminExams.Get("/analytics/courses/:courseId/learners", auth.RequireAnyPermission(auth.PermissionSystemReport), handler.GetCourseLearners)
 	adminExams.Get("/analytics/course-stats", auth.RequireAnyPermission(auth.PermissionContentManage), handler.GetCourseStats)
 	adminExams.Get("/analytics/courses/:courseId/detail", auth.RequireAnyPermission(auth.PermissionContentManage), handler.GetCourseDetailAnalytics)
+	adminExams.Get("/paths", auth.RequireAnyPermission(auth.PermissionContentManage), handler.ListLearningPathsAdmin)
+	adminExams.Post("/skills", auth.RequireAnyPermission(auth.PermissionContentManage), handler.CreateSkill)
+	adminExams.Put("/skills/:id", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UpdateSkill)
+	adminExams.Delete("/skills/:id", auth.RequireAnyPermission(auth.PermissionContentManage), handler.DeleteSkill)
+	adminExams.Post("/skills/:id/merge", auth.RequireAnyPermission(auth.PermissionContentManage), handler.MergeSkill)
+	adminExams.Put("/skill-levels", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UpdateSkillLevels)
+	adminExams.Get("/badges", auth.RequireAnyPermission(auth.PermissionContentManage), handler.ListBadgesAdmin)
+	adminExams.Post("/badges", auth.RequireAnyPermission(auth.PermissionContentManage), handler.CreateBadge)
+	adminExams.Put("/badges/:id", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UpdateBadge)
+	adminExams.Delete("/badges/:id", auth.RequireAnyPermission(auth.PermissionContentManage), handler.DeleteBadge)
+	adminExams.Post("/badges/:id/image", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UploadBadgeImage)
+	adminExams.Get("/badges/:id/awards", auth.RequireAnyPermission(auth.PermissionContentManage), handler.ListBadgeAwards)
+	adminExams.Get("/analytics/path-stats", auth.RequireAnyPermission(auth.PermissionContentManage), handler.GetPathStats)
 	adminExams.Get("/analytics/exam-stats", auth.RequireAnyPermission(auth.PermissionExamManage), handler.GetExamStats)
 	adminExams.Get("/analytics/exams/:examId/detail", auth.RequireAnyPermission(auth.PermissionExamManage), handler.GetExamDetailAnalytics)
 
@@ -130,6 +244,34 @@ func registerRoutes(app *fiber.App, cfg config.AppConfig) {
 	courses.Post("/:id/images", auth.RequireAnyPermission(auth.PermissionContentManage), handler.SaveCourseImage)
 	courses.Post("/:id/attachments", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UploadCourseAttachment)
 	courses.Delete("/:id/attachments/:attId", auth.RequireAnyPermission(auth.PermissionContentManage), handler.DeleteCourseAttachment)
+	courses.Get("/:id/reviews/manage", auth.RequireAnyPermission(auth.PermissionContentManage), handler.ListCourseReviewsForModeration)
+	courses.Post("/:id/reviews/:reviewId/hide", auth.RequireAnyPermission(auth.PermissionContentManage), handler.HideCourseReview)
+	courses.Post("/:id/reviews/:reviewId/unhide", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UnhideCourseReview)
+	courses.Put("/:id/reviews/:reviewId/reply", auth.RequireAnyPermission(auth.PermissionContentManage), handler.ReplyToCourseReview)
+	courses.Get("/:id/enrollments", auth.RequireAnyPermission(auth.PermissionContentManage), handler.ListCourseEnrollments)
+	courses.Get("/:id/cohorts", auth.RequireAnyPermission(auth.PermissionContentManage), handler.ListCohorts)
+	courses.Post("/:id/cohorts", auth.RequireAnyPermission(auth.PermissionContentManage), handler.CreateCohort)
+	courses.Put("/:id/cohorts/:cohortId", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UpdateCohort)
+	courses.Delete("/:id/cohorts/:cohortId", auth.RequireAnyPermission(auth.PermissionContentManage), handler.DeleteCohort)
+	courses.Get("/:id/cohorts/:cohortId/members", auth.RequireAnyPermission(auth.PermissionContentManage), handler.ListCohortMembers)
+	courses.Put("/:id/cohorts/:cohortId/members", auth.RequireAnyPermission(auth.PermissionContentManage), handler.SetCohortMembers)
+	courses.Post("/:id/live-sessions", auth.RequireAnyPermission(auth.PermissionContentManage), handler.CreateLiveSession)
+	courses.Put("/:id/live-sessions/:sessionId", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UpdateLiveSession)
+	courses.Delete("/:id/live-sessions/:sessionId", auth.RequireAnyPermission(auth.PermissionContentManage), handler.DeleteLiveSession)
+	// Managers approve their reports' requests without content permissions; the
+	// handler checks ownership or management.
+	courses.Post("/:id/enrollments/:username/approve", handler.ApproveEnrollment)
+	courses.Post("/:id/enrollments/:username/reject", handler.RejectEnrollment)
+	// Session instructors run their sessions without content permissions; the
+	// handlers check ownership or the session's instructor.
+	courses.Get("/:id/live-sessions", handler.ListManagedLiveSessions)
+	courses.Get("/:id/live-sessions/:sessionId/attendees", handler.ListLiveSessionAttendees)
+	courses.Put("/:id/live-sessions/:sessionId/attendance", handler.SetLiveSessionAttendance)
+	courses.Get("/:id/live-sessions/:sessionId/checkin-code", handler.GetCheckinCode)
+
+	paths := protected.Group("/paths")
+	paths.Post("", auth.RequireAnyPermission(auth.PermissionContentManage), handler.UpsertLearningPath)
+	paths.Delete("/:id", auth.RequireAnyPermission(auth.PermissionContentManage), handler.DeleteLearningPath)
 
 	// Exams — per-route permission to avoid Fiber Use-middleware stacking across groups
 	exams := protected.Group("/exams")
@@ -143,14 +285,73 @@ func registerRoutes(app *fiber.App, cfg config.AppConfig) {
 	exams.Get("/:id/attempts", auth.RequireAnyPermission(auth.PermissionSystemExamHistory), handler.GetExamAttempts)
 	exams.Post("/:id/attempts", auth.RequireAnyPermission(auth.PermissionExamTake), handler.SaveExamAttempt)
 
+	// Check-in codes are six digits: cap wrong guesses per learner and session.
+	checkinLimiter := limiter.New(limiter.Config{
+		Max:        cfg.RateLimitCheckin,
+		Expiration: 15 * time.Minute,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			username, _ := auth.CurrentUsername(c)
+			return username + ":" + c.Params("id")
+		},
+		SkipSuccessfulRequests: true,
+		LimitReached: func(c *fiber.Ctx) error {
+			return fiber.NewError(fiber.StatusTooManyRequests, "too many check-in attempts, please try again later")
+		},
+	})
+
 	// Learning progress
 	learning := protected.Group("/learning")
 	learning.Get("/progress", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetLearningProgress)
 	learning.Get("/scores", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetUserScores)
+	learning.Get("/badges", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetMyBadges)
 	learning.Post("/courses/:courseId/subtopics/:subtopicId/complete", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.MarkSubtopicComplete)
 	learning.Post("/courses/:courseId/subtopics/:subtopicId/answer", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.SubmitSubtopicAnswer)
 	learning.Post("/courses/:courseId/subtopics/:subtopicId/time", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.RecordSubtopicTime)
+	learning.Post("/courses/:courseId/subtopics/:subtopicId/sessions", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.StartReadingSession)
+	learning.Post("/sessions/:id/heartbeat", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.ReadingHeartbeat)
+	learning.Post("/sessions/:id/end", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.EndReadingSession)
+	learning.Post("/courses/:courseId/subtopics/:subtopicId/visit", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.VisitSubtopic)
+	learning.Get("/bookmarks", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.ListBookmarks)
+	learning.Put("/courses/:courseId/subtopics/:subtopicId/bookmark", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.AddBookmark)
+	learning.Delete("/courses/:courseId/subtopics/:subtopicId/bookmark", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.RemoveBookmark)
+	learning.Get("/courses/:courseId/notes", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.ListNotes)
+	learning.Get("/courses/:courseId/notes/export", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.ExportNotes)
+	learning.Post("/courses/:courseId/subtopics/:subtopicId/notes", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.CreateNote)
+	learning.Put("/notes/:id", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.UpdateNote)
+	learning.Delete("/notes/:id", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.DeleteNote)
+	learning.Get("/paths/:id/progress", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetPathProgress)
+	learning.Post("/paths/:id/enrollment", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.EnrollInPath)
+	learning.Delete("/paths/:id/enrollment", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.LeavePath)
 	learning.Post("/courses/:courseId/complete", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.CompleteCourse)
+	learning.Post("/courses/:courseId/recertify", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.RecertifyCourse)
+	learning.Get("/courses/:courseId/review", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetMyCourseReview)
+	learning.Put("/courses/:courseId/review", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.SaveCourseReview)
+	learning.Delete("/courses/:courseId/review", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.DeleteMyCourseReview)
+	learning.Get("/courses/:courseId/enrollment", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetEnrollment)
+	learning.Post("/courses/:courseId/enrollment", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.Enroll)
+	learning.Delete("/courses/:courseId/enrollment", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.Unenroll)
+	learning.Get("/courses/:courseId/cohort", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetMyCohort)
+	learning.Get("/courses/:courseId/live-sessions", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.ListLearnerLiveSessions)
+	learning.Post("/live-sessions/:id/registration", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.RegisterForLiveSession)
+	learning.Delete("/live-sessions/:id/registration", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.CancelLiveSessionRegistration)
+	learning.Post("/live-sessions/:id/checkin", auth.RequireAnyPermission(auth.PermissionContentLearn), checkinLimiter, handler.CheckInToLiveSession)
+	learning.Get("/live-sessions.ics", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.DownloadLiveSessionCalendar)
+	learning.Post("/calendar-feed", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.RotateCalendarFeed)
+	learning.Get("/leaderboard/me", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetMyLeaderboard)
+	learning.Get("/courses/:courseId/qna", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.GetCourseQnA)
 	learning.Post("/courses/:courseId/qna", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.PostQnAQuestion)
 	learning.Post("/qna/:questionId/reply", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.PostQnAReply)
+	learning.Put("/qna/:questionId", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.UpdateQnAQuestion)
+	learning.Delete("/qna/:questionId", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.DeleteQnAQuestion)
+	learning.Put("/qna/:questionId/accepted-reply", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.AcceptQnAReply)
+	learning.Put("/qna/:questionId/status", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.SetQnAStatus)
+	learning.Put("/qna/replies/:replyId", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.UpdateQnAReply)
+	learning.Delete("/qna/replies/:replyId", auth.RequireAnyPermission(auth.PermissionContentLearn), handler.DeleteQnAReply)
+
+	qna := protected.Group("/qna")
+	qna.Get("/courses/:courseId", auth.RequireAnyPermission(auth.PermissionQnAModerate), handler.GetCourseQnAForModeration)
+	qna.Post("/:questionId/hide", auth.RequireAnyPermission(auth.PermissionQnAModerate), handler.HideQnAQuestion)
+	qna.Post("/:questionId/unhide", auth.RequireAnyPermission(auth.PermissionQnAModerate), handler.UnhideQnAQuestion)
+	qna.Post("/replies/:replyId/hide", auth.RequireAnyPermission(auth.PermissionQnAModerate), handler.HideQnAReply)
+	qna.Post("/replies/:replyId/unhide", auth.RequireAnyPermission(auth.PermissionQnAModerate), handler.UnhideQnAReply)
 }
@@ -3,6 +3,7 @@ package server
 import (
 	"backend/internal/config"
 	"backend/internal/data"
+	"backend/internal/mail"
 	"fmt"
 	"log"
 )
@@ -30,10 +31,109 @@ func Start() error {
 		return fmt.Errorf("ensure exam schema failed: %w", err)
 	}
 
+	if err := data.EnsureImpersonationSchema(); err != nil {
+		return fmt.Errorf("ensure impersonation schema failed: %w", err)
+	}
+
+	if err := data.EnsureTeamSchema(); err != nil {
+		return fmt.Errorf("ensure team schema failed: %w", err)
+	}
+
+	if err := data.EnsureCertificationSchema(); err != nil {
+		return fmt.Errorf("ensure certification schema failed: %w", err)
+	}
+
+	if err := data.EnsurePathSchema(); err != nil {
+		return fmt.Errorf("ensure path schema failed: %w", err)
+	}
+
+	if err := data.EnsureAssignmentSchema(); err != nil {
+		return fmt.Errorf("ensure assignment schema failed: %w", err)
+	}
+
+	if err := data.EnsureNotificationSchema(); err != nil {
+		return fmt.Errorf("ensure notification schema failed: %w", err)
+	}
+
+	if err := data.EnsureMailSchema(); err != nil {
+		return fmt.Errorf("ensure mail schema failed: %w", err)
+	}
+
+	if err := data.EnsureAccountEmailSchema(); err != nil {
+		return fmt.Errorf("ensure account email schema failed: %w", err)
+	}
+
+	if err := data.EnsureGroupSchema(); err != nil {
+		return fmt.Errorf("ensure group schema failed: %w", err)
+	}
+
+	if err := data.EnsureRegistrationSchema(); err != nil {
+		return fmt.Errorf("ensure registration schema failed: %w", err)
+	}
+
+	if err := data.EnsureLearningNotesSchema(); err != nil {
+		return fmt.Errorf("ensure learning notes schema failed: %w", err)
+	}
+
+	if err := data.EnsureReadingSessionSchema(); err != nil {
+		return fmt.Errorf("ensure reading session schema failed: %w", err)
+	}
+
+	if err := data.EnsureCourseReviewSchema(); err != nil {
+		return fmt.Errorf("ensure course review schema failed: %w", err)
+	}
+	if err := data.EnsureEnrollmentSchema(); err != nil {
+		return fmt.Errorf("ensure enrollment schema failed: %w", err)
+	}
+	if err := data.EnsureCohortSchema(); err != nil {
+		return fmt.Errorf("ensure cohort schema failed: %w", err)
+	}
+	if err := data.EnsureLiveSessionSchema(); err != nil {
+		return fmt.Errorf("ensure live session schema failed: %w", err)
+	}
+	if err := data.EnsureBadgeSchema(); err != nil {
+		return fmt.Errorf("ensure badge schema failed: %w", err)
+	}
+	if err := data.EnsureScoreLedgerSchema(); err != nil {
+		return fmt.Errorf("ensure score ledger schema failed: %w", err)
+	}
+	if err := data.EnsureExamRewardSchema(); err != nil {
+		return fmt.Errorf("ensure exam reward schema failed: %w", err)
+	}
+	if err := data.EnsureSkillSchema(); err != nil {
+		return fmt.Errorf("ensure skill schema failed: %w", err)
+	}
+	if err := data.EnsureLeaderboardSchema(); err != nil {
+		return fmt.Errorf("ensure leaderboard schema failed: %w", err)
+	}
+	if err := data.EnsureQnASchema(); err != nil {
+		return fmt.Errorf("ensure qna schema failed: %w", err)
+	}
+
+	// Must stay last: it rewrites foreign keys to users(username) added by the schemas above.
+	if err := data.EnsurePrivacySchema(); err != nil {
+		return fmt.Errorf("ensure privacy schema failed: %w", err)
+	}
+
 	if err := data.SeedExamsFromDir(cfg.ExamSeedDir); err != nil {
 		log.Printf("seed exams warning: %v", err)
 	}
 
+	go runAssignmentReminders(cfg.AssignmentRemindDays)
+	go runLeaderboardSnapshots(cfg.LeaderboardMinutes)
+	mailer := mail.NewMailer(mail.Config{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+	if mailer.Enabled() {
+		go runMailOutbox(mailer, cfg.AppBaseURL)
+	} else {
+		log.Printf("SMTP_HOST is not set — mails stay queued in mail_outbox")
+	}
+
 	app := newFiberApp(cfg)
 	registerRoutes(app, cfg)
 